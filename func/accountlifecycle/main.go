@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Poll(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler handles the account lifecycle events of the users. A failed purge is reported as a failed message,
+// so that SQS redelivers it and the purge is completed by a later attempt.
+func Handler(ctx context.Context, event events.SQSEvent) (sqs.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return sqs.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*sqs.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, err := s3.NewS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := sqs.NewConsumer(sqsClient, sqs.ConsumerConfig{
+		QueueURL:           os.Getenv("ACCOUNT_LIFECYCLE_QUEUE_URL"),
+		DeadLetterQueueURL: os.Getenv("ACCOUNT_LIFECYCLE_DEAD_LETTER_QUEUE_URL"),
+	})
+
+	consumer.Handle(helpers.AccountCreated, func(ctx context.Context, message *sqs.Message) error {
+		event, err := parseEvent(message)
+		if err != nil {
+			return err
+		}
+
+		// The records of the user are created with the first bookmarks, there is nothing to provision.
+		log.Info().Msgf("Account created for userId %s", event.UserId)
+		return nil
+	})
+
+	consumer.Handle(helpers.AccountSuspended, func(ctx context.Context, message *sqs.Message) error {
+		event, err := parseEvent(message)
+		if err != nil {
+			return err
+		}
+
+		if err = helpers.SuspendAccount(dynamodbClient, event.UserId); err != nil {
+			return err
+		}
+		log.Info().Msgf("Account suspended for userId %s: %s", event.UserId, event.Reason)
+		return nil
+	})
+
+	consumer.Handle(helpers.AccountDeleted, func(ctx context.Context, message *sqs.Message) error {
+		event, err := parseEvent(message)
+		if err != nil {
+			return err
+		}
+
+		report, err := helpers.PurgeAccount(dynamodbClient, s3Client, event)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("Account purged for userId %s: %s", event.UserId, helpers.GetPurgeReportS3Path(report))
+		return nil
+	})
+
+	return consumer, nil
+}
+
+func parseEvent(message *sqs.Message) (*models.AccountLifecycleEvent, error) {
+	event := &models.AccountLifecycleEvent{}
+
+	if err := json.Unmarshal([]byte(message.Body), event); err != nil {
+		return nil, fmt.Errorf("%w: invalid account lifecycle message: %v", sqs.ErrPoisonMessage, err)
+	}
+	if event.UserId == "" {
+		return nil, fmt.Errorf("%w: account lifecycle message without userId", sqs.ErrPoisonMessage)
+	}
+	return event, nil
+}
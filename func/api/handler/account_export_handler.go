@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+)
+
+// PostAccountExport starts the export of all the data of the user, which is polled by its job id
+// until the archive is ready to be downloaded.
+func PostAccountExport(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	sqsClient, err := NewSQSClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	job, err := helpers.StartExport(dynamodbClient, sqsClient, userId)
+	if errors.Is(err, helpers.ErrExportInProgress) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, err.Error(), err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	auditChange(context, helpers.AuditExportStarted, "", "")
+	context.Header("Location", context.Request.URL.Path+"/"+job.JobId)
+	context.JSON(http.StatusAccepted, toExportJobResponse(job))
+}
+
+// GetAccountExport returns the status of the export job, with a presigned download url of the archive once
+// the export is completed.
+func GetAccountExport(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	job, err := helpers.GetExportJob(dynamodbClient, userId, context.Param("jobId"))
+	if errors.Is(err, helpers.ErrExportJobNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "export job not found"})
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	response := toExportJobResponse(job)
+	if job.Status == constant.Success {
+		s3Client, err := NewS3Client()
+		if err != nil {
+			helpers.SendInternalError(context, err)
+			return
+		}
+
+		response.DownloadURL, err = helpers.GetExportDownloadURL(s3Client, job)
+		if err != nil {
+			helpers.SendInternalError(context, err)
+			return
+		}
+
+		expires := helpers.TimeNow().Add(helpers.ExportURLLifetime * time.Second).UTC()
+		response.URLExpires = &expires
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+func toExportJobResponse(job *model.ExportJob) *models.ExportJobResponse {
+	response := &models.ExportJobResponse{
+		JobId:         job.JobId,
+		Status:        job.Status,
+		StatusMessage: job.StatusMessage,
+		Created:       job.CreatedTimestamp,
+		SizeBytes:     job.SizeBytes,
+	}
+
+	if !job.EndTimestamp.IsZero() {
+		response.Completed = &job.EndTimestamp
+	}
+	return response
+}
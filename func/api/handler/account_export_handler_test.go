@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	pkgSQS "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountExportTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	mockSQSClient      *sqsMocks.MockSQSClient
+	mockTimeNow        time.Time
+}
+
+func TestAccountExportSuite(t *testing.T) {
+	suite.Run(t, new(AccountExportTestSuite))
+}
+
+func (s *AccountExportTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AccountExportTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	NewS3Client = func() (pkgS3.S3Client, error) {
+		return s.mockS3Client, nil
+	}
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+	NewSQSClient = func() (pkgSQS.SQSClient, error) {
+		return s.mockSQSClient, nil
+	}
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	helpers.TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
+}
+
+func (s *AccountExportTestSuite) TestPostAccountExport() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.ExportJob{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.ExportJob{}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.ExportJob{})).Return(nil)
+	s.mockSQSClient.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	PostAccountExport(s.context)
+
+	var response models.ExportJobResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusAccepted, s.recorder.Code)
+	s.NotEmpty(response.JobId)
+	s.Equal(constant.Pending, response.Status)
+	s.Empty(response.DownloadURL)
+}
+
+func (s *AccountExportTestSuite) TestPostAccountExportWhenInProgress() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.ExportJob{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.ExportJob{{UserId: "1", JobId: "j1", Status: constant.Pending,
+			CreatedTimestamp: s.mockTimeNow}}, nil, nil)
+
+	PostAccountExport(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+}
+
+func (s *AccountExportTestSuite) TestGetAccountExport() {
+	mockutil.MockJSONRequest(s.context, "GET", []gin.Param{{Key: "jobId", Value: "j1"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.ExportJob{UserId: "1", JobId: "j1"})).
+		Return(&model.ExportJob{UserId: "1", JobId: "j1", Status: constant.Success, S3Key: "Exports/1/j1.zip",
+			SizeBytes: 2048, CreatedTimestamp: s.mockTimeNow.Add(-time.Minute), EndTimestamp: s.mockTimeNow}, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Exports/1/j1.zip"),
+		gomock.Eq(int64(helpers.ExportURLLifetime))).Return("https://signed/Exports/1/j1.zip", nil)
+
+	GetAccountExport(s.context)
+
+	var response models.ExportJobResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(constant.Success, response.Status)
+	s.Equal(int64(2048), response.SizeBytes)
+	s.Equal("https://signed/Exports/1/j1.zip", response.DownloadURL)
+	s.Equal(s.mockTimeNow.Add(helpers.ExportURLLifetime*time.Second), *response.URLExpires)
+}
+
+func (s *AccountExportTestSuite) TestGetAccountExportNotFound() {
+	mockutil.MockJSONRequest(s.context, "GET", []gin.Param{{Key: "jobId", Value: "j1"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.ExportJob{UserId: "1", JobId: "j1"})).
+		Return(nil, nil)
+
+	GetAccountExport(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	NewAnalyticsEmitter = helpers.NewAnalyticsEmitter
+
+	analyticsOnce    sync.Once
+	analyticsEmitter helpers.AnalyticsEmitter
+)
+
+// getAnalyticsEmitter returns the emitter shared by the requests of the Lambda instance, so that their events
+// are batched together, or nil when the analytics are disabled.
+func getAnalyticsEmitter() helpers.AnalyticsEmitter {
+	analyticsOnce.Do(func() {
+		var err error
+		if analyticsEmitter, err = NewAnalyticsEmitter(); err != nil {
+			log.Error().Msgf("Failure in creating analytics emitter: %v", err)
+		}
+	})
+	return analyticsEmitter
+}
+
+// trackEvent records the usage of the feature by the user. The failure to track never fails the request.
+func trackEvent(context *gin.Context, eventType string, properties map[string]interface{}) {
+	emitter := getAnalyticsEmitter()
+	if emitter == nil {
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	if err := helpers.EmitAnalyticsEvent(emitter, eventType, userId, properties); err != nil {
+		log.Error().Msgf("Failure in emitting analytics event %s for userId %s: %v", eventType, userId, err)
+	}
+}
+
+// CloseAnalytics flushes the buffered analytics events before the Lambda instance shuts down.
+func CloseAnalytics() {
+	if emitter := getAnalyticsEmitter(); emitter != nil {
+		if err := emitter.Close(); err != nil {
+			log.Error().Msgf("Failure in flushing analytics events: %v", err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/jwt"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	auditActionCxt        = "AUDIT_ACTION"
+	auditVersionBeforeCxt = "AUDIT_VERSION_BEFORE"
+	auditVersionAfterCxt  = "AUDIT_VERSION_AFTER"
+)
+
+var (
+	NewAuditSink = helpers.NewAuditSink
+)
+
+// Audit records the changes of the mutating handlers once they succeed. The handlers describe their change with
+// auditChange, while the failed requests changed nothing and are not recorded.
+func Audit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action := c.GetString(auditActionCxt)
+		if action == "" || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		userId := c.GetString(middleware.UserIDCxt)
+		record := &model.AuditRecord{
+			UserId:        userId,
+			Actor:         c.GetString(middleware.PrincipalCxt),
+			Role:          c.GetString(middleware.RoleCxt),
+			Action:        action,
+			List:          userId,
+			VersionBefore: c.GetString(auditVersionBeforeCxt),
+			VersionAfter:  c.GetString(auditVersionAfterCxt),
+			RequestId:     c.GetHeader("X-Request-Id"),
+			SourceIP:      c.ClientIP(),
+		}
+
+		if apiGWReqContext, ok := core.GetAPIGatewayContextFromContext(c.Request.Context()); ok {
+			record.RequestId = apiGWReqContext.RequestID
+			record.SourceIP = apiGWReqContext.Identity.SourceIP
+		}
+
+		// The failure to audit never fails the request, since the change is already stored.
+		if err := recordAudit(record); err != nil {
+			log.Error().Msgf("Failure in recording audit of %s for userId %s: %v", action, userId, err)
+		}
+	}
+}
+
+func recordAudit(record *model.AuditRecord) error {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	sink, err := NewAuditSink()
+	if err != nil {
+		return err
+	}
+
+	return helpers.RecordAudit(dynamodbClient, sink, record)
+}
+
+// auditChange describes the change of the bookmark list made by the handler, which is audited if the request succeeds.
+func auditChange(context *gin.Context, action, versionBefore, versionAfter string) {
+	context.Set(auditActionCxt, action)
+	context.Set(auditVersionBeforeCxt, versionBefore)
+	context.Set(auditVersionAfterCxt, versionAfter)
+}
+
+func getLatestVersion(userBookmarks *model.UserBookmarks) string {
+	if userBookmarks == nil {
+		return ""
+	}
+	return userBookmarks.LatestVersion
+}
+
+// GetBookmarksAudit returns the recent changes to the bookmarks of the account from the latest change,
+// to the account admins and auditors only.
+func GetBookmarksAudit(context *gin.Context) {
+	if !isAuditAllowed(context.GetString(middleware.RoleCxt)) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "audit log is restricted to account admins"})
+		return
+	}
+
+	query := &helpers.AuditQuery{
+		Action: context.Query("action"),
+		Next:   context.Query("next"),
+		Limit:  helpers.DefaultPageSize,
+	}
+
+	for param, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if rawValue := context.Query(param); rawValue != "" {
+			parsedTime, err := time.Parse(time.RFC3339, rawValue)
+			if err != nil {
+				helpers.SendCustomErrorMessage(context, http.StatusBadRequest, param+" must be a RFC 3339 timestamp", err)
+				return
+			}
+			*value = parsedTime
+		}
+	}
+
+	if limit, err := strconv.Atoi(context.Query("limit")); err == nil && limit > 0 {
+		query.Limit = limit
+	}
+	if query.Limit > helpers.MaxPageSize {
+		query.Limit = helpers.MaxPageSize
+	}
+
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	records, next, err := helpers.GetAuditRecords(dynamodbClient, userId, query)
+	if errors.Is(err, dynamodb.ErrInvalidPageToken) {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid next page token", err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	entries := make([]models.AuditEntry, 0, len(records))
+	for i := range records {
+		entries = append(entries, *helpers.ToAuditEntry(&records[i]))
+	}
+
+	context.JSON(http.StatusOK, &models.AuditResponse{
+		Entries:    entries,
+		TotalCount: len(entries),
+		Next:       next,
+	})
+}
+
+// isAuditAllowed permits the full access and auditor roles, and the local requests whose tokens carry no role.
+func isAuditAllowed(role string) bool {
+	switch role {
+	case jwt.RoleFullAccess.String(), jwt.RoleAuditor.String():
+		return true
+	case jwt.RoleUnknown.String():
+		return env.IsLocalOrTestEnv()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/jwt"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockTimeNow        time.Time
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
+
+func (s *AuditTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AuditTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+	s.context.Set(middleware.RoleCxt, jwt.RoleAuditor.String())
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+	NewAuditSink = func() (helpers.AuditSink, error) {
+		return nil, nil
+	}
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	helpers.TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *AuditTestSuite) serveAudited(status int) {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.UserIDCxt, "1")
+		c.Set(middleware.PrincipalCxt, "urn:emprovise:identity:us-east-1:1:user/admin")
+		c.Set(middleware.RoleCxt, jwt.RoleFullAccess.String())
+	}, Audit())
+	router.POST("/bookmarks", func(c *gin.Context) {
+		auditChange(c, helpers.AuditBookmarksAdded, "1.0.1", "1.0.2")
+		c.Status(status)
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/bookmarks", nil)
+	request.Header.Set("X-Request-Id", "r1")
+	router.ServeHTTP(s.recorder, request)
+}
+
+func (s *AuditTestSuite) TestAuditRecordsChange() {
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.AuditRecord{})).
+		DoAndReturn(func(entity interface{}) error {
+			record := entity.(*model.AuditRecord)
+			s.Equal("1", record.UserId)
+			s.Equal("urn:emprovise:identity:us-east-1:1:user/admin", record.Actor)
+			s.Equal("full-access", record.Role)
+			s.Equal(helpers.AuditBookmarksAdded, record.Action)
+			s.Equal("1", record.List)
+			s.Equal("1.0.1", record.VersionBefore)
+			s.Equal("1.0.2", record.VersionAfter)
+			s.Equal("r1", record.RequestId)
+			s.Equal("192.0.2.1", record.SourceIP)
+			return nil
+		})
+
+	s.serveAudited(http.StatusCreated)
+
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+}
+
+func (s *AuditTestSuite) TestAuditSkipsFailedChange() {
+	// The failed request changed nothing, hence there is no audit record to add.
+	s.serveAudited(http.StatusForbidden)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+}
+
+func (s *AuditTestSuite) TestGetBookmarksAudit() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", []gin.Param{{Key: "action", Value: "bookmarks.added"},
+		{Key: "limit", Value: "20"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.AuditRecord{}),
+		gomock.Any(), gomock.Not(gomock.Nil()), gomock.Eq(int32(20)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.AuditRecord{{UserId: "1", RecordId: "20091110235234.000000-0a1b2c3d", Actor: "1",
+			Role: "full-access", Action: helpers.AuditBookmarksAdded, List: "1", VersionBefore: "1.0.1",
+			VersionAfter: "1.0.2", Timestamp: s.mockTimeNow}}, nil, nil)
+
+	GetBookmarksAudit(s.context)
+
+	var response models.AuditResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(1, response.TotalCount)
+	s.Equal("20091110235234.000000-0a1b2c3d", response.Entries[0].Id)
+	s.Equal("1.0.2", response.Entries[0].VersionAfter)
+}
+
+func (s *AuditTestSuite) TestGetBookmarksAuditWithInvalidTime() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", []gin.Param{{Key: "from", Value: "yesterday"}}, nil)
+
+	GetBookmarksAudit(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *AuditTestSuite) TestGetBookmarksAuditForReadOnlyRole() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+	s.context.Set(middleware.RoleCxt, jwt.RoleReadOnly.String())
+
+	GetBookmarksAudit(s.context)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+}
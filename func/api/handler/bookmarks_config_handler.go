@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+)
+
+func GetBookmarksConfig(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	userBookmarks := helpers.GetBookmarkByUser(dynamodbClient, userId)
+
+	config := helpers.GetBookmarksConfig(userBookmarks)
+	context.JSON(http.StatusOK, &config)
+}
+
+// PutBookmarksConfig updates the settings passed in the payload, keeping the current value of the omitted settings.
+func PutBookmarksConfig(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	userBookmarks := helpers.GetBookmarkByUser(dynamodbClient, userId)
+
+	if helpers.IsDistributionPending(userBookmarks) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "distribution is in Progress"})
+		return
+	}
+
+	config := helpers.GetBookmarksConfig(userBookmarks)
+	if err = context.BindJSON(&config); err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid json payload", err)
+		return
+	}
+
+	if err = helpers.ValidateBookmarksConfig(&config); err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if err = helpers.UpdateBookmarksConfig(dynamodbClient, userId, &config); err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	latestVersion := getLatestVersion(userBookmarks)
+	auditChange(context, helpers.AuditConfigUpdated, latestVersion, latestVersion)
+	context.JSON(http.StatusOK, &config)
+}
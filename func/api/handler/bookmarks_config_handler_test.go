@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarksConfigTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+}
+
+func TestBookmarksConfigSuite(t *testing.T) {
+	suite.Run(t, new(BookmarksConfigTestSuite))
+}
+
+func (s *BookmarksConfigTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *BookmarksConfigTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+}
+
+func (s *BookmarksConfigTestSuite) TestGetBookmarksConfigDefaults() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(nil, nil)
+
+	GetBookmarksConfig(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(`{"enabled":true,"autoDistribute":false,"packageFormat":"tar.gz","pageSize":100,"enrichmentEnabled":true,`+
+		`"distributionTimeoutMinutes":10}`, s.recorder.Body.String())
+}
+
+func (s *BookmarksConfigTestSuite) TestGetBookmarksConfig() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:              "1",
+			SyncEnabled:         false,
+			AutoDistribute:      true,
+			PageSize:            25,
+			EnrichmentDisabled:  true,
+			LatestVersion:       "1.0.89",
+			DistributionTimeout: 30,
+		}, nil)
+
+	GetBookmarksConfig(s.context)
+
+	var config models.BookmarksConfig
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &config)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(models.BookmarksConfig{AutoDistribute: true, PackageFormat: "tar.gz", PageSize: 25,
+		DistributionTimeout: 30}, config)
+}
+
+func (s *BookmarksConfigTestSuite) TestPutBookmarksConfig() {
+	mockutil.MockJSONRequest(s.context, "PUT", nil, map[string]interface{}{"autoDistribute": true, "pageSize": 50,
+		"distributionTimeoutMinutes": 60})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", SyncEnabled: true, Status: constant.Success, LatestVersion: "1.0.89"}, nil)
+
+	var updated model.UserBookmarks
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}), gomock.Any()).
+		DoAndReturn(func(_ interface{}, expr expression.Expression) error {
+			return attributevalue.UnmarshalMap(setValues(&expr), &updated)
+		})
+
+	PutBookmarksConfig(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(model.UserBookmarks{UserId: "1", SyncEnabled: true, AutoDistribute: true, PackageFormat: "tar.gz",
+		PageSize: 50, DistributionTimeout: 60}, updated)
+}
+
+func (s *BookmarksConfigTestSuite) TestPutBookmarksConfigWithoutBookmarks() {
+	mockutil.MockJSONRequest(s.context, "PUT", nil, map[string]interface{}{"enabled": false})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(nil, nil)
+
+	var updated model.UserBookmarks
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}), gomock.Any()).
+		DoAndReturn(func(_ interface{}, expr expression.Expression) error {
+			return attributevalue.UnmarshalMap(setValues(&expr), &updated)
+		})
+
+	PutBookmarksConfig(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(model.UserBookmarks{UserId: "1", PackageFormat: "tar.gz", PageSize: 100, DistributionTimeout: 10}, updated)
+}
+
+func (s *BookmarksConfigTestSuite) TestPutBookmarksConfigWithInvalidSettings() {
+	mockutil.MockJSONRequest(s.context, "PUT", nil, map[string]interface{}{"packageFormat": "rar", "pageSize": 0})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(nil, nil)
+
+	PutBookmarksConfig(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *BookmarksConfigTestSuite) TestPutBookmarksConfigWhenDistributionPending() {
+	mockutil.MockJSONRequest(s.context, "PUT", nil, map[string]interface{}{"enabled": false})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked, LatestVersion: "1.0.89"}, nil)
+
+	PutBookmarksConfig(s.context)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+}
+
+var setClausePattern = regexp.MustCompile(`(#\d+) = (:\d+)`)
+
+// setValues returns the values set by the update expression by their attribute names.
+func setValues(expr *expression.Expression) map[string]types.AttributeValue {
+	values := map[string]types.AttributeValue{}
+	for _, match := range setClausePattern.FindAllStringSubmatch(*expr.Update(), -1) {
+		values[expr.Names()[match[1]]] = expr.Values()[match[2]]
+	}
+	return values
+}
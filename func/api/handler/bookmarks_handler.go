@@ -15,12 +15,12 @@ import (
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/rs/zerolog/log"
 )
 
 const (
-	JSON             string = "application/json"
-	CSV              string = "text/csv"
-	defaultPageLimit        = 100
+	JSON string = "application/json"
+	CSV  string = "text/csv"
 )
 
 var (
@@ -30,11 +30,6 @@ var (
 func GetBookmarks(context *gin.Context) {
 	bookmarks := models.BookmarkList{}
 
-	pageLimit, err := strconv.Atoi(context.Query("limit"))
-	if err != nil {
-		pageLimit = defaultPageLimit
-	}
-
 	var lastEvalRecord string
 
 	cursor, err := url.PathUnescape(context.Query("cursor"))
@@ -60,7 +55,14 @@ func GetBookmarks(context *gin.Context) {
 	}
 
 	userId := context.GetString(middleware.UserIDCxt)
-	data, err := helpers.GetBookmarksS3Object(dynamodbClient, s3Client, userId)
+	userBookmarks := helpers.GetBookmarkByUser(dynamodbClient, userId)
+
+	pageLimit, err := strconv.Atoi(context.Query("limit"))
+	if err != nil || pageLimit <= 0 {
+		pageLimit = helpers.GetPageSize(userBookmarks)
+	}
+
+	data, err := helpers.GetUserBookmarksS3Object(s3Client, userBookmarks, userId)
 	if err != nil {
 		helpers.SendCustomErrorMessage(context, http.StatusNotFound, "Bookmarks not found", err)
 		return
@@ -89,6 +91,9 @@ func GetBookmarks(context *gin.Context) {
 		Next:         nextToken,
 		BookmarkList: bookmarks.BookmarkEntry}
 
+	countBookmarks(context, response.TotalCount)
+	trackEvent(context, helpers.AnalyticsBookmarksListed, map[string]interface{}{"count": response.TotalCount,
+		"paged": lastEvalRecord != ""})
 	context.JSON(http.StatusOK, &response)
 }
 
@@ -130,13 +135,6 @@ func PutBookmarks(context *gin.Context) {
 		return
 	}
 
-	bookmarks.BookmarkEntry = validBookmarks
-	content, err = json.Marshal(&bookmarks)
-	if err != nil {
-		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid json payload", err)
-		return
-	}
-
 	dynamodbClient, err := NewDynamoDBClient()
 	if err != nil {
 		helpers.SendInternalError(context, err)
@@ -150,19 +148,55 @@ func PutBookmarks(context *gin.Context) {
 		return
 	}
 
+	bookmarks.BookmarkEntry = validBookmarks
+	if isResolveRequested(context) {
+		if isTooManyToResolve(context, len(bookmarks.BookmarkEntry)) {
+			return
+		}
+		helpers.ResolveBookmarkURLs(context.Request.Context(), bookmarks.BookmarkEntry)
+		bookmarks.BookmarkEntry = removeDuplicates(bookmarks.BookmarkEntry)
+	}
+
+	content, err = json.Marshal(&bookmarks)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid json payload", err)
+		return
+	}
+
 	s3Client, err := NewS3Client()
 	if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
 
-	bucketName := os.Getenv("BOOKMARKS_BUCKET")
-	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content)
+	distVersion, err := helpers.GetIncrementedVersion(distribution)
 	if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
+	auditChange(context, helpers.AuditBookmarksReplaced, getLatestVersion(distribution), distVersion)
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content,
+		models.BookmarksReplaced{Version: distVersion, TotalCount: len(bookmarks.BookmarkEntry)})
+	if err != nil {
+		sendBookmarksUpdateError(context, err)
+		return
+	}
+	publishBookmarksUpdated(userId, distribution, len(bookmarks.BookmarkEntry))
+
+	if err = helpers.PruneSnapshots(s3Client, userId, bookmarks.BookmarkEntry); err != nil {
+		log.Error().Msgf("Failure in deleting snapshots of replaced bookmarks for userId %s: %v", userId, err)
+	}
+
+	if isSnapshotRequested(context) {
+		queueSnapshots(userId, distVersion, bookmarks.BookmarkEntry)
+	}
 
+	countBookmarks(context, len(bookmarks.BookmarkEntry))
+	trackEvent(context, helpers.AnalyticsBookmarksImported, map[string]interface{}{
+		"count": len(bookmarks.BookmarkEntry), "contentType": strings.ToLower(contentType),
+		"domains": helpers.GetBookmarkDomains(bookmarks.BookmarkEntry)})
 	context.JSON(http.StatusCreated, &models.BookmarksResponse{
 		BookmarkList: bookmarks.BookmarkEntry,
 		TotalCount:   len(bookmarks.BookmarkEntry),
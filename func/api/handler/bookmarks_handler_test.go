@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
@@ -19,6 +21,7 @@ import (
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
 	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -57,6 +60,7 @@ var mockdist = &model.UserBookmarks{
 	EndTimestamp:   time.Now(),
 	UserId:         "1",
 	LatestVersion:  TestLatestVersion,
+	SyncEnabled:    true,
 }
 
 func TestBookmarksSuite(t *testing.T) {
@@ -160,15 +164,22 @@ func (s *BookmarksTestSuite) TestPutBookmarksAsJson() {
 	distribution := &model.UserBookmarks{UserId: "1"}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(mockdist, nil)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarksReplaced")).
+		Return(nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
 
 	s3Content := []byte(`{"bookmarks":[{"url":"https://docs.ai21.com/docs/jurassic-2-models"},{"url":"https://jalammar.github.io/illustrated-transformer/"}]}`)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bucket"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.90"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
-		&s3Content).Return(nil)
+		&s3Content).Return("", nil)
+
+	keptSnapshotKey := "Snapshots/1/" + util.MD5Hash("https://docs.ai21.com/docs/jurassic-2-models") + "/1.0.89"
+	removedSnapshotKey := "Snapshots/1/" + util.MD5Hash("https://chat.openai.com") + "/1.0.89"
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq("Snapshots/1/")).
+		Return([]types.Object{{Key: aws.String(keptSnapshotKey)}, {Key: aws.String(removedSnapshotKey)}}, nil)
+	s.mockS3Client.EXPECT().DeleteObjects(gomock.Eq("test_bucket"), gomock.Eq([]string{removedSnapshotKey})).Return(nil)
 
 	PutBookmarks(s.context)
 
@@ -185,9 +196,9 @@ func (s *BookmarksTestSuite) TestPutBookmarksAsJsonWithS3Error() {
 
 	s3Content := []byte(`{"bookmarks":[{"url":"https://docs.ai21.com/docs/jurassic-2-models"},{"url":"https://jalammar.github.io/illustrated-transformer/"}]}`)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bucket"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.90"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
-		&s3Content).Return(errors.New("s3 error"))
+		&s3Content).Return("", errors.New("s3 error"))
 
 	PutBookmarks(s.context)
 
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/rs/zerolog/log"
+)
+
+func GetBookmarksHealth(context *gin.Context) {
+	s3Client, err := NewS3Client()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	report, err := helpers.GetLinkHealthReport(s3Client, userId)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusNotFound, "no link health report found", err)
+		return
+	}
+
+	context.JSON(http.StatusOK, helpers.GroupLinkHealth(report))
+}
+
+func ReplaceRedirectedBookmarks(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	distribution := helpers.GetBookmarkByUser(dynamodbClient, userId)
+
+	if helpers.IsDistributionPending(distribution) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "distribution is in Progress"})
+		return
+	}
+
+	distEntryPath := helpers.GetUserBookmarksS3Path(distribution)
+	if distEntryPath == "" {
+		context.JSON(http.StatusNotFound, gin.H{"error": "no bookmarks found"})
+		return
+	}
+
+	s3Client, err := NewS3Client()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	report, err := helpers.GetLinkHealthReport(s3Client, userId)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusNotFound, "no link health report found", err)
+		return
+	}
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	data, err := s3Client.GetObject(bucketName, distEntryPath)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	bookmarkList := models.BookmarkList{}
+	if err = json.Unmarshal(data, &bookmarkList); err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	if helpers.ReplaceRedirectedURLs(bookmarkList.BookmarkEntry, report) == 0 {
+		context.JSON(http.StatusOK, &models.BookmarksResponse{
+			BookmarkList: bookmarkList.BookmarkEntry,
+			TotalCount:   len(bookmarkList.BookmarkEntry),
+		})
+		return
+	}
+
+	// A redirect target could already be bookmarked on its own.
+	bookmarkList.BookmarkEntry = removeDuplicates(bookmarkList.BookmarkEntry)
+
+	content, err := json.Marshal(&bookmarkList)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	distVersion, err := helpers.GetIncrementedVersion(distribution)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+	auditChange(context, helpers.AuditRedirectsReplaced, getLatestVersion(distribution), distVersion)
+
+	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content,
+		models.BookmarksReplaced{Version: distVersion, TotalCount: len(bookmarkList.BookmarkEntry)})
+	if err != nil {
+		sendBookmarksUpdateError(context, err)
+		return
+	}
+	publishBookmarksUpdated(userId, distribution, len(bookmarkList.BookmarkEntry))
+
+	report.Version = distribution.LatestVersion
+	if err = helpers.StoreLinkHealthReport(s3Client, report); err != nil {
+		log.Error().Msgf("Failure in updating link health report for userId %s: %v", userId, err)
+	}
+
+	context.JSON(http.StatusCreated, &models.BookmarksResponse{
+		BookmarkList: bookmarkList.BookmarkEntry,
+		TotalCount:   len(bookmarkList.BookmarkEntry),
+	})
+}
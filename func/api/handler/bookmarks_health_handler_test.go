@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarksHealthTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockS3Client       *s3Mocks.MockS3Client
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+}
+
+const linkHealthReport = `{"userId":"1","version":"1.0.89","links":[
+	{"url":"http://karpenter.sh","status":"Redirected","statusCode":200,"finalUrl":"https://karpenter.sh/",
+		"redirectChain":["http://karpenter.sh"]},
+	{"url":"https://chat.openai.com","status":"Healthy","statusCode":200},
+	{"url":"https://gone.example.com","status":"Broken","statusCode":410}
+]}`
+
+func TestBookmarksHealthSuite(t *testing.T) {
+	suite.Run(t, new(BookmarksHealthTestSuite))
+}
+
+func (s *BookmarksHealthTestSuite) SetupSuite() {
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bucket")
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *BookmarksHealthTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	NewS3Client = func() (pkgS3.S3Client, error) {
+		return s.mockS3Client, nil
+	}
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+}
+
+func (s *BookmarksHealthTestSuite) TestGetBookmarksHealth() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Health/1/report.json")).
+		Return([]byte(linkHealthReport), nil)
+
+	GetBookmarksHealth(s.context)
+
+	var response models.LinkHealthResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal("1.0.89", response.Version)
+	s.Len(response.Redirected, 1)
+	s.Len(response.Healthy, 1)
+	s.Len(response.Broken, 1)
+	s.Equal(http.StatusGone, response.Broken[0].StatusCode)
+	s.Empty(response.Unchecked)
+}
+
+func (s *BookmarksHealthTestSuite) TestGetBookmarksHealthWhenNotChecked() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Health/1/report.json")).
+		Return(nil, errors.New("NoSuchKey"))
+
+	GetBookmarksHealth(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
+
+func (s *BookmarksHealthTestSuite) TestReplaceRedirectedBookmarks() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	distribution := &model.UserBookmarks{UserId: "1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(&model.UserBookmarks{
+		UserId:        "1",
+		Status:        constant.Success,
+		LatestVersion: "1.0.89",
+	}, nil)
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Health/1/report.json")).
+		Return([]byte(linkHealthReport), nil)
+
+	s3Content := `{"bookmarks": [
+				{ "url": "http://karpenter.sh" },
+				{ "url": "https://karpenter.sh/" },
+				{ "url": "https://chat.openai.com" }
+			]}`
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return([]byte(s3Content), nil)
+
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.90"),
+		gomock.Eq(JSON), gomock.Eq(pkgS3.GZip), gomock.Any()).Return("", nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarksReplaced")).
+		Return(nil)
+	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
+
+	var storedReport models.LinkHealthReport
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bucket"), gomock.Eq("Health/1/report.json"),
+		gomock.Eq(JSON), gomock.Eq(pkgS3.GZip), gomock.Any()).
+		DoAndReturn(func(_, _, _, _ string, content *[]byte) error {
+			return json.Unmarshal(*content, &storedReport)
+		})
+
+	ReplaceRedirectedBookmarks(s.context)
+
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+	s.Equal(`{"totalCount":2,"next":"","bookmarks":[{"url":"https://karpenter.sh/"},{"url":"https://chat.openai.com"}]}`,
+		s.recorder.Body.String())
+	s.Equal("1.0.90", storedReport.Version)
+	s.Equal(helpers.LinkHealthy, storedReport.Links[0].Status)
+}
+
+func (s *BookmarksHealthTestSuite) TestReplaceRedirectedBookmarksWhenDistributionPending() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked, LatestVersion: "1.0.89"}, nil)
+
+	ReplaceRedirectedBookmarks(s.context)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+}
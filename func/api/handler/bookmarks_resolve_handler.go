@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/rs/zerolog/log"
+)
+
+const resolveQueryParam = "resolve"
+
+// ResolveBookmarks resolves the redirects of the stored bookmarks, restricted to the urls in the request when
+// passed, and stores the resolved urls in a new version with the duplicates by resolved url removed.
+func ResolveBookmarks(context *gin.Context) {
+	request := models.BookmarkList{}
+
+	if err := context.ShouldBindJSON(&request); err != nil {
+		log.Debug().Msg("No bookmarks passed for resolve request; defaults to all bookmarks")
+	}
+
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	distribution := helpers.GetBookmarkByUser(dynamodbClient, userId)
+
+	if helpers.IsDistributionPending(distribution) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "distribution is in Progress"})
+		return
+	}
+
+	distEntryPath := helpers.GetUserBookmarksS3Path(distribution)
+	if distEntryPath == "" {
+		context.JSON(http.StatusNotFound, gin.H{"error": "no bookmarks found"})
+		return
+	}
+
+	s3Client, err := NewS3Client()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	data, err := s3Client.GetObject(bucketName, distEntryPath)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	bookmarkList := models.BookmarkList{}
+	if err = json.Unmarshal(data, &bookmarkList); err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	requested := map[string]bool{}
+	for _, entry := range request.BookmarkEntry {
+		requested[entry.URL] = true
+	}
+
+	var resolveIndexes []int
+	var resolveEntries []models.BookmarkEntry
+
+	for i, entry := range bookmarkList.BookmarkEntry {
+		if len(requested) == 0 || requested[entry.URL] {
+			resolveIndexes = append(resolveIndexes, i)
+			resolveEntries = append(resolveEntries, entry)
+		}
+	}
+
+	if len(resolveEntries) == 0 {
+		context.JSON(http.StatusNotFound, gin.H{"error": "requested bookmarks not found"})
+		return
+	}
+	if isTooManyToResolve(context, len(resolveEntries)) {
+		return
+	}
+
+	helpers.ResolveBookmarkURLs(context.Request.Context(), resolveEntries)
+	for i, index := range resolveIndexes {
+		bookmarkList.BookmarkEntry[index] = resolveEntries[i]
+	}
+	bookmarkList.BookmarkEntry = removeDuplicates(bookmarkList.BookmarkEntry)
+
+	content, err := json.Marshal(&bookmarkList)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	distVersion, err := helpers.GetIncrementedVersion(distribution)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+	auditChange(context, helpers.AuditBookmarksResolved, getLatestVersion(distribution), distVersion)
+
+	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content,
+		models.BookmarksReplaced{Version: distVersion, TotalCount: len(bookmarkList.BookmarkEntry)})
+	if err != nil {
+		sendBookmarksUpdateError(context, err)
+		return
+	}
+	publishBookmarksUpdated(userId, distribution, len(bookmarkList.BookmarkEntry))
+
+	context.JSON(http.StatusCreated, &models.BookmarksResponse{
+		BookmarkList: bookmarkList.BookmarkEntry,
+		TotalCount:   len(bookmarkList.BookmarkEntry),
+	})
+}
+
+func isResolveRequested(context *gin.Context) bool {
+	resolve, err := strconv.ParseBool(context.DefaultQuery(resolveQueryParam, "false"))
+	return err == nil && resolve
+}
+
+// isTooManyToResolve rejects the request when more bookmarks are to be resolved than can be resolved within
+// the request, as the bookmarks are resolved while the request waits.
+func isTooManyToResolve(context *gin.Context, count int) bool {
+	if count <= helpers.MaxResolveBookmarks {
+		return false
+	}
+
+	context.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf(
+		"at most %d bookmarks can be resolved per request, pass the bookmarks to resolve in batches",
+		helpers.MaxResolveBookmarks)})
+	return true
+}
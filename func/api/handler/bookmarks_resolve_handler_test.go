@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarksResolveTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockS3Client       *s3Mocks.MockS3Client
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	server             *httptest.Server
+}
+
+func TestBookmarksResolveSuite(t *testing.T) {
+	suite.Run(t, new(BookmarksResolveTestSuite))
+}
+
+func (s *BookmarksResolveTestSuite) SetupSuite() {
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bucket")
+	s.ctrl = gomock.NewController(s.T())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusMovedPermanently)
+	})
+	s.server = httptest.NewServer(mux)
+
+	config := crawler.DefaultConfig()
+	config.MinHostInterval = 0
+	config.AllowPrivateNetworks = true
+	helpers.PageCrawler = crawler.New(config)
+}
+
+func (s *BookmarksResolveTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *BookmarksResolveTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	NewS3Client = func() (pkgS3.S3Client, error) {
+		return s.mockS3Client, nil
+	}
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+}
+
+func (s *BookmarksResolveTestSuite) TestResolveBookmarksRemovesResolvedDuplicates() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	distribution := &model.UserBookmarks{UserId: "1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(&model.UserBookmarks{
+		UserId:        "1",
+		Status:        constant.Success,
+		LatestVersion: TestLatestVersion,
+	}, nil)
+
+	s3Content := `{"bookmarks": [
+				{ "url": "` + s.server.URL + `/target" },
+				{ "url": "` + s.server.URL + `/short" }
+			]}`
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return([]byte(s3Content), nil)
+
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.90"),
+		gomock.Eq(JSON), gomock.Eq(pkgS3.GZip), gomock.Any()).Return("", nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarksReplaced")).
+		Return(nil)
+	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
+
+	ResolveBookmarks(s.context)
+
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+	s.Equal(`{"totalCount":1,"next":"","bookmarks":[{"url":"`+s.server.URL+`/target"}]}`, s.recorder.Body.String())
+}
+
+func (s *BookmarksResolveTestSuite) TestResolveBookmarksWhenRequestedBookmarkNotFound() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, models.BookmarkList{
+		BookmarkEntry: []models.BookmarkEntry{{URL: "https://bit.ly/3xyz"}},
+	})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", Status: constant.Success, LatestVersion: TestLatestVersion}, nil)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return([]byte(`{"bookmarks": [{ "url": "https://chat.openai.com" }]}`), nil)
+
+	ResolveBookmarks(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
+
+func (s *BookmarksResolveTestSuite) TestResolveBookmarksWhenTooManyBookmarks() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, nil)
+
+	bookmarkList := models.BookmarkList{}
+	for i := 0; i <= helpers.MaxResolveBookmarks; i++ {
+		bookmarkList.BookmarkEntry = append(bookmarkList.BookmarkEntry,
+			models.BookmarkEntry{URL: fmt.Sprintf("https://bit.ly/%d", i)})
+	}
+	s3Content, err := json.Marshal(&bookmarkList)
+	s.NoError(err)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", Status: constant.Success, LatestVersion: TestLatestVersion}, nil)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return(s3Content, nil)
+
+	ResolveBookmarks(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+	s.Contains(s.recorder.Body.String(), "at most 50 bookmarks")
+}
+
+func (s *BookmarksResolveTestSuite) TestRemoveDuplicatesByResolvedURL() {
+	bookmarks := removeDuplicates([]models.BookmarkEntry{
+		{URL: "https://bit.ly/3xyz", ResolvedURL: "https://karpenter.sh/"},
+		{URL: "https://karpenter.sh/"},
+		{URL: "https://t.co/abc", ResolvedURL: "https://karpenter.sh/"},
+		{URL: "https://bit.ly/3xyz"},
+		{URL: "https://chat.openai.com"},
+	})
+
+	s.Equal([]models.BookmarkEntry{
+		{URL: "https://bit.ly/3xyz", ResolvedURL: "https://karpenter.sh/"},
+		{URL: "https://chat.openai.com"},
+	}, bookmarks)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/s3"
 	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/rs/zerolog/log"
 )
 
 func FindBookmarkEntry(context *gin.Context) {
@@ -62,6 +63,7 @@ func FindBookmarkEntry(context *gin.Context) {
 		}
 	}
 
+	trackEvent(context, helpers.AnalyticsBookmarksSearched, map[string]interface{}{"found": found})
 	if found {
 		context.Status(http.StatusNoContent)
 	} else {
@@ -127,11 +129,24 @@ func FindAndDeleteBookmarkEntry(context *gin.Context) {
 		helpers.SendInternalError(context, err)
 	}
 
-	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &s3Content)
+	distVersion, err := helpers.GetIncrementedVersion(distribution)
 	if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
+	auditChange(context, helpers.AuditBookmarkEntryDeleted, getLatestVersion(distribution), distVersion)
+
+	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip,
+		&s3Content, models.BookmarkRemoved{Version: distVersion, URL: url})
+	if err != nil {
+		sendBookmarksUpdateError(context, err)
+		return
+	}
+	publishBookmarksUpdated(userId, distribution, len(bookmarkList.BookmarkEntry))
+
+	if err = helpers.DeleteSnapshots(s3Client, userId, []string{url}); err != nil {
+		log.Error().Msgf("Failure in deleting snapshots of %s for userId %s: %v", url, userId, err)
+	}
 
 	context.Status(http.StatusNoContent)
 }
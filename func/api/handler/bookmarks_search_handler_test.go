@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
@@ -16,6 +18,7 @@ import (
 	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
 
 	"github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -220,12 +223,19 @@ func (s *BookmarksSearchTestSuite) TestFindAndDeleteBookmarkEntryWithURLMatched(
 		`{"bookmarks":[{"url":"https://jalammar.github.io/illustrated-transformer/"},{"url":"https://chat.openai.com"}]}`)
 
 	s.mockS3Client.EXPECT().
-		PutObject("test_bucket", "Bookmarks/1/1.0.90", "application/json", pkgS3.GZip, &updatedContent)
+		PutObjectVersion("test_bucket", "Bookmarks/1/1.0.90", "application/json", pkgS3.GZip, &updatedContent)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(&model.UserBookmarks{})).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"BookmarkRemoved")).Return(nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
 
+	snapshotKey := "Snapshots/1/" + util.MD5Hash("https://bigscience.huggingface.co/blog/bloom") + "/1.0.89"
+	otherSnapshotKey := "Snapshots/1/" + util.MD5Hash("https://chat.openai.com") + "/1.0.89"
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq("Snapshots/1/")).
+		Return([]types.Object{{Key: aws.String(snapshotKey)}, {Key: aws.String(otherSnapshotKey)}}, nil)
+	s.mockS3Client.EXPECT().DeleteObjects(gomock.Eq("test_bucket"), gomock.Eq([]string{snapshotKey})).Return(nil)
+
 	FindAndDeleteBookmarkEntry(s.context)
 
 	s.EqualValues(http.StatusNoContent, s.context.Writer.Status())
@@ -248,17 +258,20 @@ func (s *BookmarksSearchTestSuite) TestFindAndDeleteBookmarkEntryWithIPv4NotMatc
 		`{"bookmarks":[{"url":"https://falconllm.tii.ae/falcon.html"},{"url":"https://chat.openai.com"}]}`)
 
 	s.mockS3Client.EXPECT().
-		PutObject(
+		PutObjectVersion(
 			gomock.Eq("test_bucket"),
 			gomock.Eq("Bookmarks/1/1.0.90"),
 			gomock.Eq("application/json"),
 			gomock.Eq(pkgS3.GZip),
 			gomock.Eq(&updatedContent))
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(&model.UserBookmarks{})).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"BookmarkRemoved")).Return(nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
 
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq("Snapshots/1/")).Return(nil, nil)
+
 	FindAndDeleteBookmarkEntry(s.context)
 
 	s.EqualValues(http.StatusNoContent, s.context.Writer.Status())
@@ -289,17 +302,20 @@ func (s *BookmarksSearchTestSuite) TestFindAndDeleteBookmarkEntryWithEmptyEntryL
 	updatedContent := []byte(`{"bookmarks":[]}`)
 
 	s.mockS3Client.EXPECT().
-		PutObject(
+		PutObjectVersion(
 			gomock.Eq("test_bucket"),
 			gomock.Eq("Bookmarks/1/1.0.90"),
 			gomock.Eq("application/json"),
 			gomock.Eq("gzip"),
 			gomock.Eq(&updatedContent))
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(&model.UserBookmarks{})).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"BookmarkRemoved")).Return(nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
 
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq("Snapshots/1/")).Return(nil, nil)
+
 	FindAndDeleteBookmarkEntry(s.context)
 
 	s.EqualValues(http.StatusNoContent, s.context.Writer.Status())
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+)
+
+const (
+	snapshotQueryParam = "snapshot"
+	// Snapshots are sanitized already, the policy prevents anything left over from executing or loading.
+	snapshotContentSecurityPolicy = "default-src 'none'; img-src data:; style-src 'unsafe-inline'; sandbox"
+)
+
+func GetBookmarkSnapshot(context *gin.Context) {
+	bookmarkURL, err := url.PathUnescape(context.Param("url"))
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if err = util.ValidateURL(bookmarkURL); err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	s3Client, err := NewS3Client()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	content, err := helpers.GetLatestSnapshot(s3Client, userId, bookmarkURL)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusNotFound, "Snapshot not found", err)
+		return
+	}
+
+	context.Header("Content-Security-Policy", snapshotContentSecurityPolicy)
+	context.Header("X-Content-Type-Options", "nosniff")
+	context.Data(http.StatusOK, helpers.SnapshotContentType+"; charset=utf-8", content)
+}
+
+func isSnapshotRequested(context *gin.Context) bool {
+	snapshot, err := strconv.ParseBool(context.DefaultQuery(snapshotQueryParam, "false"))
+	return err == nil && snapshot
+}
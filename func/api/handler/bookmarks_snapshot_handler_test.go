@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarksSnapshotTestSuite struct {
+	suite.Suite
+
+	ctrl         *gomock.Controller
+	recorder     *httptest.ResponseRecorder
+	context      *gin.Context
+	mockS3Client *s3Mocks.MockS3Client
+}
+
+func TestBookmarksSnapshotSuite(t *testing.T) {
+	suite.Run(t, new(BookmarksSnapshotTestSuite))
+}
+
+func (s *BookmarksSnapshotTestSuite) SetupSuite() {
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bucket")
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *BookmarksSnapshotTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	NewS3Client = func() (pkgS3.S3Client, error) {
+		return s.mockS3Client, nil
+	}
+}
+
+func (s *BookmarksSnapshotTestSuite) TestGetBookmarkSnapshotReturnsLatestVersion() {
+	bookmarkURL := "https://karpenter.sh/"
+	pathParams := []gin.Param{{Key: "url", Value: bookmarkURL}}
+	mockutil.MockJSONRequest(s.context, "GET", pathParams, nil)
+
+	prefix := "Snapshots/1/" + util.MD5Hash(bookmarkURL) + "/"
+	modifiedTime := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq(prefix)).Return([]types.Object{
+		{Key: aws.String(prefix + "1.0.9"), LastModified: aws.Time(modifiedTime.Add(time.Hour))},
+		{Key: aws.String(prefix + "1.0.10"), LastModified: aws.Time(modifiedTime.Add(2 * time.Hour))},
+		{Key: aws.String(prefix + "1.0.8"), LastModified: aws.Time(modifiedTime)},
+	}, nil)
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"), gomock.Eq(prefix+"1.0.10")).
+		Return([]byte("<html><body>Karpenter</body></html>"), nil)
+
+	GetBookmarkSnapshot(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal("<html><body>Karpenter</body></html>", s.recorder.Body.String())
+	s.Equal("text/html; charset=utf-8", s.recorder.Header().Get("Content-Type"))
+	s.Contains(s.recorder.Header().Get("Content-Security-Policy"), "default-src 'none'")
+}
+
+func (s *BookmarksSnapshotTestSuite) TestGetBookmarkSnapshotWhenNotFound() {
+	bookmarkURL := "https://karpenter.sh/"
+	pathParams := []gin.Param{{Key: "url", Value: bookmarkURL}}
+	mockutil.MockJSONRequest(s.context, "GET", pathParams, nil)
+
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"),
+		gomock.Eq("Snapshots/1/"+util.MD5Hash(bookmarkURL)+"/")).Return(nil, errors.New("s3 error"))
+
+	GetBookmarkSnapshot(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+	s.Equal(`{"error":"Snapshot not found"}`, s.recorder.Body.String())
+}
+
+func (s *BookmarksSnapshotTestSuite) TestGetBookmarkSnapshotWithInvalidURL() {
+	pathParams := []gin.Param{{Key: "url", Value: "karpenter.sh"}}
+	mockutil.MockJSONRequest(s.context, "GET", pathParams, nil)
+
+	GetBookmarkSnapshot(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
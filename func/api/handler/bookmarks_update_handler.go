@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,6 +12,12 @@ import (
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	NewSQSClient = sqs.NewSQSClient
 )
 
 func PostBookmarks(context *gin.Context) {
@@ -51,17 +58,26 @@ func PostBookmarks(context *gin.Context) {
 		return
 	}
 
+	if isResolveRequested(context) {
+		if isTooManyToResolve(context, len(validBookmarks)) {
+			return
+		}
+		helpers.ResolveBookmarkURLs(context.Request.Context(), validBookmarks)
+	}
+
 	bucketName := os.Getenv("BOOKMARKS_BUCKET")
 	distEntryPath := helpers.GetUserBookmarksS3Path(distribution)
 
+	var addedBookmarks []models.BookmarkEntry
 	if distEntryPath != "" {
-		bookmarkList, err = getExistingBookmarks(s3Client, validBookmarks, bucketName, distEntryPath)
+		bookmarkList, addedBookmarks, err = getExistingBookmarks(s3Client, validBookmarks, bucketName, distEntryPath)
 		if err != nil {
 			helpers.SendInternalError(context, err)
 			return
 		}
 	} else {
-		bookmarkList.BookmarkEntry = validBookmarks
+		bookmarkList.BookmarkEntry = removeDuplicates(validBookmarks)
+		addedBookmarks = bookmarkList.BookmarkEntry
 	}
 
 	content, err := json.Marshal(&bookmarkList)
@@ -70,12 +86,30 @@ func PostBookmarks(context *gin.Context) {
 		return
 	}
 
-	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content)
+	distVersion, err := helpers.GetIncrementedVersion(distribution)
 	if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
+	auditChange(context, helpers.AuditBookmarksAdded, getLatestVersion(distribution), distVersion)
 
+	err = helpers.AddBookmarksInS3Bucket(dynamodbClient, s3Client, distribution, userId, bucketName, JSON, s3.GZip, &content,
+		helpers.GetBookmarkAddedEvents(distVersion, addedBookmarks, len(bookmarkList.BookmarkEntry))...)
+	if err != nil {
+		sendBookmarksUpdateError(context, err)
+		return
+	}
+	publishBookmarksUpdated(userId, distribution, len(bookmarkList.BookmarkEntry))
+
+	if isSnapshotRequested(context) {
+		queueSnapshots(userId, distVersion, validBookmarks)
+	}
+
+	if helpers.IsEnrichmentEnabled(distribution) {
+		queueEnrichment(userId, validBookmarks)
+	}
+
+	countBookmarks(context, len(validBookmarks))
 	context.JSON(http.StatusCreated, &models.BookmarksResponse{
 		BookmarkList: bookmarkList.BookmarkEntry,
 		TotalCount:   len(bookmarkList.BookmarkEntry),
@@ -109,11 +143,13 @@ func DeleteBookmarks(context *gin.Context) {
 		return
 	}
 
-	err = helpers.AddOrUpdateUserBookmarks(dynamodbClient, distribution, userId,
-		fmt.Sprint(distribution.LatestVersion, helpers.DeletedVersionSuffix), true)
+	deletedVersion := fmt.Sprint(distribution.LatestVersion, helpers.DeletedVersionSuffix)
+	auditChange(context, helpers.AuditBookmarksDeleted, distribution.LatestVersion, deletedVersion)
+	err = helpers.AddOrUpdateUserBookmarks(dynamodbClient, distribution, userId, deletedVersion, true,
+		models.BookmarksReplaced{Version: deletedVersion})
 
 	if err != nil {
-		helpers.SendInternalError(context, err)
+		sendBookmarksUpdateError(context, err)
 		return
 	}
 
@@ -124,33 +160,95 @@ func DeleteBookmarks(context *gin.Context) {
 		return
 	}
 
+	if err = helpers.PruneSnapshots(s3Client, userId, nil); err != nil {
+		log.Error().Msgf("Failure in deleting snapshots for userId %s: %v", userId, err)
+	}
+
+	publishWebhookEvent(userId, helpers.WebhookEventBookmarksDeleted,
+		&models.WebhookBookmarksData{Version: distribution.LatestVersion})
+
 	context.JSON(http.StatusAccepted, gin.H{"message": "Bookmarks deletion complete"})
 }
 
-func getExistingBookmarks(s3Client s3.S3Client, newBookmarks []models.BookmarkEntry, bucketName, path string) (models.BookmarkList, error) {
+// queueEnrichment never fails the request, since the bookmarks are already stored without the metadata.
+func queueEnrichment(userId string, bookmarks []models.BookmarkEntry) {
+	queueURL := helpers.GetEnrichmentQueueURL()
+	if queueURL == "" || len(bookmarks) == 0 {
+		return
+	}
+
+	sqsClient, err := NewSQSClient()
+	if err == nil {
+		err = helpers.QueueEnrichment(sqsClient, queueURL, userId, bookmarks)
+	}
+
+	if err != nil {
+		log.Error().Msgf("Failure in queueing enrichment of bookmarks for userId %s: %v", userId, err)
+	}
+}
+
+// queueSnapshots never fails the request, since the bookmarks are already stored without their snapshots.
+func queueSnapshots(userId, distVersion string, bookmarks []models.BookmarkEntry) {
+	queueURL := helpers.GetEnrichmentQueueURL()
+	if queueURL == "" || len(bookmarks) == 0 {
+		return
+	}
+
+	sqsClient, err := NewSQSClient()
+	if err == nil {
+		err = helpers.QueueSnapshots(sqsClient, queueURL, userId, distVersion, bookmarks)
+	}
+
+	if err != nil {
+		log.Error().Msgf("Failure in queueing snapshots of bookmarks for userId %s: %v", userId, err)
+	}
+}
+
+// getExistingBookmarks appends the new bookmarks to the existing bookmarks, and returns the merged bookmarks
+// along with the new bookmarks which were not already bookmarked.
+func getExistingBookmarks(s3Client s3.S3Client, newBookmarks []models.BookmarkEntry, bucketName,
+	path string) (models.BookmarkList, []models.BookmarkEntry, error) {
 	bookmarkList := models.BookmarkList{}
 
 	data, err := s3Client.GetObject(bucketName, path)
 	if err != nil {
-		return bookmarkList, err
+		return bookmarkList, nil, err
 	}
 
 	if err := json.Unmarshal(data, &bookmarkList); err != nil {
-		return bookmarkList, err
+		return bookmarkList, nil, err
 	}
 
+	existingCount := len(removeDuplicates(bookmarkList.BookmarkEntry))
 	bookmarkList.BookmarkEntry = removeDuplicates(append(bookmarkList.BookmarkEntry, newBookmarks...))
-	return bookmarkList, nil
+	return bookmarkList, bookmarkList.BookmarkEntry[existingCount:], nil
 }
 
+// removeDuplicates keeps the first of the bookmarks sharing an url, where the resolved url of a bookmark
+// is also matched against the original and resolved urls of the other bookmarks.
 func removeDuplicates(slices []models.BookmarkEntry) []models.BookmarkEntry {
 	allKeys := make(map[string]bool)
 	list := []models.BookmarkEntry{}
 	for _, item := range slices {
-		if _, value := allKeys[item.URL]; !value {
-			allKeys[item.URL] = true
-			list = append(list, item)
+		if allKeys[item.URL] || (item.ResolvedURL != "" && allKeys[item.ResolvedURL]) {
+			continue
+		}
+
+		allKeys[item.URL] = true
+		if item.ResolvedURL != "" {
+			allKeys[item.ResolvedURL] = true
 		}
+		list = append(list, item)
 	}
 	return list
 }
+
+// sendBookmarksUpdateError responds with a conflict when the bookmarks were changed concurrently, which the client
+// can retry with the latest bookmarks.
+func sendBookmarksUpdateError(context *gin.Context, err error) {
+	if errors.Is(err, helpers.ErrBookmarksChanged) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "bookmarks changed concurrently", err)
+		return
+	}
+	helpers.SendInternalError(context, err)
+}
@@ -7,8 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/constant"
@@ -18,6 +22,8 @@ import (
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	pkgS3 "github.com/pranav-patil/go-serverless-api/pkg/s3"
 	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	pkgSQS "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -29,6 +35,7 @@ type BookmarksUpdateTestSuite struct {
 	context            *gin.Context
 	mockS3Client       *s3Mocks.MockS3Client
 	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockSQSClient      *sqsMocks.MockSQSClient
 }
 
 var bookmarksUpdateRequest = models.BookmarkList{
@@ -75,6 +82,11 @@ func (s *BookmarksUpdateTestSuite) SetupTest() {
 	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
 		return s.mockDynamoDBClient, nil
 	}
+
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+	NewSQSClient = func() (pkgSQS.SQSClient, error) {
+		return s.mockSQSClient, nil
+	}
 }
 
 func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereExistingVersionExists() {
@@ -84,7 +96,8 @@ func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereExistingVersionExists()
 	distributionSuccess.LatestVersion = TestLatestVersion
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(distributionSuccess, nil)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarkAdded")).
+		Return(nil)
 
 	s3Content := `{"bookmarks": [
 				{ "url": "https://docs.ai21.com/docs/jurassic-2-models" },
@@ -95,9 +108,9 @@ func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereExistingVersionExists()
 	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.89")).Return([]byte(s3Content), nil)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bucket"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.90"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
-		gomock.Any()).Return(nil)
+		gomock.Any()).Return("", nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.89")).Return(nil)
@@ -105,6 +118,9 @@ func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereExistingVersionExists()
 	PostBookmarks(s.context)
 
 	s.EqualValues(http.StatusCreated, s.recorder.Code)
+	s.Equal(helpers.AuditBookmarksAdded, s.context.GetString(auditActionCxt))
+	s.Equal("1.0.89", s.context.GetString(auditVersionBeforeCxt))
+	s.Equal("1.0.90", s.context.GetString(auditVersionAfterCxt))
 }
 
 func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereNoVersionExists() {
@@ -113,11 +129,57 @@ func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhereNoVersionExists() {
 	distribution := &model.UserBookmarks{UserId: "1"}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(nil, nil)
 
-	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(distribution)).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarkAdded",
+		"BookmarkAdded")).Return(nil)
+
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
+		gomock.Eq("Bookmarks/1/1.0.1"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
+		gomock.Any()).Return("", nil)
+
+	PostBookmarks(s.context)
+
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+}
+
+func (s *BookmarksUpdateTestSuite) TestPostBookmarksQueuesEnrichment() {
+	s.T().Setenv("ENRICHMENT_QUEUE_URL", "test_queue")
+	mockutil.MockJSONRequest(s.context, "POST", nil, bookmarksUpdateRequest)
+
+	distribution := &model.UserBookmarks{UserId: "1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(nil, nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarkAdded",
+		"BookmarkAdded")).Return(nil)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bucket"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.1"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
-		gomock.Any()).Return(nil)
+		gomock.Any()).Return("", nil)
+
+	s.mockSQSClient.EXPECT().SendMessage(gomock.Eq("test_queue"),
+		gomock.Eq(`{"userId":"1","urls":["https://docs.ai21.com/docs/jurassic-2-models",`+
+			`"https://jalammar.github.io/illustrated-transformer/"]}`)).Return(nil, nil)
+
+	PostBookmarks(s.context)
+
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+}
+
+func (s *BookmarksUpdateTestSuite) TestPostBookmarksWhenEnrichmentDisabled() {
+	s.T().Setenv("ENRICHMENT_QUEUE_URL", "test_queue")
+	mockutil.MockJSONRequest(s.context, "POST", nil, bookmarksUpdateRequest)
+
+	distribution := &model.UserBookmarks{UserId: "1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(&model.UserBookmarks{
+		UserId:             "1",
+		Status:             constant.Success,
+		LatestVersion:      "1.0.89_DELETED",
+		EnrichmentDisabled: true,
+	}, nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarkAdded",
+		"BookmarkAdded")).Return(nil)
+
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("test_bucket"),
+		gomock.Eq("Bookmarks/1/1.0.90"), gomock.Eq(JSON), gomock.Eq(pkgS3.GZip),
+		gomock.Any()).Return("", nil)
 
 	PostBookmarks(s.context)
 
@@ -167,15 +229,43 @@ func (s *BookmarksUpdateTestSuite) TestDeleteBookmarks() {
 	}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(distributionResult, nil)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarksReplaced")).
+		Return(nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("test_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.45")).Return(nil)
 
+	snapshotKey := "Snapshots/1/0b2f6e5f8c8b0a0b6a7e1e7e0a7e8b3c/1.0.45"
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("test_bucket"), gomock.Eq("Snapshots/1/")).
+		Return([]types.Object{{Key: aws.String(snapshotKey)}}, nil)
+	s.mockS3Client.EXPECT().DeleteObjects(gomock.Eq("test_bucket"), gomock.Eq([]string{snapshotKey})).Return(nil)
+
 	DeleteBookmarks(s.context)
 
 	s.EqualValues(http.StatusAccepted, s.recorder.Code)
 	s.Equal(`{"message":"Bookmarks deletion complete"}`, s.recorder.Body.String())
+	s.Equal(helpers.AuditBookmarksDeleted, s.context.GetString(auditActionCxt))
+	s.Equal("1.0.45", s.context.GetString(auditVersionBeforeCxt))
+}
+
+func (s *BookmarksUpdateTestSuite) TestDeleteBookmarksWhenBookmarksChanged() {
+	mockutil.MockJSONRequest(s.context, "DELETE", nil, nil)
+
+	distribution := &model.UserBookmarks{UserId: "1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(&model.UserBookmarks{
+		UserId:        "1",
+		Status:        constant.Failed,
+		LatestVersion: "1.0.45",
+	}, nil)
+
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "BookmarksReplaced")).
+		Return(&dynamodbTypes.TransactionCanceledException{CancellationReasons: []dynamodbTypes.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}}})
+
+	DeleteBookmarks(s.context)
+
+	// The bookmarks are kept when they changed since they were read.
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
 }
 
 func (s *BookmarksUpdateTestSuite) TestDeleteBookmarksWhenDistVersionAlreadyDeleted() {
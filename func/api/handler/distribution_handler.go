@@ -1,37 +1,27 @@
 package handler
 
 import (
-	"encoding/json"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/constant"
 	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
 	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
-	"github.com/pranav-patil/go-serverless-api/pkg/s3"
 	"github.com/pranav-patil/go-serverless-api/pkg/service"
+	"github.com/pranav-patil/go-serverless-api/pkg/signing"
 	"github.com/pranav-patil/go-serverless-api/pkg/stepfunc"
-	"github.com/pranav-patil/go-serverless-api/pkg/util"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/exp/slices"
 )
 
-const (
-	YYYYMMDDHHMMSS              = "20060102150405"
-	YYYYMMDDHHMMSSSSS           = "20060102.150405.002"
-	SignedURLExpirationSecs     = 300
-	DistributionBookmarksLocked = "BookmarksLocked"
-)
-
 var (
 	NewDynamoDBClient     = dynamodb.NewDynamoDBClient
 	NewStepFunctionClient = stepfunc.NewStepFunctionClient
@@ -40,17 +30,8 @@ var (
 	TimeNow          = time.Now
 )
 
-type DownloadBookmarksInput struct {
-	UserId           string `json:"userId"`
-	OperationId      string `json:"operationId"`
-	DeviceId         string `json:"deviceId"`
-	InstanceId       string `json:"instanceId"`
-	Enabled          bool   `json:"enabled"`
-	BookmarksVersion string `json:"bookmarksVersion"`
-	Checksum         string `json:"fileChkSum"`
-	S3PresignedURL   string `json:"presignedUrl"`
-	RespToken        string `json:"respToken"`
-}
+// syncDisabledMessage rejects the distributions of the users who disabled the sync of their bookmarks.
+const syncDisabledMessage = "bookmarks sync is disabled"
 
 func DistributeBookmarks(context *gin.Context) {
 	request := models.DistributeBookmarksRequest{}
@@ -59,6 +40,13 @@ func DistributeBookmarks(context *gin.Context) {
 		log.Debug().Msg("No device IDs passed for distribute request; defaults to all devices")
 	}
 
+	if request.PackageFormat != "" {
+		if err := helpers.ValidatePackageFormat(request.PackageFormat); err != nil {
+			helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
 	userId := context.GetString(middleware.UserIDCxt)
 	dynamodbClient, err := NewDynamoDBClient()
 	if err != nil {
@@ -67,8 +55,8 @@ func DistributeBookmarks(context *gin.Context) {
 	}
 
 	distribution := helpers.GetBookmarkByUser(dynamodbClient, userId)
-	if errMsg := validateDistribution(distribution); errMsg != "" {
-		context.JSON(http.StatusForbidden, gin.H{"error": errMsg})
+	if status, errMsg := validateDistribution(distribution); errMsg != "" {
+		context.JSON(status, gin.H{"error": errMsg})
 		return
 	}
 
@@ -89,44 +77,144 @@ func DistributeBookmarks(context *gin.Context) {
 		return
 	}
 
-	distEntryPath := helpers.GetUserBookmarksS3Path(distribution)
-	packageEntryPath := strings.ReplaceAll(distEntryPath, fmt.Sprintf("/%s/", userId), fmt.Sprintf("/%s/", util.MD5Hash(userId)))
-	ipPackageBucketName := os.Getenv("BOOKMARKS_SUMMARY_BUCKET")
+	sfnClient, err := NewStepFunctionClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	distributionJobList, err := helpers.DistributeToDevices(dynamodbClient, s3Client, sfnClient, distribution,
+		deviceMap, request.DeviceIDs, request.PackageFormat)
+	if errors.Is(err, helpers.ErrDistributionPending) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "distribution is in Progress"})
+		return
+	} else if errors.Is(err, helpers.ErrDistributionChanged) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "distribution was cancelled before it started", err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	auditChange(context, helpers.AuditDistributionStarted, distribution.LatestVersion, distribution.LatestVersion)
+	trackEvent(context, helpers.AnalyticsBookmarksDistributed, map[string]interface{}{
+		"devices": len(distributionJobList), "packageFormat": request.PackageFormat})
+	response := models.DistributedBookmarksResponse{DistributionJobList: distributionJobList}
+	context.JSON(http.StatusOK, &response)
+}
 
-	err = updateDistributionStatus(dynamodbClient, distribution, DistributionBookmarksLocked)
+// CancelDistribution stops the pending distribution of the operation, or the distribution still locking the bookmarks
+// after the operation, which releases the user bookmarks for edits.
+func CancelDistribution(context *gin.Context) {
+	operationId, err := strconv.ParseInt(context.Param("operationId"), 10, 64)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid operation id", err)
+		return
+	}
+
+	dynamodbClient, err := NewDynamoDBClient()
 	if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
 
-	var preSignedURL, checksum string
+	userId := context.GetString(middleware.UserIDCxt)
+	distribution := helpers.GetBookmarkByUser(dynamodbClient, userId)
+	if distribution == nil || distribution.OperationId != operationId {
+		context.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
 
-	if !strings.HasSuffix(distribution.LatestVersion, helpers.DeletedVersionSuffix) {
-		checksum, err = getBookmarksAndCreatePackage(s3Client, distribution.LatestVersion, distEntryPath, packageEntryPath)
-		if err != nil {
-			helpers.SendInternalError(context, err)
-			_ = updateDistributionStatus(dynamodbClient, distribution, constant.Failed)
-			return
-		}
+	sfnClient, err := NewStepFunctionClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
 
-		preSignedURL, err = s3Client.NewSignedGetURL(ipPackageBucketName, packageEntryPath, SignedURLExpirationSecs)
-		if err != nil {
-			helpers.SendCustomErrorMessage(context, http.StatusInternalServerError, "error in creating presigned url", err)
-			_ = updateDistributionStatus(dynamodbClient, distribution, constant.Failed)
-			return
-		}
+	distributionJobList, err := helpers.CancelDistribution(dynamodbClient, sfnClient, distribution, operationId)
+	if errors.Is(err, helpers.ErrOperationNotPending) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "operation is not in progress", err)
+		return
+	} else if errors.Is(err, helpers.ErrDistributionChanged) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "distribution changed while cancelling", err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	publishWebhookEvent(userId, helpers.WebhookEventDistributionCancelled, helpers.GetDistributionEventData(distribution))
+	auditChange(context, helpers.AuditDistributionCancelled, distribution.LatestVersion, distribution.LatestVersion)
+
+	response := models.DistributedBookmarksResponse{
+		DistributionJobList: distributionJobList,
+		TotalCount:          len(distributionJobList),
 	}
+	context.JSON(http.StatusOK, &response)
+}
 
-	distributionJobList, err := addDistributionJobs(dynamodbClient, deviceMap, request.DeviceIDs,
-		distribution, preSignedURL, checksum)
+// RetryDistribution restarts the failed and timed out device distributions of the latest operation.
+func RetryDistribution(context *gin.Context) {
+	operationId, err := strconv.ParseInt(context.Param("operationId"), 10, 64)
+	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid operation id", err)
+		return
+	}
 
+	dynamodbClient, err := NewDynamoDBClient()
 	if err != nil {
 		helpers.SendInternalError(context, err)
-		_ = updateDistributionStatus(dynamodbClient, distribution, constant.Failed)
 		return
 	}
 
-	response := models.DistributedBookmarksResponse{DistributionJobList: distributionJobList}
+	userId := context.GetString(middleware.UserIDCxt)
+	distribution := helpers.GetBookmarkByUser(dynamodbClient, userId)
+	if distribution == nil || distribution.OperationId != operationId {
+		context.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+	if !distribution.SyncEnabled {
+		context.JSON(http.StatusConflict, gin.H{"error": syncDisabledMessage})
+		return
+	}
+
+	s3Client, err := NewS3Client()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	sfnClient, err := NewStepFunctionClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	distributionJobList, err := helpers.RetryDistribution(dynamodbClient, s3Client, sfnClient, distribution, operationId)
+	if errors.Is(err, helpers.ErrDistributionPending) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "distribution is in Progress"})
+		return
+	} else if errors.Is(err, helpers.ErrNothingToRetry) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "no failed device distributions to retry", err)
+		return
+	} else if errors.Is(err, helpers.ErrRetryPackageMissing) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict,
+			"the package of the operation is no longer available, distribute the latest bookmarks instead", err)
+		return
+	} else if errors.Is(err, helpers.ErrDistributionChanged) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, "distribution was cancelled before it started", err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	auditChange(context, helpers.AuditDistributionRetried, distribution.LatestVersion, distribution.LatestVersion)
+
+	response := models.DistributedBookmarksResponse{
+		DistributionJobList: distributionJobList,
+		TotalCount:          len(distributionJobList),
+	}
 	context.JSON(http.StatusOK, &response)
 }
 
@@ -144,15 +232,21 @@ func GetDistributedBookmarks(context *gin.Context) {
 		return
 	}
 
-	deviceDistribution := &model.BookmarkDistribution{UserId: userId}
-
-	result, err := dynamodbClient.GetRecordsByKeyAndFields(deviceDistribution)
+	query, err := getDistributionHistoryQuery(context, distribution)
 	if err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	appDistributions, next, err := helpers.GetDistributionHistory(dynamodbClient, userId, query)
+	if errors.Is(err, dynamodb.ErrInvalidPageToken) {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid next page token", err)
+		return
+	} else if err != nil {
 		helpers.SendInternalError(context, err)
 		return
 	}
-	appDistributions := result.([]model.BookmarkDistribution)
-	if len(appDistributions) == 0 {
+	if len(appDistributions) == 0 && query.Next == "" {
 		context.JSON(http.StatusNotFound, gin.H{"error": "no device distributions found"})
 		return
 	}
@@ -170,7 +264,8 @@ func GetDistributedBookmarks(context *gin.Context) {
 		}
 
 		var updated bool
-		updated, err = updateDelayedApplainceDistributionToTimeout(dynamodbClient, &distrib)
+		updated, err = updateDelayedApplainceDistributionToTimeout(dynamodbClient, &distrib,
+			helpers.GetDistributionTimeout(distribution))
 		if err != nil {
 			log.Error().Msgf("Dynamodb DeviceDistribution update failed for UserId %v, DeviceId %v: %v",
 				userId, deviceId, err.Error())
@@ -180,10 +275,17 @@ func GetDistributedBookmarks(context *gin.Context) {
 			shouldUpdateDistribution = updated
 		}
 
+		// The distributions recorded before the history was kept have no version, which is the latest version
+		// when they belong to the latest operation.
+		operationId, version := helpers.GetOperationId(&distrib), distrib.Version
+		if version == "" && operationId == strconv.FormatInt(distribution.OperationId, 10) {
+			version = distribution.LatestVersion
+		}
+
 		distributionJobList = append(distributionJobList, models.WebCrawlerJob{
-			ID:             strconv.FormatInt(distribution.OperationId, 10),
+			ID:             operationId,
 			DeviceId:       deviceId,
-			PackageVersion: distribution.LatestVersion,
+			PackageVersion: version,
 			State:          distrib.Status,
 			StatusMessage:  distrib.StatusMessage,
 			StartTime:      distrib.StartTimestamp,
@@ -192,7 +294,7 @@ func GetDistributedBookmarks(context *gin.Context) {
 	}
 
 	if shouldUpdateDistribution && distribution.Status == constant.Timeout {
-		err = dynamodbClient.UpdateRecordsByKey(distribution)
+		err = helpers.SaveDistributionTimeout(dynamodbClient, distribution)
 		if err != nil {
 			log.Error().Msgf("Distribution update failed for UserId %s: %v", userId, err.Error())
 		}
@@ -201,109 +303,99 @@ func GetDistributedBookmarks(context *gin.Context) {
 	response := models.DistributedBookmarksResponse{
 		DistributionJobList: distributionJobList,
 		TotalCount:          len(distributionJobList),
+		Next:                next,
 	}
 	context.JSON(http.StatusOK, &response)
 }
 
-func updateDelayedApplainceDistributionToTimeout(dynamodbClient dynamodb.DynamoDBClient,
-	appDistribution *model.BookmarkDistribution) (bool, error) {
-	if appDistribution != nil && appDistribution.Status == constant.Pending {
-		currentTime := TimeNow()
-		difference := (currentTime.Sub(appDistribution.StartTimestamp)).Minutes()
-
-		if difference > helpers.DelayedStatusTimeInMinutes {
-			appDistribution.Status = constant.Timeout
-			appDistribution.StatusMessage = fmt.Sprintf("Setting to timeout after %v mins", difference)
-			appDistribution.EndTimestamp = currentTime
-
-			log.Debug().Msgf("Set DeviceDistribution status for userId %s to timeout after %v mins of pending status.",
-				appDistribution.UserId, difference)
+// getDistributionHistoryQuery reads the history filters from the query parameters. Without any filter,
+// the distributions of the latest operation are selected.
+func getDistributionHistoryQuery(context *gin.Context,
+	distribution *model.UserBookmarks) (*helpers.DistributionHistoryQuery, error) {
+	query := &helpers.DistributionHistoryQuery{
+		OperationId: context.Query("operationId"),
+		DeviceId:    context.Query("deviceId"),
+		Next:        context.Query("next"),
+		Limit:       helpers.GetPageSize(distribution),
+	}
 
-			return true, dynamodbClient.UpdateRecordsByKey(appDistribution)
+	if query.OperationId != "" {
+		if _, err := strconv.ParseInt(query.OperationId, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid operationId %s", query.OperationId)
+		}
+	}
+	if query.DeviceId != "" {
+		if _, err := strconv.Atoi(query.DeviceId); err != nil {
+			return nil, fmt.Errorf("invalid deviceId %s", query.DeviceId)
 		}
 	}
-	return false, nil
-}
 
-func addDistributionJobs(dynamodbClient dynamodb.DynamoDBClient, appMap map[int]string,
-	requestDeviceIds []int, distribution *model.UserBookmarks, preSignedURL, checksum string) ([]models.WebCrawlerJob, error) {
-	var distributionJobList []models.WebCrawlerJob
+	for param, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if rawValue := context.Query(param); rawValue != "" {
+			parsedTime, err := time.Parse(time.RFC3339, rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a RFC 3339 timestamp", param)
+			}
+			*value = parsedTime
+		}
+	}
 
-	currentTime := TimeNow()
-	downloadBookmarksSfn := os.Getenv("DISTRIBUTION_STATE_MACHINE_ARN")
+	if limit, err := strconv.Atoi(context.Query("limit")); err == nil && limit > 0 {
+		query.Limit = limit
+	}
+	if query.Limit > helpers.MaxPageSize {
+		query.Limit = helpers.MaxPageSize
+	}
 
-	if len(requestDeviceIds) == 0 {
-		requestDeviceIds = make([]int, 0, len(appMap))
-		for k := range appMap {
-			requestDeviceIds = append(requestDeviceIds, k)
-		}
+	if query.OperationId == "" && query.DeviceId == "" && query.From.IsZero() && query.To.IsZero() {
+		query.OperationId = strconv.FormatInt(distribution.OperationId, 10)
 	}
+	return query, nil
+}
 
-	sfnClient, err := NewStepFunctionClient()
+// GetSigningKey publishes the public key which verifies the signature of the package manifests.
+func GetSigningKey(context *gin.Context) {
+	signer, err := helpers.NewPackageSigner()
 	if err != nil {
-		return nil, err
+		helpers.SendInternalError(context, err)
+		return
 	}
 
-	jobId, err := strconv.Atoi(currentTime.UTC().Format(YYYYMMDDHHMMSS))
+	publicKeyPEM, err := signing.MarshalPublicKeyPEM(signer.PublicKey())
 	if err != nil {
-		return nil, err
+		helpers.SendInternalError(context, err)
+		return
 	}
 
-	for _, device := range requestDeviceIds {
-		deviceId := strconv.Itoa(device)
+	response := models.SigningKeyResponse{
+		KeyId:        signer.KeyId(),
+		Algorithm:    signing.Algorithm,
+		PublicKey:    base64.StdEncoding.EncodeToString(signer.PublicKey()),
+		PublicKeyPEM: string(publicKeyPEM),
+	}
+	context.JSON(http.StatusOK, &response)
+}
 
-		appDistribution := &model.BookmarkDistribution{
-			Status:         constant.Pending,
-			StartTimestamp: currentTime,
-			EndTimestamp:   time.Time{},
-			UserId:         distribution.UserId,
-			DeviceId:       deviceId,
-		}
+func updateDelayedApplainceDistributionToTimeout(dynamodbClient dynamodb.DynamoDBClient,
+	appDistribution *model.BookmarkDistribution, timeout time.Duration) (bool, error) {
+	if appDistribution != nil && appDistribution.Status == constant.Pending {
+		currentTime := TimeNow()
+		difference := (currentTime.Sub(appDistribution.StartTimestamp)).Minutes()
 
-		err = dynamodbClient.AddRecord(appDistribution)
-		if err != nil {
-			return nil, err
-		}
+		if difference > timeout.Minutes() {
+			appDistribution.Status = constant.Timeout
+			appDistribution.StatusMessage = fmt.Sprintf("Setting to timeout after %v mins", difference)
+			appDistribution.EndTimestamp = currentTime
 
-		downloadBookmarks := DownloadBookmarksInput{
-			UserId:           distribution.UserId,
-			OperationId:      strconv.FormatInt(int64(jobId), 10),
-			DeviceId:         deviceId,
-			InstanceId:       appMap[device],
-			Enabled:          distribution.SyncEnabled,
-			BookmarksVersion: distribution.LatestVersion,
-			Checksum:         checksum,
-			S3PresignedURL:   preSignedURL,
-		}
+			log.Debug().Msgf("Set DeviceDistribution status for userId %s to timeout after %v mins of pending status.",
+				appDistribution.UserId, difference)
 
-		err = sfnClient.StartExecution(downloadBookmarksSfn, uuid.NewString(), downloadBookmarks)
-		if err != nil {
-			return nil, err
+			return true, helpers.WriteWithEvents(dynamodbClient, appDistribution.UserId,
+				[]models.DomainEvent{helpers.NewDeviceDistributionCompleted(appDistribution)},
+				dynamodb.TransactUpdate(appDistribution))
 		}
-
-		distributionJobList = append(distributionJobList, models.WebCrawlerJob{
-			ID:             strconv.FormatInt(int64(jobId), 10),
-			DeviceId:       device,
-			PackageVersion: distribution.LatestVersion,
-			State:          constant.Pending,
-			StatusMessage:  "Distribution Process Triggered",
-			StartTime:      TimeNow(),
-			EndTime:        time.Time{},
-		})
-	}
-
-	distribution.OperationId = int64(jobId)
-	distribution.Status = constant.Pending
-	distribution.StartTimestamp = currentTime
-	distribution.EndTimestamp = time.Time{}
-	distribution.ModifiedBookmarks = false
-
-	err = dynamodbClient.UpdateRecordsByKey(distribution)
-	if err != nil {
-		return nil, err
 	}
-
-	return distributionJobList, nil
+	return false, nil
 }
 
 func validateDevices(jwt, userId string, requestDeviceIds []int) (deviceMap map[int]string,
@@ -338,80 +430,19 @@ func validateDevices(jwt, userId string, requestDeviceIds []int) (deviceMap map[
 	return deviceMap, invalidDeviceIds, err
 }
 
-func getBookmarksAndCreatePackage(s3Client s3.S3Client, distVersion, distEntryPath, packageEntryPath string) (string, error) {
-	var bookmarksBuilder strings.Builder
-
-	bookmarksBucketName := os.Getenv("BOOKMARKS_BUCKET")
-
-	data, err := s3Client.GetObject(bookmarksBucketName, distEntryPath)
-	if err != nil {
-		return "", err
-	}
-
-	bookmarks := models.BookmarkList{}
-	err = json.Unmarshal(data, &bookmarks)
-	if err != nil {
-		return "", err
-	}
-
-	for i, p := range bookmarks.BookmarkEntry {
-		if i > 0 {
-			bookmarksBuilder.WriteString("\n")
-		}
-
-		err = util.ValidateURL(p.URL)
-		if err != nil {
-			log.Debug().Msgf("Invalid entry: %v", p.URL)
-		} else {
-			bookmarksBuilder.WriteString(p.URL)
-		}
-	}
-
-	files := map[string]string{"version": distVersion, "ip-filtering.pkg": bookmarksBuilder.String()}
-	content, err := util.CreateTarFile(files)
-	if err != nil {
-		return "", err
-	}
-
-	// Gzip the tar buffer
-	compressedContent, err := util.ByteCompress(content)
-	if err != nil {
-		return "", err
-	}
-
-	// Write out the .tar.gz buffer to the Package S3 bucket
-	ipPackageBucketName := os.Getenv("BOOKMARKS_SUMMARY_BUCKET")
-	log.Debug().Msgf("Package bucket, key: %v, %v", ipPackageBucketName, packageEntryPath)
-
-	err = s3Client.PutObject(ipPackageBucketName, packageEntryPath, JSON, "none", &compressedContent)
-	if err != nil {
-		return "", err
-	}
-
-	return util.SHA1Checksum(compressedContent), nil
-}
-
-func validateDistribution(distribution *model.UserBookmarks) string {
-	if distribution == nil {
-		return "no Bookmarks found to distribute"
+// validateDistribution returns the status and the error message when the bookmarks cannot be distributed.
+func validateDistribution(distribution *model.UserBookmarks) (int, string) {
+	if distribution == nil || distribution.LatestVersion == "" {
+		return http.StatusForbidden, "no Bookmarks found to distribute"
+	} else if distribution.Suspended {
+		return http.StatusForbidden, "account is suspended"
+	} else if !distribution.SyncEnabled {
+		return http.StatusConflict, syncDisabledMessage
 	} else if helpers.IsDistributionPending(distribution) {
-		return "distribution is in Progress"
+		return http.StatusForbidden, "distribution is in Progress"
 	} else {
-		return ""
-	}
-}
-
-func updateDistributionStatus(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks, status string) error {
-	if status == DistributionBookmarksLocked || status == constant.Pending {
-		distribution.StartTimestamp = TimeNow()
-	} else {
-		distribution.EndTimestamp = TimeNow()
-	}
-	err := dynamodbClient.UpdateRecordsByKey(distribution)
-	if err != nil {
-		log.Error().Msgf("failed to update distribution status %s: %v", status, err)
+		return http.StatusOK, ""
 	}
-	return err
 }
 
 func isDevicesValidationError(context *gin.Context, userId string, deviceMap map[int]string, invalidDeviceIds []int) bool {
@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +11,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
@@ -22,6 +27,9 @@ import (
 	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
 	apiService "github.com/pranav-patil/go-serverless-api/pkg/service"
 	apiMocks "github.com/pranav-patil/go-serverless-api/pkg/service/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/signing"
+	pkgSNS "github.com/pranav-patil/go-serverless-api/pkg/sns"
+	snsMocks "github.com/pranav-patil/go-serverless-api/pkg/sns/mocks"
 	pkgStepFunc "github.com/pranav-patil/go-serverless-api/pkg/stepfunc"
 	stepFuncMocks "github.com/pranav-patil/go-serverless-api/pkg/stepfunc/mocks"
 	"github.com/stretchr/testify/suite"
@@ -36,6 +44,7 @@ type DistributeBookmarksTestSuite struct {
 	mockS3Client       *s3Mocks.MockS3Client
 	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
 	mockStepFuncClient *stepFuncMocks.MockStepFuncClient
+	signer             signing.Signer
 	mockTimeNow        time.Time
 }
 
@@ -44,6 +53,10 @@ func TestDistributeBookmarksSuite(t *testing.T) {
 }
 
 func (s *DistributeBookmarksTestSuite) SetupSuite() {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	s.signer = signing.NewEd25519Signer(privateKey)
+
 	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
 	s.T().Setenv("BOOKMARKS_SUMMARY_BUCKET", "test_package_bucket")
 	s.T().Setenv("DISTRIBUTION_STATE_MACHINE_ARN", "test_distribution_state_machine_arn")
@@ -51,6 +64,10 @@ func (s *DistributeBookmarksTestSuite) SetupSuite() {
 }
 
 func (s *DistributeBookmarksTestSuite) SetupTest() {
+	helpers.NewPackageSigner = func() (signing.Signer, error) {
+		return s.signer, nil
+	}
+
 	s.recorder = httptest.NewRecorder()
 	s.context = mockutil.MockGinContext(s.recorder)
 	s.context.Set(middleware.UserIDCxt, "1")
@@ -171,23 +188,32 @@ func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWithValidAppIds()
 	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.89")).Return([]byte(s3Content), nil)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"),
-		gomock.Eq("Bookmarks/c4ca4238a0b923820dcc509a6f75849b/1.0.89"), gomock.Eq(JSON), gomock.Eq("none"),
-		gomock.Any()).Return(nil)
+	packagePath := mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/")
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"), packagePath).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"), packagePath, gomock.Eq("application/gzip"),
+		gomock.Eq("none"), gomock.Any()).Return(nil)
 
-	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"),
-		gomock.Eq("Bookmarks/c4ca4238a0b923820dcc509a6f75849b/1.0.89"), gomock.Eq(int64(300))).Return("CHECKSUM", nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"), packagePath,
+		gomock.Eq(int64(300))).Return("CHECKSUM", nil)
 
+	mockdist.Packages = nil
 	distribution := &model.UserBookmarks{UserId: "1"}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(mockdist, nil)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil).MaxTimes(2)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(distribution), gomock.Any()).
+		Return(nil).MaxTimes(2)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "DistributionStarted")).
+		Return(nil)
 
 	appDistribution := &model.BookmarkDistribution{UserId: "1"}
 	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(appDistribution)).Return(nil).MaxTimes(2)
 
 	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Eq("test_distribution_state_machine_arn"),
-		gomock.Any(), gomock.AssignableToTypeOf(DownloadBookmarksInput{})).Return(nil).MaxTimes(2)
+		gomock.Any(), gomock.AssignableToTypeOf(helpers.DownloadBookmarksInput{})).Return(nil).MaxTimes(2)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(helpers.DefaultPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{}, nil, nil).Times(2)
 
 	DistributeBookmarks(s.context)
 
@@ -199,6 +225,8 @@ func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWithValidAppIds()
 
 	s.NoError(err)
 	s.Equal(2, len(distributionResponse.DistributionJobList))
+	s.Len(mockdist.Packages, 1)
+	s.Contains(mockdist.Packages, "1.0.89/tar.gz")
 	s.Contains(distributionResponse.DistributionJobList,
 		models.WebCrawlerJob{
 			ID:             "20091110235234",
@@ -239,16 +267,20 @@ func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenBookmarksDelet
 		EndTimestamp:   time.Time{},
 		UserId:         "1",
 		LatestVersion:  "1.0.89_DELETED",
+		SyncEnabled:    true,
 	}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(mockDeletedDist, nil)
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil).MaxTimes(2)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(distribution), gomock.Any()).
+		Return(nil).MaxTimes(2)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(distribution, "DistributionStarted")).
+		Return(nil).MaxTimes(1)
 
 	appDistribution := &model.BookmarkDistribution{UserId: "1"}
 	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(appDistribution)).Return(nil).MaxTimes(2)
 
 	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Eq("test_distribution_state_machine_arn"),
-		gomock.Any(), gomock.AssignableToTypeOf(DownloadBookmarksInput{})).Return(nil).MaxTimes(2)
+		gomock.Any(), gomock.AssignableToTypeOf(helpers.DownloadBookmarksInput{})).Return(nil).MaxTimes(2)
 
 	DistributeBookmarks(s.context)
 
@@ -272,6 +304,14 @@ func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenBookmarksDelet
 		})
 }
 
+func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWithInvalidPackageFormat() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, models.DistributeBookmarksRequest{PackageFormat: "rar"})
+
+	DistributeBookmarks(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
 func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenDistributionPending() {
 	distributeRequest := models.DistributeBookmarksRequest{
 		DeviceIDs: []int{46747567, 67787448},
@@ -296,18 +336,48 @@ func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenDistributionPe
 	s.EqualValues(http.StatusForbidden, s.recorder.Code)
 }
 
+func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenAccountSuspended() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, models.DistributeBookmarksRequest{DeviceIDs: []int{46747567}})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", LatestVersion: "1.0.89", Suspended: true}, nil)
+
+	DistributeBookmarks(s.context)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+	s.Contains(s.recorder.Body.String(), "account is suspended")
+}
+
+func (s *DistributeBookmarksTestSuite) TestDistributeBookmarksWhenSyncDisabled() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, models.DistributeBookmarksRequest{DeviceIDs: []int{46747567}})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", LatestVersion: "1.0.89", Status: constant.Success}, nil)
+
+	DistributeBookmarks(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+	s.Contains(s.recorder.Body.String(), "bookmarks sync is disabled")
+}
+
 func (s *DistributeBookmarksTestSuite) TestGetBookmarksAndCreatePackage() {
 	s3Content := `{"bookmarks": [{ "url": "172.12.0.101/32" }]}`
 
 	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"),
 		gomock.Eq("Bookmarks/1/1.0.89")).Return([]byte(s3Content), nil)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"),
-		gomock.Eq("Bookmarks/1/1.0.89"), gomock.Eq(JSON), gomock.Eq("none"),
-		gomock.Any()).Return(nil)
+	var packagePath string
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"), gomock.Any()).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"), gomock.Any(), gomock.Eq(JSON),
+		gomock.Eq("none"), gomock.Any()).
+		DoAndReturn(func(_, key, _, _ string, _ *[]byte) error {
+			packagePath = key
+			return nil
+		})
 
-	_, err := getBookmarksAndCreatePackage(s.mockS3Client, "1.0.89", "Bookmarks/1/1.0.89", "Bookmarks/1/1.0.89")
+	checksum, err := helpers.GetBookmarksAndCreatePackage(s.mockS3Client, "1", helpers.PackageFormatJSON, "1.0.89")
 	s.NoError(err)
+	s.Equal("Packages/c4ca4238a0b923820dcc509a6f75849b/"+checksum+".json", packagePath)
 }
 
 func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarks() {
@@ -341,9 +411,8 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarks() {
 		},
 	}
 
-	deviceDistribution := &model.BookmarkDistribution{UserId: "1"}
-	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyAndFields(
-		gomock.Eq(deviceDistribution)).Return(appDistributionSlice, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(100)), gomock.Nil(), gomock.Eq(false)).Return(appDistributionSlice, nil, nil)
 
 	GetDistributedBookmarks(s.context)
 
@@ -364,9 +433,8 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenDistributio
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(mockdist, nil)
 
 	emptyDistribSlice := []model.BookmarkDistribution{}
-	deviceDistribution := &model.BookmarkDistribution{UserId: "1"}
-	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyAndFields(
-		gomock.Eq(deviceDistribution)).Return(emptyDistribSlice, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(100)), gomock.Nil(), gomock.Eq(false)).Return(emptyDistribSlice, nil, nil)
 
 	GetDistributedBookmarks(s.context)
 
@@ -402,9 +470,8 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenPendingIsDe
 		},
 	}
 
-	deviceDistribution := &model.BookmarkDistribution{UserId: "1"}
-	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyAndFields(
-		gomock.Eq(deviceDistribution)).Return(appDistributionSlice, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(100)), gomock.Nil(), gomock.Eq(false)).Return(appDistributionSlice, nil, nil)
 
 	testUpdatedItem1 := &model.BookmarkDistribution{
 		Status:         constant.Timeout,
@@ -414,7 +481,6 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenPendingIsDe
 		DeviceId:       "67787448",
 		StatusMessage:  "Setting to timeout after 30 mins",
 	}
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(gomock.Eq(testUpdatedItem1)).Return(nil)
 
 	testUpdatedItem2 := &model.BookmarkDistribution{
 		Status:         constant.Timeout,
@@ -424,7 +490,14 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenPendingIsDe
 		DeviceId:       "190345342",
 		StatusMessage:  "Setting to timeout after 30 mins",
 	}
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(gomock.Eq(testUpdatedItem2)).Return(nil)
+
+	var timedOut []model.Entity
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.BookmarkDistribution{},
+		"DeviceDistributionCompleted")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			timedOut = append(timedOut, items[0].Entity)
+			return nil
+		}).Times(2)
 
 	distribution := &model.UserBookmarks{UserId: "1"}
 	mockPendingDist := &model.UserBookmarks{
@@ -436,14 +509,12 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenPendingIsDe
 	}
 	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(distribution)).Return(mockPendingDist, nil)
 
-	mockFailedDist := &model.UserBookmarks{
-		Status:         constant.Timeout,
-		StartTimestamp: mockStartTime,
-		EndTimestamp:   s.mockTimeNow,
-		UserId:         "1",
-		LatestVersion:  TestLatestVersion,
-	}
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(gomock.Eq(mockFailedDist)).Return(nil)
+	var timeoutValues map[string]types.AttributeValue
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(distribution), gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			timeoutValues = setValues(&expr)
+			return nil
+		})
 
 	GetDistributedBookmarks(s.context)
 
@@ -455,4 +526,399 @@ func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWhenPendingIsDe
 
 	s.NoError(err)
 	s.Equal(3, len(distResponse.DistributionJobList))
+	s.Equal([]model.Entity{testUpdatedItem1, testUpdatedItem2}, timedOut)
+	s.Equal(&types.AttributeValueMemberS{Value: constant.Timeout}, timeoutValues["status"])
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelDistribution() {
+	s.T().Setenv("DISTRIBUTION_STATE_MACHINE_ARN", "arn:aws:states:us-east-1:123456789012:stateMachine:distribution")
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:         "1",
+			OperationId:    20091110235034,
+			Status:         constant.Pending,
+			StartTimestamp: s.mockTimeNow.Add(-2 * time.Minute),
+			LatestVersion:  "1.0.89",
+		}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Status: constant.Pending,
+				ExecutionName: "execution-1"},
+			{UserId: "1", DeviceId: "67787448", OperationId: "20091110235034", Status: constant.Success,
+				ExecutionName: "execution-2"},
+		}, nil, nil)
+
+	s.mockStepFuncClient.EXPECT().StopExecution(
+		gomock.Eq("arn:aws:states:us-east-1:123456789012:execution:distribution:execution-1"),
+		gomock.Eq(constant.Cancelled), gomock.Any()).Return(nil)
+
+	var appDistribution *model.BookmarkDistribution
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.BookmarkDistribution{},
+		"DeviceDistributionCompleted")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			appDistribution = items[0].Entity.(*model.BookmarkDistribution)
+			return nil
+		})
+
+	var distribution *model.UserBookmarks
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		DoAndReturn(func(entity model.Entity, _ expression.Expression) error {
+			distribution = entity.(*model.UserBookmarks)
+			return nil
+		})
+
+	CancelDistribution(s.context)
+
+	var response models.DistributedBookmarksResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(1, response.TotalCount)
+	s.Equal(46747567, response.DistributionJobList[0].DeviceId)
+	s.Equal(constant.Cancelled, appDistribution.Status)
+	s.Equal(constant.Cancelled, distribution.Status)
+	s.False(helpers.IsDistributionPending(distribution))
+	s.True(distribution.ModifiedBookmarks)
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelLockedDistribution() {
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+	s.T().Setenv("WEBHOOK_TOPIC_ARN", "test_webhook_topic_arn")
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:         "1",
+			OperationId:    20091110235034,
+			Status:         constant.BookmarksLocked,
+			StartTimestamp: s.mockTimeNow.Add(-time.Minute),
+			LatestVersion:  "1.0.89",
+		}, nil)
+
+	var statusValues map[string]types.AttributeValue
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			statusValues = setValues(&expr)
+			return nil
+		})
+
+	mockSNSClient := snsMocks.NewMockSNSClient(s.ctrl)
+	NewSNSClient = func() (pkgSNS.SNSClient, error) {
+		return mockSNSClient, nil
+	}
+	s.T().Cleanup(func() { NewSNSClient = pkgSNS.NewSNSClient })
+	mockSNSClient.EXPECT().PublishWithAttributes(gomock.Eq("test_webhook_topic_arn"), gomock.Any(),
+		gomock.Eq(map[string]string{"eventType": helpers.WebhookEventDistributionCancelled})).Return(nil)
+
+	CancelDistribution(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(&types.AttributeValueMemberS{Value: constant.Cancelled}, statusValues["status"])
+	s.Equal(&types.AttributeValueMemberBOOL{Value: true}, statusValues["modifiedBookmarks"])
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelDistributionWhenStopFails() {
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:         "1",
+			OperationId:    20091110235034,
+			Status:         constant.Pending,
+			StartTimestamp: s.mockTimeNow.Add(-2 * time.Minute),
+			LatestVersion:  "1.0.89",
+		}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Status: constant.Pending,
+				ExecutionName: "execution-1"},
+		}, nil, nil)
+
+	s.mockStepFuncClient.EXPECT().StopExecution(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("AccessDenied"))
+
+	CancelDistribution(s.context)
+
+	s.EqualValues(http.StatusInternalServerError, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelDistributionWhenNotPending() {
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235034, Status: constant.Success}, nil)
+
+	CancelDistribution(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelDistributionWithUnknownOperation() {
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235999, Status: constant.Pending}, nil)
+
+	CancelDistribution(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestCancelDistributionWithInvalidOperation() {
+	mockutil.MockJSONRequest(s.context, "DELETE", gin.Params{{Key: "operationId", Value: "abc"}}, nil)
+
+	CancelDistribution(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistribution() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:        "1",
+			OperationId:   20091110235034,
+			Status:        constant.Failed,
+			LatestVersion: "1.0.90",
+			SyncEnabled:   true,
+			Devices:       map[string]string{"46747567": "i-1", "67787448": "i-2", "78787878": "i-3"},
+			Packages:      map[string]string{"1.0.89/tar.gz": "5ef2cb2a"},
+		}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(helpers.MaxPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Version: "1.0.89",
+				PackageChecksum: "5ef2cb2a", Status: constant.Timeout},
+			{UserId: "1", DeviceId: "67787448", OperationId: "20091110235034", Version: "1.0.89",
+				PackageChecksum: "5ef2cb2a", Status: constant.Success},
+			{UserId: "1", DeviceId: "78787878", OperationId: "20091110235034", Version: "1.0.89",
+				PackageChecksum: "5ef2cb2a", Status: constant.Failed},
+		}, nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"DistributionStarted")).Return(nil)
+
+	// The package of the operation is reused, which is only signed again.
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/5ef2cb2a.tar.gz")).Return(true, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/5ef2cb2a.tar.gz"), gomock.Any()).
+		Return("https://presigned", nil)
+
+	var appDistributions []*model.BookmarkDistribution
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.BookmarkDistribution{})).
+		DoAndReturn(func(entity model.Entity) error {
+			appDistributions = append(appDistributions, entity.(*model.BookmarkDistribution))
+			return nil
+		}).Times(2)
+
+	var inputs []helpers.DownloadBookmarksInput
+	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_, _ string, input interface{}) error {
+			inputs = append(inputs, input.(helpers.DownloadBookmarksInput))
+			return nil
+		}).Times(2)
+
+	RetryDistribution(s.context)
+
+	var response models.DistributedBookmarksResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(2, response.TotalCount)
+	s.Equal(46747567, response.DistributionJobList[0].DeviceId)
+	s.Equal(78787878, response.DistributionJobList[1].DeviceId)
+	s.Equal("1.0.89", response.DistributionJobList[0].PackageVersion)
+	s.Equal(constant.Pending, appDistributions[0].Status)
+	s.Equal("20091110235234", appDistributions[0].OperationId)
+	s.Equal("20091110235034", appDistributions[0].RetryOf)
+	s.Equal("20091110235234", response.DistributionJobList[0].ID)
+	s.Equal("i-3", inputs[1].InstanceId)
+	s.Equal("5ef2cb2a", inputs[0].Checksum)
+	s.Equal("5ef2cb2a", appDistributions[0].PackageChecksum)
+	s.Equal("https://presigned", inputs[0].S3PresignedURL)
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistributionWhenPackageDeleted() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{
+			UserId:        "1",
+			OperationId:   20091110235034,
+			Status:        constant.Failed,
+			LatestVersion: "1.0.90",
+			SyncEnabled:   true,
+			Devices:       map[string]string{"46747567": "i-1"},
+		}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(helpers.MaxPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Version: "1.0.89",
+				PackageChecksum: "5ef2cb2a", Status: constant.Failed},
+		}, nil, nil)
+
+	// The bookmarks of the superseded version are deleted, hence its missing package cannot be created again.
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/5ef2cb2a.tar.gz")).Return(false, nil)
+
+	RetryDistribution(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistributionWithoutFailures() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235034, Status: constant.Success,
+			SyncEnabled: true, Devices: map[string]string{"46747567": "i-1"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Status: constant.Success},
+		}, nil, nil)
+
+	RetryDistribution(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistributionWhenPending() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235034, Status: constant.BookmarksLocked,
+			SyncEnabled: true}, nil)
+
+	RetryDistribution(s.context)
+
+	s.EqualValues(http.StatusForbidden, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistributionWhenSyncDisabled() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235034, Status: constant.Failed}, nil)
+
+	RetryDistribution(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+	s.Contains(s.recorder.Body.String(), "bookmarks sync is disabled")
+}
+
+func (s *DistributeBookmarksTestSuite) TestRetryDistributionWithUnknownOperation() {
+	mockutil.MockJSONRequest(s.context, "POST", gin.Params{{Key: "operationId", Value: "20091110235034"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", OperationId: 20091110235999, Status: constant.Failed}, nil)
+
+	RetryDistribution(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksHistoryOfDevice() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", gin.Params{
+		{Key: "deviceId", Value: "42"},
+		{Key: "from", Value: "2009-11-01T00:00:00Z"},
+		{Key: "limit", Value: "1"},
+	}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(mockdist, nil)
+
+	lastKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "UID#1"},
+		"SK": &types.AttributeValueMemberS{Value: "DID#42#OID#20091109235234"},
+	}
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(1)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{{
+			UserId:         "1",
+			DeviceId:       "42",
+			OperationId:    "20091109235234",
+			Version:        "1.0.88",
+			Status:         constant.Success,
+			StartTimestamp: s.mockTimeNow.Add(-24 * time.Hour),
+			EndTimestamp:   s.mockTimeNow.Add(-24 * time.Hour),
+		}}, lastKey, nil)
+
+	GetDistributedBookmarks(s.context)
+
+	var distResponse models.DistributedBookmarksResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &distResponse)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(1, distResponse.TotalCount)
+	s.Equal("20091109235234", distResponse.DistributionJobList[0].ID)
+	s.Equal("1.0.88", distResponse.DistributionJobList[0].PackageVersion)
+
+	nextKey, err := pkgDynamoDB.DecodePageToken(distResponse.Next)
+	s.NoError(err)
+	s.Equal(lastKey, nextKey)
+}
+
+func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWithInvalidFilters() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", gin.Params{{Key: "from", Value: "last tuesday"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(mockdist, nil)
+
+	GetDistributedBookmarks(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestGetDistributeBookmarksWithInvalidNextToken() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", gin.Params{{Key: "next", Value: "%%%"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(mockdist, nil)
+
+	GetDistributedBookmarks(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *DistributeBookmarksTestSuite) TestGetSigningKey() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	GetSigningKey(s.context)
+
+	var response models.SigningKeyResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(s.signer.KeyId(), response.KeyId)
+	s.Equal(signing.Algorithm, response.Algorithm)
+
+	publicKey, err := base64.StdEncoding.DecodeString(response.PublicKey)
+	s.NoError(err)
+	s.Equal([]byte(s.signer.PublicKey()), publicKey)
+	s.Contains(response.PublicKeyPEM, "BEGIN PUBLIC KEY")
+}
+
+func (s *DistributeBookmarksTestSuite) TestGetSigningKeyWhenKeyMissing() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+	helpers.NewPackageSigner = func() (signing.Signer, error) {
+		return nil, signing.ErrInvalidSigningKey
+	}
+
+	GetSigningKey(s.context)
+
+	s.EqualValues(http.StatusInternalServerError, s.recorder.Code)
 }
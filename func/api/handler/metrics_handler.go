@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+)
+
+const bookmarksCountCxt = "METRICS_BOOKMARKS_COUNT"
+
+// Metrics records the latency and the status code of the requests by their routes, along with the number of
+// bookmarks of the requests whose handlers counted them with countBookmarks.
+func Metrics() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.Request.Method + " " + c.FullPath()
+		metrics.RecordRequest(route, c.Writer.Status(), time.Since(start))
+
+		if count, ok := c.Get(bookmarksCountCxt); ok {
+			metrics.RecordBookmarks(route, count.(int))
+		}
+	}
+}
+
+// countBookmarks sets the number of bookmarks listed or changed by the request, which is recorded once the request
+// completes.
+func countBookmarks(context *gin.Context, count int) {
+	context.Set(bookmarksCountCxt, count)
+}
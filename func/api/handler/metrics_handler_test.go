@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+
+	buffer *bytes.Buffer
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (s *MetricsTestSuite) SetupTest() {
+	s.buffer = &bytes.Buffer{}
+	metrics.SetDefault(metrics.New(&metrics.WriterSink{Writer: s.buffer}, metrics.Config{}))
+}
+
+func (s *MetricsTestSuite) TearDownTest() {
+	metrics.SetDefault(metrics.New(metrics.NoopSink, metrics.Config{}))
+}
+
+func (s *MetricsTestSuite) TestMetricsRecordsRouteAndBookmarks() {
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/bookmarks/:url", func(c *gin.Context) {
+		countBookmarks(c, 3)
+		c.Status(http.StatusNoContent)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/bookmarks/example", http.NoBody)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	var documents []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s.buffer.String()), "\n") {
+		document := map[string]interface{}{}
+		s.NoError(json.Unmarshal([]byte(line), &document))
+		documents = append(documents, document)
+	}
+
+	s.Len(documents, 3)
+	s.Equal("GET /bookmarks/:url", documents[0]["Route"])
+	s.Equal(float64(1), documents[0]["Requests"])
+	s.Equal("204", documents[1]["StatusCode"])
+	s.Equal(float64(3), documents[2]["BookmarksPerRequest"])
+}
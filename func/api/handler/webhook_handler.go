@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/sns"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	NewSNSClient = sns.NewSNSClient
+)
+
+// PostWebhook subscribes a webhook to the events of the user. The secret which signs the deliveries is only
+// returned in this response.
+func PostWebhook(context *gin.Context) {
+	request := models.WebhookRequest{}
+	if err := context.BindJSON(&request); err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid json payload", err)
+		return
+	}
+
+	if err := helpers.ValidateWebhookRequest(&request); err != nil {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	webhook, err := helpers.CreateWebhook(dynamodbClient, userId, &request)
+	if errors.Is(err, helpers.ErrWebhookLimitReached) {
+		helpers.SendCustomErrorMessage(context, http.StatusConflict, err.Error(), err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	auditChange(context, helpers.AuditWebhookCreated, "", "")
+	response := toWebhookResponse(webhook)
+	response.Secret = webhook.Secret
+	context.JSON(http.StatusCreated, &response)
+}
+
+func GetWebhooks(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	webhooks, err := helpers.GetWebhooks(dynamodbClient, userId)
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	webhookList := make([]models.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		webhookList = append(webhookList, toWebhookResponse(&webhooks[i]))
+	}
+
+	context.JSON(http.StatusOK, &models.WebhooksResponse{Webhooks: webhookList, TotalCount: len(webhookList)})
+}
+
+func DeleteWebhook(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	err = helpers.DeleteWebhook(dynamodbClient, userId, context.Param("webhookId"))
+	if errors.Is(err, helpers.ErrWebhookNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	auditChange(context, helpers.AuditWebhookDeleted, "", "")
+	context.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// GetWebhookDeliveries returns the delivery log of the webhook from the latest delivery.
+func GetWebhookDeliveries(context *gin.Context) {
+	dynamodbClient, err := NewDynamoDBClient()
+	if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	userId := context.GetString(middleware.UserIDCxt)
+	webhookId := context.Param("webhookId")
+
+	if _, err = helpers.GetWebhook(dynamodbClient, userId, webhookId); errors.Is(err, helpers.ErrWebhookNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	limit := helpers.DefaultPageSize
+	if queryLimit, err := strconv.Atoi(context.Query("limit")); err == nil && queryLimit > 0 {
+		limit = queryLimit
+	}
+	if limit > helpers.MaxPageSize {
+		limit = helpers.MaxPageSize
+	}
+
+	deliveries, next, err := helpers.GetWebhookDeliveries(dynamodbClient, userId, webhookId, limit,
+		context.Query("next"))
+	if errors.Is(err, dynamodb.ErrInvalidPageToken) {
+		helpers.SendCustomErrorMessage(context, http.StatusBadRequest, "invalid next page token", err)
+		return
+	} else if err != nil {
+		helpers.SendInternalError(context, err)
+		return
+	}
+
+	deliveryList := make([]models.WebhookDeliveryEntry, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryList = append(deliveryList, models.WebhookDeliveryEntry{
+			DeliveryId:    delivery.DeliveryId,
+			EventId:       delivery.EventId,
+			EventType:     delivery.EventType,
+			Attempt:       delivery.Attempt,
+			Status:        delivery.Status,
+			StatusMessage: delivery.StatusMessage,
+			ResponseCode:  delivery.ResponseCode,
+			Timestamp:     delivery.Timestamp,
+			Duration:      delivery.DurationMillis,
+		})
+	}
+
+	context.JSON(http.StatusOK, &models.WebhookDeliveriesResponse{
+		Deliveries: deliveryList,
+		TotalCount: len(deliveryList),
+		Next:       next,
+	})
+}
+
+func toWebhookResponse(webhook *model.WebhookSubscription) models.WebhookResponse {
+	return models.WebhookResponse{
+		WebhookId:  webhook.WebhookId,
+		URL:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		Created:    webhook.CreatedTimestamp,
+	}
+}
+
+// publishWebhookEvent never fails the request, since the change which triggered the event is already stored.
+func publishWebhookEvent(userId, eventType string, data interface{}) {
+	topicARN := helpers.GetWebhookTopicARN()
+	if topicARN == "" {
+		return
+	}
+
+	snsClient, err := NewSNSClient()
+	if err == nil {
+		err = helpers.PublishWebhookEvent(snsClient, topicARN, userId, eventType, data)
+	}
+
+	if err != nil {
+		log.Error().Msgf("Failure in publishing %s event for userId %s: %v", eventType, userId, err)
+	}
+}
+
+func publishBookmarksUpdated(userId string, distribution *model.UserBookmarks, totalCount int) {
+	data := models.WebhookBookmarksData{TotalCount: totalCount}
+	if distribution != nil {
+		data.Version = distribution.LatestVersion
+	}
+	publishWebhookEvent(userId, helpers.WebhookEventBookmarksUpdated, &data)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	pkgDynamoDB "github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/encryption"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	recorder           *httptest.ResponseRecorder
+	context            *gin.Context
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockTimeNow        time.Time
+}
+
+func TestWebhookSuite(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
+
+func (s *WebhookTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *WebhookTestSuite) SetupTest() {
+	s.recorder = httptest.NewRecorder()
+	s.context = mockutil.MockGinContext(s.recorder)
+	s.context.Set(middleware.UserIDCxt, "1")
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	NewDynamoDBClient = func() (pkgDynamoDB.DynamoDBClient, error) {
+		return s.mockDynamoDBClient, nil
+	}
+
+	helpers.NewWebhookSecretCipher = func() (encryption.Cipher, error) {
+		return encryption.NewAESGCMCipher(make([]byte, 32))
+	}
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	helpers.TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *WebhookTestSuite) TestPostWebhook() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, &models.WebhookRequest{URL: "https://example.com/hook",
+		Secret: "0123456789abcdef", EventTypes: []string{helpers.WebhookEventBookmarksUpdated}})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.WebhookSubscription{})).Return(nil)
+
+	PostWebhook(s.context)
+
+	var response models.WebhookResponse
+	err := json.Unmarshal(s.recorder.Body.Bytes(), &response)
+
+	s.NoError(err)
+	s.EqualValues(http.StatusCreated, s.recorder.Code)
+	s.NotEmpty(response.WebhookId)
+	s.Equal("0123456789abcdef", response.Secret)
+	s.Equal([]string{helpers.WebhookEventBookmarksUpdated}, response.EventTypes)
+	s.Equal(s.mockTimeNow, response.Created)
+}
+
+func (s *WebhookTestSuite) TestPostWebhookWithInvalidEventType() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, &models.WebhookRequest{URL: "https://example.com/hook",
+		EventTypes: []string{"bookmarks.viewed"}})
+
+	PostWebhook(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
+
+func (s *WebhookTestSuite) TestPostWebhookWhenLimitReached() {
+	mockutil.MockJSONRequest(s.context, "POST", nil, &models.WebhookRequest{URL: "https://example.com/hook",
+		EventTypes: []string{helpers.WebhookEventBookmarksUpdated}})
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return(make([]model.WebhookSubscription, helpers.MaxWebhooksPerUser), nil, nil)
+
+	PostWebhook(s.context)
+
+	s.EqualValues(http.StatusConflict, s.recorder.Code)
+}
+
+func (s *WebhookTestSuite) TestGetWebhooksWithoutSecrets() {
+	mockutil.MockJSONRequest(s.context, "GET", nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{{UserId: "1", WebhookId: "w1", URL: "https://example.com/hook",
+			Secret: "0123456789abcdef", EventTypes: []string{helpers.WebhookEventBookmarksUpdated},
+			CreatedTimestamp: s.mockTimeNow}}, nil, nil)
+
+	GetWebhooks(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(`{"webhooks":[{"webhookId":"w1","url":"https://example.com/hook","eventTypes":["bookmarks.updated"],`+
+		`"created":"2009-11-10T23:52:34Z"}],"totalCount":1}`, s.recorder.Body.String())
+}
+
+func (s *WebhookTestSuite) TestDeleteWebhook() {
+	mockutil.MockJSONRequest(s.context, "DELETE", []gin.Param{{Key: "webhookId", Value: "w1"}}, nil)
+
+	webhook := &model.WebhookSubscription{UserId: "1", WebhookId: "w1"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(webhook)).Return(webhook, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteRecordByKey(gomock.Eq(webhook)).Return(nil)
+
+	DeleteWebhook(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+}
+
+func (s *WebhookTestSuite) TestDeleteWebhookNotFound() {
+	mockutil.MockJSONRequest(s.context, "DELETE", []gin.Param{{Key: "webhookId", Value: "w1"}}, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Any()).Return(nil, nil)
+
+	DeleteWebhook(s.context)
+
+	s.EqualValues(http.StatusNotFound, s.recorder.Code)
+}
+
+func (s *WebhookTestSuite) TestGetWebhookDeliveries() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", []gin.Param{{Key: "limit", Value: "5000"}}, nil)
+	s.context.Params = []gin.Param{{Key: "webhookId", Value: "w1"}}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.WebhookSubscription{UserId: "1", WebhookId: "w1"})).
+		Return(&model.WebhookSubscription{UserId: "1", WebhookId: "w1"}, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookDelivery{}),
+		gomock.Any(), gomock.Nil(), gomock.Eq(int32(helpers.MaxPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.WebhookDelivery{{UserId: "1", WebhookId: "w1", DeliveryId: "d1", EventId: "e1",
+			EventType: helpers.WebhookEventBookmarksUpdated, Attempt: 1, Status: constant.Success, ResponseCode: 200,
+			Timestamp: s.mockTimeNow, DurationMillis: 42}}, nil, nil)
+
+	GetWebhookDeliveries(s.context)
+
+	s.EqualValues(http.StatusOK, s.recorder.Code)
+	s.Equal(`{"deliveries":[{"deliveryId":"d1","eventId":"e1","eventType":"bookmarks.updated","attempt":1,`+
+		`"status":"Success","responseCode":200,"timestamp":"2009-11-10T23:52:34Z","durationMillis":42}],`+
+		`"totalCount":1,"next":""}`, s.recorder.Body.String())
+}
+
+func (s *WebhookTestSuite) TestGetWebhookDeliveriesWithInvalidPageToken() {
+	mockutil.MockJSONRequestWithQuery(s.context, "GET", []gin.Param{{Key: "next", Value: "not-a-token"}}, nil)
+	s.context.Params = []gin.Param{{Key: "webhookId", Value: "w1"}}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Any()).
+		Return(&model.WebhookSubscription{UserId: "1", WebhookId: "w1"}, nil)
+
+	GetWebhookDeliveries(s.context)
+
+	s.EqualValues(http.StatusBadRequest, s.recorder.Code)
+}
@@ -0,0 +1,177 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AccountCreated   = "account-created"
+	AccountSuspended = "account-suspended"
+	AccountDeleted   = "account-deleted"
+
+	purgeReportRootPath = "PurgeReports"
+)
+
+// GetPurgeReportS3Path keys the purge reports of a user by the purge time, which are kept after the purge.
+func GetPurgeReportS3Path(report *models.AccountPurgeReport) string {
+	return fmt.Sprintf("%s/%s/%s.json", purgeReportRootPath, report.UserId,
+		report.StartTime.UTC().Format(timeSortableIdFmt))
+}
+
+// SuspendAccount marks the bookmarks of the user suspended, which stops their distributions. The users without
+// bookmarks have nothing to suspend, and no record is created for them.
+func SuspendAccount(dynamodbClient dynamodb.DynamoDBClient, userId string) error {
+	expr, err := expression.NewBuilder().WithUpdate(dynamodb.GenUpdateBuilder(map[string]interface{}{
+		"suspended": true,
+	})).WithCondition(expression.AttributeExists(expression.Name("PK"))).Build()
+	if err != nil {
+		return err
+	}
+
+	err = dynamodbClient.UpdateRecordsByExpression(&model.UserBookmarks{UserId: userId}, expr)
+	if dynamodb.IsConditionalCheckFailed(err) {
+		log.Info().Msgf("No bookmarks to suspend for userId %s", userId)
+		return nil
+	}
+	return err
+}
+
+// PurgeAccount deletes all the records and objects of the deleted account, and writes the purge report.
+// A failure to delete a resource does not stop the purge of the remaining resources, and is returned
+// after the report is written so that the purge is retried.
+func PurgeAccount(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client,
+	event *models.AccountLifecycleEvent) (*models.AccountPurgeReport, error) {
+	userId := event.UserId
+	report := &models.AccountPurgeReport{
+		UserId:      userId,
+		RequestedAt: event.Timestamp,
+		StartTime:   TimeNow(),
+		Status:      constant.Success,
+	}
+
+	purge := func(resource string, deleteResource func() (int, error)) {
+		deletedCount, err := deleteResource()
+		info := models.PurgedResourceInfo{Resource: resource, DeletedCount: deletedCount}
+
+		if err != nil {
+			log.Error().Msgf("Failure in purging %s for userId %s: %v", resource, userId, err)
+			info.Error = err.Error()
+			report.Status = constant.Failed
+		}
+		report.Resources = append(report.Resources, info)
+	}
+
+	purge("UserBookmarks", func() (int, error) {
+		return 1, dynamodbClient.DeleteRecordByKey(&model.UserBookmarks{UserId: userId})
+	})
+	purge("BookmarkDistribution", func() (int, error) {
+		return dynamodbClient.DeleteBatchRecords(&model.BookmarkDistribution{UserId: userId}, nil)
+	})
+	purge("WebhookDelivery", func() (int, error) {
+		return deleteWebhookDeliveries(dynamodbClient, userId)
+	})
+	purge("WebhookSubscription", func() (int, error) {
+		return dynamodbClient.DeleteBatchRecords(&model.WebhookSubscription{UserId: userId}, nil)
+	})
+	purge("OutboxEvent", func() (int, error) {
+		return dynamodbClient.DeleteBatchRecords(&model.OutboxEvent{UserId: userId}, nil)
+	})
+	purge("ExportJob", func() (int, error) {
+		return dynamodbClient.DeleteBatchRecords(&model.ExportJob{UserId: userId}, nil)
+	})
+	purge("AuditRecord", func() (int, error) {
+		return dynamodbClient.DeleteBatchRecords(&model.AuditRecord{UserId: userId}, nil)
+	})
+
+	bookmarksBucket := os.Getenv("BOOKMARKS_BUCKET")
+	prefixes := []struct{ bucket, prefix string }{
+		{bookmarksBucket, fmt.Sprintf("Bookmarks/%s/", userId)},
+		{bookmarksBucket, GetUserSnapshotsS3Path(userId)},
+		{bookmarksBucket, fmt.Sprintf("%s/%s/", healthRootPath, userId)},
+		{bookmarksBucket, fmt.Sprintf("%s/%s/", exportRootPath, userId)},
+		{os.Getenv("BOOKMARKS_SUMMARY_BUCKET"), fmt.Sprintf("Packages/%s/", util.MD5Hash(userId))},
+	}
+
+	for _, p := range prefixes {
+		purge(fmt.Sprintf("s3://%s/%s", p.bucket, p.prefix), func() (int, error) {
+			return deleteObjectsWithPrefix(s3Client, p.bucket, p.prefix)
+		})
+	}
+
+	report.EndTime = TimeNow()
+	if err := putPurgeReport(s3Client, bookmarksBucket, report); err != nil {
+		return report, err
+	}
+
+	if report.Status != constant.Success {
+		return report, fmt.Errorf("purge of userId %s is incomplete", userId)
+	}
+	return report, nil
+}
+
+// deleteWebhookDeliveries deletes the deliveries of each webhook, which are partitioned by the webhook.
+func deleteWebhookDeliveries(dynamodbClient dynamodb.DynamoDBClient, userId string) (int, error) {
+	webhooks, err := GetWebhooks(dynamodbClient, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	var deletedCount int
+	for i := range webhooks {
+		count, err := dynamodbClient.DeleteBatchRecords(&model.WebhookDelivery{UserId: userId,
+			WebhookId: webhooks[i].WebhookId}, nil)
+		deletedCount += count
+
+		if err != nil {
+			return deletedCount, err
+		}
+	}
+	return deletedCount, nil
+}
+
+// deleteObjectsWithPrefix permanently deletes every version and delete marker of the objects, as deleting the keys
+// of a versioned bucket only adds delete markers. The versions are deleted a listing at a time, as a listing returns
+// up to 1000 versions, and the deletion stops with the errors of the last listing when it deleted none of them,
+// as the next listing would return the same versions.
+func deleteObjectsWithPrefix(s3Client s3.S3Client, bucket, prefix string) (int, error) {
+	var deletedCount int
+
+	for {
+		versions, _, err := s3Client.ListObjectVersions(bucket, prefix, nil)
+		if err != nil || len(versions) == 0 {
+			return deletedCount, err
+		}
+
+		count, err := s3Client.DeleteObjectVersions(bucket, versions)
+		deletedCount += count
+
+		if count == 0 {
+			if err == nil {
+				err = fmt.Errorf("no versions of %d listed were deleted", len(versions))
+			}
+			return deletedCount, err
+		} else if err != nil {
+			log.Warn().Msgf("Failure in deleting versions of s3://%s/%s, retrying: %v", bucket, prefix, err)
+		}
+	}
+}
+
+func putPurgeReport(s3Client s3.S3Client, bucket string, report *models.AccountPurgeReport) error {
+	content, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	return s3Client.PutObject(bucket, GetPurgeReportS3Path(report), "application/json", s3.GZip, &content)
+}
@@ -0,0 +1,199 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	s3Model "github.com/pranav-patil/go-serverless-api/pkg/s3/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	mockTimeNow        time.Time
+	event              *models.AccountLifecycleEvent
+}
+
+func TestAccountHelperSuite(t *testing.T) {
+	suite.Run(t, new(AccountHelperTestSuite))
+}
+
+func (s *AccountHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AccountHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
+	s.T().Setenv("BOOKMARKS_SUMMARY_BUCKET", "test_package_bucket")
+	s.event = &models.AccountLifecycleEvent{Type: AccountDeleted, UserId: "1",
+		Timestamp: s.mockTimeNow.Add(-time.Minute)}
+}
+
+func (s *AccountHelperTestSuite) TestSuspendAccount() {
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}),
+		gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			s.Contains(expr.Values(), ":0")
+			s.Contains(*expr.Condition(), "attribute_exists")
+			return nil
+		})
+
+	s.NoError(SuspendAccount(s.mockDynamoDBClient, "1"))
+}
+
+func (s *AccountHelperTestSuite) expectRecordsPurged() {
+	s.mockDynamoDBClient.EXPECT().DeleteRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).Return(nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.BookmarkDistribution{UserId: "1"}),
+		gomock.Nil()).Return(12, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{{UserId: "1", WebhookId: "w1"}, {UserId: "1", WebhookId: "w2"}}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.WebhookDelivery{UserId: "1", WebhookId: "w1"}),
+		gomock.Nil()).Return(3, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.WebhookDelivery{UserId: "1", WebhookId: "w2"}),
+		gomock.Nil()).Return(1, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.WebhookSubscription{UserId: "1"}),
+		gomock.Nil()).Return(2, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.OutboxEvent{UserId: "1"}),
+		gomock.Nil()).Return(5, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.ExportJob{UserId: "1"}),
+		gomock.Nil()).Return(1, nil)
+	s.mockDynamoDBClient.EXPECT().DeleteBatchRecords(gomock.Eq(&model.AuditRecord{UserId: "1"}),
+		gomock.Nil()).Return(7, nil)
+}
+
+func (s *AccountHelperTestSuite) TestPurgeAccount() {
+	s.expectRecordsPurged()
+
+	bookmarkVersions := []s3Model.ObjectVersion{
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v3", IsLatest: true},
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v2"},
+		{Key: "Bookmarks/1/1.0.1", VersionId: "v1", DeleteMarker: true},
+	}
+	gomock.InOrder(
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+			gomock.Nil()).Return(bookmarkVersions, nil, nil),
+		s.mockS3Client.EXPECT().DeleteObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq(bookmarkVersions)).
+			Return(3, nil),
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+			gomock.Nil()).Return(nil, nil, nil),
+	)
+	for _, prefix := range []string{"Snapshots/1/", "Health/1/", "Exports/1/"} {
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq(prefix), gomock.Nil()).
+			Return(nil, nil, nil)
+	}
+
+	packagePrefix := "Packages/" + util.MD5Hash("1") + "/"
+	packageVersions := []s3Model.ObjectVersion{{Key: packagePrefix + "5ef2cb2a.tar.gz", VersionId: "null"}}
+	gomock.InOrder(
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_package_bucket"), gomock.Eq(packagePrefix),
+			gomock.Nil()).Return(packageVersions, nil, nil),
+		s.mockS3Client.EXPECT().DeleteObjectVersions(gomock.Eq("test_package_bucket"), gomock.Eq(packageVersions)).
+			Return(1, nil),
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_package_bucket"), gomock.Eq(packagePrefix),
+			gomock.Nil()).Return(nil, nil, nil),
+	)
+
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bookmarks_bucket"),
+		gomock.Eq("PurgeReports/1/20091110235234.000000.json"), gomock.Eq("application/json"), gomock.Eq(s3.GZip),
+		gomock.Any()).Return(nil)
+
+	report, err := PurgeAccount(s.mockDynamoDBClient, s.mockS3Client, s.event)
+
+	s.NoError(err)
+	s.Equal(constant.Success, report.Status)
+	s.Equal(s.event.Timestamp, report.RequestedAt)
+	s.Equal([]models.PurgedResourceInfo{
+		{Resource: "UserBookmarks", DeletedCount: 1},
+		{Resource: "BookmarkDistribution", DeletedCount: 12},
+		{Resource: "WebhookDelivery", DeletedCount: 4},
+		{Resource: "WebhookSubscription", DeletedCount: 2},
+		{Resource: "OutboxEvent", DeletedCount: 5},
+		{Resource: "ExportJob", DeletedCount: 1},
+		{Resource: "AuditRecord", DeletedCount: 7},
+		{Resource: "s3://test_bookmarks_bucket/Bookmarks/1/", DeletedCount: 3},
+		{Resource: "s3://test_bookmarks_bucket/Snapshots/1/"},
+		{Resource: "s3://test_bookmarks_bucket/Health/1/"},
+		{Resource: "s3://test_bookmarks_bucket/Exports/1/"},
+		{Resource: "s3://test_package_bucket/" + packagePrefix, DeletedCount: 1},
+	}, report.Resources)
+}
+
+func (s *AccountHelperTestSuite) TestPurgeAccountWhenDeleteFails() {
+	s.expectRecordsPurged()
+
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+		gomock.Nil()).Return(nil, nil, errors.New("AccessDenied"))
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Any(), gomock.Any(), gomock.Nil()).Return(nil, nil, nil).Times(4)
+
+	// The incomplete purge is still reported before it is retried.
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bookmarks_bucket"), mockutil.HasPrefix("PurgeReports/1/"),
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	report, err := PurgeAccount(s.mockDynamoDBClient, s.mockS3Client, s.event)
+
+	s.Error(err)
+	s.Equal(constant.Failed, report.Status)
+	s.Equal("AccessDenied", report.Resources[7].Error)
+}
+
+func (s *AccountHelperTestSuite) TestPurgeAccountWhenVersionsAreNotDeleted() {
+	s.expectRecordsPurged()
+
+	versions := []s3Model.ObjectVersion{
+		{Key: "Bookmarks/1/1.0.1", VersionId: "v1"},
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v2"},
+	}
+	gomock.InOrder(
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+			gomock.Nil()).Return(versions, nil, nil),
+		s.mockS3Client.EXPECT().DeleteObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq(versions)).
+			Return(1, errors.New("failed to delete 1 objects: AccessDenied")),
+		// The versions which still fail to delete stop the purge of the prefix instead of listing them again.
+		s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+			gomock.Nil()).Return(versions[1:], nil, nil),
+		s.mockS3Client.EXPECT().DeleteObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq(versions[1:])).
+			Return(0, errors.New("failed to delete 1 objects: AccessDenied")),
+	)
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Any(), gomock.Any(), gomock.Nil()).Return(nil, nil, nil).Times(4)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bookmarks_bucket"), mockutil.HasPrefix("PurgeReports/1/"),
+		gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	report, err := PurgeAccount(s.mockDynamoDBClient, s.mockS3Client, s.event)
+
+	s.Error(err)
+	s.Equal(constant.Failed, report.Status)
+	s.Equal(1, report.Resources[7].DeletedCount)
+	s.Contains(report.Resources[7].Error, "AccessDenied")
+}
+
+func (s *AccountHelperTestSuite) TestSuspendAccountWithoutBookmarks() {
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}),
+		gomock.Any()).Return(&types.ConditionalCheckFailedException{})
+
+	s.NoError(SuspendAccount(s.mockDynamoDBClient, "1"))
+}
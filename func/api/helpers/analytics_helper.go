@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	kinesis "github.com/pranav-patil/go-serverless-api/pkg/kinesis"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	AnalyticsBookmarksListed      = "bookmarks.listed"
+	AnalyticsBookmarksSearched    = "bookmarks.searched"
+	AnalyticsBookmarksImported    = "bookmarks.imported"
+	AnalyticsBookmarksDistributed = "bookmarks.distributed"
+
+	defaultAnalyticsFlushInterval = 5 * time.Second
+	domainStatDayFmt              = "2006-01-02"
+	domainStatRetention           = 400 * 24 * time.Hour
+	// domainStatEventRetention covers the retention of the analytics stream, within which the events are redelivered.
+	domainStatEventRetention = 8 * 24 * time.Hour
+	// maxDomainsPerTransaction leaves room for the marker of the event in a transaction of up to 100 items.
+	maxDomainsPerTransaction = 99
+)
+
+// AnalyticsEmitter buffers the analytics events and puts them on the stream in batches.
+type AnalyticsEmitter interface {
+	Emit(partitionKey string, data []byte) error
+	Close() error
+}
+
+// NewAnalyticsEmitter returns the emitter of the stream configured by ANALYTICS_STREAM_NAME, or nil when
+// the analytics are disabled.
+func NewAnalyticsEmitter() (AnalyticsEmitter, error) {
+	streamName := os.Getenv("ANALYTICS_STREAM_NAME")
+	if streamName == "" {
+		return nil, nil
+	}
+
+	client, err := kinesis.NewKinesisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	flushInterval, err := time.ParseDuration(os.Getenv("ANALYTICS_FLUSH_INTERVAL"))
+	if err != nil || flushInterval <= 0 {
+		flushInterval = defaultAnalyticsFlushInterval
+	}
+
+	return kinesis.NewEmitter(client, kinesis.EmitterConfig{
+		StreamName:    streamName,
+		FlushInterval: flushInterval,
+	}), nil
+}
+
+// EmitAnalyticsEvent buffers the event on the emitter partitioned by the user. The events of a user are not
+// guaranteed to be in order, which their ids and timestamps restore.
+func EmitAnalyticsEvent(emitter AnalyticsEmitter, eventType, userId string, properties map[string]interface{}) error {
+	currentTime := TimeNow()
+	event := &models.AnalyticsEvent{
+		Id:         newTimeSortableId(currentTime),
+		Type:       eventType,
+		UserId:     userId,
+		Timestamp:  currentTime,
+		Properties: properties,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return emitter.Emit(userId, data)
+}
+
+// GetBookmarkDomains counts the bookmarks by the host of their URLs, without the www prefix.
+func GetBookmarkDomains(entries []models.BookmarkEntry) map[string]int {
+	domains := map[string]int{}
+
+	for i := range entries {
+		parsedURL, err := url.Parse(entries[i].URL)
+		if err != nil || parsedURL.Hostname() == "" {
+			continue
+		}
+		domains[strings.TrimPrefix(strings.ToLower(parsedURL.Hostname()), "www.")]++
+	}
+	return domains
+}
+
+// AggregateBookmarkedDomains adds the domains of the imported bookmarks to the daily counts of the domains.
+// The events of other types are ignored. The counts of an event are added along with the marker of the event in
+// a transaction, which skips the events already counted when the batch is retried.
+func AggregateBookmarkedDomains(dynamodbClient dynamodb.DynamoDBClient, events []*models.AnalyticsEvent) error {
+	for _, event := range events {
+		if event.Type != AnalyticsBookmarksImported {
+			continue
+		}
+
+		if err := addEventDomainCounts(dynamodbClient, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addEventDomainCounts(dynamodbClient dynamodb.DynamoDBClient, event *models.AnalyticsEvent) error {
+	counts, _ := event.Properties["domains"].(map[string]interface{})
+	domains := make([]string, 0, len(counts))
+	for domain, count := range counts {
+		if count, ok := count.(float64); ok && count > 0 {
+			domains = append(domains, domain)
+		}
+	}
+	// The domains are split in the same parts on every retry of the event.
+	slices.Sort(domains)
+
+	currentTime := TimeNow()
+	day := event.Timestamp.UTC().Format(domainStatDayFmt)
+	ttl := currentTime.Add(domainStatRetention).Unix()
+
+	for part := 0; part*maxDomainsPerTransaction < len(domains); part++ {
+		partDomains := domains[part*maxDomainsPerTransaction : min((part+1)*maxDomainsPerTransaction, len(domains))]
+
+		marker, err := newDomainStatEventMarker(event.Id, part, currentTime)
+		if err != nil {
+			return err
+		}
+
+		items := []model.TransactWriteItem{marker}
+		for _, domain := range partDomains {
+			update := expression.Add(expression.Name("bookmarkCount"), expression.Value(int64(counts[domain].(float64)))).
+				Set(expression.Name("domain"), expression.Value(domain)).
+				Set(expression.Name("day"), expression.Value(day)).
+				Set(expression.Name("Ttl"), expression.Value(ttl))
+
+			expr, err := expression.NewBuilder().WithUpdate(update).Build()
+			if err != nil {
+				return err
+			}
+			items = append(items, dynamodb.TransactUpdateByExpression(&model.DomainStat{Domain: domain, Day: day}, expr))
+		}
+
+		err = dynamodbClient.TransactWriteRecords(items)
+		if dynamodb.IsConditionalCheckFailed(err) {
+			log.Info().Msgf("Skipping part %d of analytics event %s counted before", part, event.Id)
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newDomainStatEventMarker returns the write of the marker of the part of the event, which fails when the part
+// was counted before.
+func newDomainStatEventMarker(eventId string, part int, currentTime time.Time) (model.TransactWriteItem, error) {
+	update := expression.Set(expression.Name("Ttl"), expression.Value(currentTime.Add(domainStatEventRetention).Unix()))
+	expr, err := expression.NewBuilder().WithUpdate(update).
+		WithCondition(expression.AttributeNotExists(expression.Name("PK"))).Build()
+	if err != nil {
+		return model.TransactWriteItem{}, err
+	}
+
+	return dynamodb.TransactUpdateByExpression(&model.DomainStatEvent{EventId: eventId, Part: strconv.Itoa(part)},
+		expr), nil
+}
@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	awsKinesis "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesisTypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	kinesis "github.com/pranav-patil/go-serverless-api/pkg/kinesis"
+	kinesisMocks "github.com/pranav-patil/go-serverless-api/pkg/kinesis/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type AnalyticsHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockKinesisClient  *kinesisMocks.MockKinesisClient
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockTimeNow        time.Time
+}
+
+func TestAnalyticsHelperSuite(t *testing.T) {
+	suite.Run(t, new(AnalyticsHelperTestSuite))
+}
+
+func (s *AnalyticsHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AnalyticsHelperTestSuite) SetupTest() {
+	s.mockKinesisClient = kinesisMocks.NewMockKinesisClient(s.ctrl)
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *AnalyticsHelperTestSuite) TestEmitAnalyticsEvent() {
+	emitter := kinesis.NewEmitter(s.mockKinesisClient, kinesis.EmitterConfig{StreamName: "analytics"})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(2)).
+		DoAndReturn(func(streamName string, records []kinesisTypes.PutRecordsRequestEntry) (*awsKinesis.PutRecordsOutput, error) {
+			s.Equal("1", *records[0].PartitionKey)
+			s.Equal("2", *records[1].PartitionKey)
+
+			event := models.AnalyticsEvent{}
+			s.NoError(json.Unmarshal(records[0].Data, &event))
+			s.Equal(AnalyticsBookmarksImported, event.Type)
+			s.Equal("1", event.UserId)
+			s.Equal(s.mockTimeNow, event.Timestamp)
+			s.Equal(map[string]interface{}{"count": float64(3), "format": "csv"}, event.Properties)
+			return &awsKinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil
+		})
+
+	s.NoError(EmitAnalyticsEvent(emitter, AnalyticsBookmarksImported, "1",
+		map[string]interface{}{"count": 3, "format": "csv"}))
+	s.NoError(EmitAnalyticsEvent(emitter, AnalyticsBookmarksListed, "2", nil))
+	s.NoError(emitter.Close())
+}
+
+func (s *AnalyticsHelperTestSuite) TestNewAnalyticsEmitterDisabled() {
+	os.Unsetenv("ANALYTICS_STREAM_NAME")
+
+	emitter, err := NewAnalyticsEmitter()
+	s.NoError(err)
+	s.Nil(emitter)
+}
+
+func (s *AnalyticsHelperTestSuite) TestGetBookmarkDomains() {
+	domains := GetBookmarkDomains([]models.BookmarkEntry{
+		{URL: "https://www.Example.com/a"},
+		{URL: "http://example.com/b"},
+		{URL: "https://golang.org"},
+		{URL: "not a url"},
+	})
+
+	s.Equal(map[string]int{"example.com": 2, "golang.org": 1}, domains)
+}
+
+func (s *AnalyticsHelperTestSuite) TestAggregateBookmarkedDomains() {
+	day := time.Date(2009, time.November, 10, 8, 0, 0, 0, time.UTC)
+	events := []*models.AnalyticsEvent{
+		{Id: "a1", Type: AnalyticsBookmarksImported, UserId: "1", Timestamp: day,
+			Properties: map[string]interface{}{"domains": map[string]interface{}{"example.com": float64(2)}}},
+		{Id: "a2", Type: AnalyticsBookmarksListed, UserId: "1", Timestamp: day,
+			Properties: map[string]interface{}{"count": float64(10)}},
+		{Id: "a3", Type: AnalyticsBookmarksImported, UserId: "2", Timestamp: day,
+			Properties: map[string]interface{}{"domains": map[string]interface{}{"example.com": float64(3)}}},
+	}
+
+	for _, event := range []struct{ id, count string }{{"a1", "2"}, {"a3", "3"}} {
+		event := event
+		s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).
+			DoAndReturn(func(items []model.TransactWriteItem) error {
+				s.Len(items, 2)
+				s.Equal(&model.DomainStatEvent{EventId: event.id, Part: "0"}, items[0].Entity)
+				s.Contains(*items[0].Expression.Condition(), "attribute_not_exists")
+
+				s.Equal(&model.DomainStat{Domain: "example.com", Day: "2009-11-10"}, items[1].Entity)
+				s.Contains(*items[1].Expression.Update(), "ADD")
+				s.Equal(&types.AttributeValueMemberN{Value: event.count}, items[1].Expression.Values()[":0"])
+				return nil
+			})
+	}
+
+	s.NoError(AggregateBookmarkedDomains(s.mockDynamoDBClient, events))
+}
+
+func (s *AnalyticsHelperTestSuite) TestAggregateBookmarkedDomainsSkipsCountedEvents() {
+	domains := map[string]interface{}{}
+	for i := 0; i < maxDomainsPerTransaction+1; i++ {
+		domains[fmt.Sprintf("example%03d.com", i)] = float64(1)
+	}
+	events := []*models.AnalyticsEvent{{Id: "a1", Type: AnalyticsBookmarksImported, UserId: "1",
+		Timestamp: s.mockTimeNow, Properties: map[string]interface{}{"domains": domains}}}
+
+	// The first part of the retried event was counted before its second part failed.
+	gomock.InOrder(
+		s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Len(maxDomainsPerTransaction+1)).
+			DoAndReturn(func(items []model.TransactWriteItem) error {
+				s.Equal(&model.DomainStatEvent{EventId: "a1", Part: "0"}, items[0].Entity)
+				s.Equal("example000.com", items[1].Entity.(*model.DomainStat).Domain)
+				return &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed")}}}
+			}),
+		s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Len(2)).
+			DoAndReturn(func(items []model.TransactWriteItem) error {
+				s.Equal(&model.DomainStatEvent{EventId: "a1", Part: "1"}, items[0].Entity)
+				s.Equal("example099.com", items[1].Entity.(*model.DomainStat).Domain)
+				return nil
+			}),
+	)
+
+	s.NoError(AggregateBookmarkedDomains(s.mockDynamoDBClient, events))
+}
@@ -0,0 +1,235 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	cloudwatch "github.com/pranav-patil/go-serverless-api/pkg/cloudwatch"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	kinesis "github.com/pranav-patil/go-serverless-api/pkg/kinesis"
+)
+
+const (
+	AuditBookmarksAdded        = "bookmarks.added"
+	AuditBookmarksReplaced     = "bookmarks.replaced"
+	AuditBookmarksResolved     = "bookmarks.resolved"
+	AuditRedirectsReplaced     = "bookmarks.redirects-replaced"
+	AuditBookmarksDeleted      = "bookmarks.deleted"
+	AuditBookmarkEntryDeleted  = "bookmark-entry.deleted"
+	AuditConfigUpdated         = "config.updated"
+	AuditDistributionStarted   = "distribution.started"
+	AuditDistributionCancelled = "distribution.cancelled"
+	AuditDistributionRetried   = "distribution.retried"
+	AuditWebhookCreated        = "webhook.created"
+	AuditWebhookDeleted        = "webhook.deleted"
+	AuditExportStarted         = "export.started"
+
+	AuditSinkCloudWatch = "cloudwatch"
+	AuditSinkKinesis    = "kinesis"
+	AuditSinkFile       = "file"
+
+	defaultAuditRetentionDays = 90
+	defaultAuditLogStream     = "bookmarks-api"
+	defaultAuditLogFile       = "/tmp/audit.log"
+)
+
+// AuditSink ships the audit records out of the service, for their retention beyond the audit table.
+type AuditSink interface {
+	Write(entry *models.AuditEntry) error
+}
+
+// AuditQuery selects the audit records of a user, where the empty fields match everything.
+type AuditQuery struct {
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Next   string
+}
+
+// NewAuditSink returns the sink configured by AUDIT_SINK, or nil when the records are only kept in the audit table.
+func NewAuditSink() (AuditSink, error) {
+	switch sinkType := os.Getenv("AUDIT_SINK"); sinkType {
+	case "":
+		return nil, nil
+	case AuditSinkCloudWatch:
+		client, err := cloudwatch.NewCloudWatchClient()
+		if err != nil {
+			return nil, err
+		}
+
+		streamName := os.Getenv("AUDIT_LOG_STREAM")
+		if streamName == "" {
+			streamName = defaultAuditLogStream
+		}
+		return &CloudWatchAuditSink{Client: client, GroupName: os.Getenv("AUDIT_LOG_GROUP"),
+			StreamName: streamName}, nil
+	case AuditSinkKinesis:
+		client, err := kinesis.NewKinesisClient()
+		if err != nil {
+			return nil, err
+		}
+		return &KinesisAuditSink{Client: client, StreamName: os.Getenv("AUDIT_STREAM_NAME")}, nil
+	case AuditSinkFile:
+		path := os.Getenv("AUDIT_LOG_FILE")
+		if path == "" {
+			path = defaultAuditLogFile
+		}
+		return &FileAuditSink{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %s", sinkType)
+	}
+}
+
+// CloudWatchAuditSink writes each audit record as a log event of the log stream.
+type CloudWatchAuditSink struct {
+	Client        cloudwatch.CloudWatchClient
+	GroupName     string
+	StreamName    string
+	sequenceToken string
+}
+
+func (sink *CloudWatchAuditSink) Write(entry *models.AuditEntry) error {
+	message, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	sink.sequenceToken, err = sink.Client.PutLogEvents(sink.GroupName, sink.StreamName, sink.sequenceToken,
+		string(message))
+	return err
+}
+
+// KinesisAuditSink puts the audit records on the stream partitioned by the user, which keeps the records
+// of a user in order.
+type KinesisAuditSink struct {
+	Client     kinesis.KinesisClient
+	StreamName string
+}
+
+func (sink *KinesisAuditSink) Write(entry *models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = sink.Client.PutRecordWithPartitionKey(sink.StreamName, entry.UserId, data)
+	return err
+}
+
+// FileAuditSink appends the audit records to the file as JSON lines.
+type FileAuditSink struct {
+	Path string
+}
+
+func (sink *FileAuditSink) Write(entry *models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(sink.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func GetAuditRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("AUDIT_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultAuditRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// RecordAudit stores the audit record in the audit table and writes it to the sink when there is one.
+// The record is written to the sink even when it fails to be stored, so that the change is still audited.
+func RecordAudit(dynamodbClient dynamodb.DynamoDBClient, sink AuditSink, record *model.AuditRecord) error {
+	currentTime := TimeNow()
+	record.RecordId = newTimeSortableId(currentTime)
+	record.Timestamp = currentTime
+	record.Ttl = currentTime.Add(GetAuditRetention()).Unix()
+
+	err := dynamodbClient.AddRecord(record)
+	if sink != nil {
+		err = errors.Join(err, sink.Write(ToAuditEntry(record)))
+	}
+	return err
+}
+
+func ToAuditEntry(record *model.AuditRecord) *models.AuditEntry {
+	return &models.AuditEntry{
+		Id:            record.RecordId,
+		Timestamp:     record.Timestamp,
+		UserId:        record.UserId,
+		Actor:         record.Actor,
+		Role:          record.Role,
+		Action:        record.Action,
+		List:          record.List,
+		VersionBefore: record.VersionBefore,
+		VersionAfter:  record.VersionAfter,
+		RequestId:     record.RequestId,
+		SourceIP:      record.SourceIP,
+	}
+}
+
+// GetAuditRecords returns a page of the audit records of the user from the latest record. The time range
+// is matched on the record ids, which start with the time of the record.
+func GetAuditRecords(dynamodbClient dynamodb.DynamoDBClient, userId string,
+	query *AuditQuery) ([]model.AuditRecord, string, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.AuditRecord{UserId: userId})
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	fromKey := "RID#" + query.From.UTC().Format(timeSortableIdFmt)
+	// The suffix sorts after the unique part of the record ids of the same time.
+	toKey := "RID#" + query.To.UTC().Format(timeSortableIdFmt) + "~"
+
+	switch {
+	case !query.From.IsZero() && !query.To.IsZero():
+		keyCondition = keyCondition.And(expression.Key("SK").Between(expression.Value(fromKey),
+			expression.Value(toKey)))
+	case !query.From.IsZero():
+		keyCondition = keyCondition.And(expression.Key("SK").GreaterThanEqual(expression.Value(fromKey)))
+	case !query.To.IsZero():
+		keyCondition = keyCondition.And(expression.Key("SK").LessThanEqual(expression.Value(toKey)))
+	}
+
+	var filter *expression.ConditionBuilder
+	if query.Action != "" {
+		actionFilter := expression.Name("action").Equal(expression.Value(query.Action))
+		filter = &actionFilter
+	}
+
+	lastEvaluatedKey, err := dynamodb.DecodePageToken(query.Next)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, lastEvaluatedKey, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.AuditRecord{},
+		keyCondition, filter, int32(query.Limit), lastEvaluatedKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := dynamodb.EncodePageToken(lastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.([]model.AuditRecord), next, nil
+}
@@ -0,0 +1,164 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	cloudwatchMocks "github.com/pranav-patil/go-serverless-api/pkg/cloudwatch/mocks"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	kinesisMocks "github.com/pranav-patil/go-serverless-api/pkg/kinesis/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockKinesisClient  *kinesisMocks.MockKinesisClient
+	mockTimeNow        time.Time
+}
+
+func TestAuditHelperSuite(t *testing.T) {
+	suite.Run(t, new(AuditHelperTestSuite))
+}
+
+func (s *AuditHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AuditHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockKinesisClient = kinesisMocks.NewMockKinesisClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *AuditHelperTestSuite) newAuditRecord() *model.AuditRecord {
+	return &model.AuditRecord{UserId: "1", Actor: "urn:emprovise:identity:us-east-1:1:user/admin",
+		Role: "full-access", Action: AuditBookmarksAdded, List: "1", VersionBefore: "1.0.1", VersionAfter: "1.0.2",
+		RequestId: "r1", SourceIP: "10.0.0.1"}
+}
+
+func (s *AuditHelperTestSuite) TestRecordAudit() {
+	record := s.newAuditRecord()
+
+	s.mockDynamoDBClient.EXPECT().AddRecord(gomock.Eq(record)).Return(nil)
+	s.mockKinesisClient.EXPECT().PutRecordWithPartitionKey(gomock.Eq("audit"), gomock.Eq("1"), gomock.Any()).
+		DoAndReturn(func(streamName, partitionKey string, data []byte) (interface{}, error) {
+			var entry models.AuditEntry
+			s.NoError(json.Unmarshal(data, &entry))
+			s.Equal(record.RecordId, entry.Id)
+			s.Equal("1.0.1", entry.VersionBefore)
+			s.Equal("1.0.2", entry.VersionAfter)
+			s.Equal("10.0.0.1", entry.SourceIP)
+			return nil, nil
+		})
+
+	err := RecordAudit(s.mockDynamoDBClient, &KinesisAuditSink{Client: s.mockKinesisClient, StreamName: "audit"},
+		record)
+
+	s.NoError(err)
+	s.True(strings.HasPrefix(record.RecordId, "20091110235234.000000-"))
+	s.Equal(s.mockTimeNow, record.Timestamp)
+	s.Equal(s.mockTimeNow.Add(defaultAuditRetentionDays*24*time.Hour).Unix(), record.Ttl)
+}
+
+func (s *AuditHelperTestSuite) TestRecordAuditWhenStoreFails() {
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.AuditRecord{})).
+		Return(errors.New("ProvisionedThroughputExceededException"))
+	// The record is still shipped to the sink.
+	s.mockKinesisClient.EXPECT().PutRecordWithPartitionKey(gomock.Eq("audit"), gomock.Eq("1"), gomock.Any()).
+		Return(nil, nil)
+
+	err := RecordAudit(s.mockDynamoDBClient, &KinesisAuditSink{Client: s.mockKinesisClient, StreamName: "audit"},
+		s.newAuditRecord())
+
+	s.ErrorContains(err, "ProvisionedThroughputExceededException")
+}
+
+func (s *AuditHelperTestSuite) TestCloudWatchAuditSink() {
+	mockCloudWatchClient := cloudwatchMocks.NewMockCloudWatchClient(s.ctrl)
+	sink := &CloudWatchAuditSink{Client: mockCloudWatchClient, GroupName: "audit", StreamName: "bookmarks-api"}
+
+	gomock.InOrder(
+		mockCloudWatchClient.EXPECT().PutLogEvents(gomock.Eq("audit"), gomock.Eq("bookmarks-api"), gomock.Eq(""),
+			gomock.Any()).Return("token1", nil),
+		mockCloudWatchClient.EXPECT().PutLogEvents(gomock.Eq("audit"), gomock.Eq("bookmarks-api"),
+			gomock.Eq("token1"), gomock.Any()).Return("token2", nil),
+	)
+
+	// The sequence token of the previous write is reused by the next write.
+	s.NoError(sink.Write(ToAuditEntry(s.newAuditRecord())))
+	s.NoError(sink.Write(ToAuditEntry(s.newAuditRecord())))
+}
+
+func (s *AuditHelperTestSuite) TestFileAuditSink() {
+	sink := &FileAuditSink{Path: filepath.Join(s.T().TempDir(), "audit.log")}
+
+	s.NoError(sink.Write(&models.AuditEntry{Id: "a1", UserId: "1", Action: AuditBookmarksAdded}))
+	s.NoError(sink.Write(&models.AuditEntry{Id: "a2", UserId: "1", Action: AuditBookmarksDeleted}))
+
+	content, err := os.ReadFile(sink.Path)
+	s.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	s.Len(lines, 2)
+
+	var entry models.AuditEntry
+	s.NoError(json.Unmarshal([]byte(lines[1]), &entry))
+	s.Equal("a2", entry.Id)
+	s.Equal(AuditBookmarksDeleted, entry.Action)
+}
+
+func (s *AuditHelperTestSuite) TestNewAuditSink() {
+	s.T().Setenv("AUDIT_SINK", "")
+	sink, err := NewAuditSink()
+	s.NoError(err)
+	s.Nil(sink)
+
+	s.T().Setenv("AUDIT_SINK", AuditSinkFile)
+	sink, err = NewAuditSink()
+	s.NoError(err)
+	s.Equal(&FileAuditSink{Path: defaultAuditLogFile}, sink)
+
+	s.T().Setenv("AUDIT_SINK", "syslog")
+	_, err = NewAuditSink()
+	s.Error(err)
+}
+
+func (s *AuditHelperTestSuite) TestGetAuditRecords() {
+	query := &AuditQuery{Action: AuditBookmarksAdded, From: s.mockTimeNow.Add(-time.Hour), Limit: 10}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.AuditRecord{}),
+		gomock.Any(), gomock.Not(gomock.Nil()), gomock.Eq(int32(10)), gomock.Nil(), gomock.Eq(false)).
+		DoAndReturn(func(entity model.Entity, keyCondition expression.KeyConditionBuilder,
+			filter *expression.ConditionBuilder, pageLimit int32, lastEvalKey interface{},
+			scanIndexForward bool) (interface{}, interface{}, error) {
+			expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(*filter).Build()
+			s.NoError(err)
+			s.Contains(*expr.KeyCondition(), ">=")
+			s.Contains(expr.Values(), ":1")
+			return []model.AuditRecord{{UserId: "1", RecordId: "20091110235234.000000-0a1b2c3d",
+				Action: AuditBookmarksAdded}}, nil, nil
+		})
+
+	records, next, err := GetAuditRecords(s.mockDynamoDBClient, "1", query)
+
+	s.NoError(err)
+	s.Empty(next)
+	s.Len(records, 1)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/constant"
 	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
@@ -29,7 +30,9 @@ var (
 )
 
 func GetUserBookmarksS3Path(userBookmarks *model.UserBookmarks) string {
-	if userBookmarks == nil || strings.HasSuffix(userBookmarks.LatestVersion, DeletedVersionSuffix) {
+	// Records created by the settings api have no version until the first bookmarks are added.
+	if userBookmarks == nil || userBookmarks.LatestVersion == "" ||
+		strings.HasSuffix(userBookmarks.LatestVersion, DeletedVersionSuffix) {
 		return ""
 	}
 
@@ -61,7 +64,7 @@ func updateDelayedDistributionToTimeout(userBookmarks *model.UserBookmarks) {
 		currentTime := TimeNow()
 		difference := (currentTime.Sub(userBookmarks.StartTimestamp)).Minutes()
 
-		if difference > DelayedStatusTimeInMinutes {
+		if difference > GetDistributionTimeout(userBookmarks).Minutes() {
 			userBookmarks.Status = constant.Timeout
 			userBookmarks.EndTimestamp = currentTime
 			log.Debug().Msgf("Set Bookmark Distribution status for userId %s to timeout after %v mins of pending status.",
@@ -70,22 +73,63 @@ func updateDelayedDistributionToTimeout(userBookmarks *model.UserBookmarks) {
 	}
 }
 
+// AddOrUpdateUserBookmarks records the version of the user bookmarks, along with the domain events of the change.
+// The existing record is updated only while it has the version and status it was read with, so that neither the
+// concurrent changes of the bookmarks nor a distribution started meanwhile are overwritten, and ErrBookmarksChanged
+// is returned otherwise. Only the version attributes are updated, which leaves the settings of the user unchanged.
 func AddOrUpdateUserBookmarks(dynamodbClient dynamodb.DynamoDBClient, userBookmarks *model.UserBookmarks,
-	userId, distVersion string, modifiedBookmarks bool) (err error) {
+	userId, distVersion string, modifiedBookmarks bool, events ...models.DomainEvent) error {
+	var modifiedTimestamp time.Time
+	if modifiedBookmarks {
+		modifiedTimestamp = TimeNow()
+	}
+
 	if userBookmarks == nil {
 		userBookmarks = &model.UserBookmarks{
 			UserId:            userId,
 			SyncEnabled:       true,
 			LatestVersion:     distVersion,
 			ModifiedBookmarks: modifiedBookmarks,
+			ModifiedTimestamp: modifiedTimestamp,
 		}
-		err = dynamodbClient.AddRecord(userBookmarks)
-	} else {
-		userBookmarks.ModifiedBookmarks = modifiedBookmarks
-		userBookmarks.LatestVersion = distVersion
-		err = dynamodbClient.UpdateRecordsByKey(userBookmarks)
+		return WriteWithEvents(dynamodbClient, userId, events, dynamodb.TransactAdd(userBookmarks))
+	}
+
+	updates := map[string]interface{}{
+		"latestVersion":     distVersion,
+		"modifiedBookmarks": modifiedBookmarks,
+		// The changed bookmarks could distribute where the previous ones failed.
+		"distributionFailures": 0,
+	}
+	if modifiedBookmarks {
+		updates["modifiedTs"] = modifiedTimestamp
+	}
+
+	updated, err := updateWithCondition(dynamodbClient, userId, &model.UserBookmarks{UserId: userId},
+		versionUnchangedCondition(userBookmarks), updates, events...)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("bookmarks of userId %s not updated: %w", userId, ErrBookmarksChanged)
 	}
-	return err
+
+	userBookmarks.ModifiedBookmarks = modifiedBookmarks
+	userBookmarks.ModifiedTimestamp = modifiedTimestamp
+	userBookmarks.LatestVersion = distVersion
+	userBookmarks.DistributionFailures = 0
+	return nil
+}
+
+// versionUnchangedCondition matches the user bookmarks still at the version and status they were read with.
+func versionUnchangedCondition(userBookmarks *model.UserBookmarks) expression.ConditionBuilder {
+	versionName := expression.Name("latestVersion")
+
+	versionCondition := versionName.Equal(expression.Value(userBookmarks.LatestVersion))
+	if userBookmarks.LatestVersion == "" {
+		versionCondition = expression.AttributeNotExists(versionName)
+	}
+	return versionCondition.And(statusUnchangedCondition(userBookmarks.Status))
 }
 
 func ConvertCSVAndValidateBookmarks(content string) (validBookmarks, rejectedBookmarks []models.BookmarkEntry, err error) {
@@ -128,7 +172,10 @@ func ValidateBookmarks(bookmarks []models.BookmarkEntry) (validBookmarks, reject
 }
 
 func GetBookmarksS3Object(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, userId string) ([]byte, error) {
-	userBookmarks := GetBookmarkByUser(dynamodbClient, userId)
+	return GetUserBookmarksS3Object(s3Client, GetBookmarkByUser(dynamodbClient, userId), userId)
+}
+
+func GetUserBookmarksS3Object(s3Client s3.S3Client, userBookmarks *model.UserBookmarks, userId string) ([]byte, error) {
 	s3Path := GetUserBookmarksS3Path(userBookmarks)
 	if s3Path == "" {
 		return nil, fmt.Errorf("no bookmarks exists for userId %s", userId)
@@ -141,7 +188,7 @@ func GetBookmarksS3Object(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3
 func GetIncrementedVersion(userBookmarks *model.UserBookmarks) (string, error) {
 	latestVersion := DefaultBookmarksVersion
 
-	if userBookmarks != nil {
+	if userBookmarks != nil && userBookmarks.LatestVersion != "" {
 		latestVersion = userBookmarks.LatestVersion
 
 		if idx := strings.LastIndex(latestVersion, DeletedVersionSuffix); idx >= 0 {
@@ -165,7 +212,7 @@ func GetIncrementedVersion(userBookmarks *model.UserBookmarks) (string, error) {
 }
 
 func AddBookmarksInS3Bucket(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, userBookmarks *model.UserBookmarks,
-	userId, bucket, contentType, encoding string, content *[]byte) error {
+	userId, bucket, contentType, encoding string, content *[]byte, events ...models.DomainEvent) error {
 	previousVersion := GetUserBookmarksS3Path(userBookmarks)
 	distVersion, err := GetIncrementedVersion(userBookmarks)
 	if err != nil {
@@ -173,13 +220,20 @@ func AddBookmarksInS3Bucket(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.
 	}
 
 	distEntryPath := fmt.Sprintf("Bookmarks/%s/%s", userId, distVersion)
-	err = s3Client.PutObject(bucket, distEntryPath, contentType, encoding, content)
+	versionId, err := s3Client.PutObjectVersion(bucket, distEntryPath, contentType, encoding, content)
 	if err != nil {
 		return err
 	}
 
-	err = AddOrUpdateUserBookmarks(dynamodbClient, userBookmarks, userId, distVersion, true)
+	err = AddOrUpdateUserBookmarks(dynamodbClient, userBookmarks, userId, distVersion, true, events...)
 	if err != nil {
+		// Only the object version written here is deleted, as the bookmarks changed concurrently could be stored
+		// under the same path.
+		if versionId != "" {
+			if deleteErr := s3Client.DeleteObjectVersion(bucket, distEntryPath, versionId); deleteErr != nil {
+				log.Warn().Msgf("Failure in deleting unused bookmarks %s: %v", distEntryPath, deleteErr)
+			}
+		}
 		return err
 	}
 
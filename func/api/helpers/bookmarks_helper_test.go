@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/golang/mock/gomock"
 	"github.com/pranav-patil/go-serverless-api/func/api/models"
 	"github.com/pranav-patil/go-serverless-api/pkg/constant"
@@ -13,6 +15,7 @@ import (
 	"github.com/pranav-patil/go-serverless-api/pkg/s3"
 	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/exp/maps"
 )
 
 type BookmarksHelperTestSuite struct {
@@ -142,30 +145,44 @@ func (s *BookmarksHelperTestSuite) TestAddOrUpdateDistributionWhenDistributionAd
 	s.Equal("1", actualDist.UserId)
 	s.Equal("1.0.93", actualDist.LatestVersion)
 	s.Equal(true, actualDist.ModifiedBookmarks)
+	s.Equal(s.mockTimeNow, actualDist.ModifiedTimestamp)
 }
 
 func (s *BookmarksHelperTestSuite) TestAddOrUpdateDistributionWhenDistributionUpdated() {
 	userId := "1"
-	distribution := model.UserBookmarks{UserId: userId}
-
-	var capturedArgs []model.Entity
-
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).DoAndReturn(func(p model.Entity) error {
-		capturedArgs = append(capturedArgs, p)
-		return nil
-	})
+	distribution := model.UserBookmarks{UserId: userId, LatestVersion: "1.0.77", Status: constant.Success,
+		SyncEnabled: true, DistributionFailures: 2}
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: userId}),
+		gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			// Only the version attributes are updated, the settings are left unchanged.
+			s.ElementsMatch([]string{"latestVersion", "modifiedBookmarks", "modifiedTs", "distributionFailures", "status"},
+				maps.Values(expr.Names()))
+			s.Equal("1.0.78", updatedValues(&expr)["latestVersion"])
+			// The update is conditioned on the version and status read.
+			s.Contains(maps.Values(expr.Values()), &types.AttributeValueMemberS{Value: "1.0.77"})
+			s.Contains(maps.Values(expr.Values()), &types.AttributeValueMemberS{Value: constant.Success})
+			return nil
+		})
 
-	distribution = model.UserBookmarks{
-		UserId: userId,
-	}
 	err := AddOrUpdateUserBookmarks(s.mockDynamoDBClient, &distribution, userId, "1.0.78", true)
 	s.Nil(err)
-	s.Equal(1, len(capturedArgs))
+	s.Equal("1.0.78", distribution.LatestVersion)
+	s.Equal(true, distribution.ModifiedBookmarks)
+	s.Equal(s.mockTimeNow, distribution.ModifiedTimestamp)
+	s.Equal(0, distribution.DistributionFailures)
+}
 
-	actualDist := capturedArgs[0].(*model.UserBookmarks)
-	s.Equal("1", actualDist.UserId)
-	s.Equal("1.0.78", actualDist.LatestVersion)
-	s.Equal(true, actualDist.ModifiedBookmarks)
+func (s *BookmarksHelperTestSuite) TestAddOrUpdateDistributionWhenBookmarksChanged() {
+	distribution := model.UserBookmarks{UserId: "1", LatestVersion: "1.0.77"}
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}),
+		gomock.Any()).Return(&types.ConditionalCheckFailedException{})
+
+	err := AddOrUpdateUserBookmarks(s.mockDynamoDBClient, &distribution, "1", "1.0.78", true)
+	s.ErrorIs(err, ErrBookmarksChanged)
+	s.Equal("1.0.77", distribution.LatestVersion)
 }
 
 func (s *BookmarksHelperTestSuite) TestConvertCSVAndValidateBookmarks() {
@@ -286,14 +303,15 @@ func (s *BookmarksHelperTestSuite) TestGetIncrementedVersionWhenDistVersionIsDel
 func (s *BookmarksHelperTestSuite) TestAddBookmarksInS3Bucket() {
 	distribution := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.96"}
 
-	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(mockutil.AnyOfType(distribution)).Return(nil)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}),
+		gomock.Any()).Return(nil)
 
 	s3Content := []byte(
 		`{"bookmarks":[{"url":"https://jalammar.github.io/illustrated-transformer/"},{"url":"https://chat.openai.com"}]}`)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("TEST_S3_BUCKET"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"),
 		gomock.Eq("Bookmarks/1/1.0.97"), gomock.Eq("application/json"), gomock.Eq(s3.GZip),
-		&s3Content).Return(nil)
+		&s3Content).Return("", nil)
 
 	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("TEST_S3_BUCKET"),
 		gomock.Eq("Bookmarks/1/1.0.96")).Return(nil)
@@ -304,6 +322,24 @@ func (s *BookmarksHelperTestSuite) TestAddBookmarksInS3Bucket() {
 	s.Nil(err)
 }
 
+func (s *BookmarksHelperTestSuite) TestAddBookmarksInS3BucketWhenBookmarksChanged() {
+	distribution := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.96"}
+	s3Content := []byte(`{"bookmarks":[{"url":"https://chat.openai.com"}]}`)
+
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.97"),
+		gomock.Eq("application/json"), gomock.Eq(s3.GZip), &s3Content).Return("v2", nil)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(&model.UserBookmarks{UserId: "1"}),
+		gomock.Any()).Return(&types.ConditionalCheckFailedException{})
+	// Only the version written is deleted, the previous bookmarks are kept.
+	s.mockS3Client.EXPECT().DeleteObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.97"),
+		gomock.Eq("v2")).Return(nil)
+
+	err := AddBookmarksInS3Bucket(s.mockDynamoDBClient, s.mockS3Client,
+		distribution, "1", "TEST_S3_BUCKET", "application/json", s3.GZip, &s3Content)
+
+	s.ErrorIs(err, ErrBookmarksChanged)
+}
+
 func (s *BookmarksHelperTestSuite) TestAddBookmarksInS3BucketWhenPreviousDistributionIsNil() {
 	distribution := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.1"}
 
@@ -311,9 +347,9 @@ func (s *BookmarksHelperTestSuite) TestAddBookmarksInS3BucketWhenPreviousDistrib
 
 	s3Content := []byte(`{"bookmarks":[{"url":"https://jalammar.github.io/illustrated-transformer/"}]}`)
 
-	s.mockS3Client.EXPECT().PutObject(gomock.Eq("TEST_S3_BUCKET"),
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"),
 		gomock.Eq("Bookmarks/1/1.0.1"), gomock.Eq("application/json"), gomock.Eq(s3.GZip),
-		&s3Content).Return(nil)
+		&s3Content).Return("", nil)
 
 	err := AddBookmarksInS3Bucket(s.mockDynamoDBClient, s.mockS3Client,
 		nil, "1", "TEST_S3_BUCKET", "application/json", s3.GZip, &s3Content)
@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	PackageFormatTarGz = "tar.gz"
+	PackageFormatZip   = "zip"
+	PackageFormatJSON  = "json"
+
+	DefaultPackageFormat = PackageFormatTarGz
+	DefaultPageSize      = 100
+	MaxPageSize          = 1000
+
+	MaxDistributionTimeoutInMinutes = 24 * 60
+)
+
+var SupportedPackageFormats = []string{PackageFormatTarGz, PackageFormatZip, PackageFormatJSON}
+
+// GetBookmarksConfig returns the settings of the user, with defaults for the settings which were never set.
+func GetBookmarksConfig(userBookmarks *model.UserBookmarks) models.BookmarksConfig {
+	if userBookmarks == nil {
+		return models.BookmarksConfig{
+			Enabled:             true,
+			PackageFormat:       DefaultPackageFormat,
+			PageSize:            DefaultPageSize,
+			EnrichmentEnabled:   true,
+			DistributionTimeout: DelayedStatusTimeInMinutes,
+		}
+	}
+
+	return models.BookmarksConfig{
+		Enabled:             userBookmarks.SyncEnabled,
+		AutoDistribute:      userBookmarks.AutoDistribute,
+		PackageFormat:       GetPackageFormat(userBookmarks),
+		PageSize:            GetPageSize(userBookmarks),
+		EnrichmentEnabled:   !userBookmarks.EnrichmentDisabled,
+		DistributionTimeout: GetDistributionTimeoutInMinutes(userBookmarks),
+	}
+}
+
+func ValidateBookmarksConfig(config *models.BookmarksConfig) error {
+	if err := ValidatePackageFormat(config.PackageFormat); err != nil {
+		return err
+	}
+
+	if config.PageSize < 1 || config.PageSize > MaxPageSize {
+		return fmt.Errorf("page size must be between 1 and %d", MaxPageSize)
+	}
+
+	if config.DistributionTimeout < 1 || config.DistributionTimeout > MaxDistributionTimeoutInMinutes {
+		return fmt.Errorf("distribution timeout must be between 1 and %d minutes", MaxDistributionTimeoutInMinutes)
+	}
+	return nil
+}
+
+func ValidatePackageFormat(packageFormat string) error {
+	if !slices.Contains(SupportedPackageFormats, packageFormat) {
+		return fmt.Errorf("package format %s is not supported, supported formats are %v",
+			packageFormat, SupportedPackageFormats)
+	}
+	return nil
+}
+
+// UpdateBookmarksConfig sets only the settings of the user, creating the record of the user when there is none,
+// so that the bookmarks and the distributions updated concurrently are never overwritten.
+func UpdateBookmarksConfig(dynamodbClient dynamodb.DynamoDBClient, userId string, config *models.BookmarksConfig) error {
+	update := dynamodb.GenUpdateBuilder(map[string]interface{}{
+		"userId":              userId,
+		"syncEnabled":         config.Enabled,
+		"autoDistribute":      config.AutoDistribute,
+		"packageFormat":       config.PackageFormat,
+		"pageSize":            config.PageSize,
+		"enrichmentDisabled":  !config.EnrichmentEnabled,
+		"distributionTimeout": config.DistributionTimeout,
+	})
+
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	return dynamodbClient.UpdateRecordsByExpression(&model.UserBookmarks{UserId: userId}, expr)
+}
+
+func GetPackageFormat(userBookmarks *model.UserBookmarks) string {
+	if userBookmarks == nil || userBookmarks.PackageFormat == "" {
+		return DefaultPackageFormat
+	}
+	return userBookmarks.PackageFormat
+}
+
+func GetPageSize(userBookmarks *model.UserBookmarks) int {
+	if userBookmarks == nil || userBookmarks.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return userBookmarks.PageSize
+}
+
+func GetDistributionTimeoutInMinutes(userBookmarks *model.UserBookmarks) int {
+	if userBookmarks == nil || userBookmarks.DistributionTimeout <= 0 {
+		return DelayedStatusTimeInMinutes
+	}
+	return userBookmarks.DistributionTimeout
+}
+
+// GetDistributionTimeout returns the duration after which the pending or locked distribution of the user
+// is timed out.
+func GetDistributionTimeout(userBookmarks *model.UserBookmarks) time.Duration {
+	return time.Duration(GetDistributionTimeoutInMinutes(userBookmarks)) * time.Minute
+}
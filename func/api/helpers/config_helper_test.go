@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBookmarksConfigDefaults(t *testing.T) {
+	config := GetBookmarksConfig(&model.UserBookmarks{UserId: "1", SyncEnabled: true})
+
+	assert.Equal(t, models.BookmarksConfig{
+		Enabled:             true,
+		PackageFormat:       PackageFormatTarGz,
+		PageSize:            DefaultPageSize,
+		EnrichmentEnabled:   true,
+		DistributionTimeout: DelayedStatusTimeInMinutes,
+	}, config)
+	assert.Equal(t, config, GetBookmarksConfig(nil))
+}
+
+func TestValidateBookmarksConfig(t *testing.T) {
+	assert.NoError(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: 1,
+		DistributionTimeout: 1}))
+	assert.Error(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "rar", PageSize: 10,
+		DistributionTimeout: 1}))
+	assert.Error(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: 0,
+		DistributionTimeout: 1}))
+	assert.Error(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: MaxPageSize + 1,
+		DistributionTimeout: 1}))
+	assert.NoError(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: 10,
+		DistributionTimeout: MaxDistributionTimeoutInMinutes}))
+	assert.Error(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: 10,
+		DistributionTimeout: 0}))
+	assert.Error(t, ValidateBookmarksConfig(&models.BookmarksConfig{PackageFormat: "tar.gz", PageSize: 10,
+		DistributionTimeout: MaxDistributionTimeoutInMinutes + 1}))
+}
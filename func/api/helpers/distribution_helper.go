@@ -0,0 +1,770 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/google/uuid"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/stepfunc"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	YYYYMMDDHHMMSS              = "20060102150405"
+	SignedURLExpirationSecs     = 300
+	DistributionBookmarksLocked = "BookmarksLocked"
+
+	legacyStartTimePrefix = "2006-01-02T15:04:05"
+
+	defaultAutoDistributionDelayMinutes = 15
+	defaultHistoryRetentionDays         = 90
+	maxAutoDistributionBackoff          = 24 * time.Hour
+)
+
+var (
+	ErrNoDevicesRecorded   = errors.New("no devices recorded for automatic distribution")
+	ErrOperationNotPending = errors.New("operation is not pending")
+	ErrNothingToRetry      = errors.New("no failed or timed out device distributions")
+	ErrDistributionChanged = errors.New("distribution changed since it was read")
+	ErrRetryPackageMissing = errors.New("package of the operation is no longer available")
+)
+
+// DistributionHistoryQuery selects the device distributions of a user, where the empty fields match everything.
+type DistributionHistoryQuery struct {
+	OperationId string
+	DeviceId    string
+	Status      string
+	From        time.Time
+	To          time.Time
+	Limit       int
+	Next        string
+}
+
+type DownloadBookmarksInput struct {
+	UserId           string `json:"userId"`
+	OperationId      string `json:"operationId"`
+	DeviceId         string `json:"deviceId"`
+	InstanceId       string `json:"instanceId"`
+	Enabled          bool   `json:"enabled"`
+	BookmarksVersion string `json:"bookmarksVersion"`
+	BaseVersion      string `json:"baseVersion,omitempty"`
+	PackageFormat    string `json:"packageFormat"`
+	Checksum         string `json:"fileChkSum"`
+	S3PresignedURL   string `json:"presignedUrl"`
+	RespToken        string `json:"respToken"`
+}
+
+// DistributeToDevices locks the bookmarks, creates the packages of the latest version and starts a distribution
+// job for each of the device ids, or all the devices of the device map when no device ids are passed.
+// The packages are created in the package format, or the format of the user settings when it is empty.
+// The distribution is marked failed when any of the steps fail, and ErrDistributionPending is returned when another
+// distribution locked the bookmarks first.
+func DistributeToDevices(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, sfnClient stepfunc.StepFuncClient,
+	distribution *model.UserBookmarks, deviceMap map[int]string, deviceIds []int,
+	packageFormat string) ([]models.WebCrawlerJob, error) {
+	if packageFormat == "" {
+		packageFormat = GetPackageFormat(distribution)
+	}
+
+	distribution.Devices = make(map[string]string, len(deviceMap))
+	for deviceId, instanceId := range deviceMap {
+		distribution.Devices[strconv.Itoa(deviceId)] = instanceId
+	}
+
+	err := lockDistribution(dynamodbClient, distribution, map[string]interface{}{"devices": distribution.Devices})
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := newPackageSet(dynamodbClient, s3Client, distribution, distribution.LatestVersion,
+		packageFormat)
+	if err != nil {
+		failDistribution(dynamodbClient, distribution)
+		return nil, err
+	}
+
+	distributionJobList, err := addDistributionJobs(dynamodbClient, sfnClient, deviceMap, deviceIds,
+		distribution, packages)
+	if errors.Is(err, ErrDistributionChanged) {
+		return nil, err
+	} else if err != nil {
+		failDistribution(dynamodbClient, distribution)
+		return nil, err
+	}
+
+	metrics.RecordDistributions(len(distributionJobList))
+	return distributionJobList, nil
+}
+
+func addDistributionJobs(dynamodbClient dynamodb.DynamoDBClient, sfnClient stepfunc.StepFuncClient, appMap map[int]string,
+	requestDeviceIds []int, distribution *model.UserBookmarks, packages *packageSet) ([]models.WebCrawlerJob, error) {
+	var distributionJobList []models.WebCrawlerJob
+	var appDistributions []model.BookmarkDistribution
+
+	currentTime := TimeNow()
+
+	if len(requestDeviceIds) == 0 {
+		requestDeviceIds = make([]int, 0, len(appMap))
+		for k := range appMap {
+			requestDeviceIds = append(requestDeviceIds, k)
+		}
+	}
+
+	jobId := newOperationId(currentTime)
+	expiresAt := currentTime.Add(GetDistributionRetention()).Unix()
+
+	for _, device := range requestDeviceIds {
+		devicePackage, err := packages.forDevice(strconv.Itoa(device))
+		if err != nil {
+			return nil, err
+		}
+
+		appDistribution := &model.BookmarkDistribution{
+			Status:          constant.Pending,
+			StartTimestamp:  currentTime,
+			EndTimestamp:    time.Time{},
+			UserId:          distribution.UserId,
+			DeviceId:        strconv.Itoa(device),
+			OperationId:     strconv.FormatInt(jobId, 10),
+			ExecutionName:   uuid.NewString(),
+			Version:         distribution.LatestVersion,
+			BaseVersion:     devicePackage.BaseVersion,
+			PackageFormat:   packages.format,
+			PackageChecksum: packages.full.Checksum,
+			Ttl:             expiresAt,
+		}
+
+		distributionJob, err := startDistributionJob(dynamodbClient, sfnClient, distribution, appDistribution,
+			appMap[device], devicePackage)
+		if err != nil {
+			return nil, err
+		}
+		distributionJob.StatusMessage = "Distribution Process Triggered"
+		distributionJobList = append(distributionJobList, distributionJob)
+		appDistributions = append(appDistributions, *appDistribution)
+	}
+
+	distribution.OperationId = jobId
+	err := updateDistributionStatus(dynamodbClient, distribution, constant.Pending, startedUpdates(distribution,
+		map[string]interface{}{
+			"operationId":          distribution.OperationId,
+			"modifiedBookmarks":    false,
+			"distributionFailures": 0,
+		}), newDistributionStarted(distribution, packages.format, distributionJobList))
+	if errors.Is(err, ErrDistributionChanged) {
+		stopCancelledDistributions(dynamodbClient, sfnClient, distribution, appDistributions)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	distribution.EndTimestamp = time.Time{}
+	distribution.ModifiedBookmarks = false
+	distribution.DistributionFailures = 0
+	return distributionJobList, nil
+}
+
+// startDistributionJob saves the device distribution and starts its execution, which downloads the package
+// of the distribution version on the device.
+func startDistributionJob(dynamodbClient dynamodb.DynamoDBClient, sfnClient stepfunc.StepFuncClient,
+	distribution *model.UserBookmarks, appDistribution *model.BookmarkDistribution,
+	instanceId string, devicePackage *distributionPackage) (models.WebCrawlerJob, error) {
+	err := dynamodbClient.AddRecord(appDistribution)
+	if err != nil {
+		return models.WebCrawlerJob{}, err
+	}
+
+	downloadBookmarks := DownloadBookmarksInput{
+		UserId:           appDistribution.UserId,
+		OperationId:      appDistribution.OperationId,
+		DeviceId:         appDistribution.DeviceId,
+		InstanceId:       instanceId,
+		Enabled:          distribution.SyncEnabled,
+		BookmarksVersion: appDistribution.Version,
+		BaseVersion:      devicePackage.BaseVersion,
+		PackageFormat:    appDistribution.PackageFormat,
+		Checksum:         devicePackage.Checksum,
+		S3PresignedURL:   devicePackage.PreSignedURL,
+	}
+
+	err = sfnClient.StartExecution(os.Getenv("DISTRIBUTION_STATE_MACHINE_ARN"), appDistribution.ExecutionName,
+		downloadBookmarks)
+	if err != nil {
+		return models.WebCrawlerJob{}, err
+	}
+
+	deviceId, _ := strconv.Atoi(appDistribution.DeviceId)
+	return models.WebCrawlerJob{
+		ID:             appDistribution.OperationId,
+		DeviceId:       deviceId,
+		PackageVersion: appDistribution.Version,
+		State:          appDistribution.Status,
+		StatusMessage:  appDistribution.StatusMessage,
+		StartTime:      TimeNow(),
+		EndTime:        time.Time{},
+	}, nil
+}
+
+// RetryDistribution restarts the failed and timed out device distributions of the latest operation as a new
+// operation, leaving the devices which received the bookmarks untouched. The full package of the operation is reused
+// when it still exists, otherwise it is rebuilt only when the operation distributed the latest version.
+func RetryDistribution(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, sfnClient stepfunc.StepFuncClient,
+	distribution *model.UserBookmarks, operationId int64) ([]models.WebCrawlerJob, error) {
+	if IsDistributionPending(distribution) {
+		return nil, fmt.Errorf("operation %d: %w", operationId, ErrDistributionPending)
+	}
+
+	appDistributions, err := getOperationDistributions(dynamodbClient, distribution.UserId, operationId)
+	if err != nil {
+		return nil, err
+	}
+
+	var retryDistributions []model.BookmarkDistribution
+	for _, appDistribution := range appDistributions {
+		if isRetryableDistribution(&appDistribution, GetDistributionTimeout(distribution)) {
+			if _, ok := distribution.Devices[appDistribution.DeviceId]; !ok {
+				log.Warn().Msgf("No instance recorded for device %s of userId %s, skipping retry",
+					appDistribution.DeviceId, distribution.UserId)
+				continue
+			}
+			retryDistributions = append(retryDistributions, appDistribution)
+		}
+	}
+
+	if len(retryDistributions) == 0 {
+		return nil, fmt.Errorf("operation %d: %w", operationId, ErrNothingToRetry)
+	}
+
+	// Rows written before the version and format were recorded belong to the latest version, in a tar.gz package.
+	version := retryDistributions[0].Version
+	if version == "" {
+		version = distribution.LatestVersion
+	}
+	packageFormat := retryDistributions[0].PackageFormat
+	if packageFormat == "" {
+		packageFormat = PackageFormatTarGz
+	}
+
+	fullPackage, err := getRetryPackage(s3Client, distribution, version, packageFormat,
+		retryDistributions[0].PackageChecksum)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockDistribution(dynamodbClient, distribution, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullPackage == nil {
+		packages, err := newPackageSet(dynamodbClient, s3Client, distribution, version, packageFormat)
+		if err != nil {
+			failDistribution(dynamodbClient, distribution)
+			return nil, err
+		}
+		fullPackage = packages.full
+	}
+
+	currentTime := TimeNow()
+	retryOperationId := newOperationId(currentTime)
+	expiresAt := currentTime.Add(GetDistributionRetention()).Unix()
+	distributionJobList := make([]models.WebCrawlerJob, 0, len(retryDistributions))
+	var startedDistributions []model.BookmarkDistribution
+
+	// The retried devices receive the full package, which applies whatever version they hold. Each retry is
+	// recorded as a new attempt, keeping the failed distributions in the history.
+	for _, failedDistribution := range retryDistributions {
+		appDistribution := &model.BookmarkDistribution{
+			Status:          constant.Pending,
+			StatusMessage:   "Distribution Process Retried",
+			StartTimestamp:  currentTime,
+			EndTimestamp:    time.Time{},
+			UserId:          distribution.UserId,
+			DeviceId:        failedDistribution.DeviceId,
+			OperationId:     strconv.FormatInt(retryOperationId, 10),
+			RetryOf:         GetOperationId(&failedDistribution),
+			ExecutionName:   uuid.NewString(),
+			Version:         version,
+			PackageFormat:   packageFormat,
+			PackageChecksum: fullPackage.Checksum,
+			Ttl:             expiresAt,
+		}
+
+		distributionJob, err := startDistributionJob(dynamodbClient, sfnClient, distribution, appDistribution,
+			distribution.Devices[appDistribution.DeviceId], fullPackage)
+		if err != nil {
+			failDistribution(dynamodbClient, distribution)
+			return nil, err
+		}
+		distributionJobList = append(distributionJobList, distributionJob)
+		startedDistributions = append(startedDistributions, *appDistribution)
+	}
+
+	distribution.OperationId = retryOperationId
+	started := newDistributionStarted(distribution, packageFormat, distributionJobList)
+	started.Version = version
+	err = updateDistributionStatus(dynamodbClient, distribution, constant.Pending,
+		startedUpdates(distribution, map[string]interface{}{"operationId": retryOperationId}), started)
+	if errors.Is(err, ErrDistributionChanged) {
+		stopCancelledDistributions(dynamodbClient, sfnClient, distribution, startedDistributions)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	distribution.EndTimestamp = time.Time{}
+	return distributionJobList, nil
+}
+
+// getRetryPackage returns the full package distributed by the operation when it still exists in the package bucket.
+// Otherwise nil is returned for the latest version, whose package is created again from its bookmarks, while
+// ErrRetryPackageMissing is returned for the older versions, whose bookmarks are deleted once they are superseded.
+func getRetryPackage(s3Client s3.S3Client, distribution *model.UserBookmarks, version, format,
+	checksum string) (*distributionPackage, error) {
+	// The deleted bookmarks are distributed without a package.
+	if strings.HasSuffix(version, DeletedVersionSuffix) {
+		return &distributionPackage{}, nil
+	}
+
+	if checksum != "" {
+		exists, err := s3Client.ObjectExists(os.Getenv("BOOKMARKS_SUMMARY_BUCKET"),
+			GetPackageS3Path(distribution.UserId, checksum, format))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return newDistributionPackage(s3Client, distribution.UserId, format, "", checksum)
+		}
+	}
+
+	if version != distribution.LatestVersion {
+		return nil, fmt.Errorf("version %s: %w", version, ErrRetryPackageMissing)
+	}
+	return nil, nil
+}
+
+// newOperationId returns the id of the operation started at the time, which is its UTC start time.
+func newOperationId(startTime time.Time) int64 {
+	operationId, _ := strconv.ParseInt(startTime.UTC().Format(YYYYMMDDHHMMSS), 10, 64)
+	return operationId
+}
+
+// startedUpdates adds the updates of a started distribution, which clear the end time and record the packages
+// created for the distribution.
+func startedUpdates(distribution *model.UserBookmarks, updates map[string]interface{}) map[string]interface{} {
+	updates["endTs"] = time.Time{}
+	if len(distribution.Packages) > 0 {
+		updates["packages"] = distribution.Packages
+	}
+	return updates
+}
+
+func newDistributionStarted(distribution *model.UserBookmarks, packageFormat string,
+	distributionJobList []models.WebCrawlerJob) models.DistributionStarted {
+	deviceIds := make([]string, 0, len(distributionJobList))
+	for _, distributionJob := range distributionJobList {
+		deviceIds = append(deviceIds, strconv.Itoa(distributionJob.DeviceId))
+	}
+
+	return models.DistributionStarted{
+		OperationId:   distribution.OperationId,
+		Version:       distribution.LatestVersion,
+		PackageFormat: packageFormat,
+		DeviceIds:     deviceIds,
+	}
+}
+
+// isRetryableDistribution reports whether the device distribution failed, including the pending distributions
+// which are delayed beyond the timeout but not yet marked timed out.
+func isRetryableDistribution(appDistribution *model.BookmarkDistribution, timeout time.Duration) bool {
+	switch appDistribution.Status {
+	case constant.Failed, constant.Timeout:
+		return true
+	case constant.Pending:
+		return TimeNow().Sub(appDistribution.StartTimestamp) > timeout
+	default:
+		return false
+	}
+}
+
+// UpdateDistributionStatus moves the distribution to the status, updating only the status and its timestamp.
+// ErrDistributionChanged is returned when the distribution is no longer in the status and start time it was read
+// with, so that a concurrent distribution is never overwritten.
+func UpdateDistributionStatus(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks, status string) error {
+	return updateDistributionStatus(dynamodbClient, distribution, status, nil)
+}
+
+// updateDistributionStatus moves the distribution to the status along with the updates of the other attributes,
+// which are applied by the caller to the distribution once they are saved.
+func updateDistributionStatus(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks, status string,
+	updates map[string]interface{}, events ...models.DomainEvent) error {
+	currentTime := TimeNow()
+	timestampName := "endTs"
+	if status == DistributionBookmarksLocked || status == constant.Pending {
+		timestampName = "startTs"
+	}
+
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = status
+	updates[timestampName] = currentTime
+
+	updated, err := updateIfUnchanged(dynamodbClient, distribution.UserId, distribution, distribution.Status,
+		distribution.StartTimestamp, updates, events...)
+	if err == nil && !updated {
+		err = fmt.Errorf("userId %s: %w", distribution.UserId, ErrDistributionChanged)
+	}
+	if err != nil {
+		log.Error().Msgf("failed to update distribution status %s: %v", status, err)
+		return err
+	}
+
+	distribution.Status = status
+	if timestampName == "startTs" {
+		distribution.StartTimestamp = currentTime
+	} else {
+		distribution.EndTimestamp = currentTime
+	}
+	return nil
+}
+
+// SaveDistributionTimeout saves the timeout of the pending distribution, which is set when the distribution is read,
+// unless the distribution changed since it was read.
+func SaveDistributionTimeout(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks) error {
+	_, err := updateIfUnchanged(dynamodbClient, distribution.UserId, distribution, constant.Pending,
+		distribution.StartTimestamp, map[string]interface{}{"status": constant.Timeout, "endTs": distribution.EndTimestamp})
+	return err
+}
+
+// lockDistribution locks the bookmarks for a distribution, returning ErrDistributionPending when the distribution
+// was locked or started by another request since it was read.
+func lockDistribution(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks,
+	updates map[string]interface{}) error {
+	if IsDistributionPending(distribution) {
+		return fmt.Errorf("userId %s: %w", distribution.UserId, ErrDistributionPending)
+	}
+
+	err := updateDistributionStatus(dynamodbClient, distribution, DistributionBookmarksLocked, updates)
+	if errors.Is(err, ErrDistributionChanged) {
+		return fmt.Errorf("userId %s: %w", distribution.UserId, ErrDistributionPending)
+	}
+	return err
+}
+
+// failDistribution marks the locked distribution failed and counts the failure, which backs off the automatic
+// distribution of the bookmarks.
+func failDistribution(dynamodbClient dynamodb.DynamoDBClient, distribution *model.UserBookmarks) {
+	failures := distribution.DistributionFailures + 1
+	err := updateDistributionStatus(dynamodbClient, distribution, constant.Failed,
+		map[string]interface{}{"distributionFailures": failures})
+	if err == nil {
+		distribution.DistributionFailures = failures
+	}
+}
+
+// CancelDistribution stops the executions of the pending device distributions of the operation, marks them
+// cancelled and releases the lock on the bookmarks. The lock is kept when any of the executions could not be stopped,
+// as the execution could still update the device. A distribution still locking the bookmarks has no operation of its
+// own yet, hence it is cancelled through the latest operation, and the distribution stops the devices it started
+// once it finds the lock released.
+func CancelDistribution(dynamodbClient dynamodb.DynamoDBClient, sfnClient stepfunc.StepFuncClient,
+	distribution *model.UserBookmarks, operationId int64) ([]models.WebCrawlerJob, error) {
+	if distribution == nil || distribution.OperationId != operationId || !IsDistributionPending(distribution) {
+		return nil, fmt.Errorf("operation %d: %w", operationId, ErrOperationNotPending)
+	}
+
+	cancelledJobs := []models.WebCrawlerJob{}
+	if distribution.Status == constant.Pending {
+		appDistributions, err := getOperationDistributions(dynamodbClient, distribution.UserId, operationId)
+		if err != nil {
+			return nil, err
+		}
+
+		cancelledJobs, err = cancelDeviceDistributions(dynamodbClient, sfnClient, appDistributions,
+			distribution.LatestVersion, "Distribution cancelled by user")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	currentTime := TimeNow()
+
+	// The bookmarks were not delivered, hence they are still modified. The modified timestamp is renewed so that
+	// an automatic distribution does not immediately restart the cancelled distribution.
+	err := updateDistributionStatus(dynamodbClient, distribution, constant.Cancelled, map[string]interface{}{
+		"modifiedBookmarks": true,
+		"modifiedTs":        currentTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	distribution.ModifiedBookmarks = true
+	distribution.ModifiedTimestamp = currentTime
+	return cancelledJobs, nil
+}
+
+// stopCancelledDistributions cancels the device distributions started while the lock on the bookmarks was cancelled
+// or swept, as the distribution is no longer tracked.
+func stopCancelledDistributions(dynamodbClient dynamodb.DynamoDBClient, sfnClient stepfunc.StepFuncClient,
+	distribution *model.UserBookmarks, appDistributions []model.BookmarkDistribution) {
+	_, err := cancelDeviceDistributions(dynamodbClient, sfnClient, appDistributions, distribution.LatestVersion,
+		"Distribution cancelled before it started")
+	if err != nil {
+		log.Error().Msgf("failed to stop the distributions of userId %s: %v", distribution.UserId, err)
+	}
+}
+
+// cancelDeviceDistributions stops the executions of the pending device distributions and marks them cancelled with
+// the status message. The device distributions whose execution could not be stopped are left pending, and the error
+// of the last of them is returned.
+func cancelDeviceDistributions(dynamodbClient dynamodb.DynamoDBClient, sfnClient stepfunc.StepFuncClient,
+	appDistributions []model.BookmarkDistribution, version, statusMessage string) ([]models.WebCrawlerJob, error) {
+	currentTime := TimeNow()
+	downloadBookmarksSfn := os.Getenv("DISTRIBUTION_STATE_MACHINE_ARN")
+	cancelledJobs := []models.WebCrawlerJob{}
+	var stopErr error
+
+	for _, appDistribution := range appDistributions {
+		appDistribution := appDistribution
+		if appDistribution.Status != constant.Pending {
+			continue
+		}
+
+		if appDistribution.ExecutionName != "" {
+			err := sfnClient.StopExecution(stepfunc.GetExecutionArn(downloadBookmarksSfn, appDistribution.ExecutionName),
+				constant.Cancelled, fmt.Sprintf("operation %s cancelled", appDistribution.OperationId))
+			if err != nil {
+				stopErr = err
+				continue
+			}
+		}
+
+		appDistribution.Status = constant.Cancelled
+		appDistribution.StatusMessage = statusMessage
+		appDistribution.EndTimestamp = currentTime
+
+		err := WriteWithEvents(dynamodbClient, appDistribution.UserId,
+			[]models.DomainEvent{NewDeviceDistributionCompleted(&appDistribution)},
+			dynamodb.TransactUpdate(&appDistribution))
+		if err != nil {
+			return nil, err
+		}
+
+		deviceId, _ := strconv.Atoi(appDistribution.DeviceId)
+		cancelledJobs = append(cancelledJobs, models.WebCrawlerJob{
+			ID:             appDistribution.OperationId,
+			DeviceId:       deviceId,
+			PackageVersion: version,
+			State:          appDistribution.Status,
+			StatusMessage:  appDistribution.StatusMessage,
+			StartTime:      appDistribution.StartTimestamp,
+			EndTime:        appDistribution.EndTimestamp,
+		})
+	}
+
+	if stopErr != nil {
+		return nil, stopErr
+	}
+	return cancelledJobs, nil
+}
+
+// getOperationDistributions reads all the device distributions of the operation, across the pages of the query.
+func getOperationDistributions(dynamodbClient dynamodb.DynamoDBClient, userId string,
+	operationId int64) ([]model.BookmarkDistribution, error) {
+	var appDistributions []model.BookmarkDistribution
+	query := DistributionHistoryQuery{OperationId: strconv.FormatInt(operationId, 10), Limit: MaxPageSize}
+
+	for {
+		page, next, err := GetDistributionHistory(dynamodbClient, userId, &query)
+		if err != nil {
+			return nil, err
+		}
+
+		appDistributions = append(appDistributions, page...)
+		if next == "" {
+			return appDistributions, nil
+		}
+		query.Next = next
+	}
+}
+
+// GetDistributionHistory returns a page of the device distributions matching the query along with the token of the
+// next page. The distributions of a device are ordered from the latest operation, which makes the device lookups
+// a range read of the sort key. The range starts at the sort key of the device alone, which keys the distribution
+// recorded before the operations were.
+func GetDistributionHistory(dynamodbClient dynamodb.DynamoDBClient, userId string,
+	query *DistributionHistoryQuery) ([]model.BookmarkDistribution, string, error) {
+	partitionKey, sortKey, err := dynamodb.GetEntityKeys(&model.BookmarkDistribution{
+		UserId:      userId,
+		DeviceId:    query.DeviceId,
+		OperationId: query.OperationId,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	var filters []expression.ConditionBuilder
+
+	if query.DeviceId != "" {
+		// The sort key of the device is followed by "#OID#", and "$" sorts right after "#".
+		deviceSortKey, _, _ := strings.Cut(sortKey, "#OID#")
+		keyCondition = keyCondition.And(expression.Key("SK").
+			Between(expression.Value(deviceSortKey), expression.Value(deviceSortKey+"$")))
+	}
+	if query.OperationId != "" {
+		filters = append(filters, operationCondition(query.OperationId))
+	}
+
+	if query.Status != "" {
+		filters = append(filters, expression.Name("status").Equal(expression.Value(query.Status)))
+	}
+
+	// The operation ids are the UTC start times of the operations, hence they compare in time order.
+	if !query.From.IsZero() {
+		filters = append(filters, expression.Name("operationId").
+			GreaterThanEqual(expression.Value(query.From.UTC().Format(YYYYMMDDHHMMSS))))
+	}
+	if !query.To.IsZero() {
+		filters = append(filters, expression.Name("operationId").
+			LessThanEqual(expression.Value(query.To.UTC().Format(YYYYMMDDHHMMSS))))
+	}
+
+	var filter *expression.ConditionBuilder
+	for i := range filters {
+		if filter == nil {
+			filter = &filters[i]
+		} else {
+			combined := filter.And(filters[i])
+			filter = &combined
+		}
+	}
+
+	lastEvaluatedKey, err := dynamodb.DecodePageToken(query.Next)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, lastEvaluatedKey, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.BookmarkDistribution{},
+		keyCondition, filter, int32(query.Limit), lastEvaluatedKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := dynamodb.EncodePageToken(lastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.([]model.BookmarkDistribution), next, nil
+}
+
+// operationCondition matches the device distributions of the operation, including the distributions recorded before
+// the operations were. Those have no operation id, but start at the UTC start time the operation id is formatted from.
+func operationCondition(operationId string) expression.ConditionBuilder {
+	condition := expression.Name("operationId").Equal(expression.Value(operationId))
+
+	startTime, err := time.Parse(YYYYMMDDHHMMSS, operationId)
+	if err != nil {
+		return condition
+	}
+	return condition.Or(expression.AttributeNotExists(expression.Name("operationId")).
+		And(expression.Name("startTs").BeginsWith(startTime.Format(legacyStartTimePrefix))))
+}
+
+// GetOperationId returns the operation of the device distribution, which is derived from the start time of the
+// distributions recorded before the operations were.
+func GetOperationId(appDistribution *model.BookmarkDistribution) string {
+	if appDistribution.OperationId != "" || appDistribution.StartTimestamp.IsZero() {
+		return appDistribution.OperationId
+	}
+	return appDistribution.StartTimestamp.UTC().Format(YYYYMMDDHHMMSS)
+}
+
+func GetDistributionRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("DISTRIBUTION_HISTORY_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultHistoryRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func GetAutoDistributionDelay() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("AUTO_DISTRIBUTION_DELAY_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = defaultAutoDistributionDelayMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// IsAutoDistributionDue reports whether the modified bookmarks of a user with automatic distribution enabled
+// are left unchanged for the delay, so that a burst of changes is distributed only once. After failed distributions
+// the delay since the last failure doubles with each failure, up to a day.
+func IsAutoDistributionDue(userBookmarks *model.UserBookmarks, delay time.Duration) bool {
+	if userBookmarks == nil || !userBookmarks.AutoDistribute || !userBookmarks.SyncEnabled ||
+		userBookmarks.Suspended || !userBookmarks.ModifiedBookmarks || userBookmarks.LatestVersion == "" {
+		return false
+	}
+
+	updateDelayedDistributionToTimeout(userBookmarks)
+	if IsDistributionPending(userBookmarks) {
+		return false
+	}
+	if userBookmarks.DistributionFailures > 0 {
+		return TimeNow().Sub(userBookmarks.EndTimestamp) >= autoDistributionBackoff(delay, userBookmarks.DistributionFailures)
+	}
+	return TimeNow().Sub(userBookmarks.ModifiedTimestamp) >= delay
+}
+
+func autoDistributionBackoff(delay time.Duration, failures int) time.Duration {
+	backoff := delay
+	for i := 0; i < failures && backoff < maxAutoDistributionBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxAutoDistributionBackoff)
+}
+
+// AutoDistributeBookmarks distributes the bookmarks to the devices recorded by the last distribution
+// when the automatic distribution is due. It returns the started jobs, which are empty when it is not due
+// or another distribution locked the bookmarks first.
+func AutoDistributeBookmarks(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, sfnClient stepfunc.StepFuncClient,
+	userId string, delay time.Duration) ([]models.WebCrawlerJob, error) {
+	// The record is read again, as a distribution could have been started since the records were listed.
+	distribution := GetBookmarkByUser(dynamodbClient, userId)
+	if !IsAutoDistributionDue(distribution, delay) {
+		return nil, nil
+	}
+
+	if len(distribution.Devices) == 0 {
+		return nil, fmt.Errorf("userId %s: %w", userId, ErrNoDevicesRecorded)
+	}
+
+	deviceMap := make(map[int]string, len(distribution.Devices))
+	for deviceId, instanceId := range distribution.Devices {
+		id, err := strconv.Atoi(deviceId)
+		if err != nil {
+			log.Warn().Msgf("Invalid device id %s recorded for userId %s", deviceId, userId)
+			continue
+		}
+		deviceMap[id] = instanceId
+	}
+
+	jobs, err := DistributeToDevices(dynamodbClient, s3Client, sfnClient, distribution, deviceMap, nil, "")
+	if errors.Is(err, ErrDistributionPending) {
+		log.Info().Msgf("Skipping automatic distribution for userId %s, a distribution is already running", userId)
+		return nil, nil
+	}
+	return jobs, err
+}
@@ -0,0 +1,434 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/signing"
+	sfnMocks "github.com/pranav-patil/go-serverless-api/pkg/stepfunc/mocks"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/exp/maps"
+)
+
+type DistributionHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	mockStepFuncClient *sfnMocks.MockStepFuncClient
+	signer             signing.Signer
+	mockTimeNow        time.Time
+}
+
+func TestDistributionHelperSuite(t *testing.T) {
+	suite.Run(t, new(DistributionHelperTestSuite))
+}
+
+func (s *DistributionHelperTestSuite) SetupSuite() {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	s.signer = signing.NewEd25519Signer(privateKey)
+
+	s.ctrl = gomock.NewController(s.T())
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
+	s.T().Setenv("BOOKMARKS_SUMMARY_BUCKET", "test_package_bucket")
+	s.T().Setenv("DISTRIBUTION_STATE_MACHINE_ARN", "test_distribution_state_machine_arn")
+}
+
+func (s *DistributionHelperTestSuite) SetupTest() {
+	NewPackageSigner = func() (signing.Signer, error) {
+		return s.signer, nil
+	}
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	s.mockStepFuncClient = sfnMocks.NewMockStepFuncClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 9, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *DistributionHelperTestSuite) autoDistributedBookmarks() *model.UserBookmarks {
+	return &model.UserBookmarks{
+		UserId:            "1",
+		Status:            constant.Success,
+		SyncEnabled:       true,
+		AutoDistribute:    true,
+		LatestVersion:     "1.0.89",
+		ModifiedBookmarks: true,
+		ModifiedTimestamp: s.mockTimeNow.Add(-20 * time.Minute),
+		Devices:           map[string]string{"46747567": "35546", "67787448": "23678"},
+	}
+}
+
+func (s *DistributionHelperTestSuite) TestIsAutoDistributionDue() {
+	delay := 15 * time.Minute
+	s.True(IsAutoDistributionDue(s.autoDistributedBookmarks(), delay))
+
+	recentlyModified := s.autoDistributedBookmarks()
+	recentlyModified.ModifiedTimestamp = s.mockTimeNow.Add(-5 * time.Minute)
+	s.False(IsAutoDistributionDue(recentlyModified, delay))
+
+	notModified := s.autoDistributedBookmarks()
+	notModified.ModifiedBookmarks = false
+	s.False(IsAutoDistributionDue(notModified, delay))
+
+	autoDistributeDisabled := s.autoDistributedBookmarks()
+	autoDistributeDisabled.AutoDistribute = false
+	s.False(IsAutoDistributionDue(autoDistributeDisabled, delay))
+
+	suspended := s.autoDistributedBookmarks()
+	suspended.Suspended = true
+	s.False(IsAutoDistributionDue(suspended, delay))
+
+	locked := s.autoDistributedBookmarks()
+	locked.Status = DistributionBookmarksLocked
+	s.False(IsAutoDistributionDue(locked, delay))
+
+	pending := s.autoDistributedBookmarks()
+	pending.Status = constant.Pending
+	pending.StartTimestamp = s.mockTimeNow.Add(-time.Minute)
+	s.False(IsAutoDistributionDue(pending, delay))
+
+	// A distribution pending for too long has timed out and no longer holds back the automatic distribution.
+	pending.StartTimestamp = s.mockTimeNow.Add(-time.Hour)
+	s.True(IsAutoDistributionDue(pending, delay))
+
+	s.False(IsAutoDistributionDue(nil, delay))
+}
+
+func (s *DistributionHelperTestSuite) TestAutoDistributeBookmarks() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(s.autoDistributedBookmarks(), nil)
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return([]byte(`{"bookmarks": [{ "url": "https://karpenter.sh/" }]}`), nil)
+	packagePath := mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/")
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"), packagePath).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"), packagePath, gomock.Any(), gomock.Eq("none"),
+		gomock.Any()).Return(nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"), packagePath,
+		gomock.Eq(int64(300))).Return("URL", nil)
+
+	var statuses []string
+	var startedNames map[string]string
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			statuses = append(statuses, updatedValues(&expr)["status"])
+			return nil
+		})
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"DistributionStarted")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			statuses = append(statuses, updatedValues(items[0].Expression)["status"])
+			startedNames = items[0].Expression.Names()
+			return nil
+		})
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.BookmarkDistribution{})).Return(nil).Times(2)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(DefaultPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{}, nil, nil).Times(2)
+
+	var deviceInputs []DownloadBookmarksInput
+	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Eq("test_distribution_state_machine_arn"), gomock.Any(),
+		gomock.AssignableToTypeOf(DownloadBookmarksInput{})).
+		DoAndReturn(func(_, _ string, input interface{}) error {
+			deviceInputs = append(deviceInputs, input.(DownloadBookmarksInput))
+			return nil
+		}).Times(2)
+
+	jobs, err := AutoDistributeBookmarks(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, "1", 15*time.Minute)
+
+	s.NoError(err)
+	s.Len(jobs, 2)
+	s.Equal([]string{DistributionBookmarksLocked, constant.Pending}, statuses)
+	s.Contains(maps.Values(startedNames), "packages")
+	s.ElementsMatch([]string{"35546", "23678"}, []string{deviceInputs[0].InstanceId, deviceInputs[1].InstanceId})
+	s.Equal("URL", deviceInputs[0].S3PresignedURL)
+}
+
+func (s *DistributionHelperTestSuite) TestAutoDistributeBookmarksWhenDistributionStarted() {
+	userBookmarks := s.autoDistributedBookmarks()
+	userBookmarks.Status = DistributionBookmarksLocked
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(userBookmarks, nil)
+
+	jobs, err := AutoDistributeBookmarks(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, "1", 15*time.Minute)
+
+	s.NoError(err)
+	s.Empty(jobs)
+}
+
+func (s *DistributionHelperTestSuite) TestAutoDistributeBookmarksWithoutDevices() {
+	userBookmarks := s.autoDistributedBookmarks()
+	userBookmarks.Devices = nil
+
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(userBookmarks, nil)
+
+	_, err := AutoDistributeBookmarks(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, "1", 15*time.Minute)
+
+	s.True(errors.Is(err, ErrNoDevicesRecorded))
+}
+
+func (s *DistributionHelperTestSuite) TestDistributeToDevicesWhenPackageFails() {
+	userBookmarks := s.autoDistributedBookmarks()
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return(nil, errors.New("NoSuchKey"))
+
+	var statuses []string
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		DoAndReturn(func(_ model.Entity, expr expression.Expression) error {
+			statuses = append(statuses, updatedValues(&expr)["status"])
+			return nil
+		}).Times(2)
+
+	_, err := DistributeToDevices(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, userBookmarks,
+		map[int]string{46747567: "35546"}, nil, "")
+
+	s.Error(err)
+	s.Equal([]string{DistributionBookmarksLocked, constant.Failed}, statuses)
+	s.Equal(map[string]string{"46747567": "35546"}, userBookmarks.Devices)
+	s.Equal(1, userBookmarks.DistributionFailures)
+	s.Equal(constant.Failed, userBookmarks.Status)
+}
+
+func (s *DistributionHelperTestSuite) TestDistributeToDevicesWhenAlreadyLocked() {
+	userBookmarks := s.autoDistributedBookmarks()
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		Return(&types.ConditionalCheckFailedException{})
+
+	_, err := DistributeToDevices(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, userBookmarks,
+		map[int]string{46747567: "35546"}, nil, "")
+
+	s.True(errors.Is(err, ErrDistributionPending))
+	s.Equal(constant.Success, userBookmarks.Status)
+}
+
+func (s *DistributionHelperTestSuite) TestDistributeToDevicesWhenLockCancelled() {
+	userBookmarks := s.autoDistributedBookmarks()
+	userBookmarks.Packages = map[string]string{"1.0.89/tar.gz": "5ef2cb2a"}
+
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/5ef2cb2a.tar.gz")).Return(true, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Any(), gomock.Any(), gomock.Any()).Return("URL", nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(DefaultPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{}, nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		Return(nil)
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.BookmarkDistribution{})).Return(nil)
+	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	// The lock was released by a cancellation, hence the started device is stopped and cancelled.
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"DistributionStarted")).Return(&types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}},
+	})
+	s.mockStepFuncClient.EXPECT().StopExecution(gomock.Any(), gomock.Eq(constant.Cancelled), gomock.Any()).Return(nil)
+	var cancelled *model.BookmarkDistribution
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.BookmarkDistribution{},
+		"DeviceDistributionCompleted")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			cancelled = items[0].Entity.(*model.BookmarkDistribution)
+			return nil
+		})
+
+	_, err := DistributeToDevices(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, userBookmarks,
+		map[int]string{46747567: "35546"}, nil, "")
+
+	s.True(errors.Is(err, ErrDistributionChanged))
+	s.Equal(constant.Cancelled, cancelled.Status)
+	s.Equal(0, userBookmarks.DistributionFailures)
+}
+
+func (s *DistributionHelperTestSuite) TestIsAutoDistributionDueAfterFailures() {
+	delay := 15 * time.Minute
+	failed := s.autoDistributedBookmarks()
+	failed.Status = constant.Failed
+	failed.DistributionFailures = 2
+	failed.EndTimestamp = s.mockTimeNow.Add(-time.Hour)
+	s.True(IsAutoDistributionDue(failed, delay))
+
+	failed.EndTimestamp = s.mockTimeNow.Add(-30 * time.Minute)
+	s.False(IsAutoDistributionDue(failed, delay))
+
+	failed.DistributionFailures = 20
+	failed.EndTimestamp = s.mockTimeNow.Add(-23 * time.Hour)
+	s.False(IsAutoDistributionDue(failed, delay))
+
+	failed.EndTimestamp = s.mockTimeNow.Add(-24 * time.Hour)
+	s.True(IsAutoDistributionDue(failed, delay))
+}
+
+func (s *DistributionHelperTestSuite) TestRetryDistributionRebuildsMissingPackage() {
+	userBookmarks := s.autoDistributedBookmarks()
+	userBookmarks.OperationId = 20091110235034
+	userBookmarks.Status = constant.Timeout
+	userBookmarks.Packages = map[string]string{"1.0.89/tar.gz": "5ef2cb2a", "1.0.88/tar.gz": "9c1d0e7f"}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(MaxPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "46747567", OperationId: "20091110235034", Version: "1.0.89",
+				Status: constant.Pending, StartTimestamp: s.mockTimeNow.Add(-time.Hour)},
+			{UserId: "1", DeviceId: "67787448", OperationId: "20091110235034", Version: "1.0.89",
+				Status: constant.Pending, StartTimestamp: s.mockTimeNow.Add(-time.Minute)},
+		}, nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.UserBookmarks{}), gomock.Any()).
+		Return(nil)
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.UserBookmarks{},
+		"DistributionStarted")).Return(nil)
+
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/5ef2cb2a.tar.gz")).Return(false, nil)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.89")).
+		Return([]byte(`{"bookmarks":[{"url":"https://www.google.com"}]}`), nil)
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/")).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"),
+		mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/"), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Any(), gomock.Any(), gomock.Any()).Return("https://presigned", nil)
+
+	var appDistribution *model.BookmarkDistribution
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.BookmarkDistribution{})).
+		DoAndReturn(func(entity model.Entity) error {
+			appDistribution = entity.(*model.BookmarkDistribution)
+			return nil
+		})
+	s.mockStepFuncClient.EXPECT().StartExecution(gomock.Eq("test_distribution_state_machine_arn"),
+		gomock.Any(), gomock.Any()).Return(nil)
+
+	jobs, err := RetryDistribution(s.mockDynamoDBClient, s.mockS3Client, s.mockStepFuncClient, userBookmarks,
+		20091110235034)
+
+	s.NoError(err)
+	s.Len(jobs, 1)
+	s.Equal("46747567", appDistribution.DeviceId)
+	s.Equal("20091110235234", appDistribution.OperationId)
+	s.Equal(int64(20091110235234), userBookmarks.OperationId)
+	s.Equal(constant.Pending, appDistribution.Status)
+	s.Equal(s.mockTimeNow, appDistribution.StartTimestamp)
+	s.NotEmpty(appDistribution.ExecutionName)
+	s.Equal(constant.Pending, userBookmarks.Status)
+	s.True(userBookmarks.ModifiedBookmarks)
+	s.Equal(map[string]string{"1.0.89/tar.gz": appDistribution.PackageChecksum}, userBookmarks.Packages)
+	s.NotEqual("5ef2cb2a", appDistribution.PackageChecksum)
+}
+
+func (s *DistributionHelperTestSuite) TestGetDistributionHistoryOfDevice() {
+	lastKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "UID#1"},
+		"SK": &types.AttributeValueMemberS{Value: "DID#42#OID#20091110235234"},
+	}
+
+	var keyCondition expression.KeyConditionBuilder
+	var filter *expression.ConditionBuilder
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(10)), gomock.Nil(), gomock.Eq(false)).
+		DoAndReturn(func(_ model.Entity, condition expression.KeyConditionBuilder, conditionFilter *expression.ConditionBuilder,
+			_ int32, _ map[string]types.AttributeValue, _ bool) (interface{}, map[string]types.AttributeValue, error) {
+			keyCondition, filter = condition, conditionFilter
+			return []model.BookmarkDistribution{{UserId: "1", DeviceId: "42", OperationId: "20091110235234"}}, lastKey, nil
+		})
+
+	query := &DistributionHistoryQuery{
+		DeviceId: "42",
+		From:     time.Date(2009, time.November, 10, 0, 0, 0, 0, time.UTC),
+		Limit:    10,
+	}
+	appDistributions, next, err := GetDistributionHistory(s.mockDynamoDBClient, "1", query)
+
+	s.NoError(err)
+	s.Len(appDistributions, 1)
+	s.NotEmpty(next)
+
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(*filter).Build()
+	s.NoError(err)
+	s.Contains(*expr.KeyCondition(), "BETWEEN")
+	s.ElementsMatch([]string{"UID#1", "DID#42", "DID#42$", "20091110000000"}, expressionValues(&expr))
+
+	nextKey, err := dynamodb.DecodePageToken(next)
+	s.NoError(err)
+	s.Equal(lastKey, nextKey)
+}
+
+func (s *DistributionHelperTestSuite) TestGetDistributionHistoryOfOperation() {
+	var keyCondition expression.KeyConditionBuilder
+	var filter *expression.ConditionBuilder
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(100)), gomock.Nil(), gomock.Eq(false)).
+		DoAndReturn(func(_ model.Entity, condition expression.KeyConditionBuilder, conditionFilter *expression.ConditionBuilder,
+			_ int32, _ map[string]types.AttributeValue, _ bool) (interface{}, map[string]types.AttributeValue, error) {
+			keyCondition, filter = condition, conditionFilter
+			return []model.BookmarkDistribution{}, nil, nil
+		})
+
+	query := &DistributionHistoryQuery{OperationId: "20091110235234", Limit: 100}
+	appDistributions, next, err := GetDistributionHistory(s.mockDynamoDBClient, "1", query)
+
+	s.NoError(err)
+	s.Empty(appDistributions)
+	s.Empty(next)
+
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(*filter).Build()
+	s.NoError(err)
+	s.NotContains(*expr.KeyCondition(), "BETWEEN")
+	s.Contains(*expr.Filter(), "attribute_not_exists")
+	s.ElementsMatch([]string{"UID#1", "20091110235234", "2009-11-10T23:52:34"}, expressionValues(&expr))
+}
+
+func (s *DistributionHelperTestSuite) TestGetOperationId() {
+	s.Equal("20091110235234", GetOperationId(&model.BookmarkDistribution{OperationId: "20091110235234"}))
+	s.Equal("20091110235234", GetOperationId(&model.BookmarkDistribution{StartTimestamp: s.mockTimeNow}))
+	s.Empty(GetOperationId(&model.BookmarkDistribution{}))
+}
+
+func (s *DistributionHelperTestSuite) TestGetDistributionHistoryWithInvalidToken() {
+	_, _, err := GetDistributionHistory(s.mockDynamoDBClient, "1", &DistributionHistoryQuery{Limit: 10, Next: "%%%"})
+
+	s.ErrorIs(err, dynamodb.ErrInvalidPageToken)
+}
+
+// updatedValues maps the attribute names to the string values set by the update expression.
+func updatedValues(expr *expression.Expression) map[string]string {
+	values := map[string]string{}
+	for _, match := range regexp.MustCompile(`(#\d+) = (:\d+)`).FindAllStringSubmatch(*expr.Update(), -1) {
+		if value, ok := expr.Values()[match[2]].(*types.AttributeValueMemberS); ok {
+			values[expr.Names()[match[1]]] = value.Value
+		}
+	}
+	return values
+}
+
+func expressionValues(expr *expression.Expression) []string {
+	var values []string
+	for _, value := range expr.Values() {
+		values = append(values, value.(*types.AttributeValueMemberS).Value)
+	}
+	return values
+}
@@ -0,0 +1,348 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxEnrichmentConcurrency = 10
+	enrichmentFetchTypes     = "text/html,application/xhtml+xml,*/*;q=0.8"
+	defaultFaviconPath       = "/favicon.ico"
+	maxEnrichmentAttempts    = 3
+)
+
+var (
+	ErrDistributionPending = errors.New("distribution is in progress")
+	ErrBookmarksChanged    = errors.New("bookmarks changed concurrently")
+)
+
+func IsEnrichmentEnabled(userBookmarks *model.UserBookmarks) bool {
+	return userBookmarks == nil || !userBookmarks.EnrichmentDisabled
+}
+
+func GetEnrichmentQueueURL() string {
+	return os.Getenv("ENRICHMENT_QUEUE_URL")
+}
+
+// QueueEnrichment sends the bookmark urls to the enrichment queue to be enriched in the background.
+func QueueEnrichment(sqsClient sqs.SQSClient, queueURL, userId string, bookmarks []models.BookmarkEntry) error {
+	request := models.EnrichmentRequest{UserId: userId}
+	for _, entry := range bookmarks {
+		request.URLs = append(request.URLs, entry.URL)
+	}
+
+	message, err := json.Marshal(&request)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(queueURL, string(message))
+	return err
+}
+
+// EnrichBookmarks fetches the metadata of the requested urls and stores it in a new version of the user bookmarks.
+// The metadata is fetched before reading the bookmarks, so that the read-modify-write window stays small, and the
+// new version is stored only while the bookmarks are still at the version read. Otherwise, the metadata is applied
+// to the bookmarks again, up to maxEnrichmentAttempts times.
+func EnrichBookmarks(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, request *models.EnrichmentRequest) error {
+	userBookmarks := GetBookmarkByUser(dynamodbClient, request.UserId)
+	if !IsEnrichmentEnabled(userBookmarks) || GetUserBookmarksS3Path(userBookmarks) == "" {
+		return nil
+	}
+
+	metadata := FetchBookmarksMetadata(request.URLs)
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	for attempt := 1; attempt <= maxEnrichmentAttempts; attempt++ {
+		stored, err := storeBookmarksMetadata(dynamodbClient, s3Client, request.UserId, metadata)
+		if err != nil || stored {
+			return err
+		}
+		log.Info().Msgf("Bookmarks of userId %s changed during enrichment, attempt %d", request.UserId, attempt)
+	}
+
+	return fmt.Errorf("enrichment for userId %s failed: %w", request.UserId, ErrBookmarksChanged)
+}
+
+// storeBookmarksMetadata applies the metadata to the latest bookmarks, and returns false without changing the
+// bookmarks when they changed since they were read.
+func storeBookmarksMetadata(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, userId string,
+	metadata map[string]*models.BookmarkMetadata) (bool, error) {
+	userBookmarks := GetBookmarkByUser(dynamodbClient, userId)
+	if IsDistributionPending(userBookmarks) {
+		return false, fmt.Errorf("enrichment for userId %s deferred: %w", userId, ErrDistributionPending)
+	}
+
+	distEntryPath := GetUserBookmarksS3Path(userBookmarks)
+	if distEntryPath == "" || !IsEnrichmentEnabled(userBookmarks) {
+		return true, nil
+	}
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	data, err := s3Client.GetObject(bucketName, distEntryPath)
+	if err != nil {
+		return false, err
+	}
+
+	bookmarkList := models.BookmarkList{}
+	if err = json.Unmarshal(data, &bookmarkList); err != nil {
+		return false, err
+	}
+
+	enriched := false
+	for i, entry := range bookmarkList.BookmarkEntry {
+		if entryMetadata, ok := metadata[entry.URL]; ok {
+			bookmarkList.BookmarkEntry[i].Metadata = entryMetadata
+			enriched = true
+		}
+	}
+
+	// The bookmarks could have been removed while their metadata was fetched.
+	if !enriched {
+		return true, nil
+	}
+
+	content, err := json.Marshal(&bookmarkList)
+	if err != nil {
+		return false, err
+	}
+
+	distVersion, err := GetIncrementedVersion(userBookmarks)
+	if err != nil {
+		return false, err
+	}
+
+	distVersionPath := fmt.Sprintf("Bookmarks/%s/%s", userId, distVersion)
+	versionId, err := s3Client.PutObjectVersion(bucketName, distVersionPath, "application/json", s3.GZip, &content)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := updateVersionIfUnchanged(dynamodbClient, userBookmarks, distVersion,
+		models.BookmarksReplaced{Version: distVersion, TotalCount: len(bookmarkList.BookmarkEntry)})
+	if err != nil || !updated {
+		// Only the object version written here is deleted, since the bookmarks changed concurrently could be
+		// stored under the same path, which restores them when they were written before.
+		if versionId != "" {
+			if deleteErr := s3Client.DeleteObjectVersion(bucketName, distVersionPath, versionId); deleteErr != nil {
+				log.Warn().Msgf("Failure in deleting unused bookmarks %s: %v", distVersionPath, deleteErr)
+			}
+		}
+		return false, err
+	}
+
+	return true, s3Client.DeleteObject(bucketName, distEntryPath)
+}
+
+// updateVersionIfUnchanged sets the latest version of the user bookmarks only when they are still at the version
+// they were read with, so that the bookmarks changed concurrently are never overwritten.
+func updateVersionIfUnchanged(dynamodbClient dynamodb.DynamoDBClient, userBookmarks *model.UserBookmarks,
+	distVersion string, events ...models.DomainEvent) (bool, error) {
+	update := dynamodb.GenUpdateBuilder(map[string]interface{}{
+		"latestVersion":     distVersion,
+		"modifiedBookmarks": true,
+		"modifiedTs":        TimeNow(),
+	})
+	condition := dynamodb.GenConditionBuilder(map[string]interface{}{
+		"latestVersion": userBookmarks.LatestVersion,
+	})
+
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return false, err
+	}
+
+	err = WriteWithEvents(dynamodbClient, userBookmarks.UserId, events,
+		dynamodb.TransactUpdateByExpression(&model.UserBookmarks{UserId: userBookmarks.UserId}, expr))
+	if dynamodb.IsConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FetchBookmarksMetadata fetches the metadata of each url, skipping the urls which could not be fetched.
+func FetchBookmarksMetadata(bookmarkURLs []string) map[string]*models.BookmarkMetadata {
+	var mu sync.Mutex
+	metadata := map[string]*models.BookmarkMetadata{}
+	swg := sizedwaitgroup.New(maxEnrichmentConcurrency)
+
+	for _, bookmarkURL := range bookmarkURLs {
+		bookmarkURL := bookmarkURL
+		swg.Add()
+
+		go func() {
+			defer swg.Done()
+
+			entryMetadata, err := fetchBookmarkMetadata(bookmarkURL)
+			if err != nil {
+				log.Warn().Msgf("Enrichment of %s skipped: %v", bookmarkURL, err)
+				return
+			}
+
+			mu.Lock()
+			metadata[bookmarkURL] = entryMetadata
+			mu.Unlock()
+		}()
+	}
+
+	swg.Wait()
+	return metadata
+}
+
+func fetchBookmarkMetadata(bookmarkURL string) (*models.BookmarkMetadata, error) {
+	header := http.Header{}
+	header.Set("Accept", enrichmentFetchTypes)
+
+	response, err := PageCrawler.Fetch(context.Background(), http.MethodGet, bookmarkURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	metadata := &models.BookmarkMetadata{EnrichedAt: TimeNow()}
+
+	contentType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err == nil {
+		metadata.ContentType = contentType
+	}
+
+	pageURL, err := url.Parse(response.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(metadata.ContentType, "html") {
+		parsePageMetadata(response.Body, pageURL, metadata)
+	}
+
+	if metadata.FaviconURL == "" {
+		metadata.FaviconURL = pageURL.ResolveReference(&url.URL{Path: defaultFaviconPath}).String()
+	}
+	return metadata, nil
+}
+
+// parsePageMetadata reads the OpenGraph properties, title and icon links from the head of the HTML page.
+func parsePageMetadata(content []byte, pageURL *url.URL, metadata *models.BookmarkMetadata) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	var title string
+	inTitle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				log.Debug().Msgf("Failure in parsing page %s: %v", pageURL, tokenizer.Err())
+			}
+			break
+		}
+
+		token := tokenizer.Token()
+		tagName := strings.ToLower(token.Data)
+
+		if tokenType == html.EndTagToken && tagName == "head" || tokenType == html.StartTagToken && tagName == "body" {
+			break
+		}
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tagName {
+			case "title":
+				inTitle = true
+			case "meta":
+				parseMetaTag(&token, pageURL, metadata)
+			case "link":
+				parseIconLink(&token, pageURL, metadata)
+			}
+		case html.EndTagToken:
+			inTitle = inTitle && tagName != "title"
+		case html.TextToken:
+			if inTitle {
+				title += token.Data
+			}
+		}
+	}
+
+	if metadata.Title == "" {
+		metadata.Title = strings.TrimSpace(title)
+	}
+}
+
+func parseMetaTag(token *html.Token, pageURL *url.URL, metadata *models.BookmarkMetadata) {
+	property := strings.ToLower(getAttribute(token, "property"))
+	if property == "" {
+		property = strings.ToLower(getAttribute(token, "name"))
+	}
+	content := strings.TrimSpace(getAttribute(token, "content"))
+
+	switch property {
+	case "og:title":
+		metadata.Title = content
+	case "og:image", "og:image:url", "og:image:secure_url":
+		if metadata.Image == "" {
+			metadata.Image = resolvePageURL(pageURL, content)
+		}
+	case "og:site_name":
+		metadata.SiteName = content
+	}
+}
+
+func parseIconLink(token *html.Token, pageURL *url.URL, metadata *models.BookmarkMetadata) {
+	for _, rel := range strings.Fields(strings.ToLower(getAttribute(token, "rel"))) {
+		if rel == "icon" || rel == "apple-touch-icon" {
+			// A plain icon is preferred over the apple-touch-icon.
+			if metadata.FaviconURL == "" || rel == "icon" {
+				metadata.FaviconURL = resolvePageURL(pageURL, getAttribute(token, "href"))
+			}
+			return
+		}
+	}
+}
+
+func getAttribute(token *html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// resolvePageURL resolves the relative url against the page, dropping urls which are not http(s).
+func resolvePageURL(pageURL *url.URL, reference string) string {
+	parsedURL, err := url.Parse(strings.TrimSpace(reference))
+	if err != nil || reference == "" {
+		return ""
+	}
+
+	resolved := pageURL.ResolveReference(parsedURL)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
@@ -0,0 +1,214 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type EnrichmentHelperTestSuite struct {
+	suite.Suite
+	ctrl               *gomock.Controller
+	mockS3Client       *s3Mocks.MockS3Client
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockTimeNow        time.Time
+	server             *httptest.Server
+}
+
+const enrichmentTestPage = `<!DOCTYPE html>
+<html><head>
+<title> Karpenter Docs </title>
+<meta property="og:title" content="Karpenter">
+<meta property="og:image" content="/images/logo.png">
+<meta property="og:site_name" content="Karpenter Project">
+<link rel="apple-touch-icon" href="/apple-icon.png">
+<link rel="shortcut icon" href="https://cdn.karpenter.sh/favicon.svg">
+</head><body><meta property="og:title" content="Ignored"></body></html>`
+
+func TestEnrichmentHelperSuite(t *testing.T) {
+	suite.Run(t, new(EnrichmentHelperTestSuite))
+}
+
+func (s *EnrichmentHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+	s.T().Setenv("BOOKMARKS_BUCKET", "TEST_S3_BUCKET")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(enrichmentTestPage))
+	})
+	mux.HandleFunc("/guide.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF"))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	s.server = httptest.NewServer(mux)
+
+	config := crawler.DefaultConfig()
+	config.MinHostInterval = 0
+	config.AllowPrivateNetworks = true
+	PageCrawler = crawler.New(config)
+}
+
+func (s *EnrichmentHelperTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *EnrichmentHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *EnrichmentHelperTestSuite) TestFetchBookmarksMetadata() {
+	docsURL := s.server.URL + "/docs"
+	pdfURL := s.server.URL + "/guide.pdf"
+
+	metadata := FetchBookmarksMetadata([]string{docsURL, pdfURL, s.server.URL + "/missing"})
+
+	s.Len(metadata, 2)
+	s.Equal(&models.BookmarkMetadata{
+		Title:       "Karpenter",
+		Image:       s.server.URL + "/images/logo.png",
+		FaviconURL:  "https://cdn.karpenter.sh/favicon.svg",
+		SiteName:    "Karpenter Project",
+		ContentType: "text/html",
+		EnrichedAt:  s.mockTimeNow,
+	}, metadata[docsURL])
+	s.Equal(&models.BookmarkMetadata{
+		FaviconURL:  s.server.URL + "/favicon.ico",
+		ContentType: "application/pdf",
+		EnrichedAt:  s.mockTimeNow,
+	}, metadata[pdfURL])
+}
+
+func (s *EnrichmentHelperTestSuite) TestEnrichBookmarks() {
+	docsURL := s.server.URL + "/docs"
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.Success, LatestVersion: "1.0.4"}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(userBookmarks, nil).Times(2)
+
+	s3Content := `{"bookmarks": [{ "url": "` + docsURL + `" }, { "url": "https://chat.openai.com" }]}`
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.4")).
+		Return([]byte(s3Content), nil)
+
+	var stored models.BookmarkList
+	s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.5"),
+		gomock.Eq("application/json"), gomock.Eq(s3.GZip), gomock.Any()).
+		DoAndReturn(func(_, _, _, _ string, content *[]byte) (string, error) {
+			return "v1", json.Unmarshal(*content, &stored)
+		})
+
+	var condition []string
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(userBookmarks, "BookmarksReplaced")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			condition = conditionValues(items[0].Expression)
+			return nil
+		})
+	s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.4")).Return(nil)
+
+	err := EnrichBookmarks(s.mockDynamoDBClient, s.mockS3Client, &models.EnrichmentRequest{
+		UserId: "1",
+		URLs:   []string{docsURL},
+	})
+
+	s.NoError(err)
+	s.Len(stored.BookmarkEntry, 2)
+	s.Equal("Karpenter", stored.BookmarkEntry[0].Metadata.Title)
+	s.Nil(stored.BookmarkEntry[1].Metadata)
+	s.Equal([]string{"1.0.4"}, condition)
+}
+
+func (s *EnrichmentHelperTestSuite) TestEnrichBookmarksWhenBookmarksChangedConcurrently() {
+	docsURL := s.server.URL + "/docs"
+	gomock.InOrder(
+		s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+			Return(&model.UserBookmarks{UserId: "1", Status: constant.Success, LatestVersion: "1.0.4"}, nil).Times(2),
+		s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+			Return(&model.UserBookmarks{UserId: "1", Status: constant.Success, LatestVersion: "1.0.5"}, nil),
+	)
+
+	s3Content := []byte(`{"bookmarks": [{ "url": "` + docsURL + `" }]}`)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.4")).
+		Return(s3Content, nil)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.5")).
+		Return(s3Content, nil)
+
+	gomock.InOrder(
+		s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.5"),
+			gomock.Any(), gomock.Any(), gomock.Any()).Return("v1", nil),
+		s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).
+			Return(&types.ConditionalCheckFailedException{}),
+		s.mockS3Client.EXPECT().DeleteObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.5"),
+			gomock.Eq("v1")).Return(nil),
+		s.mockS3Client.EXPECT().PutObjectVersion(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.6"),
+			gomock.Any(), gomock.Any(), gomock.Any()).Return("v2", nil),
+		s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).Return(nil),
+		s.mockS3Client.EXPECT().DeleteObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.5")).Return(nil),
+	)
+
+	err := EnrichBookmarks(s.mockDynamoDBClient, s.mockS3Client, &models.EnrichmentRequest{
+		UserId: "1",
+		URLs:   []string{docsURL},
+	})
+
+	s.NoError(err)
+}
+
+func (s *EnrichmentHelperTestSuite) TestEnrichBookmarksWhenDisabled() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", LatestVersion: "1.0.4", EnrichmentDisabled: true}, nil)
+
+	err := EnrichBookmarks(s.mockDynamoDBClient, s.mockS3Client, &models.EnrichmentRequest{
+		UserId: "1",
+		URLs:   []string{s.server.URL + "/docs"},
+	})
+
+	s.NoError(err)
+}
+
+func (s *EnrichmentHelperTestSuite) TestEnrichBookmarksWhenDistributionPending() {
+	docsURL := s.server.URL + "/docs"
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", LatestVersion: "1.0.4", Status: constant.BookmarksLocked}, nil).Times(2)
+
+	err := EnrichBookmarks(s.mockDynamoDBClient, s.mockS3Client, &models.EnrichmentRequest{
+		UserId: "1",
+		URLs:   []string{docsURL},
+	})
+
+	s.ErrorIs(err, ErrDistributionPending)
+}
+
+// conditionValues returns the string values of the expression, which are only those of its condition when the
+// update sets no other strings.
+func conditionValues(expr *expression.Expression) []string {
+	var values []string
+	for name, value := range expr.Values() {
+		if stringValue, ok := value.(*types.AttributeValueMemberS); ok && strings.Contains(*expr.Condition(), name) {
+			values = append(values, stringValue.Value)
+		}
+	}
+	return values
+}
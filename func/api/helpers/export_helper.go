@@ -0,0 +1,296 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Model "github.com/pranav-patil/go-serverless-api/pkg/s3/model"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+)
+
+const (
+	exportRootPath = "Exports"
+	// ExportURLLifetime is the seconds the download url of an export is valid for.
+	ExportURLLifetime = 900
+	// exportJobTimeout is after when a pending export job is considered failed, and a new export can be started.
+	exportJobTimeout = 30 * time.Minute
+	exportRetention  = 7 * 24 * time.Hour
+	exportTimedOut   = "export timed out"
+)
+
+var (
+	ErrExportInProgress  = errors.New("an export is already in progress")
+	ErrExportJobNotFound = errors.New("export job not found")
+)
+
+func GetExportQueueURL() string {
+	return os.Getenv("EXPORT_QUEUE_URL")
+}
+
+func GetExportS3Path(userId, jobId string) string {
+	return fmt.Sprintf("%s/%s/%s.zip", exportRootPath, userId, jobId)
+}
+
+// StartExport records a pending export job of the user and queues it, unless an export of the user is
+// already in progress.
+func StartExport(dynamodbClient dynamodb.DynamoDBClient, sqsClient sqs.SQSClient,
+	userId string) (*model.ExportJob, error) {
+	latestJob, err := getLatestExportJob(dynamodbClient, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if latestJob != nil && updateTimedOutExportJob(latestJob).Status == constant.Pending {
+		return nil, ErrExportInProgress
+	}
+
+	currentTime := TimeNow()
+	job := &model.ExportJob{
+		UserId:           userId,
+		JobId:            newTimeSortableId(currentTime),
+		Status:           constant.Pending,
+		CreatedTimestamp: currentTime,
+		Ttl:              currentTime.Add(exportRetention).Unix(),
+	}
+
+	if err = dynamodbClient.AddRecord(job); err != nil {
+		return nil, err
+	}
+
+	message, err := json.Marshal(&models.ExportRequest{UserId: userId, JobId: job.JobId})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = sqsClient.SendMessage(GetExportQueueURL(), string(message))
+	return job, err
+}
+
+func getLatestExportJob(dynamodbClient dynamodb.DynamoDBClient, userId string) (*model.ExportJob, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.ExportJob{UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	result, _, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.ExportJob{},
+		keyCondition, nil, 1, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := result.([]model.ExportJob)
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// GetExportJob returns the export job of the user, where the job pending for too long is reported as failed.
+func GetExportJob(dynamodbClient dynamodb.DynamoDBClient, userId, jobId string) (*model.ExportJob, error) {
+	result, err := dynamodbClient.GetRecordByKey(&model.ExportJob{UserId: userId, JobId: jobId})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrExportJobNotFound
+	}
+
+	return updateTimedOutExportJob(result.(*model.ExportJob)), nil
+}
+
+func updateTimedOutExportJob(job *model.ExportJob) *model.ExportJob {
+	if job.Status == constant.Pending && TimeNow().Sub(job.CreatedTimestamp) > exportJobTimeout {
+		job.Status = constant.Failed
+		job.StatusMessage = exportTimedOut
+	}
+	return job
+}
+
+// GetExportDownloadURL returns the presigned url of the archive of the completed export job.
+func GetExportDownloadURL(s3Client s3.S3Client, job *model.ExportJob) (string, error) {
+	return s3Client.NewSignedGetURL(os.Getenv("BOOKMARKS_BUCKET"), job.S3Key, ExportURLLifetime)
+}
+
+// ExportAccountData archives the bookmarks of all the retained versions, the settings, the distribution
+// history, the webhooks and the snapshots of the user, and completes the export job with the archive.
+// The job which is no longer pending is not exported again.
+func ExportAccountData(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client,
+	request *models.ExportRequest) error {
+	job, err := GetExportJob(dynamodbClient, request.UserId, request.JobId)
+	if errors.Is(err, ErrExportJobNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if job.Status != constant.Pending {
+		return nil
+	}
+
+	files, err := getExportFiles(dynamodbClient, s3Client, request.UserId)
+	if err != nil {
+		return err
+	}
+
+	content, err := util.CreateZipFile(files)
+	if err != nil {
+		return err
+	}
+
+	job.S3Key = GetExportS3Path(request.UserId, request.JobId)
+	err = s3Client.PutObject(os.Getenv("BOOKMARKS_BUCKET"), job.S3Key, "application/zip", "none", &content)
+	if err != nil {
+		return err
+	}
+
+	job.Status = constant.Success
+	job.SizeBytes = int64(len(content))
+	job.EndTimestamp = TimeNow()
+	return dynamodbClient.UpdateRecordsByKey(job)
+}
+
+func getExportFiles(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client,
+	userId string) (map[string]string, error) {
+	files := map[string]string{}
+
+	userBookmarks := GetBookmarkByUser(dynamodbClient, userId)
+	if err := addExportJSONFile(files, "settings.json", GetBookmarksConfig(userBookmarks)); err != nil {
+		return nil, err
+	}
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	err := addExportObjects(s3Client, files, bucketName, fmt.Sprintf("Bookmarks/%s/", userId), "bookmarks/", ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addExportObjects(s3Client, files, bucketName, GetUserSnapshotsS3Path(userId), "snapshots/", "")
+	if err != nil {
+		return nil, err
+	}
+
+	distributions, err := getDistributionHistory(dynamodbClient, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err = addExportJSONFile(files, "distributions.json", distributions); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := GetWebhooks(dynamodbClient, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookList := make([]models.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookList = append(webhookList, models.WebhookResponse{WebhookId: webhook.WebhookId, URL: webhook.URL,
+			EventTypes: webhook.EventTypes, Created: webhook.CreatedTimestamp})
+	}
+	if err = addExportJSONFile(files, "webhooks.json", webhookList); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func addExportJSONFile(files map[string]string, filename string, data interface{}) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files[filename] = string(content)
+	return nil
+}
+
+// addExportObjects adds every retained version of the objects under the prefix to the folder of the archive, by their
+// keys relative to the prefix. The latest version of a key keeps the name of the key, including the versions of the
+// deleted keys, while the older versions are suffixed with their modified time.
+func addExportObjects(s3Client s3.S3Client, files map[string]string, bucket, prefix, folder, extension string) error {
+	exportedKeys := map[string]bool{}
+	var marker *s3Model.VersionMarker
+
+	for {
+		versions, nextMarker, err := s3Client.ListObjectVersions(bucket, prefix, marker)
+		if err != nil {
+			return err
+		}
+
+		// The versions of a key are listed from the latest.
+		for _, version := range versions {
+			if version.DeleteMarker {
+				continue
+			}
+
+			content, err := s3Client.GetObjectVersion(bucket, version.Key, version.VersionId)
+			if err != nil {
+				return err
+			}
+
+			filename := folder + strings.TrimPrefix(version.Key, prefix)
+			if exportedKeys[version.Key] {
+				filename += "." + version.LastModified.UTC().Format(timeSortableIdFmt)
+			}
+			exportedKeys[version.Key] = true
+			files[filename+extension] = string(content)
+		}
+
+		if nextMarker == nil {
+			return nil
+		}
+		marker = nextMarker
+	}
+}
+
+// getDistributionHistory returns the distribution of every device to the user, from the latest distribution.
+func getDistributionHistory(dynamodbClient dynamodb.DynamoDBClient, userId string) ([]models.WebCrawlerJob, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.BookmarkDistribution{UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	jobs := []models.WebCrawlerJob{}
+	var lastEvaluatedKey map[string]types.AttributeValue
+
+	for {
+		var result interface{}
+		result, lastEvaluatedKey, err = dynamodbClient.GetRecordsByKeyConditionPagination(
+			&model.BookmarkDistribution{}, keyCondition, nil, MaxPageSize, lastEvaluatedKey, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, distribution := range result.([]model.BookmarkDistribution) {
+			deviceId, _ := strconv.Atoi(distribution.DeviceId)
+			jobs = append(jobs, models.WebCrawlerJob{
+				ID:             distribution.OperationId,
+				DeviceId:       deviceId,
+				PackageVersion: distribution.Version,
+				State:          distribution.Status,
+				StatusMessage:  distribution.StatusMessage,
+				StartTime:      distribution.StartTimestamp,
+				EndTime:        distribution.EndTimestamp,
+			})
+		}
+
+		if len(lastEvaluatedKey) == 0 {
+			return jobs, nil
+		}
+	}
+}
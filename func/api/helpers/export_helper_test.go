@@ -0,0 +1,191 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	s3Model "github.com/pranav-patil/go-serverless-api/pkg/s3/model"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	mockSQSClient      *sqsMocks.MockSQSClient
+	mockTimeNow        time.Time
+}
+
+func TestExportHelperSuite(t *testing.T) {
+	suite.Run(t, new(ExportHelperTestSuite))
+}
+
+func (s *ExportHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *ExportHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
+	s.T().Setenv("EXPORT_QUEUE_URL", "test_export_queue")
+}
+
+func (s *ExportHelperTestSuite) expectLatestExportJob(jobs ...model.ExportJob) {
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.ExportJob{}),
+		gomock.Any(), gomock.Nil(), gomock.Eq(int32(1)), gomock.Nil(), gomock.Eq(false)).
+		Return(jobs, nil, nil)
+}
+
+func (s *ExportHelperTestSuite) TestStartExport() {
+	s.expectLatestExportJob(model.ExportJob{UserId: "1", JobId: "20091110225234.000000", Status: constant.Success})
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.ExportJob{})).Return(nil)
+	s.mockSQSClient.EXPECT().SendMessage(gomock.Eq("test_export_queue"),
+		mockutil.HasPrefix(`{"userId":"1","jobId":"20091110235234.000000-`)).Return(nil, nil)
+
+	job, err := StartExport(s.mockDynamoDBClient, s.mockSQSClient, "1")
+
+	s.NoError(err)
+	s.True(strings.HasPrefix(job.JobId, "20091110235234.000000-"))
+	s.Equal(constant.Pending, job.Status)
+	s.Equal(s.mockTimeNow.Add(exportRetention).Unix(), job.Ttl)
+}
+
+func (s *ExportHelperTestSuite) TestStartExportWhenInProgress() {
+	s.expectLatestExportJob(model.ExportJob{UserId: "1", JobId: "20091110235034.000000", Status: constant.Pending,
+		CreatedTimestamp: s.mockTimeNow.Add(-2 * time.Minute)})
+
+	_, err := StartExport(s.mockDynamoDBClient, s.mockSQSClient, "1")
+
+	s.ErrorIs(err, ErrExportInProgress)
+}
+
+func (s *ExportHelperTestSuite) TestStartExportWhenPendingJobTimedOut() {
+	s.expectLatestExportJob(model.ExportJob{UserId: "1", JobId: "20091110225234.000000", Status: constant.Pending,
+		CreatedTimestamp: s.mockTimeNow.Add(-time.Hour)})
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.ExportJob{})).Return(nil)
+	s.mockSQSClient.EXPECT().SendMessage(gomock.Eq("test_export_queue"), gomock.Any()).Return(nil, nil)
+
+	_, err := StartExport(s.mockDynamoDBClient, s.mockSQSClient, "1")
+
+	s.NoError(err)
+}
+
+func (s *ExportHelperTestSuite) TestGetExportJobNotFound() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.ExportJob{UserId: "1", JobId: "j1"})).
+		Return(nil, nil)
+
+	_, err := GetExportJob(s.mockDynamoDBClient, "1", "j1")
+
+	s.ErrorIs(err, ErrExportJobNotFound)
+}
+
+func (s *ExportHelperTestSuite) TestExportAccountData() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.ExportJob{UserId: "1", JobId: "j1"})).
+		Return(&model.ExportJob{UserId: "1", JobId: "j1", Status: constant.Pending,
+			CreatedTimestamp: s.mockTimeNow.Add(-time.Minute)}, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", SyncEnabled: true, LatestVersion: "1.0.2"}, nil)
+
+	// The listing of the versions continues on the next page.
+	nextMarker := &s3Model.VersionMarker{KeyMarker: "Bookmarks/1/1.0.1", VersionIdMarker: "v1"}
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+		gomock.Nil()).Return([]s3Model.ObjectVersion{
+		{Key: "Bookmarks/1/1.0.1", VersionId: "v2", IsLatest: true, DeleteMarker: true},
+		{Key: "Bookmarks/1/1.0.1", VersionId: "v1"},
+	}, nextMarker, nil)
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/"),
+		gomock.Eq(nextMarker)).Return([]s3Model.ObjectVersion{
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v4", IsLatest: true},
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v3", LastModified: s.mockTimeNow.Add(-time.Hour)},
+	}, nil, nil)
+	s.mockS3Client.EXPECT().GetObjectVersion(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.1"),
+		gomock.Eq("v1")).Return([]byte(`[]`), nil)
+	s.mockS3Client.EXPECT().GetObjectVersion(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.2"),
+		gomock.Eq("v4")).Return([]byte(`[{"url":"https://example.com"}]`), nil)
+	s.mockS3Client.EXPECT().GetObjectVersion(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.2"),
+		gomock.Eq("v3")).Return([]byte(`[{"url":"https://example.org"}]`), nil)
+	s.mockS3Client.EXPECT().ListObjectVersions(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Snapshots/1/"),
+		gomock.Nil()).Return(nil, nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(
+		mockutil.AnyOfType(&model.BookmarkDistribution{}), gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(),
+		gomock.Eq(false)).Return([]model.BookmarkDistribution{{UserId: "1", DeviceId: "42", OperationId: "7",
+		Version: "1.0.2", Status: constant.Success}}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(
+		mockutil.AnyOfType(&model.WebhookSubscription{}), gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(),
+		gomock.Eq(true)).Return([]model.WebhookSubscription{{UserId: "1", WebhookId: "w1",
+		URL: "https://example.com/hook", Secret: "0123456789abcdef"}}, nil, nil)
+
+	var archive []byte
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Exports/1/j1.zip"),
+		gomock.Eq("application/zip"), gomock.Eq("none"), gomock.Any()).
+		DoAndReturn(func(bucket, key, contentType, encoding string, content *[]byte) error {
+			archive = *content
+			return nil
+		})
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(gomock.Any()).DoAndReturn(func(entity interface{}) error {
+		job := entity.(*model.ExportJob)
+		s.Equal(constant.Success, job.Status)
+		s.Equal("Exports/1/j1.zip", job.S3Key)
+		s.Equal(int64(len(archive)), job.SizeBytes)
+		s.Equal(s.mockTimeNow, job.EndTimestamp)
+		return nil
+	})
+
+	s.NoError(ExportAccountData(s.mockDynamoDBClient, s.mockS3Client, &models.ExportRequest{UserId: "1", JobId: "j1"}))
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	s.NoError(err)
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		s.NoError(err)
+		content, err := io.ReadAll(rc)
+		s.NoError(err)
+		rc.Close()
+		files[file.Name] = string(content)
+	}
+
+	s.Contains(files, "settings.json")
+	s.Contains(files, "distributions.json")
+	s.Equal(`[{"url":"https://example.com"}]`, files["bookmarks/1.0.2.json"])
+	s.Equal(`[{"url":"https://example.org"}]`, files["bookmarks/1.0.2.20091110225234.000000.json"])
+	s.Equal(`[]`, files["bookmarks/1.0.1.json"])
+
+	var webhooks []models.WebhookResponse
+	s.NoError(json.Unmarshal([]byte(files["webhooks.json"]), &webhooks))
+	s.Equal("w1", webhooks[0].WebhookId)
+	s.Empty(webhooks[0].Secret)
+}
+
+func (s *ExportHelperTestSuite) TestExportAccountDataWhenNotPending() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.ExportJob{UserId: "1", JobId: "j1"})).
+		Return(&model.ExportJob{UserId: "1", JobId: "j1", Status: constant.Success}, nil)
+
+	// The redelivered message of a completed export is not exported again.
+	s.NoError(ExportAccountData(s.mockDynamoDBClient, s.mockS3Client, &models.ExportRequest{UserId: "1", JobId: "j1"}))
+}
@@ -0,0 +1,284 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+)
+
+const (
+	// Statuses of the bookmark links in the health report
+	LinkHealthy    = "Healthy"
+	LinkRedirected = "Redirected"
+	LinkBroken     = "Broken"
+	LinkUnchecked  = "Unchecked"
+
+	healthRootPath          = "Health"
+	healthReportName        = "report.json"
+	maxLinkCheckAttempts    = 3
+	maxLinkCheckConcurrency = 10
+	maxSendBatchSize        = 10
+	// The retries of a link check wait linkCheckRetryDelay, doubled after every attempt, or the Retry-After of
+	// the throttled or unavailable host, up to maxLinkCheckRetryAfter.
+	linkCheckRetryDelay    = time.Second
+	maxLinkCheckRetryAfter = 10 * time.Second
+)
+
+// sleepLinkCheck waits before retrying a link check, it is replaced by the tests.
+var sleepLinkCheck = time.Sleep
+
+func GetLinkHealthQueueURL() string {
+	return os.Getenv("LINK_HEALTH_QUEUE_URL")
+}
+
+func GetLinkHealthS3Path(userId string) string {
+	return fmt.Sprintf("%s/%s/%s", healthRootPath, userId, healthReportName)
+}
+
+// QueueLinkHealthChecks sends a link health check request for each user, so that the bookmarks of the users
+// are checked independently of each other.
+func QueueLinkHealthChecks(sqsClient sqs.SQSClient, queueURL string, userIds []string) error {
+	messages := make([]string, 0, len(userIds))
+
+	for _, userId := range userIds {
+		message, err := json.Marshal(&models.LinkHealthRequest{UserId: userId})
+		if err != nil {
+			return err
+		}
+		messages = append(messages, string(message))
+	}
+
+	for start := 0; start < len(messages); start += maxSendBatchSize {
+		end := min(start+maxSendBatchSize, len(messages))
+		if err := sqsClient.SendBatchMessages(queueURL, messages[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckBookmarksHealth checks every bookmark of the user and stores the health report, replacing the previous one.
+func CheckBookmarksHealth(s3Client s3.S3Client, userBookmarks *model.UserBookmarks) (*models.LinkHealthReport, error) {
+	distEntryPath := GetUserBookmarksS3Path(userBookmarks)
+	if distEntryPath == "" {
+		return nil, nil
+	}
+
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	data, err := s3Client.GetObject(bucketName, distEntryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarkList := models.BookmarkList{}
+	if err = json.Unmarshal(data, &bookmarkList); err != nil {
+		return nil, err
+	}
+
+	report := &models.LinkHealthReport{
+		UserId:    userBookmarks.UserId,
+		Version:   userBookmarks.LatestVersion,
+		CheckedAt: TimeNow(),
+		Links:     CheckLinks(bookmarkList.BookmarkEntry),
+	}
+
+	return report, StoreLinkHealthReport(s3Client, report)
+}
+
+// CheckLinks checks the health of the bookmarks concurrently, preserving the order of the bookmarks in the report.
+// The page crawler limits the concurrent requests and spaces out the requests to each host.
+func CheckLinks(bookmarks []models.BookmarkEntry) []models.LinkHealth {
+	links := make([]models.LinkHealth, len(bookmarks))
+	swg := sizedwaitgroup.New(maxLinkCheckConcurrency)
+
+	for i := range bookmarks {
+		index := i
+		swg.Add()
+
+		go func() {
+			defer swg.Done()
+			links[index] = CheckLinkHealth(bookmarks[index].URL)
+		}()
+	}
+
+	swg.Wait()
+	return links
+}
+
+// CheckLinkHealth issues a HEAD request, falling back to GET for servers which do not support HEAD,
+// and retries on transient failures with an exponential backoff, which honors the Retry-After of the host.
+func CheckLinkHealth(bookmarkURL string) models.LinkHealth {
+	var response *crawler.Response
+	var err error
+
+	for attempt := 1; attempt <= maxLinkCheckAttempts; attempt++ {
+		response, err = PageCrawler.FetchHeaders(context.Background(), http.MethodHead, bookmarkURL, nil)
+		if err == nil && isHeadUnsupported(response.StatusCode) {
+			response, err = PageCrawler.FetchHeaders(context.Background(), http.MethodGet, bookmarkURL, nil)
+		}
+
+		if !isRetryableLinkCheck(response, err) || attempt == maxLinkCheckAttempts {
+			break
+		}
+		sleepLinkCheck(getLinkCheckRetryDelay(attempt, response, err))
+	}
+
+	return newLinkHealth(bookmarkURL, response, err)
+}
+
+// getLinkCheckRetryDelay returns the delay before the next attempt, which is the longer of the backoff and the
+// Retry-After of a 429 or 503 response, capped at maxLinkCheckRetryAfter.
+func getLinkCheckRetryDelay(attempt int, response *crawler.Response, err error) time.Duration {
+	delay := linkCheckRetryDelay << (attempt - 1)
+	if err != nil || (response.StatusCode != http.StatusTooManyRequests &&
+		response.StatusCode != http.StatusServiceUnavailable) {
+		return delay
+	}
+
+	retryAfter := response.Header.Get("Retry-After")
+	if seconds, parseErr := strconv.Atoi(retryAfter); parseErr == nil {
+		return max(delay, min(time.Duration(seconds)*time.Second, maxLinkCheckRetryAfter))
+	} else if date, parseErr := http.ParseTime(retryAfter); parseErr == nil {
+		return max(delay, min(date.Sub(TimeNow()), maxLinkCheckRetryAfter))
+	}
+	return delay
+}
+
+func newLinkHealth(bookmarkURL string, response *crawler.Response, err error) models.LinkHealth {
+	linkHealth := models.LinkHealth{URL: bookmarkURL, LastChecked: TimeNow()}
+
+	if err != nil {
+		linkHealth.Status = LinkBroken
+		linkHealth.Error = err.Error()
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			linkHealth.Error = fmt.Sprintf("DNS failure: %v", dnsErr)
+		} else if errors.Is(err, crawler.ErrDisallowedByRobots) {
+			linkHealth.Status = LinkUnchecked
+		}
+		return linkHealth
+	}
+
+	linkHealth.StatusCode = response.StatusCode
+	linkHealth.RedirectChain = response.RedirectChain
+	linkHealth.FinalURL = response.URL
+
+	switch {
+	case response.StatusCode >= http.StatusBadRequest:
+		linkHealth.Status = LinkBroken
+	case len(response.RedirectChain) > 0 && response.URL != bookmarkURL:
+		linkHealth.Status = LinkRedirected
+	default:
+		linkHealth.Status = LinkHealthy
+	}
+	return linkHealth
+}
+
+func isHeadUnsupported(statusCode int) bool {
+	return statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented
+}
+
+func isRetryableLinkCheck(response *crawler.Response, err error) bool {
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && !dnsErr.IsTemporary {
+			return false
+		}
+		return !errors.Is(err, crawler.ErrDisallowedByRobots) && !errors.Is(err, crawler.ErrBlockedAddress) &&
+			!errors.Is(err, crawler.ErrTooManyRedirects) && !errors.Is(err, crawler.ErrUnsupportedScheme)
+	}
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+}
+
+func StoreLinkHealthReport(s3Client s3.S3Client, report *models.LinkHealthReport) error {
+	content, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	return s3Client.PutObject(os.Getenv("BOOKMARKS_BUCKET"), GetLinkHealthS3Path(report.UserId),
+		"application/json", s3.GZip, &content)
+}
+
+func GetLinkHealthReport(s3Client s3.S3Client, userId string) (*models.LinkHealthReport, error) {
+	data, err := s3Client.GetObject(os.Getenv("BOOKMARKS_BUCKET"), GetLinkHealthS3Path(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.LinkHealthReport{}
+	if err = json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func GroupLinkHealth(report *models.LinkHealthReport) *models.LinkHealthResponse {
+	response := &models.LinkHealthResponse{
+		Version:    report.Version,
+		CheckedAt:  report.CheckedAt,
+		Broken:     []models.LinkHealth{},
+		Redirected: []models.LinkHealth{},
+		Healthy:    []models.LinkHealth{},
+		Unchecked:  []models.LinkHealth{},
+	}
+
+	for _, link := range report.Links {
+		switch link.Status {
+		case LinkBroken:
+			response.Broken = append(response.Broken, link)
+		case LinkRedirected:
+			response.Redirected = append(response.Redirected, link)
+		case LinkHealthy:
+			response.Healthy = append(response.Healthy, link)
+		default:
+			response.Unchecked = append(response.Unchecked, link)
+		}
+	}
+	return response
+}
+
+// ReplaceRedirectedURLs replaces the url of every redirected bookmark with its final target, and marks the
+// replaced links healthy in the report. It returns the number of bookmarks which were replaced.
+func ReplaceRedirectedURLs(bookmarks []models.BookmarkEntry, report *models.LinkHealthReport) int {
+	finalURLs := map[string]string{}
+
+	for i := range report.Links {
+		link := &report.Links[i]
+		if link.Status == LinkRedirected && link.FinalURL != "" {
+			finalURLs[link.URL] = link.FinalURL
+		}
+	}
+
+	replaced := 0
+	for i := range bookmarks {
+		if finalURL, ok := finalURLs[bookmarks[i].URL]; ok {
+			bookmarks[i].URL = finalURL
+			replaced++
+		}
+	}
+
+	for i := range report.Links {
+		link := &report.Links[i]
+		if _, ok := finalURLs[link.URL]; ok {
+			link.URL = link.FinalURL
+			link.Status = LinkHealthy
+			link.RedirectChain = nil
+		}
+	}
+	return replaced
+}
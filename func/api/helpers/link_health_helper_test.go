@@ -0,0 +1,211 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type LinkHealthHelperTestSuite struct {
+	suite.Suite
+	ctrl          *gomock.Controller
+	mockS3Client  *s3Mocks.MockS3Client
+	mockSQSClient *sqsMocks.MockSQSClient
+	mockTimeNow   time.Time
+	server        *httptest.Server
+	flakyCalls    atomic.Int32
+	delays        []time.Duration
+}
+
+func TestLinkHealthHelperSuite(t *testing.T) {
+	suite.Run(t, new(LinkHealthHelperTestSuite))
+}
+
+func (s *LinkHealthHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+	s.T().Setenv("BOOKMARKS_BUCKET", "TEST_S3_BUCKET")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/healthy", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/healthy", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/throttled", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if s.flakyCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	s.server = httptest.NewServer(mux)
+
+	config := crawler.DefaultConfig()
+	config.MinHostInterval = 0
+	config.AllowPrivateNetworks = true
+	PageCrawler = crawler.New(config)
+}
+
+func (s *LinkHealthHelperTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *LinkHealthHelperTestSuite) SetupTest() {
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+
+	var mutex sync.Mutex
+	s.delays = nil
+	sleepLinkCheck = func(delay time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		s.delays = append(s.delays, delay)
+	}
+}
+
+func (s *LinkHealthHelperTestSuite) TestCheckLinks() {
+	links := CheckLinks([]models.BookmarkEntry{
+		{URL: s.server.URL + "/healthy"},
+		{URL: s.server.URL + "/old"},
+		{URL: s.server.URL + "/missing"},
+		{URL: s.server.URL + "/get-only"},
+		{URL: s.server.URL + "/flaky"},
+		{URL: s.server.URL + "/private/page"},
+		{URL: "http://bookmark.invalid/"},
+	})
+
+	s.Len(links, 7)
+	s.Equal(models.LinkHealth{URL: s.server.URL + "/healthy", Status: LinkHealthy, StatusCode: http.StatusOK,
+		FinalURL: s.server.URL + "/healthy", LastChecked: s.mockTimeNow}, links[0])
+	s.Equal(models.LinkHealth{URL: s.server.URL + "/old", Status: LinkRedirected, StatusCode: http.StatusOK,
+		RedirectChain: []string{s.server.URL + "/old"}, FinalURL: s.server.URL + "/healthy",
+		LastChecked: s.mockTimeNow}, links[1])
+	s.Equal(LinkBroken, links[2].Status)
+	s.Equal(http.StatusNotFound, links[2].StatusCode)
+	s.Equal(LinkHealthy, links[3].Status)
+	s.Equal(LinkHealthy, links[4].Status)
+	s.EqualValues(2, s.flakyCalls.Load())
+	s.Equal(LinkUnchecked, links[5].Status)
+	s.Equal(LinkBroken, links[6].Status)
+	s.Contains(links[6].Error, "DNS failure")
+}
+
+func (s *LinkHealthHelperTestSuite) TestCheckLinkHealthBacksOffBetweenAttempts() {
+	linkHealth := CheckLinkHealth(s.server.URL + "/throttled")
+
+	s.Equal(LinkBroken, linkHealth.Status)
+	s.Equal(http.StatusTooManyRequests, linkHealth.StatusCode)
+	s.Equal([]time.Duration{time.Second, 2 * time.Second}, s.delays)
+}
+
+func (s *LinkHealthHelperTestSuite) TestCheckLinkHealthHonorsRetryAfter() {
+	CheckLinkHealth(s.server.URL + "/throttled?after=5")
+	s.Equal([]time.Duration{5 * time.Second, 5 * time.Second}, s.delays)
+
+	// The Retry-After is capped, so that a single link cannot hold up the check of the bookmarks.
+	s.delays = nil
+	CheckLinkHealth(s.server.URL + "/throttled?after=3600")
+	s.Equal([]time.Duration{maxLinkCheckRetryAfter, maxLinkCheckRetryAfter}, s.delays)
+
+	s.delays = nil
+	after := s.mockTimeNow.Add(4 * time.Second).Format(http.TimeFormat)
+	CheckLinkHealth(s.server.URL + "/throttled?after=" + url.QueryEscape(after))
+	s.Equal([]time.Duration{4 * time.Second, 4 * time.Second}, s.delays)
+}
+
+func (s *LinkHealthHelperTestSuite) TestQueueLinkHealthChecksInBatches() {
+	var userIds []string
+	for i := 1; i <= 12; i++ {
+		userIds = append(userIds, strconv.Itoa(i))
+	}
+
+	var batches [][]string
+	s.mockSQSClient.EXPECT().SendBatchMessages(gomock.Eq("test_queue"), gomock.Any()).
+		DoAndReturn(func(_ string, messages []string) error {
+			batches = append(batches, messages)
+			return nil
+		}).Times(2)
+
+	err := QueueLinkHealthChecks(s.mockSQSClient, "test_queue", userIds)
+
+	s.NoError(err)
+	s.Len(batches[0], 10)
+	s.Equal([]string{`{"userId":"11"}`, `{"userId":"12"}`}, batches[1])
+}
+
+func (s *LinkHealthHelperTestSuite) TestCheckBookmarksHealth() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.4"}
+
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.4")).
+		Return([]byte(`{"bookmarks": [{ "url": "`+s.server.URL+`/healthy" }]}`), nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Health/1/report.json"),
+		gomock.Eq("application/json"), gomock.Eq(s3.GZip), gomock.Any()).Return(nil)
+
+	report, err := CheckBookmarksHealth(s.mockS3Client, userBookmarks)
+
+	s.NoError(err)
+	s.Equal("1.0.4", report.Version)
+	s.Equal(s.mockTimeNow, report.CheckedAt)
+	s.Len(report.Links, 1)
+}
+
+func (s *LinkHealthHelperTestSuite) TestCheckBookmarksHealthWhenBookmarksDeleted() {
+	report, err := CheckBookmarksHealth(s.mockS3Client, &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.4_DELETED"})
+
+	s.NoError(err)
+	s.Nil(report)
+}
+
+func (s *LinkHealthHelperTestSuite) TestGroupLinkHealthAndReplaceRedirectedURLs() {
+	report := &models.LinkHealthReport{
+		UserId:  "1",
+		Version: "1.0.4",
+		Links: []models.LinkHealth{
+			{URL: "http://karpenter.sh", Status: LinkRedirected, FinalURL: "https://karpenter.sh/",
+				RedirectChain: []string{"http://karpenter.sh"}},
+			{URL: "https://chat.openai.com", Status: LinkHealthy},
+			{URL: "https://gone.example.com", Status: LinkBroken, StatusCode: http.StatusGone},
+		},
+	}
+
+	grouped := GroupLinkHealth(report)
+	s.Len(grouped.Redirected, 1)
+	s.Len(grouped.Healthy, 1)
+	s.Len(grouped.Broken, 1)
+	s.Empty(grouped.Unchecked)
+
+	bookmarks := []models.BookmarkEntry{{URL: "http://karpenter.sh"}, {URL: "https://chat.openai.com"}}
+	s.Equal(1, ReplaceRedirectedURLs(bookmarks, report))
+	s.Equal("https://karpenter.sh/", bookmarks[0].URL)
+	s.Equal(models.LinkHealth{URL: "https://karpenter.sh/", Status: LinkHealthy, FinalURL: "https://karpenter.sh/"},
+		report.Links[0])
+}
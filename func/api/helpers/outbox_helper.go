@@ -0,0 +1,197 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/google/uuid"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/sns"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBookmarkAddedEvents is the most bookmarks added by a write which are published as separate events,
+	// as each event is written in the same transaction as the bookmarks.
+	MaxBookmarkAddedEvents = 25
+
+	OutboxEventTypeAttribute = "eventType"
+	OutboxCategoryAttribute  = "category"
+
+	outboxRetention   = 7 * 24 * time.Hour
+	outboxRelayDelay  = 5 * time.Minute
+	timeSortableIdFmt = "20060102150405.000000"
+)
+
+func GetDomainEventsTopicARN() string {
+	return os.Getenv("DOMAIN_EVENTS_TOPIC_ARN")
+}
+
+// newTimeSortableId returns a unique id which sorts the records of a partition by their time.
+func newTimeSortableId(timestamp time.Time) string {
+	return fmt.Sprintf("%s-%s", timestamp.UTC().Format(timeSortableIdFmt), uuid.NewString()[:8])
+}
+
+// NewOutboxEvent records the domain event of the user with the message to be published for it.
+func NewOutboxEvent(userId string, event models.DomainEvent) (*model.OutboxEvent, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	currentTime := TimeNow()
+	outboxEvent := &model.OutboxEvent{
+		UserId:           userId,
+		EventId:          newTimeSortableId(currentTime),
+		EventType:        event.EventType(),
+		CreatedTimestamp: currentTime,
+	}
+
+	message, err := json.Marshal(models.DomainEventMessage{
+		Id:        outboxEvent.EventId,
+		Type:      outboxEvent.EventType,
+		UserId:    userId,
+		Timestamp: currentTime.UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outboxEvent.Message = string(message)
+	outboxEvent.Attributes = map[string]string{
+		OutboxEventTypeAttribute: outboxEvent.EventType,
+		OutboxCategoryAttribute:  getEventCategory(event),
+	}
+	return outboxEvent, nil
+}
+
+func getEventCategory(event models.DomainEvent) string {
+	switch event.(type) {
+	case models.DistributionStarted, *models.DistributionStarted,
+		models.DeviceDistributionCompleted, *models.DeviceDistributionCompleted:
+		return "distribution"
+	default:
+		return "bookmarks"
+	}
+}
+
+// WriteWithEvents writes the items of the user along with the outbox records of the events in a single
+// transaction, so that an event is recorded if and only if its change is. The single item is written on its own
+// when there are no events.
+func WriteWithEvents(dynamodbClient dynamodb.DynamoDBClient, userId string, events []models.DomainEvent,
+	items ...model.TransactWriteItem) error {
+	if len(events) == 0 && len(items) == 1 {
+		return writeItem(dynamodbClient, items[0])
+	}
+
+	for _, event := range events {
+		outboxEvent, err := NewOutboxEvent(userId, event)
+		if err != nil {
+			return err
+		}
+		items = append(items, dynamodb.TransactAdd(outboxEvent))
+	}
+	return dynamodbClient.TransactWriteRecords(items)
+}
+
+func writeItem(dynamodbClient dynamodb.DynamoDBClient, item model.TransactWriteItem) error {
+	switch {
+	case !item.Update:
+		return dynamodbClient.AddRecord(item.Entity)
+	case item.Expression != nil:
+		return dynamodbClient.UpdateRecordsByExpression(item.Entity, *item.Expression)
+	default:
+		return dynamodbClient.UpdateRecordsByKey(item.Entity)
+	}
+}
+
+// GetBookmarkAddedEvents returns an event for each of the added bookmarks, or a single replaced event for the
+// bookmarks of the version when more than MaxBookmarkAddedEvents bookmarks were added.
+func GetBookmarkAddedEvents(version string, addedBookmarks []models.BookmarkEntry,
+	totalCount int) []models.DomainEvent {
+	if len(addedBookmarks) > MaxBookmarkAddedEvents {
+		return []models.DomainEvent{models.BookmarksReplaced{Version: version, TotalCount: totalCount}}
+	}
+
+	events := make([]models.DomainEvent, 0, len(addedBookmarks))
+	for _, bookmark := range addedBookmarks {
+		events = append(events, models.BookmarkAdded{Version: version, Bookmark: bookmark})
+	}
+	return events
+}
+
+// NewDeviceDistributionCompleted returns the completed event of the device distribution.
+func NewDeviceDistributionCompleted(appDistribution *model.BookmarkDistribution) models.DeviceDistributionCompleted {
+	return models.DeviceDistributionCompleted{
+		OperationId:   appDistribution.OperationId,
+		DeviceId:      appDistribution.DeviceId,
+		Version:       appDistribution.Version,
+		Status:        appDistribution.Status,
+		StatusMessage: appDistribution.StatusMessage,
+		EndTime:       appDistribution.EndTimestamp,
+	}
+}
+
+// RelayOutboxEvent publishes the message of the event with its attributes and marks the event published,
+// after which it expires. An event is published again when the relay fails after publishing it,
+// so the consumers must discard the duplicate event ids.
+func RelayOutboxEvent(dynamodbClient dynamodb.DynamoDBClient, snsClient sns.SNSClient, topicARN string,
+	outboxEvent *model.OutboxEvent) error {
+	if !outboxEvent.PublishedTimestamp.IsZero() {
+		return nil
+	}
+
+	err := snsClient.PublishWithAttributes(topicARN, outboxEvent.Message, outboxEvent.Attributes)
+	if err != nil {
+		return err
+	}
+
+	currentTime := TimeNow()
+	expr, err := expression.NewBuilder().WithUpdate(dynamodb.GenUpdateBuilder(map[string]interface{}{
+		"publishedTs": currentTime,
+		"Ttl":         currentTime.Add(outboxRetention).Unix(),
+	})).Build()
+	if err != nil {
+		return err
+	}
+
+	if err = dynamodbClient.UpdateRecordsByExpression(outboxEvent, expr); err != nil {
+		return err
+	}
+	outboxEvent.PublishedTimestamp = currentTime
+	return nil
+}
+
+// RelayPendingOutboxEvents relays the events which are still not published a while after they were recorded,
+// which the stream of the outbox table failed to relay. It returns the number of relayed events.
+func RelayPendingOutboxEvents(dynamodbClient dynamodb.DynamoDBClient, snsClient sns.SNSClient,
+	topicARN string) (int, error) {
+	filter := expression.AttributeNotExists(expression.Name("publishedTs"))
+	result, err := dynamodbClient.GetAllRecords(&model.OutboxEvent{}, &filter, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	outboxEvents := result.([]model.OutboxEvent)
+	var relayedCount int
+
+	for i := range outboxEvents {
+		if TimeNow().Sub(outboxEvents[i].CreatedTimestamp) < outboxRelayDelay {
+			continue
+		}
+
+		if err = RelayOutboxEvent(dynamodbClient, snsClient, topicARN, &outboxEvents[i]); err != nil {
+			log.Error().Msgf("Failure in relaying %s event %s for userId %s: %v", outboxEvents[i].EventType,
+				outboxEvents[i].EventId, outboxEvents[i].UserId, err)
+			continue
+		}
+		relayedCount++
+	}
+	return relayedCount, nil
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	snsMocks "github.com/pranav-patil/go-serverless-api/pkg/sns/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockSNSClient      *snsMocks.MockSNSClient
+	mockTimeNow        time.Time
+}
+
+func TestOutboxHelperSuite(t *testing.T) {
+	suite.Run(t, new(OutboxHelperTestSuite))
+}
+
+func (s *OutboxHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *OutboxHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockSNSClient = snsMocks.NewMockSNSClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *OutboxHelperTestSuite) TestNewOutboxEvent() {
+	outboxEvent, err := NewOutboxEvent("1", models.DistributionStarted{OperationId: 20091110235234,
+		Version: "1.0.2", PackageFormat: "tar.gz", DeviceIds: []string{"42"}})
+
+	s.NoError(err)
+	s.Equal("1", outboxEvent.UserId)
+	s.Equal("DistributionStarted", outboxEvent.EventType)
+	s.Equal(map[string]string{"eventType": "DistributionStarted", "category": "distribution"},
+		outboxEvent.Attributes)
+
+	var message models.DomainEventMessage
+	s.NoError(json.Unmarshal([]byte(outboxEvent.Message), &message))
+	s.Equal(outboxEvent.EventId, message.Id)
+	s.Equal("DistributionStarted", message.Type)
+	s.Equal(s.mockTimeNow, message.Timestamp)
+	s.JSONEq(`{"operationId":20091110235234,"version":"1.0.2","packageFormat":"tar.gz","deviceIds":["42"]}`,
+		string(message.Data))
+}
+
+func (s *OutboxHelperTestSuite) TestWriteWithEvents() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.2"}
+
+	var items []model.TransactWriteItem
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(userBookmarks, "BookmarkAdded",
+		"BookmarkAdded")).
+		DoAndReturn(func(transactItems []model.TransactWriteItem) error {
+			items = transactItems
+			return nil
+		})
+
+	err := WriteWithEvents(s.mockDynamoDBClient, "1", GetBookmarkAddedEvents("1.0.2",
+		[]models.BookmarkEntry{{URL: "https://go.dev"}, {URL: "https://pkg.go.dev"}}, 2),
+		dynamodb.TransactUpdate(userBookmarks))
+
+	s.NoError(err)
+	s.Equal(userBookmarks, items[0].Entity)
+	s.True(items[0].Update)
+	s.False(items[1].Update)
+	s.Equal("1", items[1].Entity.(*model.OutboxEvent).UserId)
+}
+
+func (s *OutboxHelperTestSuite) TestWriteWithEventsWithoutEvents() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.2"}
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByKey(gomock.Eq(userBookmarks)).Return(nil)
+
+	err := WriteWithEvents(s.mockDynamoDBClient, "1", nil, dynamodb.TransactUpdate(userBookmarks))
+
+	s.NoError(err)
+}
+
+func (s *OutboxHelperTestSuite) TestGetBookmarkAddedEvents() {
+	added := make([]models.BookmarkEntry, MaxBookmarkAddedEvents+1)
+
+	s.Len(GetBookmarkAddedEvents("1.0.2", added[:MaxBookmarkAddedEvents], 30), MaxBookmarkAddedEvents)
+	s.Equal([]models.DomainEvent{models.BookmarksReplaced{Version: "1.0.2", TotalCount: 30}},
+		GetBookmarkAddedEvents("1.0.2", added, 30))
+}
+
+func (s *OutboxHelperTestSuite) TestRelayOutboxEvent() {
+	outboxEvent := &model.OutboxEvent{UserId: "1", EventId: "e1", EventType: "BookmarksReplaced",
+		Message: `{"id":"e1"}`, Attributes: map[string]string{"eventType": "BookmarksReplaced"}}
+
+	s.mockSNSClient.EXPECT().PublishWithAttributes(gomock.Eq("domain-topic"), gomock.Eq(`{"id":"e1"}`),
+		gomock.Eq(map[string]string{"eventType": "BookmarksReplaced"})).Return(nil)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(outboxEvent), gomock.Any()).Return(nil)
+
+	err := RelayOutboxEvent(s.mockDynamoDBClient, s.mockSNSClient, "domain-topic", outboxEvent)
+
+	s.NoError(err)
+	s.Equal(s.mockTimeNow, outboxEvent.PublishedTimestamp)
+
+	// The published event is not published again.
+	s.NoError(RelayOutboxEvent(s.mockDynamoDBClient, s.mockSNSClient, "domain-topic", outboxEvent))
+}
+
+func (s *OutboxHelperTestSuite) TestRelayOutboxEventWhenPublishFails() {
+	outboxEvent := &model.OutboxEvent{UserId: "1", EventId: "e1", Message: `{"id":"e1"}`}
+
+	s.mockSNSClient.EXPECT().PublishWithAttributes(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("throttled"))
+
+	err := RelayOutboxEvent(s.mockDynamoDBClient, s.mockSNSClient, "domain-topic", outboxEvent)
+
+	s.Error(err)
+	s.True(outboxEvent.PublishedTimestamp.IsZero())
+}
+
+func (s *OutboxHelperTestSuite) TestRelayPendingOutboxEvents() {
+	s.mockDynamoDBClient.EXPECT().GetAllRecords(mockutil.AnyOfType(&model.OutboxEvent{}), gomock.Any(), gomock.Nil()).
+		DoAndReturn(func(_ model.Entity, filter *expression.ConditionBuilder, _ *expression.ProjectionBuilder) (
+			interface{}, error) {
+			s.NotNil(filter)
+			return []model.OutboxEvent{
+				{UserId: "1", EventId: "e1", Message: `{"id":"e1"}`, CreatedTimestamp: s.mockTimeNow.Add(-time.Hour)},
+				{UserId: "1", EventId: "e2", Message: `{"id":"e2"}`, CreatedTimestamp: s.mockTimeNow.Add(-time.Minute)},
+			}, nil
+		})
+
+	s.mockSNSClient.EXPECT().PublishWithAttributes(gomock.Eq("domain-topic"), gomock.Eq(`{"id":"e1"}`),
+		gomock.Any()).Return(nil)
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(mockutil.AnyOfType(&model.OutboxEvent{}), gomock.Any()).
+		Return(nil)
+
+	relayedCount, err := RelayPendingOutboxEvents(s.mockDynamoDBClient, s.mockSNSClient, "domain-topic")
+
+	s.NoError(err)
+	s.Equal(1, relayedCount)
+}
@@ -0,0 +1,498 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/signing"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	PackageTypeFull  = "full"
+	PackageTypeDelta = "delta"
+
+	packageManifestFile  = "manifest.json"
+	packageSignatureFile = "manifest.sig"
+	packageVersionFile   = "version"
+	packageEntriesFile   = "bookmarks.txt"
+	packageAddedFile     = "bookmarks-added.txt"
+	packageRemovedFile   = "bookmarks-removed.txt"
+
+	// legacyPackageEntriesFile holds the entries of the packages created before the entries were renamed,
+	// which are still read as the base of the delta packages.
+	legacyPackageEntriesFile = "ip-filtering.pkg"
+)
+
+var packageContentTypes = map[string]string{
+	PackageFormatTarGz: "application/gzip",
+	PackageFormatZip:   "application/zip",
+	PackageFormatJSON:  "application/json",
+}
+
+var NewPackageSigner = signing.NewPackageSigner
+
+// PackageManifest describes the content of a package, which holds the same files in each of the package formats.
+// A delta package only holds the entries added and removed since the base version, hence it applies only on a device
+// which has the base version.
+// The manifest holds the SHA-256 digests of the other files of the package, and the package carries the base64
+// Ed25519 signature of the manifest, which the devices verify with the published signing key.
+type PackageManifest struct {
+	Version     string            `json:"version"`
+	Type        string            `json:"type"`
+	Format      string            `json:"format"`
+	BaseVersion string            `json:"baseVersion,omitempty"`
+	Entries     int               `json:"entries,omitempty"`
+	Added       int               `json:"added,omitempty"`
+	Removed     int               `json:"removed,omitempty"`
+	KeyId       string            `json:"keyId"`
+	Files       map[string]string `json:"files"`
+}
+
+// distributionPackage is a package in the package bucket along with its presigned url for the devices.
+type distributionPackage struct {
+	BaseVersion  string
+	Checksum     string
+	PreSignedURL string
+}
+
+// packageSet provides the packages of a version to the devices. A device receives the delta against the version
+// it last applied successfully, which is shared by the devices with the same base version, or the full package
+// when the package of its base version is missing.
+// The packages are keyed by their checksum, and the checksums of the packages of the version are recorded on the
+// user bookmarks, hence the packages of an unchanged version are reused instead of being created again.
+type packageSet struct {
+	dynamodbClient dynamodb.DynamoDBClient
+	s3Client       s3.S3Client
+	signer         signing.Signer
+	distribution   *model.UserBookmarks
+	version        string
+	format         string
+	entries        []string
+	entriesLoaded  bool
+	full           *distributionPackage
+	deltas         map[string]*distributionPackage
+}
+
+// newPackageSet provides the full package of the version in the format, which is created when it was never
+// created or is missing in the package bucket.
+func newPackageSet(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, distribution *model.UserBookmarks,
+	version, format string) (*packageSet, error) {
+	signer, err := NewPackageSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	packages := &packageSet{
+		dynamodbClient: dynamodbClient,
+		s3Client:       s3Client,
+		signer:         signer,
+		distribution:   distribution,
+		version:        version,
+		format:         format,
+		full:           &distributionPackage{},
+		deltas:         make(map[string]*distributionPackage),
+	}
+
+	// The deleted bookmarks are distributed without a package.
+	if strings.HasSuffix(version, DeletedVersionSuffix) {
+		return packages, nil
+	}
+
+	fullPackage, err := packages.getRecordedPackage("")
+	if err != nil {
+		return nil, err
+	}
+
+	if fullPackage == nil {
+		distEntryPath := GetUserBookmarksS3Path(&model.UserBookmarks{UserId: distribution.UserId, LatestVersion: version})
+		var checksum string
+		packages.entries, checksum, err = createFullPackage(s3Client, signer, distribution.UserId, format, version,
+			distEntryPath)
+		if err != nil {
+			return nil, err
+		}
+		packages.entriesLoaded = true
+
+		fullPackage, err = packages.recordPackage("", checksum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	packages.full = fullPackage
+	return packages, nil
+}
+
+// forDevice returns the package for the device, which is the full package when the device has no base version.
+func (p *packageSet) forDevice(deviceId string) (*distributionPackage, error) {
+	if p.full.Checksum == "" {
+		return p.full, nil
+	}
+
+	base, err := getLastAppliedDistribution(p.dynamodbClient, p.distribution.UserId, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The distributions before the packages were keyed by checksum have no base package.
+	if base == nil || base.Version == p.version || base.PackageChecksum == "" ||
+		strings.HasSuffix(base.Version, DeletedVersionSuffix) {
+		return p.full, nil
+	}
+
+	if deltaPackage, ok := p.deltas[base.Version]; ok {
+		return deltaPackage, nil
+	}
+
+	deltaPackage, err := p.getRecordedPackage(base.Version)
+	if err == nil && deltaPackage == nil {
+		deltaPackage, err = p.createDeltaPackage(base)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.deltas[base.Version] = deltaPackage
+	return deltaPackage, nil
+}
+
+func (p *packageSet) createDeltaPackage(base *model.BookmarkDistribution) (*distributionPackage, error) {
+	ipPackageBucketName := os.Getenv("BOOKMARKS_SUMMARY_BUCKET")
+	basePackagePath := GetPackageS3Path(p.distribution.UserId, base.PackageChecksum, base.PackageFormat)
+
+	content, err := p.s3Client.GetObject(ipPackageBucketName, basePackagePath)
+	if err != nil {
+		log.Debug().Msgf("Base package %s is missing, distributing full package: %v", basePackagePath, err)
+		return p.full, nil
+	}
+
+	baseEntries, err := readPackageEntries(base.PackageFormat, content)
+	if err != nil {
+		log.Warn().Msgf("Base package %s is not readable, distributing full package: %v", basePackagePath, err)
+		return p.full, nil
+	}
+
+	entries, err := p.getEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := diffEntries(baseEntries, entries)
+	if len(added)+len(removed) >= len(entries) {
+		return p.full, nil
+	}
+
+	manifest := PackageManifest{
+		Version:     p.version,
+		Type:        PackageTypeDelta,
+		Format:      p.format,
+		BaseVersion: base.Version,
+		Added:       len(added),
+		Removed:     len(removed),
+	}
+	files := map[string]string{
+		packageAddedFile:   strings.Join(added, "\n"),
+		packageRemovedFile: strings.Join(removed, "\n"),
+	}
+
+	checksum, err := putPackage(p.s3Client, p.signer, p.distribution.UserId, &manifest, files)
+	if err != nil {
+		return nil, err
+	}
+	return p.recordPackage(base.Version, checksum)
+}
+
+// getEntries returns the entries of the version, which are read from the full package when it was reused.
+func (p *packageSet) getEntries() ([]string, error) {
+	if !p.entriesLoaded {
+		content, err := p.s3Client.GetObject(os.Getenv("BOOKMARKS_SUMMARY_BUCKET"),
+			GetPackageS3Path(p.distribution.UserId, p.full.Checksum, p.format))
+		if err != nil {
+			return nil, err
+		}
+
+		p.entries, err = readPackageEntries(p.format, content)
+		if err != nil {
+			return nil, err
+		}
+		p.entriesLoaded = true
+	}
+	return p.entries, nil
+}
+
+// getRecordedPackage returns the package of the version against the base version, which is the full package for
+// an empty base version, when it is recorded and still exists in the package bucket.
+func (p *packageSet) getRecordedPackage(baseVersion string) (*distributionPackage, error) {
+	checksum, ok := p.distribution.Packages[getPackageRecordKey(p.version, p.format, baseVersion)]
+	if !ok {
+		return nil, nil
+	}
+
+	exists, err := p.s3Client.ObjectExists(os.Getenv("BOOKMARKS_SUMMARY_BUCKET"),
+		GetPackageS3Path(p.distribution.UserId, checksum, p.format))
+	if err != nil || !exists {
+		return nil, err
+	}
+	return p.newDistributionPackage(baseVersion, checksum)
+}
+
+// recordPackage records the checksum of the package on the user bookmarks, dropping the packages of the other
+// versions, which are not distributed any more.
+func (p *packageSet) recordPackage(baseVersion, checksum string) (*distributionPackage, error) {
+	versionPrefix := p.version + "/"
+	for key := range p.distribution.Packages {
+		if !strings.HasPrefix(key, versionPrefix) {
+			delete(p.distribution.Packages, key)
+		}
+	}
+
+	if p.distribution.Packages == nil {
+		p.distribution.Packages = make(map[string]string)
+	}
+	p.distribution.Packages[getPackageRecordKey(p.version, p.format, baseVersion)] = checksum
+
+	return p.newDistributionPackage(baseVersion, checksum)
+}
+
+func (p *packageSet) newDistributionPackage(baseVersion, checksum string) (*distributionPackage, error) {
+	return newDistributionPackage(p.s3Client, p.distribution.UserId, p.format, baseVersion, checksum)
+}
+
+func newDistributionPackage(s3Client s3.S3Client, userId, format, baseVersion,
+	checksum string) (*distributionPackage, error) {
+	preSignedURL, err := s3Client.NewSignedGetURL(os.Getenv("BOOKMARKS_SUMMARY_BUCKET"),
+		GetPackageS3Path(userId, checksum, format), SignedURLExpirationSecs)
+	if err != nil {
+		return nil, fmt.Errorf("error in creating presigned url: %w", err)
+	}
+	return &distributionPackage{BaseVersion: baseVersion, Checksum: checksum, PreSignedURL: preSignedURL}, nil
+}
+
+// GetBookmarksAndCreatePackage creates the full package of the bookmarks version and returns its checksum.
+func GetBookmarksAndCreatePackage(s3Client s3.S3Client, userId, format, distVersion string) (string, error) {
+	signer, err := NewPackageSigner()
+	if err != nil {
+		return "", err
+	}
+
+	distEntryPath := GetUserBookmarksS3Path(&model.UserBookmarks{UserId: userId, LatestVersion: distVersion})
+	_, checksum, err := createFullPackage(s3Client, signer, userId, format, distVersion, distEntryPath)
+	return checksum, err
+}
+
+func createFullPackage(s3Client s3.S3Client, signer signing.Signer, userId, format, distVersion,
+	distEntryPath string) (entries []string, checksum string, err error) {
+	bookmarksBucketName := os.Getenv("BOOKMARKS_BUCKET")
+
+	data, err := s3Client.GetObject(bookmarksBucketName, distEntryPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bookmarks := models.BookmarkList{}
+	err = json.Unmarshal(data, &bookmarks)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, p := range bookmarks.BookmarkEntry {
+		err = util.ValidateURL(p.URL)
+		if err != nil {
+			log.Debug().Msgf("Invalid entry: %v", p.URL)
+		} else {
+			entries = append(entries, p.URL)
+		}
+	}
+
+	manifest := PackageManifest{Version: distVersion, Type: PackageTypeFull, Format: format, Entries: len(entries)}
+	files := map[string]string{packageEntriesFile: strings.Join(entries, "\n")}
+
+	checksum, err = putPackage(s3Client, signer, userId, &manifest, files)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, checksum, nil
+}
+
+// putPackage writes the files along with the signed manifest as a package of the manifest format to the package
+// bucket, keyed by the checksum of the package. The package is not written again when it already exists.
+func putPackage(s3Client s3.S3Client, signer signing.Signer, userId string, manifest *PackageManifest,
+	files map[string]string) (string, error) {
+	files[packageVersionFile] = manifest.Version
+
+	manifest.KeyId = signer.KeyId()
+	manifest.Files = make(map[string]string, len(files))
+	for name, content := range files {
+		digest := sha256.Sum256([]byte(content))
+		manifest.Files[name] = hex.EncodeToString(digest[:])
+	}
+
+	manifestContent, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	files[packageManifestFile] = string(manifestContent)
+	files[packageSignatureFile] = base64.StdEncoding.EncodeToString(signer.Sign(manifestContent))
+
+	content, err := encodePackage(manifest.Format, files)
+	if err != nil {
+		return "", err
+	}
+
+	checksum := util.SHA1Checksum(content)
+	packageEntryPath := GetPackageS3Path(userId, checksum, manifest.Format)
+	ipPackageBucketName := os.Getenv("BOOKMARKS_SUMMARY_BUCKET")
+
+	exists, err := s3Client.ObjectExists(ipPackageBucketName, packageEntryPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		log.Debug().Msgf("Package %v already exists", packageEntryPath)
+		return checksum, nil
+	}
+
+	// Write out the package to the Package S3 bucket
+	log.Debug().Msgf("Package bucket, key: %v, %v", ipPackageBucketName, packageEntryPath)
+
+	err = s3Client.PutObject(ipPackageBucketName, packageEntryPath, packageContentTypes[manifest.Format], "none",
+		&content)
+	if err != nil {
+		return "", err
+	}
+
+	metrics.RecordPackageSize(manifest.Format, manifest.Type, len(content))
+	return checksum, nil
+}
+
+// encodePackage archives the files in the format, where the JSON bundle is an object of the file contents
+// by their names.
+func encodePackage(format string, files map[string]string) ([]byte, error) {
+	switch format {
+	case PackageFormatTarGz:
+		content, err := util.CreateTarFile(files)
+		if err != nil {
+			return nil, err
+		}
+		return util.ByteCompress(content)
+	case PackageFormatZip:
+		return util.CreateZipFile(files)
+	case PackageFormatJSON:
+		return json.Marshal(files)
+	default:
+		return nil, fmt.Errorf("package format %s is not supported", format)
+	}
+}
+
+func decodePackage(format string, content []byte) (map[string]string, error) {
+	switch format {
+	case PackageFormatTarGz:
+		tarContent, err := util.Decompress(content)
+		if err != nil {
+			return nil, err
+		}
+		return util.ReadTarFile([]byte(tarContent))
+	case PackageFormatZip:
+		return util.ReadZipFile(content)
+	case PackageFormatJSON:
+		files := map[string]string{}
+		err := json.Unmarshal(content, &files)
+		return files, err
+	default:
+		return nil, fmt.Errorf("package format %s is not supported", format)
+	}
+}
+
+// readPackageEntries returns the entries of a full package.
+func readPackageEntries(format string, content []byte) ([]string, error) {
+	files, err := decodePackage(format, content)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, ok := files[packageEntriesFile]
+	if !ok {
+		entries, ok = files[legacyPackageEntriesFile]
+	}
+	if !ok {
+		return nil, fmt.Errorf("package has no %s file", packageEntriesFile)
+	}
+	if entries == "" {
+		return nil, nil
+	}
+	return strings.Split(entries, "\n"), nil
+}
+
+// diffEntries returns the entries added to and removed from the base entries, in the order of their lists.
+func diffEntries(baseEntries, entries []string) (added, removed []string) {
+	baseSet := make(map[string]struct{}, len(baseEntries))
+	for _, entry := range baseEntries {
+		baseSet[entry] = struct{}{}
+	}
+
+	entrySet := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		entrySet[entry] = struct{}{}
+		if _, ok := baseSet[entry]; !ok {
+			added = append(added, entry)
+		}
+	}
+
+	for _, entry := range baseEntries {
+		if _, ok := entrySet[entry]; !ok {
+			removed = append(removed, entry)
+		}
+	}
+	return added, removed
+}
+
+// getLastAppliedDistribution returns the latest successful distribution to the device, which is nil when the
+// device never received the bookmarks.
+func getLastAppliedDistribution(dynamodbClient dynamodb.DynamoDBClient, userId,
+	deviceId string) (*model.BookmarkDistribution, error) {
+	query := DistributionHistoryQuery{DeviceId: deviceId, Status: constant.Success, Limit: DefaultPageSize}
+
+	for {
+		page, next, err := GetDistributionHistory(dynamodbClient, userId, &query)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range page {
+			if page[i].Version != "" {
+				return &page[i], nil
+			}
+		}
+
+		if next == "" {
+			return nil, nil
+		}
+		query.Next = next
+	}
+}
+
+// GetPackageS3Path returns the key of the package with the checksum in the package bucket, which is keyed by
+// the hash of the user id instead of the user id.
+func GetPackageS3Path(userId, checksum, format string) string {
+	return fmt.Sprintf("Packages/%s/%s.%s", util.MD5Hash(userId), checksum, format)
+}
+
+func getPackageRecordKey(version, format, baseVersion string) string {
+	if baseVersion == "" {
+		return fmt.Sprintf("%s/%s", version, format)
+	}
+	return fmt.Sprintf("%s/%s/%s", version, format, baseVersion)
+}
@@ -0,0 +1,348 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/signing"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/stretchr/testify/suite"
+)
+
+type PackageHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	signer             signing.Signer
+}
+
+func TestPackageHelperSuite(t *testing.T) {
+	suite.Run(t, new(PackageHelperTestSuite))
+}
+
+func (s *PackageHelperTestSuite) SetupSuite() {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	s.signer = signing.NewEd25519Signer(privateKey)
+
+	s.ctrl = gomock.NewController(s.T())
+	s.T().Setenv("BOOKMARKS_SUMMARY_BUCKET", "test_package_bucket")
+}
+
+func (s *PackageHelperTestSuite) SetupTest() {
+	NewPackageSigner = func() (signing.Signer, error) {
+		return s.signer, nil
+	}
+
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+}
+
+func (s *PackageHelperTestSuite) packageSet(entries ...string) *packageSet {
+	return &packageSet{
+		dynamodbClient: s.mockDynamoDBClient,
+		s3Client:       s.mockS3Client,
+		signer:         s.signer,
+		distribution:   &model.UserBookmarks{UserId: "1", LatestVersion: "1.0.90"},
+		version:        "1.0.90",
+		format:         PackageFormatTarGz,
+		entries:        entries,
+		entriesLoaded:  true,
+		full:           &distributionPackage{Checksum: "full-checksum", PreSignedURL: "full-url"},
+		deltas:         make(map[string]*distributionPackage),
+	}
+}
+
+func (s *PackageHelperTestSuite) expectLastAppliedVersion(version string) {
+	s.expectLastAppliedPackage(version, PackageFormatTarGz, "base-checksum")
+}
+
+func (s *PackageHelperTestSuite) expectLastAppliedPackage(version, format, checksum string) {
+	var distributions []model.BookmarkDistribution
+	if version != "" {
+		distributions = append(distributions, model.BookmarkDistribution{
+			UserId: "1", DeviceId: "42", OperationId: "20091109235234", Version: version, PackageFormat: format,
+			PackageChecksum: checksum, Status: constant.Success,
+		})
+	}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(DefaultPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return(distributions, nil, nil)
+}
+
+func (s *PackageHelperTestSuite) fullPackage(entries string) []byte {
+	content, err := util.CreateTarFile(map[string]string{packageEntriesFile: entries, packageVersionFile: "1.0.89"})
+	s.NoError(err)
+	compressedContent, err := util.ByteCompress(content)
+	s.NoError(err)
+	return compressedContent
+}
+
+func (s *PackageHelperTestSuite) TestDiffEntries() {
+	added, removed := diffEntries([]string{"https://a.com", "https://b.com", "https://c.com"},
+		[]string{"https://c.com", "https://d.com", "https://a.com"})
+
+	s.Equal([]string{"https://d.com"}, added)
+	s.Equal([]string{"https://b.com"}, removed)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceCreatesDeltaPackage() {
+	packages := s.packageSet("https://a.com", "https://b.com", "https://c.com", "https://d.com")
+
+	s.expectLastAppliedVersion("1.0.89")
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/base-checksum.tar.gz")).
+		Return(s.fullPackage("https://a.com\nhttps://b.com\nhttps://c.com\nhttps://e.com"), nil)
+
+	var deltaPath string
+	var deltaContent []byte
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"), gomock.Any()).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any()).
+		DoAndReturn(func(_, key, _, _ string, content *[]byte) error {
+			deltaPath, deltaContent = key, *content
+			return nil
+		})
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"), gomock.Any(), gomock.Any()).
+		Return("delta-url", nil)
+
+	deltaPackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Equal("1.0.89", deltaPackage.BaseVersion)
+	s.Equal("delta-url", deltaPackage.PreSignedURL)
+	s.Equal(util.SHA1Checksum(deltaContent), deltaPackage.Checksum)
+	s.Equal(GetPackageS3Path("1", deltaPackage.Checksum, PackageFormatTarGz), deltaPath)
+	s.Equal(deltaPackage.Checksum, packages.distribution.Packages["1.0.90/tar.gz/1.0.89"])
+
+	tarContent, err := util.Decompress(deltaContent)
+	s.NoError(err)
+	files, err := util.ReadTarFile([]byte(tarContent))
+	s.NoError(err)
+	s.Equal("https://d.com", files[packageAddedFile])
+	s.Equal("https://e.com", files[packageRemovedFile])
+
+	var manifest PackageManifest
+	s.NoError(json.Unmarshal([]byte(files[packageManifestFile]), &manifest))
+	s.Equal(PackageTypeDelta, manifest.Type)
+	s.Equal("1.0.89", manifest.BaseVersion)
+	s.Equal(1, manifest.Added)
+	s.Equal(1, manifest.Removed)
+	s.Equal(s.signer.KeyId(), manifest.KeyId)
+	s.Len(manifest.Files, 3)
+	for name, digest := range manifest.Files {
+		fileDigest := sha256.Sum256([]byte(files[name]))
+		s.Equal(hex.EncodeToString(fileDigest[:]), digest, name)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(files[packageSignatureFile])
+	s.NoError(err)
+	s.True(signing.Verify(s.signer.PublicKey(), []byte(files[packageManifestFile]), signature))
+
+	// The delta is shared by the devices with the same base version.
+	s.expectLastAppliedVersion("1.0.89")
+
+	sharedPackage, err := packages.forDevice("43")
+
+	s.NoError(err)
+	s.Same(deltaPackage, sharedPackage)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceWhenBasePackageMissing() {
+	packages := s.packageSet("https://a.com", "https://b.com")
+
+	s.expectLastAppliedVersion("1.0.85")
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/base-checksum.tar.gz")).
+		Return(nil, errors.New("NoSuchKey"))
+
+	devicePackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Same(packages.full, devicePackage)
+	s.Empty(devicePackage.BaseVersion)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceWhenDeltaIsLarger() {
+	packages := s.packageSet("https://a.com", "https://b.com")
+
+	s.expectLastAppliedVersion("1.0.89")
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/base-checksum.tar.gz")).
+		Return(s.fullPackage("https://c.com"), nil)
+
+	devicePackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Same(packages.full, devicePackage)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceWithoutDistributions() {
+	packages := s.packageSet("https://a.com")
+
+	s.expectLastAppliedVersion("")
+
+	devicePackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Same(packages.full, devicePackage)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceWhenBaseHasNoChecksum() {
+	packages := s.packageSet("https://a.com")
+
+	// The distributions before the packages were keyed by checksum have no base package.
+	s.expectLastAppliedPackage("1.0.89", PackageFormatTarGz, "")
+
+	devicePackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Same(packages.full, devicePackage)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceReusesRecordedDeltaPackage() {
+	packages := s.packageSet()
+	packages.entriesLoaded = false
+	packages.distribution.Packages = map[string]string{"1.0.90/tar.gz/1.0.89": "delta-checksum"}
+
+	s.expectLastAppliedVersion("1.0.89")
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/delta-checksum.tar.gz")).Return(true, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/delta-checksum.tar.gz"), gomock.Any()).
+		Return("delta-url", nil)
+
+	deltaPackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Equal(&distributionPackage{BaseVersion: "1.0.89", Checksum: "delta-checksum", PreSignedURL: "delta-url"},
+		deltaPackage)
+}
+
+func (s *PackageHelperTestSuite) TestNewPackageSetReusesRecordedPackage() {
+	distribution := &model.UserBookmarks{
+		UserId:   "1",
+		Packages: map[string]string{"1.0.90/tar.gz": "full-checksum", "1.0.89/tar.gz": "old-checksum"},
+	}
+
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/full-checksum.tar.gz")).Return(true, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/full-checksum.tar.gz"), gomock.Any()).
+		Return("full-url", nil)
+
+	packages, err := newPackageSet(s.mockDynamoDBClient, s.mockS3Client, distribution, "1.0.90", PackageFormatTarGz)
+
+	s.NoError(err)
+	s.Equal(&distributionPackage{Checksum: "full-checksum", PreSignedURL: "full-url"}, packages.full)
+	s.Len(distribution.Packages, 2)
+}
+
+func (s *PackageHelperTestSuite) TestNewPackageSetWhenPackageExists() {
+	distribution := &model.UserBookmarks{
+		UserId:   "1",
+		Packages: map[string]string{"1.0.90/tar.gz": "missing-checksum", "1.0.89/tar.gz": "old-checksum"},
+	}
+	s.T().Setenv("BOOKMARKS_BUCKET", "test_bookmarks_bucket")
+
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/missing-checksum.tar.gz")).Return(false, nil)
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_bookmarks_bucket"), gomock.Eq("Bookmarks/1/1.0.90")).
+		Return([]byte(`{"bookmarks": [{ "url": "https://a.com" }]}`), nil)
+
+	// The identical package of another version is not written again.
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"),
+		mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/")).Return(true, nil)
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Eq("test_package_bucket"),
+		mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/"), gomock.Any()).Return("full-url", nil)
+
+	packages, err := newPackageSet(s.mockDynamoDBClient, s.mockS3Client, distribution, "1.0.90", PackageFormatTarGz)
+
+	s.NoError(err)
+	s.Equal([]string{"https://a.com"}, packages.entries)
+	s.Equal(map[string]string{"1.0.90/tar.gz": packages.full.Checksum}, distribution.Packages)
+}
+
+func (s *PackageHelperTestSuite) TestEncodeAndDecodePackage() {
+	files := map[string]string{packageVersionFile: "1.0.89", packageEntriesFile: "https://a.com\nhttps://b.com"}
+
+	for _, format := range SupportedPackageFormats {
+		s.Run(format, func() {
+			content, err := encodePackage(format, files)
+			s.NoError(err)
+
+			decoded, err := decodePackage(format, content)
+			s.NoError(err)
+			s.Equal(files, decoded)
+			s.NotEmpty(packageContentTypes[format])
+		})
+	}
+
+	_, err := encodePackage("rar", files)
+	s.Error(err)
+}
+
+func (s *PackageHelperTestSuite) TestReadPackageEntriesOfLegacyPackage() {
+	content, err := encodePackage(PackageFormatTarGz, map[string]string{
+		packageVersionFile:       "1.0.89",
+		legacyPackageEntriesFile: "https://a.com\nhttps://b.com",
+	})
+	s.NoError(err)
+
+	entries, err := readPackageEntries(PackageFormatTarGz, content)
+
+	s.NoError(err)
+	s.Equal([]string{"https://a.com", "https://b.com"}, entries)
+}
+
+func (s *PackageHelperTestSuite) TestForDeviceCreatesDeltaPackageInFormat() {
+	packages := s.packageSet("https://a.com", "https://b.com", "https://c.com")
+	packages.format = PackageFormatJSON
+
+	baseContent, err := encodePackage(PackageFormatZip, map[string]string{
+		packageVersionFile: "1.0.89",
+		packageEntriesFile: "https://a.com\nhttps://b.com",
+	})
+	s.NoError(err)
+
+	s.expectLastAppliedPackage("1.0.89", PackageFormatZip, "base-checksum")
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("test_package_bucket"),
+		gomock.Eq("Packages/c4ca4238a0b923820dcc509a6f75849b/base-checksum.zip")).Return(baseContent, nil)
+
+	var deltaContent []byte
+	s.mockS3Client.EXPECT().ObjectExists(gomock.Eq("test_package_bucket"), gomock.Any()).Return(false, nil)
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("test_package_bucket"),
+		mockutil.HasPrefix("Packages/c4ca4238a0b923820dcc509a6f75849b/"), gomock.Eq("application/json"),
+		gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_, _, _, _ string, content *[]byte) error {
+			deltaContent = *content
+			return nil
+		})
+	s.mockS3Client.EXPECT().NewSignedGetURL(gomock.Any(), gomock.Any(), gomock.Any()).Return("delta-url", nil)
+
+	deltaPackage, err := packages.forDevice("42")
+
+	s.NoError(err)
+	s.Equal("1.0.89", deltaPackage.BaseVersion)
+
+	files := map[string]string{}
+	s.NoError(json.Unmarshal(deltaContent, &files))
+	s.Equal("https://c.com", files[packageAddedFile])
+	s.Equal("1.0.90", files[packageVersionFile])
+	s.Contains(files[packageManifestFile], `"format":"json"`)
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxResolveBookmarks is the number of bookmarks which can be resolved within a request.
+	MaxResolveBookmarks   = 50
+	maxResolveConcurrency = 10
+)
+
+var (
+	// ResolveTimeout bounds the time spent resolving the bookmarks of a request, well within the API timeout.
+	ResolveTimeout = 15 * time.Second
+)
+
+// ResolveURL follows the redirects of the url, up to the redirect limit of the crawler, and returns the final url.
+func ResolveURL(ctx context.Context, bookmarkURL string) (string, error) {
+	response, err := PageCrawler.FetchHeaders(ctx, http.MethodHead, bookmarkURL, nil)
+	if err == nil && isHeadUnsupported(response.StatusCode) {
+		response, err = PageCrawler.FetchHeaders(ctx, http.MethodGet, bookmarkURL, nil)
+	}
+
+	if err != nil {
+		return "", err
+	}
+	return response.URL, nil
+}
+
+// ResolveBookmarkURLs sets the resolved url of every bookmark which redirects to a different url, within the
+// ResolveTimeout. Bookmarks which cannot be resolved in time are kept as they are.
+func ResolveBookmarkURLs(ctx context.Context, bookmarks []models.BookmarkEntry) {
+	ctx, cancel := context.WithTimeout(ctx, ResolveTimeout)
+	defer cancel()
+
+	swg := sizedwaitgroup.New(maxResolveConcurrency)
+
+	for i := range bookmarks {
+		entry := &bookmarks[i]
+		swg.Add()
+
+		go func() {
+			defer swg.Done()
+
+			resolvedURL, err := ResolveURL(ctx, entry.URL)
+			if err != nil {
+				log.Warn().Msgf("Resolution of %s skipped: %v", entry.URL, err)
+				return
+			}
+
+			if resolvedURL != entry.URL {
+				entry.ResolvedURL = resolvedURL
+			}
+		}()
+	}
+
+	swg.Wait()
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	"github.com/stretchr/testify/suite"
+)
+
+type ResolveHelperTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func TestResolveHelperSuite(t *testing.T) {
+	suite.Run(t, new(ResolveHelperTestSuite))
+}
+
+func (s *ResolveHelperTestSuite) SetupSuite() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/hop", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/hop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	s.server = httptest.NewServer(mux)
+
+	config := crawler.DefaultConfig()
+	config.MinHostInterval = 0
+	config.AllowPrivateNetworks = true
+	PageCrawler = crawler.New(config)
+}
+
+func (s *ResolveHelperTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *ResolveHelperTestSuite) TestResolveBookmarkURLs() {
+	bookmarks := []models.BookmarkEntry{
+		{URL: s.server.URL + "/short"},
+		{URL: s.server.URL + "/get-only"},
+		{URL: s.server.URL + "/target"},
+		{URL: s.server.URL + "/loop"},
+	}
+
+	ResolveBookmarkURLs(context.Background(), bookmarks)
+
+	s.Equal([]models.BookmarkEntry{
+		{URL: s.server.URL + "/short", ResolvedURL: s.server.URL + "/target"},
+		{URL: s.server.URL + "/get-only", ResolvedURL: s.server.URL + "/target"},
+		{URL: s.server.URL + "/target"},
+		{URL: s.server.URL + "/loop"},
+	}, bookmarks)
+}
+
+func (s *ResolveHelperTestSuite) TestResolveURLWithTooManyRedirects() {
+	_, err := ResolveURL(context.Background(), s.server.URL+"/loop")
+	s.ErrorIs(err, crawler.ErrTooManyRedirects)
+}
@@ -0,0 +1,282 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	SnapshotContentType = "text/html"
+	// SnapshotMessageType is the message type of the snapshot requests, which share the enrichment queue.
+	SnapshotMessageType       = "snapshot"
+	snapshotRootPath          = "Snapshots"
+	maxSnapshotConcurrency    = 10
+	snapshotFetchContentTypes = "text/html,application/xhtml+xml"
+	// maxSnapshotURLsPerMessage keeps each snapshot request within the enricher timeout, even when all its urls
+	// are fetched one after another from the same host.
+	maxSnapshotURLsPerMessage = 20
+)
+
+var (
+	// PageCrawler fetches the bookmarked pages, it is shared so that the robots.txt cache and host limits apply
+	// across all the fetches of the lambda instance.
+	PageCrawler = crawler.New(crawler.DefaultConfig())
+)
+
+func GetUserSnapshotsS3Path(userId string) string {
+	return fmt.Sprintf("%s/%s/", snapshotRootPath, userId)
+}
+
+func GetSnapshotS3Prefix(userId, bookmarkURL string) string {
+	return fmt.Sprint(GetUserSnapshotsS3Path(userId), util.MD5Hash(bookmarkURL), "/")
+}
+
+func GetSnapshotS3Path(userId, bookmarkURL, distVersion string) string {
+	return fmt.Sprint(GetSnapshotS3Prefix(userId, bookmarkURL), distVersion)
+}
+
+// QueueSnapshots sends the bookmark urls to the enrichment queue, to be snapshotted in the background under
+// the bookmarks version. The urls are split into messages of up to maxSnapshotURLsPerMessage urls.
+func QueueSnapshots(sqsClient sqs.SQSClient, queueURL, userId, distVersion string,
+	bookmarks []models.BookmarkEntry) error {
+	urls := make([]string, 0, len(bookmarks))
+	for _, entry := range bookmarks {
+		urls = append(urls, entry.URL)
+	}
+
+	for _, chunk := range util.ChunkSlice(urls, maxSnapshotURLsPerMessage) {
+		message, err := json.Marshal(&models.SnapshotRequest{UserId: userId, Version: distVersion, URLs: chunk})
+		if err != nil {
+			return err
+		}
+
+		_, err = sqsClient.SendMessageWithAttributes(queueURL, string(message),
+			map[string]string{sqs.DefaultTypeAttribute: SnapshotMessageType})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSnapshots stores the sanitized HTML page of each requested url under the bookmarks version.
+// The pages which could not be fetched are logged and skipped, while the failures to store a snapshot
+// are returned so that the request is retried. The urls which were removed from the bookmarks since the
+// request was queued are skipped, as their snapshots would never be deleted.
+func CreateSnapshots(dynamodbClient dynamodb.DynamoDBClient, s3Client s3.S3Client, request *models.SnapshotRequest) error {
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+	swg := sizedwaitgroup.New(maxSnapshotConcurrency)
+	bookmarked := &bookmarkedURLs{dynamodbClient: dynamodbClient, s3Client: s3Client, userId: request.UserId,
+		version: request.Version}
+
+	var mu sync.Mutex
+	var errs error
+
+	addError := func(err error) {
+		mu.Lock()
+		errs = errors.Join(errs, err)
+		mu.Unlock()
+	}
+
+	for _, bookmarkURL := range request.URLs {
+		bookmarkURL := bookmarkURL
+		swg.Add()
+
+		go func() {
+			defer swg.Done()
+
+			content, err := fetchSnapshot(bookmarkURL)
+			if err != nil {
+				log.Warn().Msgf("Snapshot of %s skipped for userId %s: %v", bookmarkURL, request.UserId, err)
+				return
+			}
+
+			found, err := bookmarked.contains(bookmarkURL)
+			if err != nil {
+				addError(fmt.Errorf("failed to read bookmarks for snapshot of %s: %w", bookmarkURL, err))
+				return
+			} else if !found {
+				log.Info().Msgf("Snapshot of %s skipped for userId %s: bookmark removed", bookmarkURL, request.UserId)
+				return
+			}
+
+			err = s3Client.PutObject(bucketName, GetSnapshotS3Path(request.UserId, bookmarkURL, request.Version),
+				SnapshotContentType, s3.GZip, &content)
+			if err != nil {
+				addError(fmt.Errorf("failed to store snapshot of %s: %w", bookmarkURL, err))
+			}
+		}()
+	}
+
+	swg.Wait()
+	return errs
+}
+
+// bookmarkedURLs tells whether the urls of a snapshot request are still bookmarked by the user. The bookmarks
+// still at the version of the request hold all its urls, otherwise the latest bookmark list is read once per
+// version.
+type bookmarkedURLs struct {
+	dynamodbClient dynamodb.DynamoDBClient
+	s3Client       s3.S3Client
+	userId         string
+	version        string
+
+	mutex       sync.Mutex
+	listVersion string
+	urls        map[string]bool
+}
+
+func (b *bookmarkedURLs) contains(bookmarkURL string) (bool, error) {
+	result, err := b.dynamodbClient.GetRecordByKey(&model.UserBookmarks{UserId: b.userId})
+	if err != nil {
+		return false, err
+	}
+
+	userBookmarks, _ := result.(*model.UserBookmarks)
+	if GetUserBookmarksS3Path(userBookmarks) == "" {
+		return false, nil
+	} else if userBookmarks.LatestVersion == b.version {
+		return true, nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.urls == nil || b.listVersion != userBookmarks.LatestVersion {
+		data, err := GetUserBookmarksS3Object(b.s3Client, userBookmarks, b.userId)
+		if err != nil {
+			return false, err
+		}
+
+		bookmarkList := models.BookmarkList{}
+		if err = json.Unmarshal(data, &bookmarkList); err != nil {
+			return false, err
+		}
+
+		b.urls = map[string]bool{}
+		for _, entry := range bookmarkList.BookmarkEntry {
+			b.urls[entry.URL] = true
+		}
+		b.listVersion = userBookmarks.LatestVersion
+	}
+	return b.urls[bookmarkURL], nil
+}
+
+// GetLatestSnapshot returns the most recently stored snapshot of the bookmark.
+func GetLatestSnapshot(s3Client s3.S3Client, userId, bookmarkURL string) ([]byte, error) {
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+
+	objects, err := s3Client.ListObjects(bucketName, GetSnapshotS3Prefix(userId, bookmarkURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var latestKey string
+	var latestTime time.Time
+
+	for _, object := range objects {
+		if object.Key != nil && object.LastModified != nil && !object.LastModified.Before(latestTime) {
+			latestKey = *object.Key
+			latestTime = *object.LastModified
+		}
+	}
+
+	if latestKey == "" {
+		return nil, fmt.Errorf("no snapshot exists for %s", bookmarkURL)
+	}
+
+	return s3Client.GetObject(bucketName, latestKey)
+}
+
+// DeleteSnapshots removes all snapshot versions of the bookmark urls.
+func DeleteSnapshots(s3Client s3.S3Client, userId string, bookmarkURLs []string) error {
+	remove := map[string]bool{}
+
+	for _, bookmarkURL := range bookmarkURLs {
+		remove[GetSnapshotS3Prefix(userId, bookmarkURL)] = true
+	}
+
+	return deleteSnapshotsByPrefix(s3Client, userId, func(prefix string) bool {
+		return remove[prefix]
+	})
+}
+
+// PruneSnapshots removes the snapshots of all the bookmarks which are no longer in the bookmark list.
+// Passing an empty bookmark list removes all the snapshots of the user.
+func PruneSnapshots(s3Client s3.S3Client, userId string, bookmarks []models.BookmarkEntry) error {
+	keep := map[string]bool{}
+
+	for _, entry := range bookmarks {
+		keep[GetSnapshotS3Prefix(userId, entry.URL)] = true
+	}
+
+	return deleteSnapshotsByPrefix(s3Client, userId, func(prefix string) bool {
+		return !keep[prefix]
+	})
+}
+
+func deleteSnapshotsByPrefix(s3Client s3.S3Client, userId string, shouldDelete func(prefix string) bool) error {
+	bucketName := os.Getenv("BOOKMARKS_BUCKET")
+
+	objects, err := s3Client.ListObjects(bucketName, GetUserSnapshotsS3Path(userId))
+	if err != nil {
+		return err
+	}
+
+	var objectKeys []string
+
+	for _, object := range objects {
+		if object.Key == nil {
+			continue
+		}
+
+		key := *object.Key
+		if index := strings.LastIndex(key, "/"); index >= 0 && shouldDelete(key[:index+1]) {
+			objectKeys = append(objectKeys, key)
+		}
+	}
+
+	if len(objectKeys) == 0 {
+		return nil
+	}
+
+	return s3Client.DeleteObjects(bucketName, objectKeys)
+}
+
+func fetchSnapshot(bookmarkURL string) ([]byte, error) {
+	header := http.Header{}
+	header.Set("Accept", snapshotFetchContentTypes)
+
+	response, err := PageCrawler.Fetch(context.Background(), http.MethodGet, bookmarkURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("unsupported content type %s", contentType)
+	}
+
+	return util.SanitizeHTML(response.Body)
+}
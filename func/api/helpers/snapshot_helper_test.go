@@ -0,0 +1,157 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	awsSQS "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	s3Mocks "github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/stretchr/testify/suite"
+)
+
+type SnapshotHelperTestSuite struct {
+	suite.Suite
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockS3Client       *s3Mocks.MockS3Client
+	mockSQSClient      *sqsMocks.MockSQSClient
+	server             *httptest.Server
+}
+
+func TestSnapshotHelperSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotHelperTestSuite))
+}
+
+func (s *SnapshotHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+	s.T().Setenv("BOOKMARKS_BUCKET", "TEST_S3_BUCKET")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><body onload="track()"><script>track()</script><p>Saved</p></body></html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("PNG"))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	s.server = httptest.NewServer(mux)
+
+	config := crawler.DefaultConfig()
+	config.MinHostInterval = 0
+	config.AllowPrivateNetworks = true
+	PageCrawler = crawler.New(config)
+}
+
+func (s *SnapshotHelperTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *SnapshotHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockS3Client = s3Mocks.NewMockS3Client(s.ctrl)
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+}
+
+func (s *SnapshotHelperTestSuite) TestQueueSnapshotsSendsSnapshotMessage() {
+	s.mockSQSClient.EXPECT().SendMessageWithAttributes(gomock.Eq("test_queue"),
+		gomock.Eq(`{"userId":"1","version":"1.0.7","urls":["https://karpenter.sh/"]}`),
+		gomock.Eq(map[string]string{sqs.DefaultTypeAttribute: SnapshotMessageType})).Return(nil, nil)
+
+	err := QueueSnapshots(s.mockSQSClient, "test_queue", "1", "1.0.7",
+		[]models.BookmarkEntry{{URL: "https://karpenter.sh/"}})
+	s.NoError(err)
+}
+
+func (s *SnapshotHelperTestSuite) TestQueueSnapshotsSplitsURLs() {
+	bookmarks := make([]models.BookmarkEntry, 0, maxSnapshotURLsPerMessage+1)
+	for i := 0; i <= maxSnapshotURLsPerMessage; i++ {
+		bookmarks = append(bookmarks, models.BookmarkEntry{URL: fmt.Sprintf("https://karpenter.sh/%d", i)})
+	}
+
+	var counts []int
+	s.mockSQSClient.EXPECT().SendMessageWithAttributes(gomock.Eq("test_queue"), gomock.Any(),
+		gomock.Eq(map[string]string{sqs.DefaultTypeAttribute: SnapshotMessageType})).
+		DoAndReturn(func(_, message string, _ map[string]string) (*awsSQS.SendMessageOutput, error) {
+			request := models.SnapshotRequest{}
+			s.NoError(json.Unmarshal([]byte(message), &request))
+			s.Equal("1.0.7", request.Version)
+			counts = append(counts, len(request.URLs))
+			return nil, nil
+		}).Times(2)
+
+	err := QueueSnapshots(s.mockSQSClient, "test_queue", "1", "1.0.7", bookmarks)
+	s.NoError(err)
+	s.Equal([]int{maxSnapshotURLsPerMessage, 1}, counts)
+}
+
+func (s *SnapshotHelperTestSuite) TestCreateSnapshotsStoresOnlySanitizedHTMLPages() {
+	pageURL := s.server.URL + "/page"
+	sanitizedPage := []byte(`<html><body><p>Saved</p></body></html>`)
+
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("TEST_S3_BUCKET"),
+		gomock.Eq("Snapshots/1/"+util.MD5Hash(pageURL)+"/1.0.7"), gomock.Eq(SnapshotContentType),
+		gomock.Eq(s3.GZip), gomock.Eq(&sanitizedPage)).Return(nil).Times(1)
+	s.expectBookmarksVersion("1.0.7")
+
+	err := CreateSnapshots(s.mockDynamoDBClient, s.mockS3Client, &models.SnapshotRequest{UserId: "1", Version: "1.0.7",
+		URLs: []string{pageURL, s.server.URL + "/image", s.server.URL + "/missing"}})
+	s.NoError(err)
+}
+
+func (s *SnapshotHelperTestSuite) TestCreateSnapshotsReturnsStoreFailures() {
+	s.mockS3Client.EXPECT().PutObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any()).Return(errors.New("throttled")).Times(1)
+	s.expectBookmarksVersion("1.0.7")
+
+	err := CreateSnapshots(s.mockDynamoDBClient, s.mockS3Client, &models.SnapshotRequest{UserId: "1", Version: "1.0.7",
+		URLs: []string{s.server.URL + "/page"}})
+	s.ErrorContains(err, "throttled")
+}
+
+func (s *SnapshotHelperTestSuite) TestCreateSnapshotsSkipsRemovedBookmarks() {
+	pageURL := s.server.URL + "/page"
+	s.expectBookmarksVersion("1.0.8")
+
+	// The bookmark was removed by the version stored after the snapshot was requested.
+	s.mockS3Client.EXPECT().GetObject(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Bookmarks/1/1.0.8")).
+		Return([]byte(`{"bookmarks":[{"url":"https://karpenter.sh/"}]}`), nil)
+
+	err := CreateSnapshots(s.mockDynamoDBClient, s.mockS3Client, &models.SnapshotRequest{UserId: "1",
+		Version: "1.0.7", URLs: []string{pageURL}})
+	s.NoError(err)
+}
+
+func (s *SnapshotHelperTestSuite) TestCreateSnapshotsSkipsDeletedBookmarks() {
+	s.expectBookmarksVersion("1.0.7" + DeletedVersionSuffix)
+
+	err := CreateSnapshots(s.mockDynamoDBClient, s.mockS3Client, &models.SnapshotRequest{UserId: "1",
+		Version: "1.0.7", URLs: []string{s.server.URL + "/page"}})
+	s.NoError(err)
+}
+
+func (s *SnapshotHelperTestSuite) expectBookmarksVersion(version string) {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.UserBookmarks{UserId: "1"})).
+		Return(&model.UserBookmarks{UserId: "1", LatestVersion: version}, nil).AnyTimes()
+}
+
+func (s *SnapshotHelperTestSuite) TestPruneSnapshotsWhenNoSnapshotsExist() {
+	s.mockS3Client.EXPECT().ListObjects(gomock.Eq("TEST_S3_BUCKET"), gomock.Eq("Snapshots/1/")).Return(nil, nil)
+
+	err := PruneSnapshots(s.mockS3Client, "1", []models.BookmarkEntry{{URL: "https://karpenter.sh/"}})
+	s.NoError(err)
+}
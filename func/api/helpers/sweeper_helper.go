@@ -0,0 +1,142 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/rs/zerolog/log"
+)
+
+// IsStaleDistribution reports whether the distribution of the user is pending or holds the lock on the bookmarks
+// beyond the distribution timeout of the user.
+func IsStaleDistribution(userBookmarks *model.UserBookmarks) bool {
+	return IsDistributionPending(userBookmarks) &&
+		TimeNow().Sub(userBookmarks.StartTimestamp) > GetDistributionTimeout(userBookmarks)
+}
+
+// SweepStaleDistribution moves the stale distribution of the user to timeout when it is pending, or to failed when
+// it still holds the lock on the bookmarks, as the distribution stopped before any of the devices were updated.
+// The pending device distributions of the operation are timed out as well. The distribution is left unchanged when
+// it was updated after it was read, and false is returned.
+func SweepStaleDistribution(dynamodbClient dynamodb.DynamoDBClient, userBookmarks *model.UserBookmarks) (bool, error) {
+	if !IsStaleDistribution(userBookmarks) {
+		return false, nil
+	}
+
+	status := constant.Timeout
+	if userBookmarks.Status == constant.BookmarksLocked {
+		status = constant.Failed
+	}
+
+	timeout := GetDistributionTimeout(userBookmarks)
+	if userBookmarks.OperationId != 0 {
+		err := sweepStaleDeviceDistributions(dynamodbClient, userBookmarks.UserId, userBookmarks.OperationId, timeout)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	currentTime := TimeNow()
+	swept, err := updateIfUnchanged(dynamodbClient, userBookmarks.UserId, userBookmarks, userBookmarks.Status,
+		userBookmarks.StartTimestamp, map[string]interface{}{"status": status, "endTs": currentTime})
+	if err != nil || !swept {
+		return false, err
+	}
+
+	log.Info().Msgf("Set distribution status for userId %s from %s to %s after %v", userBookmarks.UserId,
+		userBookmarks.Status, status, currentTime.Sub(userBookmarks.StartTimestamp).Round(time.Second))
+
+	userBookmarks.Status = status
+	userBookmarks.EndTimestamp = currentTime
+	return true, nil
+}
+
+func sweepStaleDeviceDistributions(dynamodbClient dynamodb.DynamoDBClient, userId string, operationId int64,
+	timeout time.Duration) error {
+	appDistributions, err := getOperationDistributions(dynamodbClient, userId, operationId)
+	if err != nil {
+		return err
+	}
+
+	currentTime := TimeNow()
+	for i := range appDistributions {
+		appDistribution := &appDistributions[i]
+		if appDistribution.Status != constant.Pending || currentTime.Sub(appDistribution.StartTimestamp) <= timeout {
+			continue
+		}
+
+		timedOut := *appDistribution
+		timedOut.Status = constant.Timeout
+		timedOut.StatusMessage = fmt.Sprintf("Setting to timeout after %v mins", timeout.Minutes())
+		timedOut.EndTimestamp = currentTime
+
+		_, err = updateIfUnchanged(dynamodbClient, userId, appDistribution, constant.Pending, appDistribution.StartTimestamp,
+			map[string]interface{}{
+				"status":        timedOut.Status,
+				"statusMessage": timedOut.StatusMessage,
+				"endTs":         currentTime,
+			}, NewDeviceDistributionCompleted(&timedOut))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateIfUnchanged updates the record only when it still has the status and start time it was read with,
+// so that the concurrent updates of a distribution are never overwritten. The events are recorded only
+// along with the update.
+func updateIfUnchanged(dynamodbClient dynamodb.DynamoDBClient, userId string, entity model.Entity, status string,
+	startTimestamp time.Time, updates map[string]interface{}, events ...models.DomainEvent) (bool, error) {
+	return updateWithCondition(dynamodbClient, userId, entity, unchangedCondition(status, startTimestamp), updates,
+		events...)
+}
+
+// updateWithCondition updates the record along with the events only when the condition holds, and returns false
+// when it does not.
+func updateWithCondition(dynamodbClient dynamodb.DynamoDBClient, userId string, entity model.Entity,
+	condition expression.ConditionBuilder, updates map[string]interface{}, events ...models.DomainEvent) (bool, error) {
+	update := dynamodb.GenUpdateBuilder(updates)
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return false, err
+	}
+
+	err = WriteWithEvents(dynamodbClient, userId, events, dynamodb.TransactUpdateByExpression(entity, expr))
+	if dynamodb.IsConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unchangedCondition matches the record still in the status and start time it was read with. The records which
+// never had a start time are matched by the missing attribute.
+func unchangedCondition(status string, startTimestamp time.Time) expression.ConditionBuilder {
+	startName := expression.Name("startTs")
+
+	startCondition := startName.Equal(expression.Value(startTimestamp))
+	if startTimestamp.IsZero() {
+		startCondition = expression.AttributeNotExists(startName).Or(startCondition)
+	}
+	return statusUnchangedCondition(status).And(startCondition)
+}
+
+// statusUnchangedCondition matches the record still in the status it was read with. The records which never had
+// a status are matched by the missing attribute, and the timed out status, which is only set on read, matches the
+// stored pending status.
+func statusUnchangedCondition(status string) expression.ConditionBuilder {
+	statusName := expression.Name("status")
+
+	switch status {
+	case "":
+		return expression.AttributeNotExists(statusName)
+	case constant.Timeout:
+		return statusName.In(expression.Value(constant.Timeout), expression.Value(constant.Pending))
+	}
+	return statusName.Equal(expression.Value(status))
+}
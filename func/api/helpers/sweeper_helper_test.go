@@ -0,0 +1,155 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type SweeperHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockTimeNow        time.Time
+}
+
+func TestSweeperHelperSuite(t *testing.T) {
+	suite.Run(t, new(SweeperHelperTestSuite))
+}
+
+func (s *SweeperHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *SweeperHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 9, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *SweeperHelperTestSuite) TestIsStaleDistribution() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.Pending,
+		StartTimestamp: s.mockTimeNow.Add(-15 * time.Minute)}
+	s.True(IsStaleDistribution(userBookmarks))
+
+	// The timeout of the user overrides the default timeout.
+	userBookmarks.DistributionTimeout = 20
+	s.False(IsStaleDistribution(userBookmarks))
+
+	userBookmarks.Status = constant.BookmarksLocked
+	userBookmarks.StartTimestamp = s.mockTimeNow.Add(-time.Hour)
+	s.True(IsStaleDistribution(userBookmarks))
+
+	userBookmarks.Status = constant.Success
+	s.False(IsStaleDistribution(userBookmarks))
+	s.False(IsStaleDistribution(nil))
+}
+
+func (s *SweeperHelperTestSuite) TestSweepStaleDistributionReleasesLock() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked,
+		StartTimestamp: s.mockTimeNow.Add(-time.Hour)}
+
+	var expr expression.Expression
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(userBookmarks), gomock.Any()).
+		DoAndReturn(func(_ model.Entity, updateExpr expression.Expression) error {
+			expr = updateExpr
+			return nil
+		})
+
+	swept, err := SweepStaleDistribution(s.mockDynamoDBClient, userBookmarks)
+
+	s.NoError(err)
+	s.True(swept)
+	s.Equal(constant.Failed, userBookmarks.Status)
+	s.Equal(s.mockTimeNow, userBookmarks.EndTimestamp)
+
+	// The lock is released only when it is still held by the same distribution.
+	var names []string
+	for _, name := range expr.Names() {
+		names = append(names, name)
+	}
+	s.ElementsMatch([]string{"status", "startTs", "endTs"}, names)
+	s.NotNil(expr.Condition())
+}
+
+func (s *SweeperHelperTestSuite) TestSweepStaleDistributionTimesOutDevices() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", OperationId: 20091110232234, Status: constant.Pending,
+		StartTimestamp: s.mockTimeNow.Add(-30 * time.Minute), DistributionTimeout: 20}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.BookmarkDistribution{}),
+		gomock.Any(), gomock.Any(), gomock.Eq(int32(MaxPageSize)), gomock.Nil(), gomock.Eq(false)).
+		Return([]model.BookmarkDistribution{
+			{UserId: "1", DeviceId: "42", OperationId: "20091110232234", Status: constant.Pending,
+				StartTimestamp: s.mockTimeNow.Add(-30 * time.Minute)},
+			{UserId: "1", DeviceId: "43", OperationId: "20091110232234", Status: constant.Success,
+				StartTimestamp: s.mockTimeNow.Add(-30 * time.Minute)},
+			{UserId: "1", DeviceId: "44", OperationId: "20091110232234", Status: constant.Pending,
+				StartTimestamp: s.mockTimeNow.Add(-5 * time.Minute)},
+		}, nil, nil)
+
+	var sweptDevices []string
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(mockutil.TransactionOf(&model.BookmarkDistribution{},
+		"DeviceDistributionCompleted")).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			sweptDevices = append(sweptDevices, items[0].Entity.(*model.BookmarkDistribution).DeviceId)
+			return nil
+		})
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(userBookmarks), gomock.Any()).Return(nil)
+
+	swept, err := SweepStaleDistribution(s.mockDynamoDBClient, userBookmarks)
+
+	s.NoError(err)
+	s.True(swept)
+	s.Equal([]string{"42"}, sweptDevices)
+	s.Equal(constant.Timeout, userBookmarks.Status)
+}
+
+func (s *SweeperHelperTestSuite) TestSweepStaleDistributionWhenUpdatedConcurrently() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked,
+		StartTimestamp: s.mockTimeNow.Add(-time.Hour)}
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(userBookmarks), gomock.Any()).
+		Return(&types.ConditionalCheckFailedException{})
+
+	swept, err := SweepStaleDistribution(s.mockDynamoDBClient, userBookmarks)
+
+	s.NoError(err)
+	s.False(swept)
+	s.Equal(constant.BookmarksLocked, userBookmarks.Status)
+}
+
+func (s *SweeperHelperTestSuite) TestSweepStaleDistributionWhenUpdateFails() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked,
+		StartTimestamp: s.mockTimeNow.Add(-time.Hour)}
+
+	s.mockDynamoDBClient.EXPECT().UpdateRecordsByExpression(gomock.Eq(userBookmarks), gomock.Any()).
+		Return(errors.New("ProvisionedThroughputExceededException"))
+
+	swept, err := SweepStaleDistribution(s.mockDynamoDBClient, userBookmarks)
+
+	s.Error(err)
+	s.False(swept)
+}
+
+func (s *SweeperHelperTestSuite) TestSweepRecentDistribution() {
+	userBookmarks := &model.UserBookmarks{UserId: "1", Status: constant.BookmarksLocked,
+		StartTimestamp: s.mockTimeNow.Add(-time.Minute)}
+
+	swept, err := SweepStaleDistribution(s.mockDynamoDBClient, userBookmarks)
+
+	s.NoError(err)
+	s.False(swept)
+}
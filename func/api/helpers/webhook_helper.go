@@ -0,0 +1,536 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/google/uuid"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/encryption"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/sns"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	WebhookEventBookmarksUpdated      = "bookmarks.updated"
+	WebhookEventBookmarksDeleted      = "bookmarks.deleted"
+	WebhookEventDistributionCompleted = "distribution.completed"
+	WebhookEventDistributionCancelled = "distribution.cancelled"
+
+	WebhookIdHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	MaxWebhooksPerUser     = 10
+	MaxWebhookAttempts     = 5
+	minWebhookSecretLength = 16
+
+	webhookRetryBaseDelay    = 30 * time.Second
+	webhookRetryMaxDelay     = 15 * time.Minute // Maximum delay of the SQS messages
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+	webhookResponseBodyLimit = 256
+)
+
+var (
+	SupportedWebhookEventTypes = []string{
+		WebhookEventBookmarksUpdated, WebhookEventBookmarksDeleted, WebhookEventDistributionCompleted,
+		WebhookEventDistributionCancelled,
+	}
+
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrWebhookLimitReached = fmt.Errorf("no more than %d webhooks are allowed", MaxWebhooksPerUser)
+
+	// WebhookHTTPClient refuses to connect to non-public addresses.
+	WebhookHTTPClient = newWebhookHTTPClient(false)
+)
+
+// newWebhookHTTPClient returns the client of the deliveries, which never follows the redirects, as they are reported
+// as failed deliveries. The private networks are only allowed by the tests against local servers.
+func newWebhookHTTPClient(allowPrivateNetworks bool) *http.Client {
+	config := crawler.DefaultConfig()
+	config.AllowPrivateNetworks = allowPrivateNetworks
+
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: crawler.NewTransport(config),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func GetWebhookTopicARN() string {
+	return os.Getenv("WEBHOOK_TOPIC_ARN")
+}
+
+// ValidateWebhookRequest checks the webhook url, which must be https and must not name a local or non-public host
+// outside the local and test environments, and the event types of the subscription.
+func ValidateWebhookRequest(request *models.WebhookRequest) error {
+	webhookURL, err := url.Parse(request.URL)
+	if err != nil || webhookURL.Host == "" {
+		return fmt.Errorf("invalid webhook url %s", request.URL)
+	}
+
+	if webhookURL.Scheme != "https" && (webhookURL.Scheme != "http" || !env.IsLocalOrTestEnv()) {
+		return fmt.Errorf("webhook url %s must use https", request.URL)
+	}
+
+	if !env.IsLocalOrTestEnv() && !isPublicWebhookHost(webhookURL.Hostname()) {
+		return fmt.Errorf("webhook url %s must not target a private address", request.URL)
+	}
+
+	if request.Secret != "" && len(request.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("webhook secret must be at least %d characters", minWebhookSecretLength)
+	}
+
+	if len(request.EventTypes) == 0 {
+		return fmt.Errorf("event types are required, supported event types are %v", SupportedWebhookEventTypes)
+	}
+
+	for _, eventType := range request.EventTypes {
+		if !slices.Contains(SupportedWebhookEventTypes, eventType) {
+			return fmt.Errorf("event type %s is not supported, supported event types are %v",
+				eventType, SupportedWebhookEventTypes)
+		}
+	}
+	return nil
+}
+
+// isPublicWebhookHost rejects the local host names and the non-public ip addresses. The addresses the other host
+// names resolve to are checked on each delivery.
+func isPublicWebhookHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return util.IsPublicIP(addr)
+	}
+	return true
+}
+
+// NewWebhookSecretCipher is replaced by the tests to encrypt the webhook secrets with a test key.
+var NewWebhookSecretCipher = encryption.NewWebhookSecretCipher
+
+// CreateWebhook subscribes the webhook to the event types, with a generated secret when none is passed. The secret
+// is stored encrypted, while the returned webhook holds the plaintext secret which is only shown at the creation.
+func CreateWebhook(dynamodbClient dynamodb.DynamoDBClient, userId string,
+	request *models.WebhookRequest) (*model.WebhookSubscription, error) {
+	webhooks, err := GetWebhooks(dynamodbClient, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) >= MaxWebhooksPerUser {
+		return nil, ErrWebhookLimitReached
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secretBytes := make([]byte, 32)
+		if _, err = rand.Read(secretBytes); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(secretBytes)
+	}
+
+	secretCipher, err := NewWebhookSecretCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := secretCipher.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	eventTypes := make([]string, 0, len(request.EventTypes))
+	for _, eventType := range request.EventTypes {
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	webhook := &model.WebhookSubscription{
+		UserId:           userId,
+		WebhookId:        uuid.NewString(),
+		URL:              request.URL,
+		Secret:           encryptedSecret,
+		EventTypes:       eventTypes,
+		CreatedTimestamp: TimeNow(),
+	}
+
+	if err = dynamodbClient.AddRecord(webhook); err != nil {
+		return nil, err
+	}
+
+	created := *webhook
+	created.Secret = secret
+	return &created, nil
+}
+
+func GetWebhooks(dynamodbClient dynamodb.DynamoDBClient, userId string) ([]model.WebhookSubscription, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.WebhookSubscription{UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	result, _, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.WebhookSubscription{},
+		keyCondition, nil, MaxWebhooksPerUser, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return result.([]model.WebhookSubscription), nil
+}
+
+func GetWebhook(dynamodbClient dynamodb.DynamoDBClient, userId, webhookId string) (*model.WebhookSubscription, error) {
+	result, err := dynamodbClient.GetRecordByKey(&model.WebhookSubscription{UserId: userId, WebhookId: webhookId})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("webhook %s: %w", webhookId, ErrWebhookNotFound)
+	}
+	return result.(*model.WebhookSubscription), nil
+}
+
+// DeleteWebhook deletes the subscription, while its deliveries are left to expire.
+func DeleteWebhook(dynamodbClient dynamodb.DynamoDBClient, userId, webhookId string) error {
+	webhook, err := GetWebhook(dynamodbClient, userId, webhookId)
+	if err != nil {
+		return err
+	}
+	return dynamodbClient.DeleteRecordByKey(webhook)
+}
+
+// GetWebhookDeliveries returns a page of the deliveries of the webhook from the latest along with the token
+// of the next page.
+func GetWebhookDeliveries(dynamodbClient dynamodb.DynamoDBClient, userId, webhookId string, limit int,
+	next string) ([]model.WebhookDelivery, string, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.WebhookDelivery{UserId: userId, WebhookId: webhookId})
+	if err != nil {
+		return nil, "", err
+	}
+
+	lastEvaluatedKey, err := dynamodb.DecodePageToken(next)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	result, lastEvaluatedKey, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.WebhookDelivery{},
+		keyCondition, nil, int32(limit), lastEvaluatedKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err = dynamodb.EncodePageToken(lastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.([]model.WebhookDelivery), next, nil
+}
+
+// PublishWebhookEvent publishes the event of the user to the webhook topic, from which it is queued for the
+// delivery to the subscribed webhooks.
+func PublishWebhookEvent(snsClient sns.SNSClient, topicARN, userId, eventType string, data interface{}) error {
+	eventData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(models.WebhookMessage{
+		Event: models.WebhookEvent{
+			Id:        uuid.NewString(),
+			Type:      eventType,
+			UserId:    userId,
+			Timestamp: TimeNow().UTC(),
+			Data:      eventData,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return snsClient.PublishWithAttributes(topicARN, string(message), map[string]string{"eventType": eventType})
+}
+
+// GetDistributionEventData returns the data of the distribution.completed and distribution.cancelled events of the
+// distribution.
+func GetDistributionEventData(distribution *model.UserBookmarks) *models.WebhookDistributionData {
+	return &models.WebhookDistributionData{
+		OperationId: distribution.OperationId,
+		Version:     distribution.LatestVersion,
+		Status:      distribution.Status,
+		StartTime:   distribution.StartTimestamp,
+		EndTime:     distribution.EndTimestamp,
+	}
+}
+
+// SignWebhookPayload returns the HMAC-SHA256 signature of the timestamp and the payload, which lets the receivers
+// verify the payload and reject the replayed deliveries.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhookMessage delivers the queued event to the subscribed webhooks of the user, or to the webhook
+// of a retried delivery. A failed delivery is queued again with a backoff, until the attempts are exhausted
+// and it is moved to the dead letter queue. The returned errors are of the delivery bookkeeping only, after
+// which the redelivered message skips the webhooks whose outcome of the event was recorded.
+func DeliverWebhookMessage(dynamodbClient dynamodb.DynamoDBClient, sqsClient sqs.SQSClient,
+	message *models.WebhookMessage) error {
+	if message.WebhookId != "" {
+		return retryWebhookDelivery(dynamodbClient, sqsClient, message)
+	}
+
+	webhooks, err := GetWebhooks(dynamodbClient, message.Event.UserId)
+	if err != nil {
+		return err
+	}
+
+	outcomes, err := getWebhookEventOutcomes(dynamodbClient, &message.Event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range webhooks {
+		if !slices.Contains(webhooks[i].EventTypes, message.Event.Type) {
+			continue
+		}
+
+		if _, delivered := outcomes[webhooks[i].WebhookId]; delivered {
+			log.Info().Msgf("Skipping redelivered event %s to webhook %s", message.Event.Id, webhooks[i].WebhookId)
+			continue
+		}
+
+		// The other webhooks are still delivered, so that only the failed ones are delivered again.
+		if err = deliverWebhook(dynamodbClient, sqsClient, &webhooks[i], &message.Event, 1); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func retryWebhookDelivery(dynamodbClient dynamodb.DynamoDBClient, sqsClient sqs.SQSClient,
+	message *models.WebhookMessage) error {
+	webhook, err := GetWebhook(dynamodbClient, message.Event.UserId, message.WebhookId)
+	if errors.Is(err, ErrWebhookNotFound) {
+		log.Info().Msgf("Discarding delivery of event %s to deleted webhook %s", message.Event.Id, message.WebhookId)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	result, err := dynamodbClient.GetRecordByKey(&model.WebhookEventOutcome{UserId: message.Event.UserId,
+		EventId: message.Event.Id, WebhookId: message.WebhookId})
+	if err != nil {
+		return err
+	}
+
+	if result != nil && result.(*model.WebhookEventOutcome).Attempt >= message.Attempt {
+		log.Info().Msgf("Skipping redelivered attempt %d of event %s to webhook %s", message.Attempt,
+			message.Event.Id, message.WebhookId)
+		return nil
+	}
+	return deliverWebhook(dynamodbClient, sqsClient, webhook, &message.Event, message.Attempt)
+}
+
+// getWebhookEventOutcomes returns the attempts of the event recorded by the webhook ids.
+func getWebhookEventOutcomes(dynamodbClient dynamodb.DynamoDBClient, event *models.WebhookEvent) (map[string]int, error) {
+	partitionKey, _, err := dynamodb.GetEntityKeys(&model.WebhookEventOutcome{UserId: event.UserId, EventId: event.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	keyCondition := expression.Key("PK").Equal(expression.Value(partitionKey))
+	result, _, err := dynamodbClient.GetRecordsByKeyConditionPagination(&model.WebhookEventOutcome{},
+		keyCondition, nil, MaxWebhooksPerUser, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := map[string]int{}
+	for _, outcome := range result.([]model.WebhookEventOutcome) {
+		outcomes[outcome.WebhookId] = outcome.Attempt
+	}
+	return outcomes, nil
+}
+
+func deliverWebhook(dynamodbClient dynamodb.DynamoDBClient, sqsClient sqs.SQSClient,
+	webhook *model.WebhookSubscription, event *models.WebhookEvent, attempt int) error {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	secret, err := decryptWebhookSecret(webhook)
+	if err != nil {
+		return err
+	}
+
+	startTime := TimeNow()
+	delivery := &model.WebhookDelivery{
+		UserId:     webhook.UserId,
+		WebhookId:  webhook.WebhookId,
+		DeliveryId: newTimeSortableId(startTime),
+		EventId:    event.Id,
+		EventType:  event.Type,
+		Attempt:    attempt,
+		Timestamp:  startTime,
+		Ttl:        startTime.Add(webhookDeliveryRetention).Unix(),
+	}
+
+	delivery.ResponseCode, delivery.StatusMessage = postWebhook(webhook, secret, event, delivery.DeliveryId)
+	delivery.DurationMillis = TimeNow().Sub(startTime).Milliseconds()
+
+	delivery.Status = constant.Success
+	if delivery.ResponseCode < http.StatusOK || delivery.ResponseCode >= http.StatusMultipleChoices {
+		delivery.Status = constant.Failed
+
+		retryMessage, err := scheduleWebhookRetry(sqsClient, webhook.WebhookId, event, attempt)
+		if err != nil {
+			return err
+		}
+		delivery.StatusMessage = fmt.Sprintf("%s, %s", delivery.StatusMessage, retryMessage)
+	}
+
+	outcome := &model.WebhookEventOutcome{
+		UserId:    webhook.UserId,
+		EventId:   event.Id,
+		WebhookId: webhook.WebhookId,
+		Attempt:   attempt,
+		Status:    delivery.Status,
+		Ttl:       delivery.Ttl,
+	}
+	return dynamodbClient.TransactWriteRecords([]model.TransactWriteItem{
+		dynamodb.TransactAdd(delivery), dynamodb.TransactAdd(outcome),
+	})
+}
+
+// decryptWebhookSecret returns the plaintext secret of the webhook, where the secrets of the webhooks created
+// before their encryption are stored in plaintext.
+func decryptWebhookSecret(webhook *model.WebhookSubscription) (string, error) {
+	if !encryption.IsEncrypted(webhook.Secret) {
+		return webhook.Secret, nil
+	}
+
+	secretCipher, err := NewWebhookSecretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := secretCipher.Decrypt(webhook.Secret)
+	if err != nil {
+		return "", fmt.Errorf("secret of webhook %s: %w", webhook.WebhookId, err)
+	}
+	return string(secret), nil
+}
+
+// postWebhook posts the event signed with the secret to the webhook url and returns the response code, which is
+// zero when the webhook could not be reached, along with the status message of the delivery.
+func postWebhook(webhook *model.WebhookSubscription, secret string, event *models.WebhookEvent,
+	deliveryId string) (int, string) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	timestamp := TimeNow().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookIdHeader, webhook.WebhookId)
+	request.Header.Set(WebhookEventHeader, event.Type)
+	request.Header.Set(WebhookDeliveryHeader, deliveryId)
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, timestamp, payload))
+
+	response, err := WebhookHTTPClient.Do(request)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, webhookResponseBodyLimit))
+	if len(body) == 0 {
+		return response.StatusCode, response.Status
+	}
+	return response.StatusCode, fmt.Sprintf("%s: %s", response.Status, body)
+}
+
+// scheduleWebhookRetry queues the delivery of the event to the webhook again after a delay which doubles
+// with each attempt, or moves it to the dead letter queue when the attempts are exhausted.
+func scheduleWebhookRetry(sqsClient sqs.SQSClient, webhookId string, event *models.WebhookEvent,
+	attempt int) (string, error) {
+	message, err := json.Marshal(models.WebhookMessage{Event: *event, WebhookId: webhookId, Attempt: attempt + 1})
+	if err != nil {
+		return "", err
+	}
+
+	if attempt >= MaxWebhookAttempts {
+		message, err = json.Marshal(models.WebhookMessage{Event: *event, WebhookId: webhookId, Attempt: attempt})
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = sqsClient.SendMessage(os.Getenv("WEBHOOK_DEAD_LETTER_QUEUE_URL"), string(message)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("moved to dead letter queue after %d attempts", attempt), nil
+	}
+
+	delay := GetWebhookRetryDelay(attempt)
+	_, err = sqsClient.SendDelayedMessage(os.Getenv("WEBHOOK_QUEUE_URL"), string(message), int32(delay.Seconds()))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("retrying in %v", delay), nil
+}
+
+// GetWebhookRetryDelay returns the delay before the next attempt of a delivery, which doubles with each
+// failed attempt up to the maximum delay of the queue.
+func GetWebhookRetryDelay(attempt int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempt && delay < webhookRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > webhookRetryMaxDelay {
+		delay = webhookRetryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,378 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/crawler"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/encryption"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	snsMocks "github.com/pranav-patil/go-serverless-api/pkg/sns/mocks"
+	sqsMocks "github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+const testWebhookSecret = "0123456789abcdef0123456789abcdef"
+
+type WebhookHelperTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	mockSQSClient      *sqsMocks.MockSQSClient
+	mockSNSClient      *snsMocks.MockSNSClient
+	mockTimeNow        time.Time
+	event              models.WebhookEvent
+	secretCipher       encryption.Cipher
+	encryptedSecret    string
+}
+
+func TestWebhookHelperSuite(t *testing.T) {
+	suite.Run(t, new(WebhookHelperTestSuite))
+}
+
+func (s *WebhookHelperTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *WebhookHelperTestSuite) SetupTest() {
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.mockSQSClient = sqsMocks.NewMockSQSClient(s.ctrl)
+	s.mockSNSClient = snsMocks.NewMockSNSClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 9, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+
+	s.event = models.WebhookEvent{Id: "e1", Type: WebhookEventBookmarksUpdated, UserId: "1",
+		Timestamp: s.mockTimeNow, Data: json.RawMessage(`{"version":"1.0.2","totalCount":3}`)}
+
+	var err error
+	s.secretCipher, err = encryption.NewAESGCMCipher(make([]byte, 32))
+	s.NoError(err)
+	NewWebhookSecretCipher = func() (encryption.Cipher, error) {
+		return s.secretCipher, nil
+	}
+
+	s.encryptedSecret, err = s.secretCipher.Encrypt([]byte(testWebhookSecret))
+	s.NoError(err)
+
+	// The deliveries are sent to the local test servers.
+	WebhookHTTPClient = newWebhookHTTPClient(true)
+	s.T().Cleanup(func() { WebhookHTTPClient = newWebhookHTTPClient(false) })
+
+	s.T().Setenv("WEBHOOK_QUEUE_URL", "webhook-queue")
+	s.T().Setenv("WEBHOOK_DEAD_LETTER_QUEUE_URL", "webhook-dlq")
+}
+
+// expectDeliveryRecorded expects the delivery to be recorded along with the outcome of the event, and returns
+// the recorded delivery.
+func (s *WebhookHelperTestSuite) expectDeliveryRecorded() *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{}
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).
+		DoAndReturn(func(items []model.TransactWriteItem) error {
+			s.Len(items, 2)
+			*delivery = *items[0].Entity.(*model.WebhookDelivery)
+
+			outcome := items[1].Entity.(*model.WebhookEventOutcome)
+			s.Equal(model.WebhookEventOutcome{UserId: delivery.UserId, EventId: delivery.EventId,
+				WebhookId: delivery.WebhookId, Attempt: delivery.Attempt, Status: delivery.Status, Ttl: delivery.Ttl},
+				*outcome)
+			return nil
+		})
+	return delivery
+}
+
+func (s *WebhookHelperTestSuite) TestValidateWebhookRequest() {
+	events := []string{WebhookEventBookmarksUpdated}
+
+	s.NoError(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://example.com/hook", EventTypes: events}))
+	s.NoError(ValidateWebhookRequest(&models.WebhookRequest{URL: "http://localhost:8080/hook", EventTypes: events}))
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "example.com/hook", EventTypes: events}))
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "ftp://example.com/hook", EventTypes: events}))
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://example.com/hook"}))
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://example.com/hook",
+		EventTypes: []string{"bookmarks.viewed"}}))
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://example.com/hook", Secret: "short",
+		EventTypes: events}))
+
+	// The plain http webhooks are only allowed for local testing.
+	s.T().Setenv("STAGE", "production")
+	s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: "http://example.com/hook", EventTypes: events}))
+}
+
+func (s *WebhookHelperTestSuite) TestCreateWebhook() {
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Eq(int32(MaxWebhooksPerUser)), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{}, nil, nil)
+
+	var stored *model.WebhookSubscription
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.WebhookSubscription{})).
+		DoAndReturn(func(entity model.Entity) error {
+			stored = entity.(*model.WebhookSubscription)
+			return nil
+		})
+
+	webhook, err := CreateWebhook(s.mockDynamoDBClient, "1", &models.WebhookRequest{URL: "https://example.com/hook",
+		EventTypes: []string{WebhookEventBookmarksUpdated, WebhookEventBookmarksUpdated}})
+
+	s.NoError(err)
+	s.Equal(stored.WebhookId, webhook.WebhookId)
+	s.NotEmpty(webhook.WebhookId)
+	s.Len(webhook.Secret, 64)
+
+	// Only the encrypted secret is stored.
+	s.True(encryption.IsEncrypted(stored.Secret))
+	secret, err := s.secretCipher.Decrypt(stored.Secret)
+	s.NoError(err)
+	s.Equal(webhook.Secret, string(secret))
+	s.Equal([]string{WebhookEventBookmarksUpdated}, webhook.EventTypes)
+	s.Equal(s.mockTimeNow, webhook.CreatedTimestamp)
+}
+
+func (s *WebhookHelperTestSuite) TestCreateWebhookWhenLimitReached() {
+	webhooks := make([]model.WebhookSubscription, MaxWebhooksPerUser)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).Return(webhooks, nil, nil)
+
+	_, err := CreateWebhook(s.mockDynamoDBClient, "1", &models.WebhookRequest{URL: "https://example.com/hook",
+		EventTypes: []string{WebhookEventBookmarksUpdated}})
+
+	s.ErrorIs(err, ErrWebhookLimitReached)
+}
+
+func (s *WebhookHelperTestSuite) TestPublishWebhookEvent() {
+	var published string
+	s.mockSNSClient.EXPECT().PublishWithAttributes(gomock.Eq("webhook-topic"), gomock.Any(),
+		gomock.Eq(map[string]string{"eventType": WebhookEventBookmarksDeleted})).
+		DoAndReturn(func(_, message string, _ map[string]string) error {
+			published = message
+			return nil
+		})
+
+	err := PublishWebhookEvent(s.mockSNSClient, "webhook-topic", "1", WebhookEventBookmarksDeleted,
+		&models.WebhookBookmarksData{Version: "1.0.2-deleted"})
+	s.NoError(err)
+
+	message := models.WebhookMessage{}
+	s.NoError(json.Unmarshal([]byte(published), &message))
+	s.Empty(message.WebhookId)
+	s.NotEmpty(message.Event.Id)
+	s.Equal(WebhookEventBookmarksDeleted, message.Event.Type)
+	s.Equal("1", message.Event.UserId)
+	s.JSONEq(`{"version":"1.0.2-deleted","totalCount":0}`, string(message.Event.Data))
+}
+
+func (s *WebhookHelperTestSuite) TestSignWebhookPayload() {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(`1258026754.{"id":"e1"}`))
+
+	s.Equal("sha256="+hex.EncodeToString(mac.Sum(nil)),
+		SignWebhookPayload(testWebhookSecret, 1258026754, []byte(`{"id":"e1"}`)))
+	s.NotEqual(SignWebhookPayload(testWebhookSecret, 1258026754, []byte(`{"id":"e1"}`)),
+		SignWebhookPayload(testWebhookSecret, 1258026755, []byte(`{"id":"e1"}`)))
+}
+
+func (s *WebhookHelperTestSuite) TestGetWebhookRetryDelay() {
+	s.Equal(30*time.Second, GetWebhookRetryDelay(1))
+	s.Equal(time.Minute, GetWebhookRetryDelay(2))
+	s.Equal(8*time.Minute, GetWebhookRetryDelay(5))
+	s.Equal(15*time.Minute, GetWebhookRetryDelay(6))
+	s.Equal(15*time.Minute, GetWebhookRetryDelay(20))
+}
+
+func (s *WebhookHelperTestSuite) TestValidateWebhookRequestWithPrivateHost() {
+	s.T().Setenv("STAGE", "production")
+	events := []string{WebhookEventBookmarksUpdated}
+
+	s.NoError(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://example.com/hook", EventTypes: events}))
+	s.NoError(ValidateWebhookRequest(&models.WebhookRequest{URL: "https://93.184.216.34/hook", EventTypes: events}))
+	for _, privateURL := range []string{"https://localhost/hook", "https://api.localhost./hook",
+		"https://127.0.0.1:8443/hook", "https://10.0.0.1/hook", "https://169.254.169.254/latest", "https://[::1]/hook",
+		"https://[fd00::1]/hook"} {
+		s.Error(ValidateWebhookRequest(&models.WebhookRequest{URL: privateURL, EventTypes: events}), privateURL)
+	}
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookToPrivateAddress() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	WebhookHTTPClient = newWebhookHTTPClient(false)
+	webhook := &model.WebhookSubscription{UserId: "1", WebhookId: "w1", URL: server.URL, Secret: testWebhookSecret}
+
+	responseCode, statusMessage := postWebhook(webhook, testWebhookSecret, &s.event, "d1")
+
+	s.Zero(responseCode)
+	s.Contains(statusMessage, crawler.ErrBlockedAddress.Error())
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageToSubscribedWebhooks() {
+	var requests []*http.Request
+	var payload []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		payload, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{
+			{UserId: "1", WebhookId: "w1", URL: server.URL, Secret: s.encryptedSecret,
+				EventTypes: []string{WebhookEventBookmarksUpdated}},
+			{UserId: "1", WebhookId: "w2", URL: server.URL, Secret: s.encryptedSecret,
+				EventTypes: []string{WebhookEventDistributionCompleted}},
+		}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookEventOutcome{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookEventOutcome{}, nil, nil)
+
+	delivery := s.expectDeliveryRecorded()
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient, &models.WebhookMessage{Event: s.event})
+
+	s.NoError(err)
+	s.Len(requests, 1)
+
+	header := requests[0].Header
+	s.Equal("w1", header.Get(WebhookIdHeader))
+	s.Equal(WebhookEventBookmarksUpdated, header.Get(WebhookEventHeader))
+	s.Equal(delivery.DeliveryId, header.Get(WebhookDeliveryHeader))
+	s.Equal(strconv.FormatInt(s.mockTimeNow.Unix(), 10), header.Get(WebhookTimestampHeader))
+	s.Equal(SignWebhookPayload(testWebhookSecret, s.mockTimeNow.Unix(), payload), header.Get(WebhookSignatureHeader))
+
+	s.Equal("w1", delivery.WebhookId)
+	s.Equal("e1", delivery.EventId)
+	s.Equal(1, delivery.Attempt)
+	s.Equal(constant.Success, delivery.Status)
+	s.Equal(http.StatusNoContent, delivery.ResponseCode)
+	s.Equal(s.mockTimeNow.Add(30*24*time.Hour).Unix(), delivery.Ttl)
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageSkipsDeliveredWebhooks() {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get(WebhookIdHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookSubscription{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookSubscription{
+			{UserId: "1", WebhookId: "w1", URL: server.URL, Secret: s.encryptedSecret,
+				EventTypes: []string{WebhookEventBookmarksUpdated}},
+			{UserId: "1", WebhookId: "w2", URL: server.URL, Secret: s.encryptedSecret,
+				EventTypes: []string{WebhookEventBookmarksUpdated}},
+			{UserId: "1", WebhookId: "w3", URL: server.URL, Secret: s.encryptedSecret,
+				EventTypes: []string{WebhookEventBookmarksUpdated}},
+		}, nil, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyConditionPagination(mockutil.AnyOfType(&model.WebhookEventOutcome{}),
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Nil(), gomock.Eq(true)).
+		Return([]model.WebhookEventOutcome{{UserId: "1", EventId: "e1", WebhookId: "w1", Attempt: 1}}, nil, nil)
+
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).Return(errors.New("throttled"))
+	s.mockDynamoDBClient.EXPECT().TransactWriteRecords(gomock.Any()).Return(nil)
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient, &models.WebhookMessage{Event: s.event})
+
+	s.ErrorContains(err, "throttled")
+	s.Equal([]string{"w2", "w3"}, requests)
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageRetriesFailedDelivery() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhook := &model.WebhookSubscription{UserId: "1", WebhookId: "w1", URL: server.URL, Secret: s.encryptedSecret,
+		EventTypes: []string{WebhookEventBookmarksUpdated}}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Eq(&model.WebhookSubscription{UserId: "1", WebhookId: "w1"})).
+		Return(webhook, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(
+		gomock.Eq(&model.WebhookEventOutcome{UserId: "1", EventId: "e1", WebhookId: "w1"})).
+		Return(&model.WebhookEventOutcome{UserId: "1", EventId: "e1", WebhookId: "w1", Attempt: 1}, nil)
+
+	var retried string
+	s.mockSQSClient.EXPECT().SendDelayedMessage(gomock.Eq("webhook-queue"), gomock.Any(), gomock.Eq(int32(60))).
+		DoAndReturn(func(_, message string, _ int32) (interface{}, error) {
+			retried = message
+			return nil, nil
+		})
+
+	delivery := s.expectDeliveryRecorded()
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient,
+		&models.WebhookMessage{Event: s.event, WebhookId: "w1", Attempt: 2})
+
+	s.NoError(err)
+	s.Equal(constant.Failed, delivery.Status)
+	s.Equal(2, delivery.Attempt)
+	s.Equal(http.StatusServiceUnavailable, delivery.ResponseCode)
+	s.Contains(delivery.StatusMessage, "retrying in 1m0s")
+
+	message := models.WebhookMessage{}
+	s.NoError(json.Unmarshal([]byte(retried), &message))
+	s.Equal(models.WebhookMessage{Event: s.event, WebhookId: "w1", Attempt: 3}, message)
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageToDeadLetterQueue() {
+	// The secrets of the webhooks created before their encryption are stored in plaintext.
+	webhook := &model.WebhookSubscription{UserId: "1", WebhookId: "w1", URL: "http://127.0.0.1:0/hook",
+		Secret: testWebhookSecret, EventTypes: []string{WebhookEventBookmarksUpdated}}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(mockutil.AnyOfType(&model.WebhookSubscription{})).Return(webhook, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(mockutil.AnyOfType(&model.WebhookEventOutcome{})).Return(nil, nil)
+	s.mockSQSClient.EXPECT().SendMessage(gomock.Eq("webhook-dlq"), gomock.Any()).Return(nil, nil)
+
+	delivery := s.expectDeliveryRecorded()
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient,
+		&models.WebhookMessage{Event: s.event, WebhookId: "w1", Attempt: MaxWebhookAttempts})
+
+	s.NoError(err)
+	s.Equal(constant.Failed, delivery.Status)
+	s.Zero(delivery.ResponseCode)
+	s.Contains(delivery.StatusMessage, "moved to dead letter queue after 5 attempts")
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageSkipsRedeliveredAttempt() {
+	webhook := &model.WebhookSubscription{UserId: "1", WebhookId: "w1", URL: "http://127.0.0.1:0/hook",
+		Secret: testWebhookSecret, EventTypes: []string{WebhookEventBookmarksUpdated}}
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(mockutil.AnyOfType(&model.WebhookSubscription{})).Return(webhook, nil)
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(mockutil.AnyOfType(&model.WebhookEventOutcome{})).
+		Return(&model.WebhookEventOutcome{UserId: "1", EventId: "e1", WebhookId: "w1", Attempt: 2}, nil)
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient,
+		&models.WebhookMessage{Event: s.event, WebhookId: "w1", Attempt: 2})
+
+	s.NoError(err)
+}
+
+func (s *WebhookHelperTestSuite) TestDeliverWebhookMessageToDeletedWebhook() {
+	s.mockDynamoDBClient.EXPECT().GetRecordByKey(gomock.Any()).Return(nil, nil)
+
+	err := DeliverWebhookMessage(s.mockDynamoDBClient, s.mockSQSClient,
+		&models.WebhookMessage{Event: s.event, WebhookId: "w1", Attempt: 2})
+
+	s.NoError(err)
+}
@@ -2,27 +2,44 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
 	"github.com/gin-gonic/gin"
+	"github.com/pranav-patil/go-serverless-api/func/api/handler"
 	"github.com/pranav-patil/go-serverless-api/func/api/middleware"
 	"github.com/pranav-patil/go-serverless-api/func/api/routes"
+	cloudwatch "github.com/pranav-patil/go-serverless-api/pkg/cloudwatch"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
 	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/rs/zerolog/log"
 )
 
+const defaultLogShippingStream = "bookmarks-api"
+
 var ginLambda *ginadapter.GinLambda
 
 func main() {
 	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+	logWriter := newLogWriter()
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
 	middleware.Attach(router)
 	routes.APIRouter(router)
+	env.OnShutdown(func() {
+		handler.CloseAnalytics()
+		if logWriter != nil {
+			if err := logWriter.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failure in flushing logs: %v\n", err)
+			}
+		}
+	})
 
 	if env.IsLocalOrTestEnv() {
 		err := router.Run(":8080")
@@ -39,3 +56,31 @@ func main() {
 func Handler(ctx context.Context, request *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	return ginLambda.ProxyWithContext(ctx, *request)
 }
+
+// newLogWriter ships the logs to the log group of LOG_SHIPPING_GROUP as well, when it is set, under the log stream
+// of the Lambda instance.
+func newLogWriter() *cloudwatch.Writer {
+	groupName := os.Getenv("LOG_SHIPPING_GROUP")
+	if groupName == "" {
+		return nil
+	}
+
+	client, err := cloudwatch.NewCloudWatchClient()
+	if err != nil {
+		log.Error().Msgf("Failure in creating log shipping client: %v", err)
+		return nil
+	}
+
+	streamName := os.Getenv("AWS_LAMBDA_LOG_STREAM_NAME")
+	if streamName == "" {
+		streamName = defaultLogShippingStream
+	}
+
+	writer := cloudwatch.NewWriter(client, cloudwatch.WriterConfig{
+		GroupName:     groupName,
+		StreamName:    streamName,
+		FlushInterval: cloudwatch.DefaultFlushInterval,
+	})
+	logger.AddOutput(writer)
+	return writer
+}
@@ -11,6 +11,8 @@ import (
 
 const (
 	UserIDCxt       string = "USER_ID"
+	PrincipalCxt    string = "PRINCIPAL"
+	RoleCxt         string = "ROLE"
 	LaunchDarklyCxt string = "LD_CLIENT"
 	JWTToken        string = "JWT_TOKEN"
 )
@@ -51,5 +53,7 @@ func extractUserIDFromJWT() gin.HandlerFunc {
 
 		c.Set(JWTToken, authToken)
 		c.Set(UserIDCxt, jwtToken.Account())
+		c.Set(PrincipalCxt, jwtToken.Principal())
+		c.Set(RoleCxt, jwtToken.Role().String())
 	}
 }
@@ -1,9 +1,34 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type BookmarkEntry struct {
-	URL string `json:"url"`
+	URL         string            `json:"url"`
+	ResolvedURL string            `json:"resolvedUrl,omitempty"`
+	Metadata    *BookmarkMetadata `json:"metadata,omitempty"`
+}
+
+type BookmarkMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	FaviconURL  string    `json:"faviconUrl,omitempty"`
+	SiteName    string    `json:"siteName,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	EnrichedAt  time.Time `json:"enrichedAt"`
+}
+
+type EnrichmentRequest struct {
+	UserId string   `json:"userId"`
+	URLs   []string `json:"urls"`
+}
+
+type SnapshotRequest struct {
+	UserId  string   `json:"userId"`
+	Version string   `json:"version"`
+	URLs    []string `json:"urls"`
 }
 
 type BookmarkList struct {
@@ -17,7 +42,8 @@ type BookmarksResponse struct {
 }
 
 type DistributeBookmarksRequest struct {
-	DeviceIDs []int `json:"devicesIds"`
+	DeviceIDs     []int  `json:"devicesIds"`
+	PackageFormat string `json:"packageFormat,omitempty"`
 }
 
 type InvalidDistributeBookmarksResponse struct {
@@ -31,7 +57,13 @@ type InValidBookmarksResponse struct {
 }
 
 type BookmarksConfig struct {
-	Enabled bool `json:"enabled"`
+	Enabled           bool   `json:"enabled"`
+	AutoDistribute    bool   `json:"autoDistribute"`
+	PackageFormat     string `json:"packageFormat"`
+	PageSize          int    `json:"pageSize"`
+	EnrichmentEnabled bool   `json:"enrichmentEnabled"`
+	// DistributionTimeout is the minutes after which a pending or locked distribution is timed out.
+	DistributionTimeout int `json:"distributionTimeoutMinutes"`
 }
 
 type WebCrawlerJob struct {
@@ -49,3 +81,233 @@ type DistributedBookmarksResponse struct {
 	TotalCount          int             `json:"totalCount"`
 	Next                string          `json:"next"`
 }
+
+type SigningKeyResponse struct {
+	KeyId        string `json:"keyId"`
+	Algorithm    string `json:"algorithm"`
+	PublicKey    string `json:"publicKey"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+}
+
+type LinkHealth struct {
+	URL           string    `json:"url"`
+	Status        string    `json:"status"`
+	StatusCode    int       `json:"statusCode,omitempty"`
+	RedirectChain []string  `json:"redirectChain,omitempty"`
+	FinalURL      string    `json:"finalUrl,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	LastChecked   time.Time `json:"lastChecked"`
+}
+
+type LinkHealthRequest struct {
+	UserId string `json:"userId"`
+}
+
+type LinkHealthReport struct {
+	UserId    string       `json:"userId"`
+	Version   string       `json:"version"`
+	CheckedAt time.Time    `json:"checkedAt"`
+	Links     []LinkHealth `json:"links"`
+}
+
+type LinkHealthResponse struct {
+	Version    string       `json:"version"`
+	CheckedAt  time.Time    `json:"checkedAt"`
+	Broken     []LinkHealth `json:"broken"`
+	Redirected []LinkHealth `json:"redirected"`
+	Healthy    []LinkHealth `json:"healthy"`
+	Unchecked  []LinkHealth `json:"unchecked"`
+}
+
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+type WebhookResponse struct {
+	WebhookId  string    `json:"webhookId"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Secret     string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	Created    time.Time `json:"created"`
+}
+
+type WebhooksResponse struct {
+	Webhooks   []WebhookResponse `json:"webhooks"`
+	TotalCount int               `json:"totalCount"`
+}
+
+// WebhookEvent is the payload posted to the webhooks. The id is unique for the event and is shared by the
+// retried deliveries, which lets the receivers discard the duplicates.
+type WebhookEvent struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	UserId    string          `json:"userId"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// WebhookMessage is queued for the delivery of the event, to all the subscribed webhooks of the user
+// or to a single webhook when the delivery is retried.
+type WebhookMessage struct {
+	Event     WebhookEvent `json:"event"`
+	WebhookId string       `json:"webhookId,omitempty"`
+	Attempt   int          `json:"attempt,omitempty"`
+}
+
+type WebhookDeliveryEntry struct {
+	DeliveryId    string    `json:"deliveryId"`
+	EventId       string    `json:"eventId"`
+	EventType     string    `json:"eventType"`
+	Attempt       int       `json:"attempt"`
+	Status        string    `json:"status"`
+	StatusMessage string    `json:"statusMessage,omitempty"`
+	ResponseCode  int       `json:"responseCode,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Duration      int64     `json:"durationMillis"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryEntry `json:"deliveries"`
+	TotalCount int                    `json:"totalCount"`
+	Next       string                 `json:"next"`
+}
+
+type WebhookBookmarksData struct {
+	Version    string `json:"version,omitempty"`
+	TotalCount int    `json:"totalCount"`
+}
+
+type WebhookDistributionData struct {
+	OperationId int64     `json:"operationId"`
+	Version     string    `json:"version"`
+	Status      string    `json:"status"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+}
+
+// DomainEvent is a change of the bookmarks or the distributions of a user, which is published to the downstream
+// consumers through the outbox.
+type DomainEvent interface {
+	EventType() string
+}
+
+type BookmarksReplaced struct {
+	Version    string `json:"version"`
+	TotalCount int    `json:"totalCount"`
+}
+
+type BookmarkAdded struct {
+	Version  string        `json:"version"`
+	Bookmark BookmarkEntry `json:"bookmark"`
+}
+
+type BookmarkRemoved struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+type DistributionStarted struct {
+	OperationId   int64    `json:"operationId"`
+	Version       string   `json:"version"`
+	PackageFormat string   `json:"packageFormat"`
+	DeviceIds     []string `json:"deviceIds"`
+}
+
+type DeviceDistributionCompleted struct {
+	OperationId   string    `json:"operationId"`
+	DeviceId      string    `json:"deviceId"`
+	Version       string    `json:"version"`
+	Status        string    `json:"status"`
+	StatusMessage string    `json:"statusMessage,omitempty"`
+	EndTime       time.Time `json:"endTime"`
+}
+
+func (BookmarksReplaced) EventType() string           { return "BookmarksReplaced" }
+func (BookmarkAdded) EventType() string               { return "BookmarkAdded" }
+func (BookmarkRemoved) EventType() string             { return "BookmarkRemoved" }
+func (DistributionStarted) EventType() string         { return "DistributionStarted" }
+func (DeviceDistributionCompleted) EventType() string { return "DeviceDistributionCompleted" }
+
+// DomainEventMessage is the message published for a domain event. The id is unique for the event,
+// which lets the consumers discard the events relayed more than once.
+type DomainEventMessage struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	UserId    string          `json:"userId"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// AccountLifecycleEvent is a change of the account of a user, received from the account lifecycle queue.
+type AccountLifecycleEvent struct {
+	Type      string    `json:"type"`
+	UserId    string    `json:"userId"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// AccountPurgeReport records the data of the deleted account which was purged, for auditing.
+type AccountPurgeReport struct {
+	UserId      string               `json:"userId"`
+	RequestedAt time.Time            `json:"requestedAt"`
+	StartTime   time.Time            `json:"startTime"`
+	EndTime     time.Time            `json:"endTime"`
+	Status      string               `json:"status"`
+	Resources   []PurgedResourceInfo `json:"resources"`
+}
+
+type PurgedResourceInfo struct {
+	Resource     string `json:"resource"`
+	DeletedCount int    `json:"deletedCount"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ExportRequest is the queued request to archive the data of the user for the export job.
+type ExportRequest struct {
+	UserId string `json:"userId"`
+	JobId  string `json:"jobId"`
+}
+
+type ExportJobResponse struct {
+	JobId         string     `json:"jobId"`
+	Status        string     `json:"status"`
+	StatusMessage string     `json:"statusMessage,omitempty"`
+	Created       time.Time  `json:"created"`
+	Completed     *time.Time `json:"completed,omitempty"`
+	SizeBytes     int64      `json:"sizeBytes,omitempty"`
+	DownloadURL   string     `json:"downloadUrl,omitempty"`
+	URLExpires    *time.Time `json:"downloadUrlExpires,omitempty"`
+}
+
+// AuditEntry is the audit record of a change to the bookmarks, as shipped to the audit sink and queried by
+// the account admins.
+type AuditEntry struct {
+	Id            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	UserId        string    `json:"userId"`
+	Actor         string    `json:"actor"`
+	Role          string    `json:"role"`
+	Action        string    `json:"action"`
+	List          string    `json:"list"`
+	VersionBefore string    `json:"versionBefore,omitempty"`
+	VersionAfter  string    `json:"versionAfter,omitempty"`
+	RequestId     string    `json:"requestId,omitempty"`
+	SourceIP      string    `json:"sourceIp,omitempty"`
+}
+
+type AuditResponse struct {
+	Entries    []AuditEntry `json:"entries"`
+	TotalCount int          `json:"totalCount"`
+	Next       string       `json:"next"`
+}
+
+// AnalyticsEvent is the usage of a feature by a user, as put on the analytics stream.
+type AnalyticsEvent struct {
+	Id         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	UserId     string                 `json:"userId"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
@@ -8,18 +8,38 @@ import (
 )
 
 func APIRouter(router *gin.Engine) {
-	apiRouter := router.Group("/emprovise/api").Use(h.Validate())
+	apiRouter := router.Group("/emprovise/api").Use(h.Metrics(), h.Validate(), h.Audit())
 
 	apiRouter.GET("/bookmarks", h.GetBookmarks)
 	apiRouter.POST("/bookmarks", h.PostBookmarks)
 	apiRouter.PUT("/bookmarks", h.PutBookmarks)
 	apiRouter.DELETE("/bookmarks", h.DeleteBookmarks)
 
+	apiRouter.GET("/bookmarks/config", h.GetBookmarksConfig)
+	apiRouter.PUT("/bookmarks/config", h.PutBookmarksConfig)
+
+	apiRouter.POST("/bookmarks/resolve", h.ResolveBookmarks)
+	apiRouter.GET("/bookmarks/health", h.GetBookmarksHealth)
+	apiRouter.POST("/bookmarks/health/redirects", h.ReplaceRedirectedBookmarks)
+	apiRouter.GET("/bookmarks/audit", h.GetBookmarksAudit)
+
 	apiRouter.HEAD("/bookmarks/:url", h.FindBookmarkEntry)
 	apiRouter.DELETE("/bookmarks/:url", h.FindAndDeleteBookmarkEntry)
+	apiRouter.GET("/bookmarks/:url/snapshot", h.GetBookmarkSnapshot)
 
 	apiRouter.POST("/bookmarks/summary", h.DistributeBookmarks)
+	apiRouter.DELETE("/bookmarks/summary/:operationId", h.CancelDistribution)
+	apiRouter.POST("/bookmarks/summary/:operationId/retry", h.RetryDistribution)
 	apiRouter.GET("/bookmarks/pages", h.GetDistributedBookmarks)
+	apiRouter.GET("/bookmarks/pages/signing-key", h.GetSigningKey)
+
+	apiRouter.GET("/webhooks", h.GetWebhooks)
+	apiRouter.POST("/webhooks", h.PostWebhook)
+	apiRouter.DELETE("/webhooks/:webhookId", h.DeleteWebhook)
+	apiRouter.GET("/webhooks/:webhookId/deliveries", h.GetWebhookDeliveries)
+
+	apiRouter.POST("/account/export", h.PostAccountExport)
+	apiRouter.GET("/account/export/:jobId", h.GetAccountExport)
 
 	router.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"code": "NOT_FOUND", "message": "Service not found"})
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	"github.com/pranav-patil/go-serverless-api/pkg/stepfunc"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+	lambda.Start(Handler)
+}
+
+// Handler sweeps the users on schedule and distributes the bookmarks which were modified and left
+// unchanged for the debounce delay. A failure for one user is logged and does not stop the remaining users.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	s3Client, err := s3.NewS3Client()
+	if err != nil {
+		return err
+	}
+
+	sfnClient, err := stepfunc.NewStepFunctionClient()
+	if err != nil {
+		return err
+	}
+
+	result, err := dynamodbClient.GetAllRecords(&model.UserBookmarks{}, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	delay := helpers.GetAutoDistributionDelay()
+	allUserBookmarks := result.([]model.UserBookmarks)
+
+	for i := range allUserBookmarks {
+		if !helpers.IsAutoDistributionDue(&allUserBookmarks[i], delay) {
+			continue
+		}
+
+		userId := allUserBookmarks[i].UserId
+		jobs, err := helpers.AutoDistributeBookmarks(dynamodbClient, s3Client, sfnClient, userId, delay)
+		if err != nil {
+			log.Error().Msgf("Failure in automatic distribution for userId %s: %v", userId, err)
+			continue
+		}
+
+		if len(jobs) > 0 {
+			log.Info().Msgf("Started automatic distribution of bookmarks for userId %s to %d devices", userId, len(jobs))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	kinesis "github.com/pranav-patil/go-serverless-api/pkg/kinesis"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const consumerName = "domain-stats"
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Run(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler aggregates the most bookmarked domains from the analytics events. A failed batch is bisected to the
+// failed record, from which the shard is retried, while the events counted before are skipped.
+func Handler(ctx context.Context, event events.KinesisEvent) (kinesis.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return kinesis.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*kinesis.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	kinesisClient, err := kinesis.NewKinesisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	streamName := os.Getenv("ANALYTICS_STREAM_NAME")
+	checkpoints := &kinesis.DynamoDBCheckpointStore{Client: dynamodbClient, ConsumerName: consumerName,
+		StreamName: streamName}
+
+	return kinesis.NewConsumer(kinesisClient, checkpoints, kinesis.ConsumerConfig{StreamName: streamName},
+		func(ctx context.Context, records []*kinesis.Record) error {
+			analyticsEvents := make([]*models.AnalyticsEvent, 0, len(records))
+
+			for _, record := range records {
+				analyticsEvent := &models.AnalyticsEvent{}
+				// The malformed events are skipped, as they fail on every retry.
+				if err := json.Unmarshal(record.Data, analyticsEvent); err != nil {
+					log.Warn().Msgf("Skipping malformed record %s of shard %s: %v", record.SequenceNumber,
+						record.ShardId, err)
+					continue
+				}
+				analyticsEvents = append(analyticsEvents, analyticsEvent)
+			}
+
+			return helpers.AggregateBookmarkedDomains(dynamodbClient, analyticsEvents)
+		}), nil
+}
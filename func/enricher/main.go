@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+// enrichmentVisibilityTimeout is the visibility timeout of the enrichment queue.
+const enrichmentVisibilityTimeout = 360 * time.Second
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Poll(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler enriches or snapshots the bookmarks of each queued request. Only the failed requests are reported,
+// so that SQS redelivers them and eventually moves them to the dead letter queue.
+func Handler(ctx context.Context, event events.SQSEvent) (sqs.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return sqs.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*sqs.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, err := s3.NewS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := sqs.NewConsumer(sqsClient, sqs.ConsumerConfig{
+		QueueURL:           os.Getenv("ENRICHMENT_QUEUE_URL"),
+		DeadLetterQueueURL: os.Getenv("ENRICHMENT_DEAD_LETTER_QUEUE_URL"),
+		VisibilityTimeout:  enrichmentVisibilityTimeout,
+	})
+
+	consumer.Handle(helpers.SnapshotMessageType, func(ctx context.Context, message *sqs.Message) error {
+		request := models.SnapshotRequest{}
+
+		if err := json.Unmarshal([]byte(message.Body), &request); err != nil {
+			return fmt.Errorf("%w: invalid snapshot message: %v", sqs.ErrPoisonMessage, err)
+		}
+
+		if err := helpers.CreateSnapshots(dynamodbClient, s3Client, &request); err != nil {
+			log.Error().Msgf("Failure in creating snapshots for userId %s: %v", request.UserId, err)
+			return err
+		}
+		return nil
+	})
+
+	consumer.HandleDefault(func(ctx context.Context, message *sqs.Message) error {
+		request := models.EnrichmentRequest{}
+
+		if err := json.Unmarshal([]byte(message.Body), &request); err != nil {
+			return fmt.Errorf("%w: invalid enrichment message: %v", sqs.ErrPoisonMessage, err)
+		}
+
+		if err := helpers.EnrichBookmarks(dynamodbClient, s3Client, &request); err != nil {
+			log.Error().Msgf("Failure in enriching bookmarks for userId %s: %v", request.UserId, err)
+			return err
+		}
+		return nil
+	})
+
+	return consumer, nil
+}
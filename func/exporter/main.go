@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+const visibilityTimeout = 900 * time.Second
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Poll(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler exports the account data of the queued export jobs.
+func Handler(ctx context.Context, event events.SQSEvent) (sqs.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return sqs.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*sqs.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, err := s3.NewS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := sqs.NewConsumer(sqsClient, sqs.ConsumerConfig{
+		QueueURL:           helpers.GetExportQueueURL(),
+		DeadLetterQueueURL: os.Getenv("EXPORT_DEAD_LETTER_QUEUE_URL"),
+		VisibilityTimeout:  visibilityTimeout,
+	})
+
+	consumer.HandleDefault(func(ctx context.Context, message *sqs.Message) error {
+		request := &models.ExportRequest{}
+
+		if err := json.Unmarshal([]byte(message.Body), request); err != nil {
+			return fmt.Errorf("%w: invalid export message: %v", sqs.ErrPoisonMessage, err)
+		}
+		if request.UserId == "" || request.JobId == "" {
+			return fmt.Errorf("%w: export message without userId or jobId", sqs.ErrPoisonMessage)
+		}
+
+		if err := helpers.ExportAccountData(dynamodbClient, s3Client, request); err != nil {
+			return err
+		}
+		log.Info().Msgf("Exported account data of userId %s for job %s", request.UserId, request.JobId)
+		return nil
+	})
+
+	return consumer, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+	lambda.Start(Handler)
+}
+
+// Handler queues a link health check of the bookmarks of every user with bookmarks on schedule, which the link
+// checker handles for each user independently, so that a slow or failing user never holds up the others.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return err
+	}
+
+	result, err := dynamodbClient.GetAllRecords(&model.UserBookmarks{}, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	allUserBookmarks := result.([]model.UserBookmarks)
+
+	var userIds []string
+	for i := range allUserBookmarks {
+		if helpers.GetUserBookmarksS3Path(&allUserBookmarks[i]) != "" {
+			userIds = append(userIds, allUserBookmarks[i].UserId)
+		}
+	}
+
+	log.Info().Msgf("Queueing link health checks of bookmarks for %d users", len(userIds))
+	return helpers.QueueLinkHealthChecks(sqsClient, helpers.GetLinkHealthQueueURL(), userIds)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+// visibilityTimeout is the visibility timeout of the link health queue.
+const visibilityTimeout = 900 * time.Second
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Poll(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler checks the links of the bookmarks of the user of each queued request and stores the health report.
+func Handler(ctx context.Context, event events.SQSEvent) (sqs.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return sqs.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*sqs.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, err := s3.NewS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := sqs.NewConsumer(sqsClient, sqs.ConsumerConfig{
+		QueueURL:           helpers.GetLinkHealthQueueURL(),
+		DeadLetterQueueURL: os.Getenv("LINK_HEALTH_DEAD_LETTER_QUEUE_URL"),
+		VisibilityTimeout:  visibilityTimeout,
+	})
+
+	consumer.HandleDefault(func(ctx context.Context, message *sqs.Message) error {
+		request := &models.LinkHealthRequest{}
+
+		if err := json.Unmarshal([]byte(message.Body), request); err != nil {
+			return fmt.Errorf("%w: invalid link health message: %v", sqs.ErrPoisonMessage, err)
+		}
+		if request.UserId == "" {
+			return fmt.Errorf("%w: link health message without userId", sqs.ErrPoisonMessage)
+		}
+
+		result, err := dynamodbClient.GetRecordByKey(&model.UserBookmarks{UserId: request.UserId})
+		if err != nil || result == nil {
+			return err
+		}
+
+		report, err := helpers.CheckBookmarksHealth(s3Client, result.(*model.UserBookmarks))
+		if err != nil {
+			log.Error().Msgf("Failure in checking link health for userId %s: %v", request.UserId, err)
+			return err
+		}
+
+		if report != nil {
+			log.Debug().Msgf("Checked %d links for userId %s", len(report.Links), report.UserId)
+		}
+		return nil
+	})
+
+	return consumer, nil
+}
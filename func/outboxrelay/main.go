@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/sns"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+	lambda.Start(Handler)
+}
+
+// Handler relays the events inserted in the outbox table to the domain events topic. A failure fails the batch,
+// which the stream retries in halves, while the events left unpublished are relayed later by the sweeper.
+func Handler(ctx context.Context, event events.DynamoDBEvent) error {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	snsClient, err := sns.NewSNSClient()
+	if err != nil {
+		return err
+	}
+
+	topicARN := helpers.GetDomainEventsTopicARN()
+
+	for i := range event.Records {
+		if event.Records[i].EventName != string(events.DynamoDBOperationTypeInsert) {
+			continue
+		}
+
+		outboxEvent := toOutboxEvent(event.Records[i].Change.NewImage)
+		if err = helpers.RelayOutboxEvent(dynamodbClient, snsClient, topicARN, outboxEvent); err != nil {
+			log.Error().Msgf("Failure in relaying %s event %s for userId %s: %v", outboxEvent.EventType,
+				outboxEvent.EventId, outboxEvent.UserId, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toOutboxEvent(image map[string]events.DynamoDBAttributeValue) *model.OutboxEvent {
+	outboxEvent := &model.OutboxEvent{
+		UserId:     getString(image, "userId"),
+		EventId:    getString(image, "eventId"),
+		EventType:  getString(image, "eventType"),
+		Message:    getString(image, "message"),
+		Attributes: map[string]string{},
+	}
+
+	if attributes, ok := image["attributes"]; ok && attributes.DataType() == events.DataTypeMap {
+		for name, value := range attributes.Map() {
+			outboxEvent.Attributes[name] = value.String()
+		}
+	}
+
+	outboxEvent.CreatedTimestamp, _ = time.Parse(time.RFC3339Nano, getString(image, "createdTs"))
+	outboxEvent.PublishedTimestamp, _ = time.Parse(time.RFC3339Nano, getString(image, "publishedTs"))
+	return outboxEvent
+}
+
+func getString(image map[string]events.DynamoDBAttributeValue, name string) string {
+	if value, ok := image[name]; ok && value.DataType() == events.DataTypeString {
+		return value.String()
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/pkg/constant"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	"github.com/pranav-patil/go-serverless-api/pkg/sns"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+	lambda.Start(Handler)
+}
+
+// Handler sweeps the pending and locked distributions on schedule, which are timed out otherwise only when read,
+// and releases the locks on the bookmarks left by the distributions which never completed.
+// A failure for one user is logged and does not stop the remaining users.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	filter := expression.Name("status").In(expression.Value(constant.Pending),
+		expression.Value(constant.BookmarksLocked))
+	result, err := dynamodbClient.GetAllRecords(&model.UserBookmarks{}, &filter, nil)
+	if err != nil {
+		return err
+	}
+
+	allUserBookmarks := result.([]model.UserBookmarks)
+	var sweptCount int
+
+	for i := range allUserBookmarks {
+		userId := allUserBookmarks[i].UserId
+		swept, err := helpers.SweepStaleDistribution(dynamodbClient, &allUserBookmarks[i])
+		if err != nil {
+			log.Error().Msgf("Failure in sweeping distribution for userId %s: %v", userId, err)
+			continue
+		}
+
+		if swept {
+			sweptCount++
+			publishDistributionCompleted(&allUserBookmarks[i])
+		}
+	}
+
+	log.Info().Msgf("Swept %d stale distributions of %d pending distributions", sweptCount, len(allUserBookmarks))
+
+	relayPendingOutboxEvents(dynamodbClient)
+	return nil
+}
+
+// relayPendingOutboxEvents publishes the domain events which the outbox stream failed to relay,
+// and never fails the sweep.
+func relayPendingOutboxEvents(dynamodbClient dynamodb.DynamoDBClient) {
+	topicARN := helpers.GetDomainEventsTopicARN()
+	if topicARN == "" {
+		return
+	}
+
+	snsClient, err := sns.NewSNSClient()
+	if err != nil {
+		log.Error().Msgf("Failure in relaying pending outbox events: %v", err)
+		return
+	}
+
+	relayedCount, err := helpers.RelayPendingOutboxEvents(dynamodbClient, snsClient, topicARN)
+	if err != nil {
+		log.Error().Msgf("Failure in relaying pending outbox events: %v", err)
+		return
+	}
+
+	if relayedCount > 0 {
+		log.Info().Msgf("Relayed %d pending outbox events", relayedCount)
+	}
+}
+
+// publishDistributionCompleted notifies the webhooks of the user of the swept distribution, and never fails the sweep.
+func publishDistributionCompleted(userBookmarks *model.UserBookmarks) {
+	topicARN := helpers.GetWebhookTopicARN()
+	if topicARN == "" {
+		return
+	}
+
+	snsClient, err := sns.NewSNSClient()
+	if err == nil {
+		err = helpers.PublishWebhookEvent(snsClient, topicARN, userBookmarks.UserId,
+			helpers.WebhookEventDistributionCompleted, helpers.GetDistributionEventData(userBookmarks))
+	}
+
+	if err != nil {
+		log.Error().Msgf("Failure in publishing distribution event for userId %s: %v", userBookmarks.UserId, err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pranav-patil/go-serverless-api/func/api/helpers"
+	"github.com/pranav-patil/go-serverless-api/func/api/models"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/logger"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
+	sqs "github.com/pranav-patil/go-serverless-api/pkg/sqs"
+	"github.com/rs/zerolog/log"
+)
+
+// webhookVisibilityTimeout is the visibility timeout of the webhook queue.
+const webhookVisibilityTimeout = 180 * time.Second
+
+func main() {
+	logger.SetGlobalLevel(os.Getenv("LOG_LEVEL"))
+	metrics.Init()
+
+	if env.IsLocalOrTestEnv() {
+		consumer, err := newConsumer()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = consumer.Poll(context.Background()); err != nil {
+			panic(err)
+		}
+	} else {
+		lambda.Start(Handler)
+	}
+}
+
+// Handler delivers the queued webhook events. The failed deliveries are retried through the queue by
+// DeliverWebhookMessage itself, so only a failure in recording a delivery is reported as a failed message.
+func Handler(ctx context.Context, event events.SQSEvent) (sqs.BatchResponse, error) {
+	consumer, err := newConsumer()
+	if err != nil {
+		return sqs.BatchResponse{}, err
+	}
+
+	return consumer.HandleLambdaEvent(ctx, event)
+}
+
+func newConsumer() (*sqs.Consumer, error) {
+	dynamodbClient, err := dynamodb.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	sqsClient, err := sqs.NewSQSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := sqs.NewConsumer(sqsClient, sqs.ConsumerConfig{
+		QueueURL:           os.Getenv("WEBHOOK_QUEUE_URL"),
+		DeadLetterQueueURL: os.Getenv("WEBHOOK_DEAD_LETTER_QUEUE_URL"),
+		VisibilityTimeout:  webhookVisibilityTimeout,
+	})
+
+	consumer.HandleDefault(func(ctx context.Context, message *sqs.Message) error {
+		webhookMessage := models.WebhookMessage{}
+
+		if err := json.Unmarshal([]byte(message.Body), &webhookMessage); err != nil {
+			return fmt.Errorf("%w: invalid webhook message: %v", sqs.ErrPoisonMessage, err)
+		}
+
+		if err := helpers.DeliverWebhookMessage(dynamodbClient, sqsClient, &webhookMessage); err != nil {
+			log.Error().Msgf("Failure in delivering %s event for userId %s: %v", webhookMessage.Event.Type,
+				webhookMessage.Event.UserId, err)
+			return err
+		}
+		return nil
+	})
+
+	return consumer, nil
+}
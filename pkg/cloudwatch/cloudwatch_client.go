@@ -2,6 +2,7 @@ package helper
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -9,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -17,9 +19,13 @@ type cloudwatchAPI struct {
 	CW *cloudwatchlogs.Client
 }
 
+//go:generate mockgen -destination mocks/cloudwatch_client_mock.go -package mocks . CloudWatchClient
+
 type CloudWatchClient interface {
+	CreateLogGroup(groupName string) error
 	CreateLogStream(groupName, streamName string) error
 	PutLogEvents(groupName, streamName, nextSequenceToken, message string) (string, error)
+	PutLogEventBatch(groupName, streamName string, events []types.InputLogEvent) error
 }
 
 func NewCloudWatchClient() (CloudWatchClient, error) {
@@ -39,11 +45,19 @@ func NewCloudWatchClient() (CloudWatchClient, error) {
 		return cloudwatchAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	cwClient := cloudwatchlogs.NewFromConfig(cfg)
 	cloudwatchAPI.CW = cwClient
 	return cloudwatchAPI, nil
 }
 
+func (api *cloudwatchAPI) CreateLogGroup(groupName string) error {
+	_, err := api.CW.CreateLogGroup(context.TODO(), &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(groupName),
+	})
+	return err
+}
+
 func (api *cloudwatchAPI) CreateLogStream(groupName, streamName string) error {
 	createIn := cloudwatchlogs.CreateLogStreamInput{
 		LogGroupName:  aws.String(groupName),
@@ -85,6 +99,26 @@ func (api *cloudwatchAPI) PutLogEvents(groupName, streamName, nextSequenceToken,
 	return *output.NextSequenceToken, err
 }
 
+// PutLogEventBatch puts the events in the chronological order on the log stream, without the sequence token
+// which is no longer required by PutLogEvents.
+func (api *cloudwatchAPI) PutLogEventBatch(groupName, streamName string, events []types.InputLogEvent) error {
+	output, err := api.CW.PutLogEvents(context.TODO(), &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(groupName),
+		LogStreamName: aws.String(streamName),
+		LogEvents:     events,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rejected := output.RejectedLogEventsInfo; rejected != nil {
+		return fmt.Errorf("rejected log events too old before index %d, too new from index %d, expired before index %d",
+			aws.ToInt32(rejected.TooOldLogEventEndIndex), aws.ToInt32(rejected.TooNewLogEventStartIndex),
+			aws.ToInt32(rejected.ExpiredLogEventEndIndex))
+	}
+	return nil
+}
+
 func (api *cloudwatchAPI) getNextSequenceToken(groupName, streamName string) (string, error) {
 	describeIn := cloudwatchlogs.DescribeLogStreamsInput{
 		LogGroupName:        aws.String(groupName),
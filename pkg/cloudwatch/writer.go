@@ -0,0 +1,279 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const (
+	// MaxBatchEvents, MaxBatchBytes and MaxBatchSpan are the limits of a PutLogEvents request, where every event
+	// counts its message with EventOverheadBytes towards the bytes, and MaxEventBytes is the limit of an event.
+	MaxBatchEvents     = 10000
+	MaxBatchBytes      = 1048576
+	MaxBatchSpan       = 24 * time.Hour
+	MaxEventBytes      = 262144
+	EventOverheadBytes = 26
+
+	DefaultFlushInterval = 5 * time.Second
+	// MaxBufferBytes bounds the events kept for the retries of the failed batches, beyond which the oldest events
+	// are dropped.
+	MaxBufferBytes = 4 * MaxBatchBytes
+)
+
+var TimeNow = time.Now
+
+type WriterConfig struct {
+	GroupName  string
+	StreamName string
+	// FlushInterval flushes the buffered events periodically, while zero flushes them only at the size threshold.
+	FlushInterval time.Duration
+	// FlushBytes is the size of the buffered events which flushes the buffer, up to MaxBatchBytes by default.
+	FlushBytes int
+}
+
+// Writer buffers the log events and puts them on the log stream in batches, creating the log group and the log
+// stream when they are missing. Each write is a log event, which lets zerolog log straight to the log stream.
+// The failures of the writer are reported on stderr, since logging them could write to the writer again.
+// The batches failed by throttling or by the unavailable service are buffered again for the next flush.
+type Writer struct {
+	client CloudWatchClient
+	config WriterConfig
+
+	mutex       sync.Mutex
+	buffer      []types.InputLogEvent
+	bufferBytes int
+	// retryAt holds back the flushes at the size threshold after a failed flush, until the next flush interval.
+	retryAt time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewWriter(client CloudWatchClient, config WriterConfig) *Writer {
+	if config.FlushBytes <= 0 || config.FlushBytes > MaxBatchBytes {
+		config.FlushBytes = MaxBatchBytes
+	}
+
+	writer := &Writer{
+		client: client,
+		config: config,
+		done:   make(chan struct{}),
+	}
+
+	if config.FlushInterval > 0 {
+		writer.wg.Add(1)
+		go writer.flushPeriodically()
+	}
+	return writer
+}
+
+// Write buffers the message as a log event of the current time, without its trailing newline, and flushes
+// the buffer once it reaches the size threshold. The message beyond the event limit is truncated on a rune
+// boundary, as the log events must be valid UTF-8.
+func (w *Writer) Write(p []byte) (int, error) {
+	message := p
+	if length := len(message); length > 0 && message[length-1] == '\n' {
+		message = message[:length-1]
+	}
+	if len(message) == 0 {
+		return len(p), nil
+	}
+	if len(message) > MaxEventBytes-EventOverheadBytes {
+		message = truncateMessage(message, MaxEventBytes-EventOverheadBytes)
+	}
+
+	event := types.InputLogEvent{
+		// The message is copied, as zerolog reuses its buffers.
+		Message:   aws.String(string(message)),
+		Timestamp: aws.Int64(TimeNow().UnixMilli()),
+	}
+
+	w.mutex.Lock()
+	w.buffer = append(w.buffer, event)
+	w.bufferBytes += len(message) + EventOverheadBytes
+	w.dropOldestEvents()
+	full := (w.bufferBytes >= w.config.FlushBytes || len(w.buffer) >= MaxBatchEvents) && !TimeNow().Before(w.retryAt)
+	w.mutex.Unlock()
+
+	if full {
+		if err := w.Flush(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush puts all the buffered events on the log stream, in as many batches as the limits require. The batches
+// which failed with a retryable error are buffered again ahead of the events written meanwhile.
+func (w *Writer) Flush() error {
+	w.mutex.Lock()
+	events := w.buffer
+	w.buffer = nil
+	w.bufferBytes = 0
+	w.mutex.Unlock()
+
+	var errs error
+	var failed []types.InputLogEvent
+	for _, batch := range splitBatches(events) {
+		err := w.putLogEvents(batch)
+		if err != nil && isRetryable(err) {
+			failed = append(failed, batch...)
+		}
+		errs = errors.Join(errs, err)
+	}
+
+	if len(failed) > 0 {
+		w.rebuffer(failed)
+	}
+	return errs
+}
+
+// rebuffer puts the events of the failed batches back in the buffer, and holds back the flushes at the size
+// threshold until the next flush interval.
+func (w *Writer) rebuffer(events []types.InputLogEvent) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, event := range events {
+		w.bufferBytes += len(*event.Message) + EventOverheadBytes
+	}
+	w.buffer = append(events, w.buffer...)
+	w.dropOldestEvents()
+
+	retryDelay := w.config.FlushInterval
+	if retryDelay <= 0 {
+		retryDelay = DefaultFlushInterval
+	}
+	w.retryAt = TimeNow().Add(retryDelay)
+}
+
+// dropOldestEvents drops the oldest buffered events beyond MaxBufferBytes, which keeps the buffer bounded
+// while the log events cannot be put. It is called with the mutex held.
+func (w *Writer) dropOldestEvents() {
+	dropped := 0
+	for w.bufferBytes > MaxBufferBytes && dropped < len(w.buffer) {
+		w.bufferBytes -= len(*w.buffer[dropped].Message) + EventOverheadBytes
+		dropped++
+	}
+
+	if dropped > 0 {
+		w.buffer = w.buffer[dropped:]
+		fmt.Fprintf(os.Stderr, "Dropped %d log events of %s beyond the buffer limit\n", dropped, w.config.GroupName)
+	}
+}
+
+// Close stops the periodic flush and flushes the buffered events.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	w.wg.Wait()
+
+	return w.Flush()
+}
+
+func (w *Writer) flushPeriodically() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failure in flushing log events to %s: %v\n", w.config.GroupName, err)
+			}
+		}
+	}
+}
+
+// splitBatches sorts the events by their timestamps, as required within a batch, and splits them into batches
+// within the count, bytes and time span limits.
+func splitBatches(events []types.InputLogEvent) [][]types.InputLogEvent {
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	var batches [][]types.InputLogEvent
+	var batch []types.InputLogEvent
+	batchBytes := 0
+
+	for _, event := range events {
+		eventBytes := len(*event.Message) + EventOverheadBytes
+
+		if len(batch) > 0 && (len(batch) >= MaxBatchEvents || batchBytes+eventBytes > MaxBatchBytes ||
+			*event.Timestamp-*batch[0].Timestamp >= MaxBatchSpan.Milliseconds()) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+
+		batch = append(batch, event)
+		batchBytes += eventBytes
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// putLogEvents puts the batch on the log stream, and creates the missing log group and log stream before
+// putting the batch again.
+func (w *Writer) putLogEvents(batch []types.InputLogEvent) error {
+	err := w.client.PutLogEventBatch(w.config.GroupName, w.config.StreamName, batch)
+
+	var notFoundErr *types.ResourceNotFoundException
+	if !errors.As(err, &notFoundErr) {
+		return err
+	}
+
+	if err = w.client.CreateLogGroup(w.config.GroupName); err != nil && !isAlreadyExists(err) {
+		return err
+	}
+	if err = w.client.CreateLogStream(w.config.GroupName, w.config.StreamName); err != nil && !isAlreadyExists(err) {
+		return err
+	}
+
+	return w.client.PutLogEventBatch(w.config.GroupName, w.config.StreamName, batch)
+}
+
+// isRetryable reports whether the batch could be put later, after the throttling, the unavailable service or the
+// failure to send the request. The rejected events and the invalid requests fail the same way again.
+func isRetryable(err error) bool {
+	var unavailableErr *types.ServiceUnavailableException
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &unavailableErr) || errors.As(err, &sendErr) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "ThrottlingException" || apiErr.ErrorFault() == smithy.FaultServer)
+}
+
+// truncateMessage truncates the message to at most limit bytes, without splitting the UTF-8 sequence at the limit.
+func truncateMessage(message []byte, limit int) []byte {
+	for limit > 0 && !utf8.RuneStart(message[limit]) {
+		limit--
+	}
+	return message[:limit]
+}
+
+func isAlreadyExists(err error) bool {
+	var existsErr *types.ResourceAlreadyExistsException
+	return errors.As(err, &existsErr)
+}
@@ -0,0 +1,227 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/cloudwatch/mocks"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type WriterTestSuite struct {
+	suite.Suite
+
+	ctrl                 *gomock.Controller
+	mockCloudWatchClient *mocks.MockCloudWatchClient
+	mockTimeNow          time.Time
+}
+
+func TestWriterSuite(t *testing.T) {
+	suite.Run(t, new(WriterTestSuite))
+}
+
+func (s *WriterTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *WriterTestSuite) SetupTest() {
+	s.mockCloudWatchClient = mocks.NewMockCloudWatchClient(s.ctrl)
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func getMessages(events []types.InputLogEvent) []string {
+	messages := make([]string, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, *event.Message)
+	}
+	return messages
+}
+
+func (s *WriterTestSuite) TestFlushSortsEventsByTimestamp() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Any()).
+		DoAndReturn(func(groupName, streamName string, events []types.InputLogEvent) error {
+			s.Equal([]string{"first", "second", "third"}, getMessages(events))
+			return nil
+		})
+
+	for message, offset := range map[string]time.Duration{"third": time.Second, "first": -time.Second, "second": 0} {
+		TimeNow = func() time.Time {
+			return s.mockTimeNow.Add(offset)
+		}
+		_, err := writer.Write([]byte(message + "\n"))
+		s.NoError(err)
+	}
+	s.NoError(writer.Flush())
+	// Nothing is left to flush.
+	s.NoError(writer.Flush())
+}
+
+func (s *WriterTestSuite) TestWriteFlushesAtSizeThreshold() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream",
+		FlushBytes: 2 * (EventOverheadBytes + 5)})
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(2)).
+		Return(nil)
+
+	for _, message := range []string{"one-1", "two-2", "three"} {
+		n, err := writer.Write([]byte(message))
+		s.NoError(err)
+		s.Equal(len(message), n)
+	}
+}
+
+func (s *WriterTestSuite) TestSplitBatches() {
+	var events []types.InputLogEvent
+	for i := 0; i < MaxBatchEvents+1; i++ {
+		events = append(events, types.InputLogEvent{Message: aws.String("a"), Timestamp: aws.Int64(1)})
+	}
+	s.Len(splitBatches(events), 2)
+
+	large := strings.Repeat("a", MaxEventBytes-EventOverheadBytes)
+	events = nil
+	for i := 0; i < 5; i++ {
+		events = append(events, types.InputLogEvent{Message: aws.String(large), Timestamp: aws.Int64(1)})
+	}
+	batches := splitBatches(events)
+	s.Len(batches, 2)
+	s.Len(batches[0], 4)
+
+	start := s.mockTimeNow.UnixMilli()
+	batches = splitBatches([]types.InputLogEvent{
+		{Message: aws.String("a"), Timestamp: aws.Int64(start + MaxBatchSpan.Milliseconds())},
+		{Message: aws.String("b"), Timestamp: aws.Int64(start)},
+		{Message: aws.String("c"), Timestamp: aws.Int64(start + MaxBatchSpan.Milliseconds() - 1)},
+	})
+	s.Equal([][]string{{"b", "c"}, {"a"}}, [][]string{getMessages(batches[0]), getMessages(batches[1])})
+}
+
+func (s *WriterTestSuite) TestFlushCreatesMissingGroupAndStream() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	gomock.InOrder(
+		s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+			Return(&types.ResourceNotFoundException{}),
+		s.mockCloudWatchClient.EXPECT().CreateLogGroup(gomock.Eq("group")).
+			Return(&types.ResourceAlreadyExistsException{}),
+		s.mockCloudWatchClient.EXPECT().CreateLogStream(gomock.Eq("group"), gomock.Eq("stream")).Return(nil),
+		s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+			Return(nil),
+	)
+
+	_, err := writer.Write([]byte("message"))
+	s.NoError(err)
+	s.NoError(writer.Flush())
+}
+
+func (s *WriterTestSuite) TestFlushReturnsFailure() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+		Return(errors.New("ThrottlingException"))
+
+	_, err := writer.Write([]byte("message"))
+	s.NoError(err)
+	s.ErrorContains(writer.Flush(), "ThrottlingException")
+}
+
+func (s *WriterTestSuite) TestFlushRebuffersRetryableFailure() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream",
+		FlushBytes: EventOverheadBytes + len("first")})
+
+	gomock.InOrder(
+		s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+			Return(&types.ServiceUnavailableException{}),
+		s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Any()).
+			DoAndReturn(func(groupName, streamName string, events []types.InputLogEvent) error {
+				s.Equal([]string{"first", "second"}, getMessages(events))
+				return nil
+			}),
+	)
+
+	_, err := writer.Write([]byte("first"))
+	s.Error(err)
+
+	// The size threshold does not flush again until the next flush interval.
+	_, err = writer.Write([]byte("second"))
+	s.NoError(err)
+
+	s.NoError(writer.Flush())
+	s.NoError(writer.Flush())
+}
+
+func (s *WriterTestSuite) TestFlushDropsNonRetryableFailure() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+		Return(&types.InvalidParameterException{})
+
+	_, err := writer.Write([]byte("message"))
+	s.NoError(err)
+	s.Error(writer.Flush())
+	// The rejected batch is not put again.
+	s.NoError(writer.Flush())
+}
+
+func (s *WriterTestSuite) TestRebufferDropsOldestEvents() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	message := strings.Repeat("a", MaxEventBytes-EventOverheadBytes)
+	events := make([]types.InputLogEvent, 0, MaxBufferBytes/MaxEventBytes+1)
+	for i := 0; i < cap(events); i++ {
+		events = append(events, types.InputLogEvent{Message: aws.String(message), Timestamp: aws.Int64(int64(i))})
+	}
+	writer.rebuffer(events)
+
+	s.Len(writer.buffer, MaxBufferBytes/MaxEventBytes)
+	s.Equal(int64(1), *writer.buffer[0].Timestamp)
+	s.Equal(MaxBufferBytes, writer.bufferBytes)
+}
+
+func (s *WriterTestSuite) TestWriteTruncatesOnRuneBoundary() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream"})
+
+	// The two bytes rune straddles the event limit.
+	message := strings.Repeat("a", MaxEventBytes-EventOverheadBytes-1) + "é"
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Len(1)).
+		DoAndReturn(func(groupName, streamName string, events []types.InputLogEvent) error {
+			s.True(utf8.ValidString(*events[0].Message))
+			s.Equal(message[:len(message)-2], *events[0].Message)
+			return nil
+		})
+
+	_, err := writer.Write([]byte(message))
+	s.NoError(err)
+	s.NoError(writer.Flush())
+}
+
+func (s *WriterTestSuite) TestZerologWritesToWriter() {
+	writer := NewWriter(s.mockCloudWatchClient, WriterConfig{GroupName: "group", StreamName: "stream",
+		FlushInterval: time.Hour})
+
+	s.mockCloudWatchClient.EXPECT().PutLogEventBatch(gomock.Eq("group"), gomock.Eq("stream"), gomock.Any()).
+		DoAndReturn(func(groupName, streamName string, events []types.InputLogEvent) error {
+			s.Equal([]string{`{"level":"info","message":"first"}`, `{"level":"warn","message":"second"}`},
+				getMessages(events))
+			return nil
+		})
+
+	logger := zerolog.New(writer)
+	logger.Info().Msg("first")
+	logger.Warn().Msg("second")
+
+	s.NoError(writer.Close())
+}
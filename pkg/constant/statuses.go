@@ -7,4 +7,5 @@ const (
 	Failed          = "Failed"
 	Timeout         = "Timeout"
 	BookmarksLocked = "BookmarksLocked"
+	Cancelled       = "Cancelled"
 )
@@ -0,0 +1,355 @@
+// Package crawler fetches bookmarked pages on behalf of users while behaving as a polite crawler.
+// It honours robots.txt rules and crawl-delay, bounds the concurrency and request rate per host,
+// identifies itself with a fixed User-Agent and refuses to connect to non-public IP addresses,
+// which is enforced on the resolved address of every connection including redirects.
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/util"
+)
+
+const (
+	AgentToken = "EmproviseBot"
+	UserAgent  = AgentToken + "/1.0 (+https://emprovise.com/bot)"
+
+	robotsPath          = "/robots.txt"
+	maxRobotsBytes      = 500 * 1024
+	robotsFailureTTL    = 5 * time.Minute
+	dialTimeoutDuration = 5 * time.Second
+)
+
+var (
+	ErrBlockedAddress     = errors.New("connection to non-public address is not allowed")
+	ErrDisallowedByRobots = errors.New("url is disallowed by robots.txt")
+	ErrTooManyRedirects   = errors.New("too many redirects")
+	ErrUnsupportedScheme  = errors.New("only http and https urls can be fetched")
+)
+
+type Config struct {
+	Timeout         time.Duration
+	MaxRedirects    int
+	MaxBodyBytes    int64
+	MaxConnsPerHost int
+	MinHostInterval time.Duration
+	RobotsCacheTTL  time.Duration
+	// AllowPrivateNetworks disables the SSRF protection, it must only be used by tests against local servers.
+	AllowPrivateNetworks bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:         10 * time.Second,
+		MaxRedirects:    10,
+		MaxBodyBytes:    5 * 1024 * 1024,
+		MaxConnsPerHost: 2,
+		MinHostInterval: 500 * time.Millisecond,
+		RobotsCacheTTL:  time.Hour,
+	}
+}
+
+// Response is the fully read response of the final request after following all the redirects.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// URL is the final url after following the redirects.
+	URL string
+	// RedirectChain lists the urls which were redirected, in the order they were requested.
+	RedirectChain []string
+}
+
+type Crawler struct {
+	config       Config
+	client       *http.Client
+	robotsClient *http.Client
+	limiter      *hostLimiter
+
+	robotsMu    sync.Mutex
+	robotsCache map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	ready   chan struct{}
+	rules   *RobotsRules
+	err     error
+	expires time.Time
+}
+
+func New(config Config) *Crawler {
+	crawler := &Crawler{
+		config:      config,
+		limiter:     newHostLimiter(config.MaxConnsPerHost, config.MinHostInterval),
+		robotsCache: map[string]*robotsEntry{},
+	}
+
+	transport := NewTransport(config)
+
+	crawler.robotsClient = &http.Client{
+		Timeout:       config.Timeout,
+		Transport:     &userAgentTransport{transport},
+		CheckRedirect: crawler.checkRedirect,
+	}
+	crawler.client = &http.Client{
+		Timeout:       config.Timeout,
+		Transport:     &politeTransport{crawler: crawler, next: &userAgentTransport{transport}},
+		CheckRedirect: crawler.checkRedirect,
+	}
+	return crawler
+}
+
+// NewTransport returns the transport which refuses to connect to non-public addresses, unless the config allows
+// the private networks. It serves the clients which send requests to user provided urls without the crawler policies.
+func NewTransport(config Config) *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeoutDuration}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = checkDialAddress
+	}
+
+	return &http.Transport{
+		// Proxies from the environment would bypass the address check of the dialer.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		TLSHandshakeTimeout:   dialTimeoutDuration,
+		ResponseHeaderTimeout: config.Timeout,
+	}
+}
+
+// Client returns the http client applying the crawler policies to every request, for callers which
+// need to process the response body as a stream.
+func (c *Crawler) Client() *http.Client {
+	return c.client
+}
+
+// Fetch requests the url and reads the response body up to the configured size limit.
+func (c *Crawler) Fetch(ctx context.Context, method, rawURL string, header http.Header) (*Response, error) {
+	return c.do(ctx, method, rawURL, header, true)
+}
+
+// FetchHeaders requests the url without reading the response body, for checks which only need the status.
+func (c *Crawler) FetchHeaders(ctx context.Context, method, rawURL string, header http.Header) (*Response, error) {
+	return c.do(ctx, method, rawURL, header, false)
+}
+
+func (c *Crawler) do(ctx context.Context, method, rawURL string, header http.Header, readBody bool) (*Response, error) {
+	if err := validateScheme(rawURL); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, rawURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var body []byte
+	if readBody {
+		body, err = io.ReadAll(io.LimitReader(response.Body, c.config.MaxBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Response{
+		StatusCode:    response.StatusCode,
+		Header:        response.Header,
+		Body:          body,
+		URL:           response.Request.URL.String(),
+		RedirectChain: redirectChain(response.Request),
+	}, nil
+}
+
+func (c *Crawler) checkRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) > c.config.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, c.config.MaxRedirects)
+	}
+	return validateScheme(request.URL.String())
+}
+
+// robotsRules returns the cached robots.txt rules of the url host, fetching them at most once per cache period.
+func (c *Crawler) robotsRules(ctx context.Context, target *url.URL) (*RobotsRules, error) {
+	key := target.Scheme + "://" + target.Host
+
+	c.robotsMu.Lock()
+	entry, ok := c.robotsCache[key]
+	if !ok || (isClosed(entry.ready) && time.Now().After(entry.expires)) {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		c.robotsCache[key] = entry
+		c.robotsMu.Unlock()
+
+		entry.rules, entry.expires, entry.err = c.fetchRobots(ctx, key)
+		if entry.err != nil {
+			// Connection failures are not cached, so that the next request retries the host.
+			c.robotsMu.Lock()
+			delete(c.robotsCache, key)
+			c.robotsMu.Unlock()
+		}
+		close(entry.ready)
+		return entry.rules, entry.err
+	}
+	c.robotsMu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.rules, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Crawler) fetchRobots(ctx context.Context, hostURL string) (*RobotsRules, time.Time, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, hostURL+robotsPath, http.NoBody)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	release, err := c.limiter.Acquire(ctx, request.URL.Host, 0)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer release()
+
+	response, err := c.robotsClient.Do(request)
+	if err != nil {
+		// The host is unreachable, the failure is returned so that callers see the cause instead of a disallow.
+		return nil, time.Time{}, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		content, err := io.ReadAll(io.LimitReader(response.Body, maxRobotsBytes))
+		if err != nil {
+			return disallowAll(), time.Now().Add(robotsFailureTTL), nil
+		}
+		return ParseRobots(content, AgentToken), time.Now().Add(c.config.RobotsCacheTTL), nil
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		// A missing robots.txt places no restrictions on crawling.
+		return &RobotsRules{}, time.Now().Add(c.config.RobotsCacheTTL), nil
+	default:
+		return disallowAll(), time.Now().Add(robotsFailureTTL), nil
+	}
+}
+
+// politeTransport checks robots.txt and waits for the host limits before sending each request, including redirects.
+type politeTransport struct {
+	crawler *Crawler
+	next    http.RoundTripper
+}
+
+func (t *politeTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	rules, err := t.crawler.robotsRules(ctx, request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.Allowed(request.URL.RequestURI()) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, request.URL)
+	}
+
+	release, err := t.crawler.limiter.Acquire(ctx, request.URL.Host, rules.CrawlDelay())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// The host slot is held until the body is consumed, since the connection stays busy till then.
+	response.Body = &releaseOnClose{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+type userAgentTransport struct {
+	next http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", UserAgent)
+	return t.next.RoundTrip(request)
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+// checkDialAddress runs after DNS resolution for every connection, hence it also covers redirects and DNS rebinding.
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	addr, err := util.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !util.IsPublicIP(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+func validateScheme(rawURL string) error {
+	if err := util.ValidateURL(rawURL); err != nil {
+		return err
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if scheme := strings.ToLower(parsedURL.Scheme); scheme != "http" && scheme != "https" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedScheme, rawURL)
+	}
+	return nil
+}
+
+func redirectChain(request *http.Request) []string {
+	var chain []string
+	for request.Response != nil {
+		request = request.Response.Request
+		chain = append([]string{request.URL.String()}, chain...)
+	}
+	return chain
+}
+
+func disallowAll() *RobotsRules {
+	return &RobotsRules{rules: []robotsRule{{path: "/", allow: false}}}
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CrawlerTestSuite struct {
+	suite.Suite
+	server         *httptest.Server
+	robotsRequests atomic.Int32
+	userAgents     chan string
+}
+
+func TestCrawlerSuite(t *testing.T) {
+	suite.Run(t, new(CrawlerTestSuite))
+}
+
+func (s *CrawlerTestSuite) SetupTest() {
+	s.robotsRequests.Store(0)
+	s.userAgents = make(chan string, 100)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		s.robotsRequests.Add(1)
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		s.userAgents <- r.UserAgent()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html>page</html>"))
+	})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/to-private", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private/page", http.StatusFound)
+	})
+	s.server = httptest.NewServer(mux)
+}
+
+func (s *CrawlerTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *CrawlerTestSuite) newCrawler() *Crawler {
+	config := DefaultConfig()
+	config.MinHostInterval = 0
+	config.MaxRedirects = 3
+	config.AllowPrivateNetworks = true
+	return New(config)
+}
+
+func (s *CrawlerTestSuite) TestFetchFollowsRedirectsAndRecordsChain() {
+	response, err := s.newCrawler().Fetch(context.Background(), http.MethodGet, s.server.URL+"/short", nil)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, response.StatusCode)
+	s.Equal("<html>page</html>", string(response.Body))
+	s.Equal(s.server.URL+"/page", response.URL)
+	s.Equal([]string{s.server.URL + "/short", s.server.URL + "/moved"}, response.RedirectChain)
+	s.Equal(UserAgent, <-s.userAgents)
+}
+
+func (s *CrawlerTestSuite) TestFetchHeadersSkipsBody() {
+	response, err := s.newCrawler().FetchHeaders(context.Background(), http.MethodHead, s.server.URL+"/short", nil)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, response.StatusCode)
+	s.Empty(response.Body)
+	s.Equal(s.server.URL+"/page", response.URL)
+	s.Len(response.RedirectChain, 2)
+}
+
+func (s *CrawlerTestSuite) TestFetchCachesRobots() {
+	crawler := s.newCrawler()
+
+	for i := 0; i < 3; i++ {
+		_, err := crawler.Fetch(context.Background(), http.MethodGet, s.server.URL+"/page", nil)
+		s.NoError(err)
+	}
+	s.EqualValues(1, s.robotsRequests.Load())
+}
+
+func (s *CrawlerTestSuite) TestFetchDisallowedByRobots() {
+	crawler := s.newCrawler()
+
+	_, err := crawler.Fetch(context.Background(), http.MethodGet, s.server.URL+"/private/page", nil)
+	s.ErrorIs(err, ErrDisallowedByRobots)
+
+	// Redirect targets are checked against robots.txt as well.
+	_, err = crawler.Fetch(context.Background(), http.MethodGet, s.server.URL+"/to-private", nil)
+	s.ErrorIs(err, ErrDisallowedByRobots)
+}
+
+func (s *CrawlerTestSuite) TestFetchStopsRedirectLoop() {
+	_, err := s.newCrawler().Fetch(context.Background(), http.MethodGet, s.server.URL+"/loop", nil)
+	s.ErrorIs(err, ErrTooManyRedirects)
+}
+
+func (s *CrawlerTestSuite) TestFetchRefusesPrivateAddresses() {
+	config := DefaultConfig()
+	crawler := New(config)
+
+	_, err := crawler.Fetch(context.Background(), http.MethodGet, s.server.URL+"/page", nil)
+	s.ErrorIs(err, ErrBlockedAddress)
+
+	_, err = crawler.Fetch(context.Background(), http.MethodGet, "http://169.254.169.254/latest/meta-data/", nil)
+	s.ErrorIs(err, ErrBlockedAddress)
+}
+
+func (s *CrawlerTestSuite) TestFetchRefusesUnsupportedScheme() {
+	_, err := s.newCrawler().Fetch(context.Background(), http.MethodGet, "ftp://example.com/file", nil)
+	s.ErrorIs(err, ErrUnsupportedScheme)
+}
+
+func (s *CrawlerTestSuite) TestHostLimiterSpacesRequests() {
+	limiter := newHostLimiter(1, 50*time.Millisecond)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.com", 0)
+		s.NoError(err)
+		release()
+	}
+	s.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+
+	release, err := limiter.Acquire(context.Background(), "example.com", 0)
+	s.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "example.com", 0)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	release()
+}
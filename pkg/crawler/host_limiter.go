@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter bounds the number of in-flight requests per host and spaces out consecutive requests
+// to the same host by at least the configured interval, or the robots.txt crawl-delay when larger.
+type hostLimiter struct {
+	maxConns    int
+	minInterval time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots       chan struct{}
+	nextRequest time.Time
+}
+
+func newHostLimiter(maxConns int, minInterval time.Duration) *hostLimiter {
+	if maxConns <= 0 {
+		maxConns = 1
+	}
+	return &hostLimiter{
+		maxConns:    maxConns,
+		minInterval: minInterval,
+		hosts:       map[string]*hostState{},
+	}
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{slots: make(chan struct{}, l.maxConns)}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// Acquire blocks until a request to the host is permitted and returns the function releasing the slot.
+func (l *hostLimiter) Acquire(ctx context.Context, host string, crawlDelay time.Duration) (func(), error) {
+	state := l.state(host)
+
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-state.slots }
+
+	interval := l.minInterval
+	if crawlDelay > interval {
+		interval = crawlDelay
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := state.nextRequest
+	if start.Before(now) {
+		start = now
+	}
+	state.nextRequest = start.Add(interval)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type robotsRule struct {
+	path  string
+	allow bool
+}
+
+// RobotsRules are the robots.txt directives which apply to our user agent.
+type RobotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses the robots.txt content and selects the group matching the user agent product token,
+// falling back to the "*" group. Rules of every group naming the agent are merged as per RFC 9309.
+func ParseRobots(content []byte, agentToken string) *RobotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgentLines := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgentLines {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgentLines = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgentLines = false
+			// An empty disallow matches nothing, hence it is same as not having the rule.
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{path: value, allow: key == "allow"})
+		case "crawl-delay":
+			inAgentLines = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	agentToken = strings.ToLower(agentToken)
+	matched := &RobotsRules{}
+	wildcard := &RobotsRules{}
+	agentMatched := false
+
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			target := wildcard
+			if agent != "*" {
+				if !strings.Contains(agentToken, agent) {
+					continue
+				}
+				target = matched
+				agentMatched = true
+			}
+			target.rules = append(target.rules, group.rules...)
+			if group.crawlDelay > target.crawlDelay {
+				target.crawlDelay = group.crawlDelay
+			}
+			break
+		}
+	}
+
+	if agentMatched {
+		return matched
+	}
+	return wildcard
+}
+
+// Allowed reports whether the path (including the query) may be crawled.
+// The most specific, i.e. the longest matching rule wins and allow wins a tie.
+func (r *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == robotsPath {
+		return true
+	}
+
+	allowed := true
+	longestMatch := -1
+
+	for _, rule := range r.rules {
+		if !matchRobotsPath(rule.path, path) {
+			continue
+		}
+		if len(rule.path) > longestMatch || (len(rule.path) == longestMatch && rule.allow) {
+			longestMatch = len(rule.path)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the delay requested between consecutive requests to the host.
+func (r *RobotsRules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// matchRobotsPath matches the path against the rule pattern which supports "*" wildcards and a "$" end anchor.
+func matchRobotsPath(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	position := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		// The last part of an anchored pattern must match the end of the path, not its first occurrence.
+		if anchored && i == len(parts)-1 {
+			return strings.HasSuffix(path[position:], parts[i])
+		}
+		index := strings.Index(path[position:], parts[i])
+		if index < 0 {
+			return false
+		}
+		position += index + len(parts[i])
+	}
+
+	return !anchored || position == len(path)
+}
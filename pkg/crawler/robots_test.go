@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RobotsTestSuite struct {
+	suite.Suite
+}
+
+func TestRobotsSuite(t *testing.T) {
+	suite.Run(t, new(RobotsTestSuite))
+}
+
+func (s *RobotsTestSuite) TestParseRobotsSelectsAgentGroup() {
+	content := []byte(`
+# comments are ignored
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: EmproviseBot
+Disallow: /private/
+Allow: /private/shared
+Crawl-delay: 2.5
+`)
+
+	rules := ParseRobots(content, AgentToken)
+
+	s.Equal(2500*time.Millisecond, rules.CrawlDelay())
+	s.True(rules.Allowed("/"))
+	s.True(rules.Allowed("/private"))
+	s.False(rules.Allowed("/private/notes.html"))
+	s.True(rules.Allowed("/private/shared/notes.html"))
+}
+
+func (s *RobotsTestSuite) TestParseRobotsFallsBackToWildcardGroup() {
+	content := []byte(`
+User-agent: Googlebot
+Disallow:
+
+User-agent: *
+Disallow: /search
+Crawl-delay: 1
+`)
+
+	rules := ParseRobots(content, AgentToken)
+
+	s.Equal(time.Second, rules.CrawlDelay())
+	s.False(rules.Allowed("/search?q=karpenter"))
+	s.True(rules.Allowed("/docs"))
+	s.True(rules.Allowed("/robots.txt"))
+}
+
+func (s *RobotsTestSuite) TestAllowedWithWildcardsAndAnchors() {
+	rules := ParseRobots([]byte(`
+User-agent: *
+Disallow: /*.pdf$
+Disallow: /tmp/*/cache
+Allow: /$
+Disallow: /
+`), AgentToken)
+
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{"/", true},
+		{"/index.html", false},
+	}
+	for _, testCase := range testCases {
+		s.Equal(testCase.expected, rules.Allowed(testCase.path), testCase.path)
+	}
+
+	rules = ParseRobots([]byte(`
+User-agent: *
+Disallow: /*.pdf$
+Disallow: /tmp/*/cache
+`), AgentToken)
+
+	testCases = []struct {
+		path     string
+		expected bool
+	}{
+		{"/docs/guide.pdf", false},
+		{"/docs/guide.pdf?download=1", true},
+		{"/docs/guide.pdf.html", true},
+		{"/tmp/user/cache/page", false},
+		{"/tmp/cache", true},
+	}
+	for _, testCase := range testCases {
+		s.Equal(testCase.expected, rules.Allowed(testCase.path), testCase.path)
+	}
+}
+
+func (s *RobotsTestSuite) TestParseRobotsWithEmptyContent() {
+	rules := ParseRobots(nil, AgentToken)
+
+	s.True(rules.Allowed("/anything"))
+	s.Zero(rules.CrawlDelay())
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
 	"github.com/pranav-patil/go-serverless-api/pkg/util"
@@ -44,6 +45,9 @@ type DynamoDBClient interface {
 	GetRecordsByPagination(entity model.Entity, pageLimit int32,
 		lastEvaluatedKey map[string]types.AttributeValue,
 		scanIndexForward bool) (interface{}, map[string]types.AttributeValue, error)
+	GetRecordsByKeyConditionPagination(entity model.Entity, keyCondition expression.KeyConditionBuilder,
+		filter *expression.ConditionBuilder, pageLimit int32, lastEvaluatedKey map[string]types.AttributeValue,
+		scanIndexForward bool) (interface{}, map[string]types.AttributeValue, error)
 	UpdateRecordsByKey(entity model.Entity) error
 	UpdateRecordsByParams(entity model.Entity, queryParams map[string]interface{}) error
 	UpdateRecordsByExpression(entity model.Entity, expr expression.Expression) error
@@ -52,6 +56,22 @@ type DynamoDBClient interface {
 	DeleteRecordByKeyAndExpression(entity model.Entity, condExp *expression.ConditionBuilder) error
 	DeleteBatchRecords(entity model.Entity, inputFilter *expression.ConditionBuilder) (int, error)
 	DeleteTable(tableName string) error
+	TransactWriteRecords(items []model.TransactWriteItem) error
+}
+
+// TransactAdd adds the entity within the transaction, like AddRecord.
+func TransactAdd(entity model.Entity) model.TransactWriteItem {
+	return model.TransactWriteItem{Entity: entity}
+}
+
+// TransactUpdate updates the existing record of the entity within the transaction, like UpdateRecordsByKey.
+func TransactUpdate(entity model.Entity) model.TransactWriteItem {
+	return model.TransactWriteItem{Entity: entity, Update: true}
+}
+
+// TransactUpdateByExpression updates the record of the entity within the transaction, like UpdateRecordsByExpression.
+func TransactUpdateByExpression(entity model.Entity, expr expression.Expression) model.TransactWriteItem {
+	return model.TransactWriteItem{Entity: entity, Update: true, Expression: &expr}
 }
 
 type dynamodbAPI struct {
@@ -59,9 +79,10 @@ type dynamodbAPI struct {
 }
 
 const (
-	retryAttempt   int64 = 100
-	maxBatchSize   int   = 25
-	maxConcurrency int   = 40
+	retryAttempt       int64 = 100
+	maxBatchSize       int   = 25
+	maxTransactionSize int   = 100
+	maxConcurrency     int   = 40
 )
 
 func NewDynamoDBClient() (DynamoDBClient, error) {
@@ -81,6 +102,7 @@ func NewDynamoDBClient() (DynamoDBClient, error) {
 		return dynamodbAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	dynamodbAPI.DynamoDB = dynamodb.NewFromConfig(cfg)
 	return dynamodbAPI, nil
 }
@@ -378,15 +400,46 @@ func (api *dynamodbAPI) GetRecordsByPagination(entity model.Entity, pageLimit in
 		ConsistentRead:            aws.Bool(false),
 	}
 
-	if lastEvalKey != nil {
-		queryInput.ExclusiveStartKey = lastEvalKey
+	return api.queryPages(entity, queryInput, pageLimit, lastEvalKey)
+}
+
+// GetRecordsByKeyConditionPagination queries a page of records matching the key condition and the optional filter.
+// The filter is applied after reading, hence the query continues reading until the page is full or the records end.
+func (api *dynamodbAPI) GetRecordsByKeyConditionPagination(entity model.Entity, keyCondition expression.KeyConditionBuilder,
+	filter *expression.ConditionBuilder, pageLimit int32, lastEvalKey map[string]types.AttributeValue,
+	scanIndexForward bool) (result interface{}, lastKey map[string]types.AttributeValue, err error) {
+	exprBuilder := expression.NewBuilder().WithKeyCondition(keyCondition)
+	if filter != nil {
+		exprBuilder = exprBuilder.WithFilter(*filter)
 	}
 
-	p := dynamodb.NewQueryPaginator(api.DynamoDB, queryInput)
+	expr, err := exprBuilder.Build()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	queryInput := &dynamodb.QueryInput{
+		TableName:                 aws.String(entity.GetTableName()),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		Limit:                     aws.Int32(pageLimit),
+		ScanIndexForward:          aws.Bool(scanIndexForward),
+		ConsistentRead:            aws.Bool(false),
+	}
+
+	return api.queryPages(entity, queryInput, pageLimit, lastEvalKey)
+}
+
+func (api *dynamodbAPI) queryPages(entity model.Entity, queryInput *dynamodb.QueryInput, pageLimit int32,
+	lastEvalKey map[string]types.AttributeValue) (result interface{}, lastKey map[string]types.AttributeValue, err error) {
+	if lastEvalKey != nil {
+		queryInput.ExclusiveStartKey = lastEvalKey
+	}
+
+	p := dynamodb.NewQueryPaginator(api.DynamoDB, queryInput)
+
 	var collectiveResult []map[string]types.AttributeValue
 	ctxt := context.TODO()
 	var singlePage *dynamodb.QueryOutput
@@ -415,33 +468,51 @@ func (api *dynamodbAPI) GetRecordsByPagination(entity model.Entity, pageLimit in
 }
 
 func (api *dynamodbAPI) UpdateRecordsByKey(entity model.Entity) error {
+	updateExpr, err := buildUpdateByKeyExpression(entity)
+	if err != nil {
+		return err
+	}
+	return api.UpdateRecordsByExpression(entity, updateExpr)
+}
+
+// buildUpdateByKeyExpression builds the update of the field values of the entity, on the condition that the record
+// of its keys exists.
+func buildUpdateByKeyExpression(entity model.Entity) (expression.Expression, error) {
 	queryParams := make(map[string]interface{})
 
 	partitionKey, err := getKeyValue(entity, model.PartitionKeyTag)
 	if err != nil {
-		return err
+		return expression.Expression{}, err
 	}
 	queryParams["PK"] = partitionKey
 
 	sortKey, err := getKeyValue(entity, model.SortKeyTag)
 	if err != nil {
-		return err
+		return expression.Expression{}, err
 	}
 	if sortKey != "" {
 		queryParams["SK"] = sortKey
 	}
 
-	return api.UpdateRecordsByParams(entity, queryParams)
+	return buildUpdateByParamsExpression(entity, queryParams)
 }
 
 // The records will be updated using the values of fields within the Entity, except the
 // Partition key & Sort key and the corresponding attributes which form the keys.
 // The Query Parameters is used to filter records based on additional criteria.
 func (api *dynamodbAPI) UpdateRecordsByParams(entity model.Entity, queryParams map[string]interface{}) error {
-	updateMap, err := util.StructToMap(entity, model.DynamoDBTag, false, model.PartitionKeyTag, model.SortKeyTag)
+	updateExpr, err := buildUpdateByParamsExpression(entity, queryParams)
 	if err != nil {
 		return err
 	}
+	return api.UpdateRecordsByExpression(entity, updateExpr)
+}
+
+func buildUpdateByParamsExpression(entity model.Entity, queryParams map[string]interface{}) (expression.Expression, error) {
+	updateMap, err := util.StructToMap(entity, model.DynamoDBTag, false, model.PartitionKeyTag, model.SortKeyTag)
+	if err != nil {
+		return expression.Expression{}, err
+	}
 	delete(updateMap, "PK")
 	delete(updateMap, "SK")
 
@@ -451,12 +522,7 @@ func (api *dynamodbAPI) UpdateRecordsByParams(entity model.Entity, queryParams m
 		exprBuilder = exprBuilder.WithCondition(GenConditionBuilder(queryParams))
 	}
 
-	updateExpr, err := exprBuilder.Build()
-	if err != nil {
-		return err
-	}
-
-	return api.UpdateRecordsByExpression(entity, updateExpr)
+	return exprBuilder.Build()
 }
 
 func (api *dynamodbAPI) UpdateRecordsByExpression(entity model.Entity, expr expression.Expression) error {
@@ -472,6 +538,65 @@ func (api *dynamodbAPI) UpdateRecordsByExpression(entity model.Entity, expr expr
 	return err
 }
 
+// TransactWriteRecords writes all the items or none of them. The transaction is cancelled when the condition of
+// any of the updates fails, which is reported by IsConditionalCheckFailed.
+func (api *dynamodbAPI) TransactWriteRecords(items []model.TransactWriteItem) error {
+	if len(items) == 0 || len(items) > maxTransactionSize {
+		return fmt.Errorf("transaction must have 1 to %d items, found %d items", maxTransactionSize, len(items))
+	}
+
+	transactItems := make([]types.TransactWriteItem, 0, len(items))
+	for _, item := range items {
+		transactItem, err := buildTransactWriteItem(item)
+		if err != nil {
+			return err
+		}
+		transactItems = append(transactItems, transactItem)
+	}
+
+	_, err := api.DynamoDB.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	return err
+}
+
+func buildTransactWriteItem(item model.TransactWriteItem) (types.TransactWriteItem, error) {
+	if !item.Update {
+		if err := loadEntityKeys(item.Entity); err != nil {
+			return types.TransactWriteItem{}, err
+		}
+
+		record, err := attributevalue.MarshalMap(item.Entity)
+		if err != nil {
+			return types.TransactWriteItem{}, err
+		}
+
+		return types.TransactWriteItem{Put: &types.Put{
+			TableName: aws.String(item.Entity.GetTableName()),
+			Item:      record,
+		}}, nil
+	}
+
+	var updateExpr expression.Expression
+	if item.Expression != nil {
+		updateExpr = *item.Expression
+	} else {
+		var err error
+		if updateExpr, err = buildUpdateByKeyExpression(item.Entity); err != nil {
+			return types.TransactWriteItem{}, err
+		}
+	}
+
+	return types.TransactWriteItem{Update: &types.Update{
+		TableName:                 aws.String(item.Entity.GetTableName()),
+		Key:                       getKeys(item.Entity),
+		ConditionExpression:       updateExpr.Condition(),
+		UpdateExpression:          updateExpr.Update(),
+		ExpressionAttributeNames:  updateExpr.Names(),
+		ExpressionAttributeValues: updateExpr.Values(),
+	}}, nil
+}
+
 // Deletes single record with keys.
 func (api *dynamodbAPI) DeleteRecordByKey(entity model.Entity) error {
 	_, err := api.DynamoDB.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
@@ -543,9 +668,14 @@ func (api *dynamodbAPI) DeleteBatchRecords(entity model.Entity, inputFilter *exp
 			return 0, err
 		}
 
+		// The filtered pages can be empty, which are not written as a batch cannot be empty
+		if len(result.Items) == 0 {
+			continue
+		}
+
 		var writeReqs []types.WriteRequest
 		tableName := entity.GetTableName()
-		count = int(result.Count)
+		count += int(result.Count)
 
 		for _, item := range result.Items {
 			writeReqs = append(writeReqs, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: item}})
@@ -664,6 +794,18 @@ func getKeyValue(entity model.Entity, keyTag string) (string, error) {
 	return key, nil
 }
 
+// GetEntityKeys returns the partition key and the sort key values of the entity, as stored in the PK and SK fields.
+// The sort key is empty when none of the sort key fields are set.
+func GetEntityKeys(entity model.Entity) (partitionKey, sortKey string, err error) {
+	partitionKey, err = getKeyValue(entity, model.PartitionKeyTag)
+	if err != nil {
+		return "", "", err
+	}
+
+	sortKey, err = getKeyValue(entity, model.SortKeyTag)
+	return partitionKey, sortKey, err
+}
+
 func loadEntityKeys(entity model.Entity) error {
 	partitionKey, err := getKeyValue(entity, model.PartitionKeyTag)
 	if err != nil {
@@ -744,3 +886,22 @@ func (api *dynamodbAPI) processEntityBatch(batchSlice []model.Entity) (err error
 	}
 	return err
 }
+
+// IsConditionalCheckFailed reports whether the write was rejected by its condition expression,
+// which means the record was changed after it was read.
+func IsConditionalCheckFailed(err error) bool {
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailed) {
+		return true
+	}
+
+	var transactionCanceled *types.TransactionCanceledException
+	if errors.As(err, &transactionCanceled) {
+		for _, reason := range transactionCanceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -264,6 +264,75 @@ func (s *DynamoDBClientTestSuite) TestUpdateRecordsByKey() {
 	s.NoError(err)
 }
 
+func (s *DynamoDBClientTestSuite) TestTransactWriteRecords() {
+	var input *dynamodb.TransactWriteItemsInput
+	s.mockDynamoDBClient.EXPECT().TransactWriteItems(context.TODO(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params *dynamodb.TransactWriteItemsInput,
+			_ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			input = params
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	distribution := &model.UserBookmarks{UserId: "12900", LatestVersion: "1.0.67"}
+	statusUpdate, err := expression.NewBuilder().
+		WithUpdate(expression.Set(expression.Name("status"), expression.Value(constant.Timeout))).Build()
+	s.NoError(err)
+
+	err = s.api.TransactWriteRecords([]model.TransactWriteItem{
+		TransactUpdate(distribution),
+		TransactAdd(appDistribution),
+		TransactUpdateByExpression(appDistribution, statusUpdate),
+	})
+
+	s.NoError(err)
+	s.Len(input.TransactItems, 3)
+	s.Equal("UID#12900", input.TransactItems[0].Update.Key["PK"].(*types.AttributeValueMemberS).Value)
+	s.NotNil(input.TransactItems[0].Update.ConditionExpression)
+	s.Equal("UID#23434", input.TransactItems[1].Put.Item["PK"].(*types.AttributeValueMemberS).Value)
+	s.Nil(input.TransactItems[2].Update.ConditionExpression)
+
+	s.Error(s.api.TransactWriteRecords(nil))
+}
+
+func (s *DynamoDBClientTestSuite) TestIsConditionalCheckFailed() {
+	s.True(IsConditionalCheckFailed(&types.ConditionalCheckFailedException{}))
+	s.True(IsConditionalCheckFailed(fmt.Errorf("update: %w", &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")}},
+	})))
+	s.False(IsConditionalCheckFailed(&types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{{Code: aws.String("TransactionConflict")}},
+	}))
+	s.False(IsConditionalCheckFailed(nil))
+}
+
+func (s *DynamoDBClientTestSuite) TestDeleteBatchRecords() {
+	item := map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "UID#23434"},
+		"SK": &types.AttributeValueMemberS{Value: "DID#1"}}
+
+	gomock.InOrder(
+		s.mockDynamoDBClient.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&dynamodb.ScanOutput{Count: 0, LastEvaluatedKey: item}, nil),
+		s.mockDynamoDBClient.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&dynamodb.ScanOutput{Count: 2, Items: []map[string]types.AttributeValue{item, item}}, nil),
+	)
+
+	var input *dynamodb.BatchWriteItemInput
+	s.mockDynamoDBClient.EXPECT().BatchWriteItem(context.TODO(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params *dynamodb.BatchWriteItemInput,
+			_ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			input = params
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		})
+
+	count, err := s.api.DeleteBatchRecords(&model.BookmarkDistribution{UserId: "23434"}, nil)
+
+	// The empty page of the scan is skipped and the deleted records of all pages are counted.
+	s.NoError(err)
+	s.Equal(2, count)
+	s.Len(input.RequestItems[appDistribution.GetTableName()], 2)
+}
+
 func (s *DynamoDBClientTestSuite) TestDeleteTable() {
 	tableName := MockDeviceDistributionTableName
 
@@ -324,6 +393,17 @@ func (s *DynamoDBClientTestSuite) TestGetKeyValue() {
 			model.SortKeyTag,
 			"DID#44364564",
 		},
+
+		{"Device Distribution entity of operation",
+			&model.BookmarkDistribution{
+				UserId:      "1",
+				DeviceId:    "44364564",
+				OperationId: "20091117203458",
+				Status:      constant.Success,
+			},
+			model.SortKeyTag,
+			"DID#44364564#OID#20091117203458",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -400,3 +480,82 @@ func (s *DynamoDBClientTestSuite) TestLoadKeysAndConvertToMap() {
 		})
 	}
 }
+
+func (s *DynamoDBClientTestSuite) TestGetRecordsByKeyConditionPagination() {
+	firstPage := &dynamodb.QueryOutput{
+		Count: 1,
+		Items: []map[string]types.AttributeValue{
+			{
+				"PK":       &types.AttributeValueMemberS{Value: "UID#1"},
+				"SK":       &types.AttributeValueMemberS{Value: "DID#42#OID#20091110235234"},
+				"deviceId": &types.AttributeValueMemberS{Value: "42"},
+			},
+		},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: "UID#1"},
+			"SK": &types.AttributeValueMemberS{Value: "DID#42#OID#20091110235234"},
+		},
+	}
+	secondPage := &dynamodb.QueryOutput{
+		Count: 2,
+		Items: []map[string]types.AttributeValue{
+			{
+				"PK":       &types.AttributeValueMemberS{Value: "UID#1"},
+				"SK":       &types.AttributeValueMemberS{Value: "DID#42#OID#20091111235234"},
+				"deviceId": &types.AttributeValueMemberS{Value: "42"},
+			},
+			{
+				"PK":       &types.AttributeValueMemberS{Value: "UID#1"},
+				"SK":       &types.AttributeValueMemberS{Value: "DID#42#OID#20091112235234"},
+				"deviceId": &types.AttributeValueMemberS{Value: "42"},
+			},
+		},
+	}
+
+	var queryInputs []*dynamodb.QueryInput
+	s.mockDynamoDBClient.EXPECT().Query(gomock.Any(), gomock.AssignableToTypeOf(&dynamodb.QueryInput{}), gomock.Any()).
+		DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			queryInputs = append(queryInputs, input)
+			if len(queryInputs) == 1 {
+				return firstPage, nil
+			}
+			return secondPage, nil
+		}).Times(2)
+
+	keyCondition := expression.Key("PK").Equal(expression.Value("UID#1")).
+		And(expression.Key("SK").BeginsWith("DID#42#"))
+	filter := expression.Name("operationId").GreaterThanEqual(expression.Value("20091111000000"))
+
+	result, lastKey, err := s.api.GetRecordsByKeyConditionPagination(&model.BookmarkDistribution{}, keyCondition,
+		&filter, 2, nil, true)
+	outRows := result.([]model.BookmarkDistribution)
+
+	s.NoError(err)
+	s.Equal(2, len(outRows))
+	s.Equal("DID#42#OID#20091111235234", outRows[1].SK)
+	s.Equal(&types.AttributeValueMemberS{Value: "DID#42#OID#20091111235234"}, lastKey["SK"])
+	s.NotNil(queryInputs[0].FilterExpression)
+	s.Equal(firstPage.LastEvaluatedKey, queryInputs[1].ExclusiveStartKey)
+}
+
+func (s *DynamoDBClientTestSuite) TestPageToken() {
+	lastKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "UID#1"},
+		"SK": &types.AttributeValueMemberS{Value: "DID#42#OID#20091110235234"},
+	}
+
+	token, err := EncodePageToken(lastKey)
+	s.NoError(err)
+	s.NotEmpty(token)
+
+	decodedKey, err := DecodePageToken(token)
+	s.NoError(err)
+	s.Equal(lastKey, decodedKey)
+
+	token, err = EncodePageToken(nil)
+	s.NoError(err)
+	s.Empty(token)
+
+	_, err = DecodePageToken("not-a-token")
+	s.ErrorIs(err, ErrInvalidPageToken)
+}
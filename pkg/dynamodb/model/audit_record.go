@@ -0,0 +1,45 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultAuditTableName = "audit_log"
+
+// AuditRecord is a change made to the bookmarks of the user, which is queried by the account admins.
+// The records of the user are ordered by the record ids which start with the time of the change.
+type AuditRecord struct {
+	PK            string    `dynamodbav:"PK"`
+	SK            string    `dynamodbav:"SK"`
+	UserId        string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	RecordId      string    `dynamodbav:"recordId,omitempty" sortKey:"RID"`
+	Actor         string    `dynamodbav:"actor,omitempty"` // Principal which made the change
+	Role          string    `dynamodbav:"role,omitempty"`
+	Action        string    `dynamodbav:"action,omitempty"`
+	List          string    `dynamodbav:"list,omitempty"` // Bookmark list changed by the action
+	VersionBefore string    `dynamodbav:"versionBefore,omitempty"`
+	VersionAfter  string    `dynamodbav:"versionAfter,omitempty"`
+	RequestId     string    `dynamodbav:"requestId,omitempty"`
+	SourceIP      string    `dynamodbav:"sourceIp,omitempty"`
+	Timestamp     time.Time `dynamodbav:"timestamp,omitempty"`
+	Ttl           int64     `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the record is expired
+}
+
+func (record *AuditRecord) GetTableName() string {
+	tableName := os.Getenv("AUDIT_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultAuditTableName
+	}
+
+	return tableName
+}
+
+func (record *AuditRecord) String() string {
+	return fmt.Sprintf("UserId: %v\n\tRecordId: %v\n\tActor: %v\n\tAction: %v\n",
+		record.UserId, record.RecordId, record.Actor, record.Action)
+}
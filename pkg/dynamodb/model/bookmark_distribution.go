@@ -15,14 +15,22 @@ type BookmarkDistributionEntity interface {
 var defaultAppDistTableName = "bookmark_distribution"
 
 type BookmarkDistribution struct {
-	PK             string    `dynamodbav:"PK"`
-	SK             string    `dynamodbav:"SK"`
-	UserId         string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
-	DeviceId       string    `dynamodbav:"deviceId,omitempty" sortKey:"DID"`
-	Status         string    `dynamodbav:"status,omitempty"`        // Pending, Failed, Success
-	StatusMessage  string    `dynamodbav:"statusMessage,omitempty"` // Download bookmarks, enable policy, UDM load
-	StartTimestamp time.Time `dynamodbav:"startTs,omitempty"`
-	EndTimestamp   time.Time `dynamodbav:"endTs"`
+	PK              string    `dynamodbav:"PK"`
+	SK              string    `dynamodbav:"SK"`
+	UserId          string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	DeviceId        string    `dynamodbav:"deviceId,omitempty" sortKey:"DID"`
+	OperationId     string    `dynamodbav:"operationId,omitempty" sortKey:"OID"`
+	RetryOf         string    `dynamodbav:"retryOf,omitempty"`       // Operation whose failed distribution is retried
+	Status          string    `dynamodbav:"status,omitempty"`        // Pending, Failed, Success, Cancelled
+	StatusMessage   string    `dynamodbav:"statusMessage,omitempty"` // Download bookmarks, enable policy, UDM load
+	StartTimestamp  time.Time `dynamodbav:"startTs,omitempty"`
+	EndTimestamp    time.Time `dynamodbav:"endTs"`
+	ExecutionName   string    `dynamodbav:"executionName,omitempty"`
+	Version         string    `dynamodbav:"version,omitempty"`
+	BaseVersion     string    `dynamodbav:"baseVersion,omitempty"` // Version the delta package applies to
+	PackageFormat   string    `dynamodbav:"packageFormat,omitempty"`
+	PackageChecksum string    `dynamodbav:"packageChecksum,omitempty"` // Full package of the version, base of the deltas
+	Ttl             int64     `dynamodbav:"Ttl,omitempty"`             // Epoch seconds after which the record is expired
 }
 
 func (distrib *BookmarkDistribution) GetTableName() string {
@@ -36,8 +44,9 @@ func (distrib *BookmarkDistribution) GetTableName() string {
 }
 
 func (distrib *BookmarkDistribution) String() string {
-	return fmt.Sprintf("Status: %v\n\tStatusMessage: %v\n\tStartTs: %v\n\tEndTs: %v\n\tUserId: %v\n\tDeviceId: %v\n",
+	return fmt.Sprintf(
+		"Status: %v\n\tStatusMessage: %v\n\tStartTs: %v\n\tEndTs: %v\n\tUserId: %v\n\tDeviceId: %v\n\tOperationId: %v\n",
 		distrib.Status, distrib.StatusMessage,
 		distrib.StartTimestamp, distrib.EndTimestamp,
-		distrib.UserId, distrib.DeviceId)
+		distrib.UserId, distrib.DeviceId, distrib.OperationId)
 }
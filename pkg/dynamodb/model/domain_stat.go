@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultDomainStatTableName = "domain_stat"
+
+// DomainStat is the number of bookmarks of the domain imported by the users during the day, aggregated from
+// the analytics stream. The days of the domain are ordered by the day in the 2006-01-02 format.
+type DomainStat struct {
+	PK            string `dynamodbav:"PK"`
+	SK            string `dynamodbav:"SK"`
+	Domain        string `dynamodbav:"domain,omitempty" partitionKey:"DOM"`
+	Day           string `dynamodbav:"day,omitempty" sortKey:"DAY"`
+	BookmarkCount int64  `dynamodbav:"bookmarkCount,omitempty"`
+	Ttl           int64  `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the record is expired
+}
+
+// DomainStatEvent marks the part of the domains of an analytics event which was added to the domain counts, so that
+// the redelivered events are not counted again. The domains of an event are counted in parts of a transaction each.
+type DomainStatEvent struct {
+	PK      string `dynamodbav:"PK"`
+	SK      string `dynamodbav:"SK"`
+	EventId string `dynamodbav:"eventId,omitempty" partitionKey:"EVT"`
+	Part    string `dynamodbav:"part,omitempty" sortKey:"PART"`
+	Ttl     int64  `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the record is expired
+}
+
+func (stat *DomainStat) GetTableName() string {
+	tableName := os.Getenv("DOMAIN_STAT_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultDomainStatTableName
+	}
+
+	return tableName
+}
+
+func (stat *DomainStat) String() string {
+	return fmt.Sprintf("Domain: %v\n\tDay: %v\n\tBookmarkCount: %v\n", stat.Domain, stat.Day, stat.BookmarkCount)
+}
+
+func (event *DomainStatEvent) GetTableName() string {
+	return (&DomainStat{}).GetTableName()
+}
+
+func (event *DomainStatEvent) String() string {
+	return fmt.Sprintf("EventId: %v\n\tPart: %v\n", event.EventId, event.Part)
+}
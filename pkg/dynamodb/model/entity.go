@@ -1,10 +1,20 @@
 package model
 
+import "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+
 type Entity interface {
 	GetTableName() string
 	String() string
 }
 
+// TransactWriteItem is a write of an entity within a transaction. The entity is added when the update is not set,
+// otherwise it is updated by its keys with the expression, or with its field values when the expression is nil.
+type TransactWriteItem struct {
+	Entity     Entity
+	Update     bool
+	Expression *expression.Expression
+}
+
 // Define PartitionKey and SortKey with Fieldnames using below tags in Entity types
 const (
 	DynamoDBTag     = "dynamodbav"
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultExportJobTableName = "export_job"
+
+// ExportJob is a request of the user to export all their data, which is archived in the background.
+// The jobs of the user are ordered by the job ids which start with the request time.
+type ExportJob struct {
+	PK               string    `dynamodbav:"PK"`
+	SK               string    `dynamodbav:"SK"`
+	UserId           string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	JobId            string    `dynamodbav:"jobId,omitempty" sortKey:"JID"`
+	Status           string    `dynamodbav:"status,omitempty"` // Pending, Success, Failed
+	StatusMessage    string    `dynamodbav:"statusMessage,omitempty"`
+	S3Key            string    `dynamodbav:"s3Key,omitempty"` // Archive of the exported data in the bookmarks bucket
+	SizeBytes        int64     `dynamodbav:"sizeBytes,omitempty"`
+	CreatedTimestamp time.Time `dynamodbav:"createdTs,omitempty"`
+	EndTimestamp     time.Time `dynamodbav:"endTs,omitempty"`
+	Ttl              int64     `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the record is expired
+}
+
+func (job *ExportJob) GetTableName() string {
+	tableName := os.Getenv("EXPORT_JOB_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultExportJobTableName
+	}
+
+	return tableName
+}
+
+func (job *ExportJob) String() string {
+	return fmt.Sprintf("UserId: %v\n\tJobId: %v\n\tStatus: %v\n\tS3Key: %v\n",
+		job.UserId, job.JobId, job.Status, job.S3Key)
+}
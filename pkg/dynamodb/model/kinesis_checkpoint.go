@@ -0,0 +1,39 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultKinesisCheckpointTableName = "kinesis_checkpoint"
+
+// KinesisCheckpoint is the sequence number of the last record of the shard processed by the consumer of the stream.
+// The shards of the consumer are ordered by their shard ids.
+type KinesisCheckpoint struct {
+	PK               string    `dynamodbav:"PK"`
+	SK               string    `dynamodbav:"SK"`
+	ConsumerName     string    `dynamodbav:"consumerName,omitempty" partitionKey:"CON"`
+	ShardId          string    `dynamodbav:"shardId,omitempty" sortKey:"SHARD"`
+	StreamName       string    `dynamodbav:"streamName,omitempty"`
+	SequenceNumber   string    `dynamodbav:"sequenceNumber,omitempty"`
+	ShardEnded       bool      `dynamodbav:"shardEnded,omitempty"` // All the records of the closed shard are processed
+	UpdatedTimestamp time.Time `dynamodbav:"updatedTs,omitempty"`
+}
+
+func (checkpoint *KinesisCheckpoint) GetTableName() string {
+	tableName := os.Getenv("KINESIS_CHECKPOINT_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultKinesisCheckpointTableName
+	}
+
+	return tableName
+}
+
+func (checkpoint *KinesisCheckpoint) String() string {
+	return fmt.Sprintf("ConsumerName: %v\n\tShardId: %v\n\tSequenceNumber: %v\n\tShardEnded: %v\n",
+		checkpoint.ConsumerName, checkpoint.ShardId, checkpoint.SequenceNumber, checkpoint.ShardEnded)
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultOutboxTableName = "outbox"
+
+// OutboxEvent is a domain event recorded in the same transaction as the change of the user, which is relayed
+// to the domain events topic from the stream of the outbox table. The events of the user are ordered by the
+// event ids which start with the event time.
+type OutboxEvent struct {
+	PK                 string            `dynamodbav:"PK"`
+	SK                 string            `dynamodbav:"SK"`
+	UserId             string            `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	EventId            string            `dynamodbav:"eventId,omitempty" sortKey:"EID"`
+	EventType          string            `dynamodbav:"eventType,omitempty"`
+	Message            string            `dynamodbav:"message,omitempty"`    // JSON message published to the topic
+	Attributes         map[string]string `dynamodbav:"attributes,omitempty"` // Message attributes for the filter policies
+	CreatedTimestamp   time.Time         `dynamodbav:"createdTs,omitempty"`
+	PublishedTimestamp time.Time         `dynamodbav:"publishedTs,omitempty"`
+	Ttl                int64             `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the published event is expired
+}
+
+func (event *OutboxEvent) GetTableName() string {
+	tableName := os.Getenv("OUTBOX_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultOutboxTableName
+	}
+
+	return tableName
+}
+
+func (event *OutboxEvent) String() string {
+	return fmt.Sprintf("UserId: %v\n\tEventId: %v\n\tEventType: %v\n\tPublished: %v\n",
+		event.UserId, event.EventId, event.EventType, event.PublishedTimestamp)
+}
@@ -15,15 +15,32 @@ type UserBookmarksEntity interface {
 const defaultDistTableName = "user_bookmarks"
 
 type UserBookmarks struct {
-	PK                string    `dynamodbav:"PK"`
-	UserId            string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
-	OperationId       int64     `dynamodbav:"operationId,omitempty"`
-	Status            string    `dynamodbav:"status,omitempty"`
-	StartTimestamp    time.Time `dynamodbav:"startTs,omitempty"`
-	EndTimestamp      time.Time `dynamodbav:"endTs,omitempty"`
-	SyncEnabled       bool      `dynamodbav:"syncEnabled"`
-	LatestVersion     string    `dynamodbav:"latestVersion,omitempty"`
-	ModifiedBookmarks bool      `dynamodbav:"modifiedBookmarks"`
+	PK                 string    `dynamodbav:"PK"`
+	UserId             string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	OperationId        int64     `dynamodbav:"operationId,omitempty"`
+	Status             string    `dynamodbav:"status,omitempty"`
+	StartTimestamp     time.Time `dynamodbav:"startTs,omitempty"`
+	EndTimestamp       time.Time `dynamodbav:"endTs,omitempty"`
+	SyncEnabled        bool      `dynamodbav:"syncEnabled"`
+	LatestVersion      string    `dynamodbav:"latestVersion,omitempty"`
+	ModifiedBookmarks  bool      `dynamodbav:"modifiedBookmarks"`
+	ModifiedTimestamp  time.Time `dynamodbav:"modifiedTs,omitempty"`
+	EnrichmentDisabled bool      `dynamodbav:"enrichmentDisabled"`
+	AutoDistribute     bool      `dynamodbav:"autoDistribute"`
+	PackageFormat      string    `dynamodbav:"packageFormat,omitempty"`
+	PageSize           int       `dynamodbav:"pageSize,omitempty"`
+	// DistributionTimeout is in minutes, DelayedStatusTimeInMinutes when it is not set.
+	DistributionTimeout int `dynamodbav:"distributionTimeout,omitempty"`
+	// Devices maps the device ids to instance ids as of the last distribution, used by automatic distributions.
+	Devices map[string]string `dynamodbav:"devices,omitempty"`
+	// Packages maps "version/format" and "version/format/baseVersion" to the checksums of the full and delta
+	// packages of the version, which key the packages in the package bucket.
+	Packages map[string]string `dynamodbav:"packages,omitempty"`
+	// Suspended accounts keep their bookmarks, which are not distributed until the account is reinstated.
+	Suspended bool `dynamodbav:"suspended"`
+	// DistributionFailures counts the distributions which failed in a row before any device was started,
+	// which backs off the automatic distribution of the same bookmarks.
+	DistributionFailures int `dynamodbav:"distributionFailures,omitempty"`
 }
 
 func (userBookmarks *UserBookmarks) GetTableName() string {
@@ -38,7 +55,7 @@ func (userBookmarks *UserBookmarks) GetTableName() string {
 
 func (userBookmarks *UserBookmarks) String() string {
 	return fmt.Sprintf(
-		"UserId: %v\n\tEnabled: %v\n\tLatestVersion: %v\n\tModifiedBookmarks: %v\n",
+		"UserId: %v\n\tEnabled: %v\n\tLatestVersion: %v\n\tModifiedBookmarks: %v\n\tAutoDistribute: %v\n",
 		userBookmarks.UserId, userBookmarks.SyncEnabled,
-		userBookmarks.LatestVersion, userBookmarks.ModifiedBookmarks)
+		userBookmarks.LatestVersion, userBookmarks.ModifiedBookmarks, userBookmarks.AutoDistribute)
 }
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+)
+
+const defaultWebhookTableName = "webhook"
+
+// WebhookSubscription is a webhook of the user, which is notified of the events of the subscribed types.
+type WebhookSubscription struct {
+	PK               string    `dynamodbav:"PK"`
+	SK               string    `dynamodbav:"SK"`
+	UserId           string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	WebhookId        string    `dynamodbav:"webhookId,omitempty" sortKey:"WID"`
+	URL              string    `dynamodbav:"url,omitempty"`
+	Secret           string    `dynamodbav:"secret,omitempty"` // HMAC key of the delivery signatures
+	EventTypes       []string  `dynamodbav:"eventTypes,omitempty"`
+	CreatedTimestamp time.Time `dynamodbav:"createdTs,omitempty"`
+}
+
+// WebhookDelivery is an attempt to deliver an event to the webhook. The deliveries of a webhook share
+// the partition, ordered by the delivery ids which start with the delivery time.
+type WebhookDelivery struct {
+	PK             string    `dynamodbav:"PK"`
+	SK             string    `dynamodbav:"SK"`
+	UserId         string    `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	WebhookId      string    `dynamodbav:"webhookId,omitempty" partitionKey:"WID"`
+	DeliveryId     string    `dynamodbav:"deliveryId,omitempty" sortKey:"DID"`
+	EventId        string    `dynamodbav:"eventId,omitempty"`
+	EventType      string    `dynamodbav:"eventType,omitempty"`
+	Attempt        int       `dynamodbav:"attempt,omitempty"`
+	Status         string    `dynamodbav:"status,omitempty"` // Success, Failed
+	StatusMessage  string    `dynamodbav:"statusMessage,omitempty"`
+	ResponseCode   int       `dynamodbav:"responseCode,omitempty"`
+	Timestamp      time.Time `dynamodbav:"deliveredTs,omitempty"`
+	DurationMillis int64     `dynamodbav:"durationMillis,omitempty"`
+	Ttl            int64     `dynamodbav:"Ttl,omitempty"` // Epoch seconds after which the record is expired
+}
+
+// WebhookEventOutcome is the outcome of the latest delivery attempt of an event to the webhook. The outcomes of an
+// event share the partition, so that a redelivered event skips the webhooks it was already delivered to.
+type WebhookEventOutcome struct {
+	PK        string `dynamodbav:"PK"`
+	SK        string `dynamodbav:"SK"`
+	UserId    string `dynamodbav:"userId,omitempty" partitionKey:"UID"`
+	EventId   string `dynamodbav:"eventId,omitempty" partitionKey:"EID"`
+	WebhookId string `dynamodbav:"webhookId,omitempty" sortKey:"WID"`
+	Attempt   int    `dynamodbav:"attempt,omitempty"`
+	Status    string `dynamodbav:"status,omitempty"` // Success, Failed
+	Ttl       int64  `dynamodbav:"Ttl,omitempty"`    // Epoch seconds after which the record is expired
+}
+
+func getWebhookTableName() string {
+	tableName := os.Getenv("WEBHOOK_TABLE_NAME")
+
+	if tableName == "" && env.IsLocalOrTestEnv() {
+		tableName = defaultWebhookTableName
+	}
+
+	return tableName
+}
+
+func (webhook *WebhookSubscription) GetTableName() string {
+	return getWebhookTableName()
+}
+
+func (webhook *WebhookSubscription) String() string {
+	return fmt.Sprintf("UserId: %v\n\tWebhookId: %v\n\tURL: %v\n\tEventTypes: %v\n",
+		webhook.UserId, webhook.WebhookId, webhook.URL, webhook.EventTypes)
+}
+
+func (delivery *WebhookDelivery) GetTableName() string {
+	return getWebhookTableName()
+}
+
+func (delivery *WebhookDelivery) String() string {
+	return fmt.Sprintf(
+		"UserId: %v\n\tWebhookId: %v\n\tDeliveryId: %v\n\tEventId: %v\n\tAttempt: %v\n\tStatus: %v\n",
+		delivery.UserId, delivery.WebhookId, delivery.DeliveryId, delivery.EventId, delivery.Attempt, delivery.Status)
+}
+
+func (outcome *WebhookEventOutcome) GetTableName() string {
+	return getWebhookTableName()
+}
+
+func (outcome *WebhookEventOutcome) String() string {
+	return fmt.Sprintf("UserId: %v\n\tEventId: %v\n\tWebhookId: %v\n\tAttempt: %v\n\tStatus: %v\n",
+		outcome.UserId, outcome.EventId, outcome.WebhookId, outcome.Attempt, outcome.Status)
+}
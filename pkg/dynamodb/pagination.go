@@ -0,0 +1,55 @@
+package dynamodb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// EncodePageToken converts the last evaluated key of a query into an opaque token for the api clients.
+// An empty token is returned when there are no more records.
+func EncodePageToken(lastEvaluatedKey map[string]types.AttributeValue) (string, error) {
+	if len(lastEvaluatedKey) == 0 {
+		return "", nil
+	}
+
+	keys := map[string]string{}
+	if err := attributevalue.UnmarshalMap(lastEvaluatedKey, &keys); err != nil {
+		return "", err
+	}
+
+	content, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(content), nil
+}
+
+// DecodePageToken converts the token created by EncodePageToken back into the exclusive start key of a query.
+func DecodePageToken(token string) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+
+	keys := map[string]string{}
+	if err = json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+
+	if keys["PK"] == "" {
+		return nil, fmt.Errorf("%w: partition key is missing", ErrInvalidPageToken)
+	}
+
+	return attributevalue.MarshalMap(keys)
+}
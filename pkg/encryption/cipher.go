@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/secrets"
+)
+
+const (
+	Algorithm = "AES-256-GCM"
+
+	WebhookSecretKeyID      = "bookmarks/config/webhook-secret-key.json"
+	WebhookSecretKeyFileEnv = "WEBHOOK_SECRET_KEY_FILE"
+
+	// ciphertextPrefix marks the encrypted values, which tells them apart from the values stored in plaintext.
+	ciphertextPrefix = "enc:v1:"
+	keySize          = 32
+)
+
+var (
+	ErrInvalidKey        = errors.New("invalid AES-256 key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cipher encrypts the values stored at rest, such as the webhook secrets.
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+type aesGCMCipher struct {
+	aead cipher.AEAD
+}
+
+type secretKeySecret struct {
+	Key string `json:"key"` // base64 encoded 32 bytes key
+}
+
+var webhookSecretCipher = &secrets.Loader[Cipher]{
+	SecretID: WebhookSecretKeyID,
+	FileEnv:  WebhookSecretKeyFileEnv,
+	ParseSecret: func(secretString string) (Cipher, error) {
+		key, err := ParseSecretKey(secretString)
+		if err != nil {
+			return nil, err
+		}
+		return NewAESGCMCipher(key)
+	},
+}
+
+func NewAESGCMCipher(key []byte) (Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("%w: key has %d bytes", ErrInvalidKey, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCMCipher{aead: aead}, nil
+}
+
+// NewWebhookSecretCipher returns the cipher with the webhook secret key, which is read from secrets manager,
+// or from the file of the WEBHOOK_SECRET_KEY_FILE environment variable in the local and test environments.
+func NewWebhookSecretCipher() (Cipher, error) {
+	return webhookSecretCipher.Load()
+}
+
+// ParseSecretKey parses the key from the secret, a json object with the base64 encoded key.
+func ParseSecretKey(secretString string) ([]byte, error) {
+	var secret secretKeySecret
+	if err := json.Unmarshal([]byte(secretString), &secret); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(secret.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	if len(key) != keySize {
+		return nil, fmt.Errorf("%w: key has %d bytes", ErrInvalidKey, len(key))
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether the value was encrypted by a cipher, as the values stored before the encryption
+// are left in plaintext.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// Encrypt returns the prefixed base64 encoding of the random nonce followed by the sealed plaintext.
+func (c *aesGCMCipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return ciphertextPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *aesGCMCipher) Decrypt(ciphertext string) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return nil, fmt.Errorf("%w: missing prefix %s", ErrInvalidCiphertext, ciphertextPrefix)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, ciphertextPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidCiphertext)
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,145 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/secrets"
+	"github.com/stretchr/testify/suite"
+)
+
+type CipherTestSuite struct {
+	suite.Suite
+
+	key    []byte
+	secret string
+}
+
+func TestCipherSuite(t *testing.T) {
+	suite.Run(t, new(CipherTestSuite))
+}
+
+func (s *CipherTestSuite) SetupTest() {
+	s.key = make([]byte, keySize)
+	_, err := rand.Read(s.key)
+	s.NoError(err)
+
+	secret, err := json.Marshal(secretKeySecret{Key: base64.StdEncoding.EncodeToString(s.key)})
+	s.NoError(err)
+	s.secret = string(secret)
+
+	webhookSecretCipher.Reset()
+	getSecretString := secrets.GetSecretString
+	s.T().Cleanup(func() {
+		secrets.GetSecretString = getSecretString
+	})
+}
+
+func (s *CipherTestSuite) TestEncryptAndDecrypt() {
+	cipher, err := NewAESGCMCipher(s.key)
+	s.NoError(err)
+
+	ciphertext, err := cipher.Encrypt([]byte("webhook-secret"))
+	s.NoError(err)
+	s.True(IsEncrypted(ciphertext))
+	s.NotContains(ciphertext, "webhook-secret")
+
+	// The random nonce encrypts the same plaintext differently.
+	other, err := cipher.Encrypt([]byte("webhook-secret"))
+	s.NoError(err)
+	s.NotEqual(ciphertext, other)
+
+	plaintext, err := cipher.Decrypt(ciphertext)
+	s.NoError(err)
+	s.Equal("webhook-secret", string(plaintext))
+}
+
+func (s *CipherTestSuite) TestDecryptInvalidCiphertext() {
+	cipher, err := NewAESGCMCipher(s.key)
+	s.NoError(err)
+
+	ciphertext, err := cipher.Encrypt([]byte("webhook-secret"))
+	s.NoError(err)
+
+	otherKey := make([]byte, keySize)
+	otherCipher, err := NewAESGCMCipher(otherKey)
+	s.NoError(err)
+
+	_, err = otherCipher.Decrypt(ciphertext)
+	s.ErrorIs(err, ErrInvalidCiphertext)
+
+	for _, invalid := range []string{"webhook-secret", ciphertextPrefix + "!", ciphertextPrefix + "AAAA"} {
+		_, err = cipher.Decrypt(invalid)
+		s.ErrorIs(err, ErrInvalidCiphertext, invalid)
+	}
+}
+
+func (s *CipherTestSuite) TestNewAESGCMCipherWithInvalidKey() {
+	_, err := NewAESGCMCipher(s.key[:16])
+	s.ErrorIs(err, ErrInvalidKey)
+
+	_, err = ParseSecretKey(`{"key": "c2hvcnQ="}`)
+	s.ErrorIs(err, ErrInvalidKey)
+}
+
+func (s *CipherTestSuite) TestNewWebhookSecretCipherFromFile() {
+	keyFile := filepath.Join(s.T().TempDir(), "webhook-secret-key.json")
+	s.NoError(os.WriteFile(keyFile, []byte(s.secret), 0o600))
+	s.T().Setenv("STAGE", "testing")
+	s.T().Setenv(WebhookSecretKeyFileEnv, keyFile)
+
+	cipher, err := NewWebhookSecretCipher()
+	s.NoError(err)
+
+	ciphertext, err := cipher.Encrypt([]byte("webhook-secret"))
+	s.NoError(err)
+
+	keyCipher, err := NewAESGCMCipher(s.key)
+	s.NoError(err)
+	plaintext, err := keyCipher.Decrypt(ciphertext)
+	s.NoError(err)
+	s.Equal("webhook-secret", string(plaintext))
+
+	// The key is read once per lambda container.
+	s.NoError(os.Remove(keyFile))
+	cached, err := NewWebhookSecretCipher()
+	s.NoError(err)
+	s.Same(cipher, cached)
+}
+
+func (s *CipherTestSuite) TestNewWebhookSecretCipherFromSecret() {
+	s.T().Setenv("STAGE", "production")
+
+	var secretID string
+	secrets.GetSecretString = func(id string) (string, error) {
+		secretID = id
+		return s.secret, nil
+	}
+
+	_, err := NewWebhookSecretCipher()
+
+	s.NoError(err)
+	s.Equal(WebhookSecretKeyID, secretID)
+}
+
+func (s *CipherTestSuite) TestNewWebhookSecretCipherWhenSecretFails() {
+	s.T().Setenv("STAGE", "production")
+	secrets.GetSecretString = func(string) (string, error) {
+		return "", errors.New("AccessDeniedException")
+	}
+
+	_, err := NewWebhookSecretCipher()
+	s.Error(err)
+
+	secrets.GetSecretString = func(string) (string, error) {
+		return `{"key": ""}`, nil
+	}
+
+	_, err = NewWebhookSecretCipher()
+	s.ErrorIs(err, ErrInvalidKey)
+}
@@ -0,0 +1,73 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	extensionAPIVersion = "2020-01-01"
+	extensionName       = "GoShutdownHook"
+)
+
+// OnShutdown calls the callback once the process is asked to terminate, to flush what is still buffered.
+// In Lambda the process is killed without a signal, unless an extension is registered, hence an internal
+// extension without any events is registered so that Lambda sends SIGTERM before the shutdown.
+func OnShutdown(callback func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		<-signals
+		callback()
+		os.Exit(0)
+	}()
+
+	if runtimeAPI := os.Getenv("AWS_LAMBDA_RUNTIME_API"); runtimeAPI != "" {
+		if err := registerShutdownExtension(runtimeAPI); err != nil {
+			log.Warn().Msgf("Failure in registering shutdown extension: %v", err)
+		}
+	}
+}
+
+func registerShutdownExtension(runtimeAPI string) error {
+	baseURL := fmt.Sprintf("http://%s/%s/extension", runtimeAPI, extensionAPIVersion)
+
+	request, err := http.NewRequest(http.MethodPost, baseURL+"/register", bytes.NewBufferString(`{"events":[]}`))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Lambda-Extension-Name", extensionName)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	extensionId := response.Header.Get("Lambda-Extension-Identifier")
+
+	// The extension has to ask for its next event to complete the initialization. Without any events registered,
+	// the request never returns.
+	go func() {
+		nextRequest, err := http.NewRequest(http.MethodGet, baseURL+"/event/next", http.NoBody)
+		if err != nil {
+			return
+		}
+		nextRequest.Header.Set("Lambda-Extension-Identifier", extensionId)
+
+		if nextResponse, err := http.DefaultClient.Do(nextRequest); err == nil {
+			nextResponse.Body.Close()
+		}
+	}()
+	return nil
+}
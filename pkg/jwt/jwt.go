@@ -2,6 +2,7 @@ package jwt
 
 type JWTAPI interface {
 	Account() string
+	Principal() string
 	Role() EmproviseRole
 }
 
@@ -14,8 +14,9 @@ import (
 )
 
 type Jwt struct {
-	account string
-	role    EmproviseRole
+	account   string
+	principal string
+	role      EmproviseRole
 }
 
 type EmproviseClaims struct {
@@ -80,7 +81,7 @@ func getEmproviseRole(claimRole string) (EmproviseRole, error) {
 func NewJwt(tokenString string) (*Jwt, error) {
 	if env.IsLocalOrTestEnv() {
 		accountID := strconv.Itoa(mockJwtMap()[tokenString])
-		return &Jwt{accountID, RoleUnknown}, nil
+		return &Jwt{accountID, accountID, RoleUnknown}, nil
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &EmproviseClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -125,13 +126,22 @@ func NewJwt(tokenString string) (*Jwt, error) {
 
 	log.Debug().Msgf("accountId: %s, role: %s", claims.Principal.AccountID, cloudOneRole)
 
-	return &Jwt{claims.Principal.AccountID, cloudOneRole}, nil
+	principal := claims.Principal.URN
+	if principal == "" {
+		principal = claims.Principal.AccountID
+	}
+	return &Jwt{claims.Principal.AccountID, principal, cloudOneRole}, nil
 }
 
 func (p *Jwt) Account() string {
 	return p.account
 }
 
+// Principal identifies the user or the api key of the account which made the request, by its URN when present.
+func (p *Jwt) Principal() string {
+	return p.principal
+}
+
 func (p *Jwt) Role() EmproviseRole {
 	return p.role
 }
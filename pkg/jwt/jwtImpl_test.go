@@ -48,6 +48,7 @@ func (s *JWTTestSuite) TestNewJwt() {
 	s.Nil(err)
 
 	s.Equal(mockAccountID, jwtInstance.Account())
+	s.Equal(mockAccountID, jwtInstance.Principal())
 	s.Equal(RoleFullAccess, jwtInstance.Role())
 
 	mockToken = EmproviseJwtGenerator(
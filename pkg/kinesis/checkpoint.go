@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+)
+
+// Checkpoint is the position of the consumer in a shard.
+type Checkpoint struct {
+	SequenceNumber string
+	ShardEnded     bool
+}
+
+// CheckpointStore keeps the checkpoints of the shards of a consumer, from which the consumer resumes on restart.
+type CheckpointStore interface {
+	GetCheckpoints() (map[string]Checkpoint, error)
+	Checkpoint(shardId, sequenceNumber string, shardEnded bool) error
+}
+
+// DynamoDBCheckpointStore keeps the checkpoints in the checkpoint table, under the name of the consumer.
+type DynamoDBCheckpointStore struct {
+	Client       dynamodb.DynamoDBClient
+	ConsumerName string
+	StreamName   string
+}
+
+func (store *DynamoDBCheckpointStore) GetCheckpoints() (map[string]Checkpoint, error) {
+	result, err := store.Client.GetRecordsByKeyAndFields(&model.KinesisCheckpoint{ConsumerName: store.ConsumerName})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := map[string]Checkpoint{}
+	for _, checkpoint := range result.([]model.KinesisCheckpoint) {
+		checkpoints[checkpoint.ShardId] = Checkpoint{
+			SequenceNumber: checkpoint.SequenceNumber,
+			ShardEnded:     checkpoint.ShardEnded,
+		}
+	}
+	return checkpoints, nil
+}
+
+func (store *DynamoDBCheckpointStore) Checkpoint(shardId, sequenceNumber string, shardEnded bool) error {
+	return store.Client.AddRecord(&model.KinesisCheckpoint{
+		ConsumerName:     store.ConsumerName,
+		ShardId:          shardId,
+		StreamName:       store.StreamName,
+		SequenceNumber:   sequenceNumber,
+		ShardEnded:       shardEnded,
+		UpdatedTimestamp: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,363 @@
+package helper
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultGetRecordsLimit   = 1000
+	DefaultPollInterval      = time.Second
+	DefaultShardSyncInterval = time.Minute
+)
+
+// Record is a stream record received either from the Lambda Kinesis event or by reading the shard.
+type Record struct {
+	ShardId          string
+	SequenceNumber   string
+	PartitionKey     string
+	Data             []byte
+	ArrivalTimestamp time.Time
+}
+
+// RecordsHandler handles the records of a shard in their order. The failed batch is bisected to find the failed
+// record, which calls the handler again with the halves of the batch, so the handler should either fail without
+// any effect or tolerate records handled twice.
+type RecordsHandler func(ctx context.Context, records []*Record) error
+
+// BatchItemFailure identifies the failed record of a batch by its sequence number, from which the batch is retried.
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// BatchResponse is the partial batch response of a Lambda Kinesis trigger with the ReportBatchItemFailures
+// function response type, so that only the records from the failed record are retried.
+type BatchResponse struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+type ConsumerConfig struct {
+	StreamName string
+	// InitialPosition of the shards without a checkpoint, TRIM_HORIZON by default.
+	InitialPosition types.ShardIteratorType
+	// Limit of the records of a shard read at a time, which is also the largest batch of the handler.
+	Limit int32
+	// PollInterval between the reads of a shard without new records.
+	PollInterval time.Duration
+	// ShardSyncInterval between the listings of the shards, which starts the shards created by splits and merges.
+	ShardSyncInterval time.Duration
+	// MaxRetries of the failed record of a shard, which is skipped afterwards.
+	MaxRetries int
+	// RetryDelay before the first retry, which doubles at every retry.
+	RetryDelay time.Duration
+}
+
+// Consumer passes the stream records to the handler in batches, the same way for a Lambda Kinesis trigger and
+// for a worker reading the shards. The worker is the only consumer of the stream under its checkpoints, as the
+// shards are not leased.
+type Consumer struct {
+	client      KinesisClient
+	checkpoints CheckpointStore
+	config      ConsumerConfig
+	handler     RecordsHandler
+
+	mutex   sync.Mutex
+	running map[string]bool
+}
+
+func NewConsumer(client KinesisClient, checkpoints CheckpointStore, config ConsumerConfig,
+	handler RecordsHandler) *Consumer {
+	if config.InitialPosition == "" {
+		config.InitialPosition = types.ShardIteratorTypeTrimHorizon
+	}
+	if config.Limit <= 0 {
+		config.Limit = DefaultGetRecordsLimit
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.ShardSyncInterval <= 0 {
+		config.ShardSyncInterval = DefaultShardSyncInterval
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+
+	return &Consumer{
+		client:      client,
+		checkpoints: checkpoints,
+		config:      config,
+		handler:     handler,
+		running:     map[string]bool{},
+	}
+}
+
+// HandleLambdaEvent handles the records of the Lambda Kinesis event and reports the first failed record,
+// from which Lambda retries the shard while the records before it are checkpointed.
+func (c *Consumer) HandleLambdaEvent(ctx context.Context, event events.KinesisEvent) (BatchResponse, error) {
+	records := make([]*Record, 0, len(event.Records))
+
+	for i := range event.Records {
+		eventRecord := &event.Records[i]
+		// The event id is the shard id and the sequence number separated by a colon.
+		shardId, _, _ := strings.Cut(eventRecord.EventID, ":")
+
+		records = append(records, &Record{
+			ShardId:          shardId,
+			SequenceNumber:   eventRecord.Kinesis.SequenceNumber,
+			PartitionKey:     eventRecord.Kinesis.PartitionKey,
+			Data:             eventRecord.Kinesis.Data,
+			ArrivalTimestamp: eventRecord.Kinesis.ApproximateArrivalTimestamp.UTC(),
+		})
+	}
+
+	response := BatchResponse{BatchItemFailures: []BatchItemFailure{}}
+	if handled, err := c.process(ctx, records); err != nil {
+		failed := records[handled]
+		log.Error().Msgf("Failure in handling record %s of shard %s: %v", failed.SequenceNumber, failed.ShardId, err)
+
+		response.BatchItemFailures = append(response.BatchItemFailures,
+			BatchItemFailure{ItemIdentifier: failed.SequenceNumber})
+	}
+	return response, nil
+}
+
+// Run reads the shards of the stream from their checkpoints until the context is done. The child shards of a split
+// or a merge are read once their parent shards are read to the end, which keeps the records of a partition key in
+// order across the shards.
+func (c *Consumer) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	shardEnded := make(chan struct{}, 1)
+
+	ticker := time.NewTicker(c.config.ShardSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.syncShards(ctx, &wg, shardEnded); err != nil {
+			log.Error().Msgf("Failure in listing shards of stream %s: %v", c.config.StreamName, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-ticker.C:
+		case <-shardEnded:
+		}
+	}
+}
+
+// syncShards starts reading the shards which are ready and not already read.
+func (c *Consumer) syncShards(ctx context.Context, wg *sync.WaitGroup, shardEnded chan<- struct{}) error {
+	shards, err := c.client.ListShards(c.config.StreamName)
+	if err != nil {
+		return err
+	}
+
+	checkpoints, err := c.checkpoints.GetCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, shardId := range getReadyShards(shards, checkpoints) {
+		if c.running[shardId] {
+			continue
+		}
+		c.running[shardId] = true
+
+		wg.Add(1)
+		go func(shardId, sequenceNumber string) {
+			defer wg.Done()
+
+			ended := c.consumeShard(ctx, shardId, sequenceNumber)
+
+			c.mutex.Lock()
+			delete(c.running, shardId)
+			c.mutex.Unlock()
+
+			if ended {
+				select {
+				case shardEnded <- struct{}{}:
+				default:
+				}
+			}
+		}(shardId, checkpoints[shardId].SequenceNumber)
+	}
+	return nil
+}
+
+// getReadyShards returns the shards which are not read to the end, and whose parent shards are either read
+// to the end or expired from the stream.
+func getReadyShards(shards []types.Shard, checkpoints map[string]Checkpoint) []string {
+	listed := map[string]bool{}
+	for i := range shards {
+		listed[aws.ToString(shards[i].ShardId)] = true
+	}
+
+	isDone := func(shardId *string) bool {
+		if shardId == nil || !listed[*shardId] {
+			return true
+		}
+		return checkpoints[*shardId].ShardEnded
+	}
+
+	var ready []string
+	for i := range shards {
+		shard := &shards[i]
+		if isDone(shard.ShardId) || !isDone(shard.ParentShardId) || !isDone(shard.AdjacentParentShardId) {
+			continue
+		}
+		ready = append(ready, *shard.ShardId)
+	}
+	return ready
+}
+
+// consumeShard reads the shard after the sequence number until the context is done, and returns whether
+// the closed shard was read to the end.
+func (c *Consumer) consumeShard(ctx context.Context, shardId, sequenceNumber string) bool {
+	iterator, err := c.getShardIterator(shardId, sequenceNumber)
+	if err != nil {
+		log.Error().Msgf("Failure in getting iterator of shard %s: %v", shardId, err)
+		return false
+	}
+
+	for ctx.Err() == nil {
+		output, err := c.client.GetRecords(iterator, c.config.Limit)
+		if err != nil {
+			log.Error().Msgf("Failure in getting records of shard %s: %v", shardId, err)
+			if !sleep(ctx, c.config.PollInterval) {
+				return false
+			}
+
+			// The iterator expires after five minutes, hence it is renewed from the last checkpoint.
+			if iterator, err = c.getShardIterator(shardId, sequenceNumber); err != nil {
+				log.Error().Msgf("Failure in getting iterator of shard %s: %v", shardId, err)
+				return false
+			}
+			continue
+		}
+
+		records := make([]*Record, 0, len(output.Records))
+		for i := range output.Records {
+			records = append(records, &Record{
+				ShardId:          shardId,
+				SequenceNumber:   aws.ToString(output.Records[i].SequenceNumber),
+				PartitionKey:     aws.ToString(output.Records[i].PartitionKey),
+				Data:             output.Records[i].Data,
+				ArrivalTimestamp: aws.ToTime(output.Records[i].ApproximateArrivalTimestamp),
+			})
+		}
+
+		if len(records) > 0 {
+			if !c.handleShardRecords(ctx, shardId, records) {
+				return false
+			}
+			sequenceNumber = records[len(records)-1].SequenceNumber
+		}
+
+		if output.NextShardIterator == nil {
+			c.checkpoint(shardId, sequenceNumber, true)
+			log.Info().Msgf("Shard %s of stream %s is read to the end", shardId, c.config.StreamName)
+			return true
+		}
+		iterator = *output.NextShardIterator
+
+		if len(records) == 0 && !sleep(ctx, c.config.PollInterval) {
+			return false
+		}
+	}
+	return false
+}
+
+func (c *Consumer) getShardIterator(shardId, sequenceNumber string) (string, error) {
+	if sequenceNumber == "" {
+		return c.client.GetShardIterator(c.config.StreamName, shardId, c.config.InitialPosition, "")
+	}
+	return c.client.GetShardIterator(c.config.StreamName, shardId, types.ShardIteratorTypeAfterSequenceNumber,
+		sequenceNumber)
+}
+
+// handleShardRecords handles the records, checkpointing the handled records, and retries the failed record
+// with backoff until it is skipped. It returns false when the context is done before the records are handled.
+func (c *Consumer) handleShardRecords(ctx context.Context, shardId string, records []*Record) bool {
+	for failures := 0; len(records) > 0; {
+		handled, err := c.process(ctx, records)
+		if handled > 0 {
+			c.checkpoint(shardId, records[handled-1].SequenceNumber, false)
+			records = records[handled:]
+			failures = 0
+		}
+		if err == nil {
+			return true
+		}
+
+		if failures++; failures > c.config.MaxRetries {
+			log.Error().Msgf("Skipping record %s of shard %s after %d retries: %v", records[0].SequenceNumber,
+				shardId, c.config.MaxRetries, err)
+			c.checkpoint(shardId, records[0].SequenceNumber, false)
+			records = records[1:]
+			failures = 0
+			continue
+		}
+
+		delay := c.config.RetryDelay << (failures - 1)
+		log.Warn().Msgf("Retrying record %s of shard %s after %v: %v", records[0].SequenceNumber, shardId, delay, err)
+		if !sleep(ctx, delay) {
+			return false
+		}
+	}
+	return true
+}
+
+// process handles the records and bisects the failed batch until the failed record is found. It returns
+// the number of records handled before the failed record, with the error of the failed record.
+func (c *Consumer) process(ctx context.Context, records []*Record) (int, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	err := c.handler(ctx, records)
+	if err == nil {
+		return len(records), nil
+	}
+	if len(records) == 1 {
+		return 0, err
+	}
+
+	half := len(records) / 2
+	if handled, err := c.process(ctx, records[:half]); err != nil {
+		return handled, err
+	}
+	handled, err := c.process(ctx, records[half:])
+	return half + handled, err
+}
+
+// checkpoint stores the position of the consumer in the shard. The failure to checkpoint only reads the records
+// again after a restart.
+func (c *Consumer) checkpoint(shardId, sequenceNumber string, shardEnded bool) {
+	if err := c.checkpoints.Checkpoint(shardId, sequenceNumber, shardEnded); err != nil {
+		log.Error().Msgf("Failure in checkpointing shard %s at %s: %v", shardId, sequenceNumber, err)
+	}
+}
+
+func sleep(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
@@ -0,0 +1,245 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/golang/mock/gomock"
+	dynamoMocks "github.com/pranav-patil/go-serverless-api/pkg/dynamodb/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+	"github.com/pranav-patil/go-serverless-api/pkg/kinesis/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsumerTestSuite struct {
+	suite.Suite
+
+	ctrl               *gomock.Controller
+	mockKinesisClient  *mocks.MockKinesisClient
+	mockDynamoDBClient *dynamoMocks.MockDynamoDBClient
+	checkpoints        *memoryCheckpointStore
+}
+
+// memoryCheckpointStore keeps the checkpoints in the order they were made.
+type memoryCheckpointStore struct {
+	mutex       sync.Mutex
+	checkpoints map[string]Checkpoint
+	history     []string
+}
+
+func (store *memoryCheckpointStore) GetCheckpoints() (map[string]Checkpoint, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	checkpoints := map[string]Checkpoint{}
+	for shardId, checkpoint := range store.checkpoints {
+		checkpoints[shardId] = checkpoint
+	}
+	return checkpoints, nil
+}
+
+func (store *memoryCheckpointStore) Checkpoint(shardId, sequenceNumber string, shardEnded bool) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.checkpoints[shardId] = Checkpoint{SequenceNumber: sequenceNumber, ShardEnded: shardEnded}
+	if shardEnded {
+		sequenceNumber += ":end"
+	}
+	store.history = append(store.history, shardId+":"+sequenceNumber)
+	return nil
+}
+
+func TestConsumerSuite(t *testing.T) {
+	suite.Run(t, new(ConsumerTestSuite))
+}
+
+func (s *ConsumerTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *ConsumerTestSuite) SetupTest() {
+	s.mockKinesisClient = mocks.NewMockKinesisClient(s.ctrl)
+	s.mockDynamoDBClient = dynamoMocks.NewMockDynamoDBClient(s.ctrl)
+	s.checkpoints = &memoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+func newKinesisEvent(sequenceNumbers ...string) events.KinesisEvent {
+	event := events.KinesisEvent{}
+	for _, sequenceNumber := range sequenceNumbers {
+		event.Records = append(event.Records, events.KinesisEventRecord{
+			EventID: "shardId-000000000000:" + sequenceNumber,
+			Kinesis: events.KinesisRecord{SequenceNumber: sequenceNumber, PartitionKey: "1",
+				Data: []byte(sequenceNumber)},
+		})
+	}
+	return event
+}
+
+func getSequenceNumbers(records []*Record) []string {
+	sequenceNumbers := make([]string, 0, len(records))
+	for _, record := range records {
+		sequenceNumbers = append(sequenceNumbers, record.SequenceNumber)
+	}
+	return sequenceNumbers
+}
+
+func (s *ConsumerTestSuite) TestHandleLambdaEvent() {
+	var handled []string
+	consumer := NewConsumer(s.mockKinesisClient, s.checkpoints, ConsumerConfig{StreamName: "analytics"},
+		func(ctx context.Context, records []*Record) error {
+			s.Equal("shardId-000000000000", records[0].ShardId)
+			handled = append(handled, getSequenceNumbers(records)...)
+			return nil
+		})
+
+	response, err := consumer.HandleLambdaEvent(context.TODO(), newKinesisEvent("1", "2", "3"))
+
+	s.NoError(err)
+	s.Equal([]string{"1", "2", "3"}, handled)
+	s.Equal(BatchResponse{BatchItemFailures: []BatchItemFailure{}}, response)
+}
+
+func (s *ConsumerTestSuite) TestHandleLambdaEventBisectsFailedBatch() {
+	var handled []string
+	consumer := NewConsumer(s.mockKinesisClient, s.checkpoints, ConsumerConfig{StreamName: "analytics"},
+		func(ctx context.Context, records []*Record) error {
+			for _, record := range records {
+				if string(record.Data) == "4" {
+					return errors.New("malformed record")
+				}
+			}
+			handled = append(handled, getSequenceNumbers(records)...)
+			return nil
+		})
+
+	response, err := consumer.HandleLambdaEvent(context.TODO(), newKinesisEvent("1", "2", "3", "4", "5", "6"))
+
+	s.NoError(err)
+	s.Equal([]string{"1", "2", "3"}, handled)
+	s.Equal(BatchResponse{BatchItemFailures: []BatchItemFailure{{ItemIdentifier: "4"}}}, response)
+}
+
+func (s *ConsumerTestSuite) TestGetReadyShards() {
+	shards := []types.Shard{
+		{ShardId: aws.String("shardId-0"), ParentShardId: aws.String("shardId-expired")},
+		{ShardId: aws.String("shardId-1")},
+		{ShardId: aws.String("shardId-2"), ParentShardId: aws.String("shardId-0")},
+		{ShardId: aws.String("shardId-3"), ParentShardId: aws.String("shardId-0")},
+		{ShardId: aws.String("shardId-4"), ParentShardId: aws.String("shardId-2"),
+			AdjacentParentShardId: aws.String("shardId-1")},
+	}
+
+	s.Equal([]string{"shardId-0", "shardId-1"}, getReadyShards(shards, map[string]Checkpoint{}))
+	s.Equal([]string{"shardId-1", "shardId-2", "shardId-3"}, getReadyShards(shards,
+		map[string]Checkpoint{"shardId-0": {SequenceNumber: "9", ShardEnded: true}}))
+	s.Equal([]string{"shardId-3", "shardId-4"}, getReadyShards(shards, map[string]Checkpoint{
+		"shardId-0": {ShardEnded: true}, "shardId-1": {ShardEnded: true}, "shardId-2": {ShardEnded: true}}))
+}
+
+func (s *ConsumerTestSuite) TestHandleShardRecordsSkipsFailedRecordAfterRetries() {
+	attempts := 0
+	var handled []string
+	consumer := NewConsumer(s.mockKinesisClient, s.checkpoints, ConsumerConfig{StreamName: "analytics",
+		MaxRetries: 2, RetryDelay: time.Millisecond},
+		func(ctx context.Context, records []*Record) error {
+			for _, record := range records {
+				if record.SequenceNumber == "2" {
+					attempts++
+					return errors.New("throttled")
+				}
+			}
+			handled = append(handled, getSequenceNumbers(records)...)
+			return nil
+		})
+
+	s.True(consumer.handleShardRecords(context.TODO(), "shardId-0", []*Record{
+		{SequenceNumber: "1"}, {SequenceNumber: "2"}, {SequenceNumber: "3"},
+	}))
+
+	s.Equal([]string{"1", "3"}, handled)
+	s.Equal([]string{"shardId-0:1", "shardId-0:2", "shardId-0:3"}, s.checkpoints.history)
+	// Every attempt bisects the batch down to the failed record, the first attempt from the whole batch and
+	// the two retries from the failed record on.
+	s.Equal(7, attempts)
+}
+
+func (s *ConsumerTestSuite) TestRunReadsParentShardBeforeChildShard() {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	s.checkpoints.checkpoints["shardId-1"] = Checkpoint{SequenceNumber: "10"}
+	shards := []types.Shard{
+		{ShardId: aws.String("shardId-1")},
+		{ShardId: aws.String("shardId-2"), ParentShardId: aws.String("shardId-1")},
+	}
+
+	var handled []string
+	consumer := NewConsumer(s.mockKinesisClient, s.checkpoints, ConsumerConfig{StreamName: "analytics",
+		PollInterval: time.Millisecond, ShardSyncInterval: time.Hour},
+		func(ctx context.Context, records []*Record) error {
+			handled = append(handled, getSequenceNumbers(records)...)
+			if records[len(records)-1].SequenceNumber == "20" {
+				cancel()
+			}
+			return nil
+		})
+
+	s.mockKinesisClient.EXPECT().ListShards(gomock.Eq("analytics")).Return(shards, nil).Times(2)
+	s.mockKinesisClient.EXPECT().GetShardIterator(gomock.Eq("analytics"), gomock.Eq("shardId-1"),
+		gomock.Eq(types.ShardIteratorTypeAfterSequenceNumber), gomock.Eq("10")).Return("iterator-1", nil)
+	s.mockKinesisClient.EXPECT().GetRecords(gomock.Eq("iterator-1"), gomock.Eq(int32(DefaultGetRecordsLimit))).
+		Return(&kinesis.GetRecordsOutput{Records: []types.Record{
+			{SequenceNumber: aws.String("11"), PartitionKey: aws.String("1"), Data: []byte("a")},
+			{SequenceNumber: aws.String("12"), PartitionKey: aws.String("2"), Data: []byte("b")},
+		}}, nil)
+	s.mockKinesisClient.EXPECT().GetShardIterator(gomock.Eq("analytics"), gomock.Eq("shardId-2"),
+		gomock.Eq(types.ShardIteratorTypeTrimHorizon), gomock.Eq("")).Return("iterator-2", nil)
+	s.mockKinesisClient.EXPECT().GetRecords(gomock.Eq("iterator-2"), mockutil.AnyOfType(int32(0))).
+		Return(&kinesis.GetRecordsOutput{NextShardIterator: aws.String("iterator-3"), Records: []types.Record{
+			{SequenceNumber: aws.String("20"), PartitionKey: aws.String("1"), Data: []byte("c")},
+		}}, nil)
+
+	s.NoError(consumer.Run(ctx))
+
+	s.Equal([]string{"11", "12", "20"}, handled)
+	s.Equal([]string{"shardId-1:12", "shardId-1:12:end", "shardId-2:20"}, s.checkpoints.history)
+}
+
+func (s *ConsumerTestSuite) TestDynamoDBCheckpointStore() {
+	store := &DynamoDBCheckpointStore{Client: s.mockDynamoDBClient, ConsumerName: "domain-stats",
+		StreamName: "analytics"}
+
+	s.mockDynamoDBClient.EXPECT().GetRecordsByKeyAndFields(gomock.Eq(&model.KinesisCheckpoint{
+		ConsumerName: "domain-stats"})).Return([]model.KinesisCheckpoint{
+		{ConsumerName: "domain-stats", ShardId: "shardId-1", SequenceNumber: "12", ShardEnded: true},
+		{ConsumerName: "domain-stats", ShardId: "shardId-2", SequenceNumber: "20"},
+	}, nil)
+	s.mockDynamoDBClient.EXPECT().AddRecord(mockutil.AnyOfType(&model.KinesisCheckpoint{})).
+		DoAndReturn(func(entity model.Entity) error {
+			checkpoint := entity.(*model.KinesisCheckpoint)
+			s.Equal("domain-stats", checkpoint.ConsumerName)
+			s.Equal("shardId-2", checkpoint.ShardId)
+			s.Equal("analytics", checkpoint.StreamName)
+			s.Equal("21", checkpoint.SequenceNumber)
+			s.False(checkpoint.ShardEnded)
+			return nil
+		})
+
+	checkpoints, err := store.GetCheckpoints()
+	s.NoError(err)
+	s.Equal(map[string]Checkpoint{
+		"shardId-1": {SequenceNumber: "12", ShardEnded: true},
+		"shardId-2": {SequenceNumber: "20"},
+	}, checkpoints)
+
+	s.NoError(store.Checkpoint("shardId-2", "21", false))
+}
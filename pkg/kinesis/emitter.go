@@ -0,0 +1,221 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBatchRecords and MaxBatchBytes are the limits of a PutRecords request, and MaxRecordBytes the limit of
+	// a record with its partition key.
+	MaxBatchRecords = 500
+	MaxBatchBytes   = 5 * 1024 * 1024
+	MaxRecordBytes  = 1024 * 1024
+
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = 100 * time.Millisecond
+
+	// pendingBatches are the full batches waiting for the background flush, beyond which Emit waits for the flush.
+	pendingBatches = 4
+)
+
+type EmitterConfig struct {
+	StreamName string
+	// BatchSize is the number of buffered records which are flushed at once, up to MaxBatchRecords by default.
+	BatchSize int
+	// FlushInterval flushes the buffered records periodically, while zero flushes them only once the batch is full.
+	FlushInterval time.Duration
+	// MaxRetries of the failed records of a batch, which are dropped afterwards.
+	MaxRetries int
+	// RetryDelay before the first retry, which doubles at every retry.
+	RetryDelay time.Duration
+}
+
+// Emitter buffers the records and puts them on the stream in batches from the background, retrying the records
+// which failed in a batch with backoff. The records are not kept in order, as a retried record lands after the
+// later records of its partition key which succeeded.
+type Emitter struct {
+	client KinesisClient
+	config EmitterConfig
+
+	mutex       sync.Mutex
+	buffer      []types.PutRecordsRequestEntry
+	bufferBytes int
+
+	batches   chan []types.PutRecordsRequestEntry
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewEmitter(client KinesisClient, config EmitterConfig) *Emitter {
+	if config.BatchSize <= 0 || config.BatchSize > MaxBatchRecords {
+		config.BatchSize = MaxBatchRecords
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+
+	emitter := &Emitter{
+		client:  client,
+		config:  config,
+		batches: make(chan []types.PutRecordsRequestEntry, pendingBatches),
+		done:    make(chan struct{}),
+	}
+
+	emitter.wg.Add(1)
+	go emitter.flushInBackground()
+	return emitter
+}
+
+// Emit buffers the record, and hands the buffer to the background flush when the record fills the batch, so that
+// the caller never waits for the retries of a batch. Emit only waits while the pending batches are full.
+func (e *Emitter) Emit(partitionKey string, data []byte) error {
+	recordBytes := len(partitionKey) + len(data)
+	if recordBytes > MaxRecordBytes {
+		return fmt.Errorf("record of %d bytes exceeds the limit of %d bytes", recordBytes, MaxRecordBytes)
+	}
+
+	e.mutex.Lock()
+	var batch []types.PutRecordsRequestEntry
+	if e.bufferBytes+recordBytes > MaxBatchBytes {
+		batch = e.takeBuffer()
+	}
+
+	e.buffer = append(e.buffer, types.PutRecordsRequestEntry{Data: data, PartitionKey: aws.String(partitionKey)})
+	e.bufferBytes += recordBytes
+
+	if batch == nil && len(e.buffer) >= e.config.BatchSize {
+		batch = e.takeBuffer()
+	}
+	e.mutex.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+
+	select {
+	case <-e.done:
+		// The closed emitter has no background flush.
+		return e.putRecords(batch)
+	default:
+	}
+
+	select {
+	case e.batches <- batch:
+		return nil
+	case <-e.done:
+		return e.putRecords(batch)
+	}
+}
+
+// Flush puts all the pending batches and the buffered records on the stream.
+func (e *Emitter) Flush() error {
+	var errs []error
+	for pending := true; pending; {
+		select {
+		case batch := <-e.batches:
+			errs = append(errs, e.putRecords(batch))
+		default:
+			pending = false
+		}
+	}
+
+	e.mutex.Lock()
+	batch := e.takeBuffer()
+	e.mutex.Unlock()
+
+	if len(batch) > 0 {
+		errs = append(errs, e.putRecords(batch))
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background flush and flushes the pending batches and the buffered records.
+func (e *Emitter) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	e.wg.Wait()
+
+	return e.Flush()
+}
+
+func (e *Emitter) takeBuffer() []types.PutRecordsRequestEntry {
+	batch := e.buffer
+	e.buffer = nil
+	e.bufferBytes = 0
+	return batch
+}
+
+// flushInBackground puts the full batches handed over by Emit, and flushes the buffered records on the flush
+// interval when one is configured.
+func (e *Emitter) flushInBackground() {
+	defer e.wg.Done()
+
+	var tick <-chan time.Time
+	if e.config.FlushInterval > 0 {
+		ticker := time.NewTicker(e.config.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-e.done:
+			return
+		case batch := <-e.batches:
+			err = e.putRecords(batch)
+		case <-tick:
+			err = e.Flush()
+		}
+
+		if err != nil {
+			log.Error().Msgf("Failure in flushing records to stream %s: %v", e.config.StreamName, err)
+		}
+	}
+}
+
+// putRecords puts the batch on the stream, and retries the records which failed with a throttling or an
+// internal error, as reported by the FailedRecordCount of the output.
+func (e *Emitter) putRecords(records []types.PutRecordsRequestEntry) error {
+	delay := e.config.RetryDelay
+
+	for attempt := 0; ; attempt++ {
+		output, err := e.client.PutRecordBatch(e.config.StreamName, records)
+
+		if err == nil {
+			if output.FailedRecordCount == nil || *output.FailedRecordCount == 0 {
+				return nil
+			}
+
+			var failed []types.PutRecordsRequestEntry
+			for i, result := range output.Records {
+				if result.ErrorCode != nil && i < len(records) {
+					failed = append(failed, records[i])
+				}
+			}
+			records = failed
+			err = fmt.Errorf("%d records failed", len(records))
+		}
+
+		if attempt >= e.config.MaxRetries {
+			return fmt.Errorf("dropped %d records to stream %s after %d retries: %w", len(records),
+				e.config.StreamName, attempt, err)
+		}
+
+		log.Warn().Msgf("Retrying %d records to stream %s after %v: %v", len(records), e.config.StreamName, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,163 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/kinesis/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmitterTestSuite struct {
+	suite.Suite
+
+	ctrl              *gomock.Controller
+	mockKinesisClient *mocks.MockKinesisClient
+}
+
+func TestEmitterSuite(t *testing.T) {
+	suite.Run(t, new(EmitterTestSuite))
+}
+
+func (s *EmitterTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *EmitterTestSuite) SetupTest() {
+	s.mockKinesisClient = mocks.NewMockKinesisClient(s.ctrl)
+}
+
+func partitionKeys(records []types.PutRecordsRequestEntry) []string {
+	keys := make([]string, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, *record.PartitionKey)
+	}
+	return keys
+}
+
+func (s *EmitterTestSuite) TestEmitFlushesFullBatch() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics", BatchSize: 2})
+	flushed := make(chan struct{})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Any()).
+		DoAndReturn(func(streamName string, records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
+			s.Equal([]string{"1", "2"}, partitionKeys(records))
+			close(flushed)
+			return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil
+		})
+
+	s.NoError(emitter.Emit("1", []byte(`{"type":"bookmarks.listed"}`)))
+	s.NoError(emitter.Emit("2", []byte(`{"type":"bookmarks.searched"}`)))
+	// The third record stays buffered until the next batch.
+	s.NoError(emitter.Emit("3", []byte(`{"type":"bookmarks.imported"}`)))
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		s.Fail("full batch was not flushed")
+	}
+}
+
+func (s *EmitterTestSuite) TestEmitDoesNotWaitForRetries() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics", BatchSize: 1,
+		RetryDelay: time.Hour})
+	attempted := make(chan struct{})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(1)).
+		DoAndReturn(func(streamName string, records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
+			close(attempted)
+			return nil, errors.New("ProvisionedThroughputExceededException")
+		})
+
+	done := make(chan error)
+	go func() {
+		done <- emitter.Emit("1", []byte("a"))
+	}()
+
+	select {
+	case err := <-done:
+		s.NoError(err)
+	case <-time.After(time.Second):
+		s.Fail("emit waited for the retry of the batch")
+	}
+	<-attempted
+}
+
+func (s *EmitterTestSuite) TestFlushRetriesFailedRecords() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics", RetryDelay: time.Millisecond})
+
+	gomock.InOrder(
+		s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(3)).
+			Return(&kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(2), Records: []types.PutRecordsResultEntry{
+				{ErrorCode: aws.String("ProvisionedThroughputExceededException")},
+				{SequenceNumber: aws.String("49590338271490256608559692538361571095921575989136588898")},
+				{ErrorCode: aws.String("InternalFailure")},
+			}}, nil),
+		s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Any()).
+			DoAndReturn(func(streamName string, records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
+				// Only the failed records are retried.
+				s.Equal([]string{"1", "3"}, partitionKeys(records))
+				return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil
+			}),
+	)
+
+	s.NoError(emitter.Emit("1", []byte("a")))
+	s.NoError(emitter.Emit("2", []byte("b")))
+	s.NoError(emitter.Emit("3", []byte("c")))
+	s.NoError(emitter.Flush())
+}
+
+func (s *EmitterTestSuite) TestFlushDropsRecordsAfterRetries() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics", MaxRetries: 2,
+		RetryDelay: time.Millisecond})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(1)).
+		Return(nil, errors.New("ResourceNotFoundException")).Times(3)
+
+	s.NoError(emitter.Emit("1", []byte("a")))
+	s.ErrorContains(emitter.Flush(), "dropped 1 records to stream analytics after 2 retries")
+}
+
+func (s *EmitterTestSuite) TestEmitRejectsOversizedRecord() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics"})
+
+	s.Error(emitter.Emit("1", make([]byte, MaxRecordBytes)))
+	s.NoError(emitter.Flush())
+}
+
+func (s *EmitterTestSuite) TestCloseFlushesBufferedRecords() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics", FlushInterval: time.Hour})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(1)).
+		Return(&kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil)
+
+	s.NoError(emitter.Emit("1", []byte("a")))
+	s.NoError(emitter.Close())
+	s.NoError(emitter.Close())
+}
+
+func (s *EmitterTestSuite) TestFlushPeriodically() {
+	emitter := NewEmitter(s.mockKinesisClient, EmitterConfig{StreamName: "analytics",
+		FlushInterval: 10 * time.Millisecond})
+	flushed := make(chan struct{})
+
+	s.mockKinesisClient.EXPECT().PutRecordBatch(gomock.Eq("analytics"), gomock.Len(1)).
+		DoAndReturn(func(streamName string, records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
+			close(flushed)
+			return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil
+		})
+
+	s.NoError(emitter.Emit("1", []byte("a")))
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		s.Fail("records were not flushed on the interval")
+	}
+	s.NoError(emitter.Close())
+}
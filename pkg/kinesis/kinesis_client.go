@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -19,10 +20,18 @@ type kinesisAPI struct {
 	KINESIS *kinesis.Client
 }
 
+//go:generate mockgen -destination mocks/kinesis_client_mock.go -package mocks . KinesisClient
+
 type KinesisClient interface {
 	PutRecord(streamName, accessKeyId, secretAccessKey, sessionToken string, data []byte) (*kinesis.PutRecordOutput, error)
+	PutRecordWithPartitionKey(streamName, partitionKey string, data []byte) (*kinesis.PutRecordOutput, error)
 	PutRecords(streamName, accessKeyId, secretAccessKey, sessionToken string,
 		recordsRequest []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error)
+	PutRecordBatch(streamName string, records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error)
+	ListShards(streamName string) ([]types.Shard, error)
+	GetShardIterator(streamName, shardId string, iteratorType types.ShardIteratorType,
+		sequenceNumber string) (string, error)
+	GetRecords(shardIterator string, limit int32) (*kinesis.GetRecordsOutput, error)
 }
 
 func NewKinesisClient() (KinesisClient, error) {
@@ -42,6 +51,7 @@ func NewKinesisClient() (KinesisClient, error) {
 		return kinesisAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	kinesisClient := kinesis.NewFromConfig(cfg)
 	kinesisAPI.KINESIS = kinesisClient
 	return kinesisAPI, nil
@@ -64,6 +74,20 @@ func (api *kinesisAPI) PutRecord(streamName, accessKeyId, secretAccessKey, sessi
 	)
 }
 
+// PutRecordWithPartitionKey puts the record with the credentials of the client, where the records with the same
+// partition key are kept in order on the same shard.
+func (api *kinesisAPI) PutRecordWithPartitionKey(streamName, partitionKey string,
+	data []byte) (*kinesis.PutRecordOutput, error) {
+	return api.KINESIS.PutRecord(
+		context.TODO(),
+		&kinesis.PutRecordInput{
+			Data:         data,
+			PartitionKey: aws.String(partitionKey),
+			StreamName:   aws.String(streamName),
+		},
+	)
+}
+
 func (api *kinesisAPI) PutRecords(streamName, accessKeyId, secretAccessKey, sessionToken string,
 	records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
 	return api.KINESIS.PutRecords(
@@ -77,3 +101,67 @@ func (api *kinesisAPI) PutRecords(streamName, accessKeyId, secretAccessKey, sess
 		},
 	)
 }
+
+// PutRecordBatch puts the records with their own partition keys using the credentials of the client. The records
+// which failed are reported by their error codes in the output, while the request itself succeeds.
+func (api *kinesisAPI) PutRecordBatch(streamName string,
+	records []types.PutRecordsRequestEntry) (*kinesis.PutRecordsOutput, error) {
+	return api.KINESIS.PutRecords(
+		context.TODO(),
+		&kinesis.PutRecordsInput{
+			Records:    records,
+			StreamName: aws.String(streamName),
+		},
+	)
+}
+
+// ListShards returns all the shards of the stream within its retention period, including the closed parent shards
+// of the shards which were split or merged.
+func (api *kinesisAPI) ListShards(streamName string) ([]types.Shard, error) {
+	var shards []types.Shard
+	input := &kinesis.ListShardsInput{StreamName: aws.String(streamName)}
+
+	for {
+		output, err := api.KINESIS.ListShards(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, output.Shards...)
+
+		if output.NextToken == nil {
+			return shards, nil
+		}
+		// The stream name must not be set along with the next token.
+		input = &kinesis.ListShardsInput{NextToken: output.NextToken}
+	}
+}
+
+// GetShardIterator returns the iterator of the shard from the position of the iterator type, where the sequence
+// number is only used by the AT_SEQUENCE_NUMBER and AFTER_SEQUENCE_NUMBER types.
+func (api *kinesisAPI) GetShardIterator(streamName, shardId string, iteratorType types.ShardIteratorType,
+	sequenceNumber string) (string, error) {
+	input := &kinesis.GetShardIteratorInput{
+		StreamName:        aws.String(streamName),
+		ShardId:           aws.String(shardId),
+		ShardIteratorType: iteratorType,
+	}
+	if sequenceNumber != "" {
+		input.StartingSequenceNumber = aws.String(sequenceNumber)
+	}
+
+	output, err := api.KINESIS.GetShardIterator(context.TODO(), input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.ShardIterator), nil
+}
+
+// GetRecords returns the records of the shard from the iterator, with the iterator of the next records which is nil
+// once the closed shard has no more records.
+func (api *kinesisAPI) GetRecords(shardIterator string, limit int32) (*kinesis.GetRecordsOutput, error) {
+	input := &kinesis.GetRecordsInput{ShardIterator: aws.String(shardIterator)}
+	if limit > 0 {
+		input.Limit = aws.Int32(limit)
+	}
+	return api.KINESIS.GetRecords(context.TODO(), input)
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"io"
+	"os"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -21,3 +23,8 @@ func SetGlobalLevel(l string) {
 
 	log.Logger = log.With().Timestamp().Stack().Caller().Logger()
 }
+
+// AddOutput writes the logs to the writer as well as to stderr, which Lambda ships to the log group of the function.
+func AddOutput(writer io.Writer) {
+	log.Logger = log.Output(zerolog.MultiLevelWriter(os.Stderr, writer))
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const awsCallMetricsID = "AWSCallMetrics"
+
+// InstrumentAWSConfig records the latency and the failures of every call of the clients created with the config,
+// including the retries of the call. The middleware is added after the service metadata is registered in the
+// context of the call.
+func InstrumentAWSConfig(cfg *aws.Config) {
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(awsCallMetricsID,
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+				middleware.InitializeOutput, middleware.Metadata, error) {
+				start := TimeNow()
+				out, metadata, err := next.HandleInitialize(ctx, in)
+
+				RecordAWSCall(awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx),
+					TimeNow().Sub(start), err)
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
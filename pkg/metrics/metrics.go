@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/rs/zerolog/log"
+)
+
+type Unit string
+
+const (
+	UnitMilliseconds Unit = "Milliseconds"
+	UnitCount        Unit = "Count"
+	UnitBytes        Unit = "Bytes"
+
+	defaultNamespace = "Emprovise/Bookmarks"
+)
+
+var TimeNow = time.Now
+
+type Metric struct {
+	Name  string
+	Value float64
+	Unit  Unit
+}
+
+// Sink receives the metrics as Embedded Metric Format documents, one JSON document at a time.
+type Sink interface {
+	Write(document []byte) error
+}
+
+// WriterSink writes each document on its own line, such as to stdout which Lambda ships to CloudWatch Logs
+// where the metrics are extracted from the documents.
+type WriterSink struct {
+	Writer io.Writer
+}
+
+func (sink *WriterSink) Write(document []byte) error {
+	_, err := sink.Writer.Write(append(document, '\n'))
+	return err
+}
+
+type noopSink struct{}
+
+func (noopSink) Write(document []byte) error {
+	return nil
+}
+
+// NoopSink drops the metrics, which is the sink of the default metrics until Init is called.
+var NoopSink Sink = noopSink{}
+
+type Config struct {
+	Namespace string
+	// Dimensions of every metric, along with the dimensions of the metric.
+	Dimensions map[string]string
+}
+
+// Metrics emits the metrics aggregated by the configured dimensions as well as their own dimensions.
+type Metrics struct {
+	sink   Sink
+	config Config
+}
+
+func New(sink Sink, config Config) *Metrics {
+	if config.Namespace == "" {
+		config.Namespace = defaultNamespace
+	}
+	return &Metrics{sink: sink, config: config}
+}
+
+var (
+	defaultMutex   sync.RWMutex
+	defaultMetrics = New(NoopSink, Config{})
+)
+
+// Default returns the metrics emitted by the Record functions.
+func Default() *Metrics {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultMetrics
+}
+
+func SetDefault(metrics *Metrics) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultMetrics = metrics
+}
+
+// Init emits the default metrics to stdout with the config of the environment, while the local and test
+// environments keep the no-op sink.
+func Init() {
+	if env.IsLocalOrTestEnv() {
+		return
+	}
+	SetDefault(New(&WriterSink{Writer: os.Stdout}, ConfigFromEnv()))
+}
+
+// ConfigFromEnv reads the namespace of METRICS_NAMESPACE and the dimensions of METRICS_DIMENSIONS, as comma
+// separated name=value pairs. The Service dimension defaults to the Lambda function name.
+func ConfigFromEnv() Config {
+	config := Config{Namespace: os.Getenv("METRICS_NAMESPACE"), Dimensions: map[string]string{}}
+
+	for _, pair := range strings.Split(os.Getenv("METRICS_DIMENSIONS"), ",") {
+		if name, value, found := strings.Cut(strings.TrimSpace(pair), "="); found && name != "" {
+			config.Dimensions[name] = value
+		}
+	}
+
+	if _, found := config.Dimensions["Service"]; !found && os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		config.Dimensions["Service"] = os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	}
+	return config
+}
+
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit,omitempty"`
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+// Emit writes the metrics as one document, with the dimensions overriding the configured dimensions of
+// the same names. The failure to emit is only logged.
+func (m *Metrics) Emit(dimensions map[string]string, metrics ...Metric) {
+	document := map[string]interface{}{}
+
+	for name, value := range m.config.Dimensions {
+		document[name] = value
+	}
+	for name, value := range dimensions {
+		document[name] = value
+	}
+
+	dimensionNames := make([]string, 0, len(document))
+	for name := range document {
+		dimensionNames = append(dimensionNames, name)
+	}
+	sort.Strings(dimensionNames)
+
+	definitions := make([]metricDefinition, 0, len(metrics))
+	for _, metric := range metrics {
+		document[metric.Name] = metric.Value
+		definitions = append(definitions, metricDefinition{Name: metric.Name, Unit: metric.Unit})
+	}
+
+	document["_aws"] = metadata{
+		Timestamp: TimeNow().UnixMilli(),
+		CloudWatchMetrics: []metricDirective{{
+			Namespace:  m.config.Namespace,
+			Dimensions: [][]string{dimensionNames},
+			Metrics:    definitions,
+		}},
+	}
+
+	data, err := json.Marshal(document)
+	if err == nil {
+		err = m.sink.Write(data)
+	}
+	if err != nil {
+		log.Warn().Msgf("Failure in emitting metrics: %v", err)
+	}
+}
+
+// RecordRequest records the latency and the status code of the request to the route.
+func RecordRequest(route string, statusCode int, latency time.Duration) {
+	metrics := Default()
+
+	metrics.Emit(map[string]string{"Route": route},
+		Metric{Name: "Latency", Value: toMilliseconds(latency), Unit: UnitMilliseconds},
+		Metric{Name: "Requests", Value: 1, Unit: UnitCount},
+		Metric{Name: "ClientErrors", Value: toCount(statusCode >= 400 && statusCode < 500), Unit: UnitCount},
+		Metric{Name: "ServerErrors", Value: toCount(statusCode >= 500), Unit: UnitCount})
+	metrics.Emit(map[string]string{"Route": route, "StatusCode": strconv.Itoa(statusCode)},
+		Metric{Name: "Responses", Value: 1, Unit: UnitCount})
+}
+
+// RecordBookmarks records the number of bookmarks listed or changed by the request to the route.
+func RecordBookmarks(route string, count int) {
+	Default().Emit(map[string]string{"Route": route},
+		Metric{Name: "BookmarksPerRequest", Value: float64(count), Unit: UnitCount})
+}
+
+// RecordPackageSize records the size of the distribution package written of the format and the type.
+func RecordPackageSize(format, packageType string, size int) {
+	Default().Emit(map[string]string{"PackageFormat": format, "PackageType": packageType},
+		Metric{Name: "PackageSize", Value: float64(size), Unit: UnitBytes})
+}
+
+// RecordDistributions records the number of devices to which the bookmarks are distributed.
+func RecordDistributions(count int) {
+	Default().Emit(nil, Metric{Name: "Distributions", Value: float64(count), Unit: UnitCount})
+}
+
+// RecordAWSCall records the latency and the failure of the call of the operation of the AWS service.
+func RecordAWSCall(service, operation string, latency time.Duration, err error) {
+	Default().Emit(map[string]string{"AWSService": service, "Operation": operation},
+		Metric{Name: "AWSCallLatency", Value: toMilliseconds(latency), Unit: UnitMilliseconds},
+		Metric{Name: "AWSCallErrors", Value: toCount(err != nil), Unit: UnitCount})
+}
+
+func toMilliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func toCount(condition bool) float64 {
+	if condition {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+
+	buffer      *bytes.Buffer
+	mockTimeNow time.Time
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (s *MetricsTestSuite) SetupTest() {
+	s.buffer = &bytes.Buffer{}
+	SetDefault(New(&WriterSink{Writer: s.buffer}, Config{Dimensions: map[string]string{"Service": "api"}}))
+
+	s.mockTimeNow = time.Date(2009, time.November, 10, 23, 52, 34, 0, time.UTC)
+	TimeNow = func() time.Time {
+		return s.mockTimeNow
+	}
+}
+
+func (s *MetricsTestSuite) TearDownTest() {
+	SetDefault(New(NoopSink, Config{}))
+}
+
+func (s *MetricsTestSuite) getDocuments() []map[string]interface{} {
+	var documents []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s.buffer.String()), "\n") {
+		document := map[string]interface{}{}
+		s.NoError(json.Unmarshal([]byte(line), &document))
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+func (s *MetricsTestSuite) TestEmit() {
+	Default().Emit(map[string]string{"Route": "GET /bookmarks"},
+		Metric{Name: "Latency", Value: 12.5, Unit: UnitMilliseconds},
+		Metric{Name: "Requests", Value: 1, Unit: UnitCount})
+
+	s.JSONEq(`{
+		"_aws": {
+			"Timestamp": 1257897154000,
+			"CloudWatchMetrics": [{
+				"Namespace": "Emprovise/Bookmarks",
+				"Dimensions": [["Route", "Service"]],
+				"Metrics": [{"Name": "Latency", "Unit": "Milliseconds"}, {"Name": "Requests", "Unit": "Count"}]
+			}]
+		},
+		"Service": "api",
+		"Route": "GET /bookmarks",
+		"Latency": 12.5,
+		"Requests": 1
+	}`, s.buffer.String())
+}
+
+func (s *MetricsTestSuite) TestRecordRequest() {
+	RecordRequest("PUT /bookmarks", http.StatusNotFound, 1500*time.Microsecond)
+
+	documents := s.getDocuments()
+	s.Len(documents, 2)
+
+	s.Equal("PUT /bookmarks", documents[0]["Route"])
+	s.Equal(1.5, documents[0]["Latency"])
+	s.Equal(float64(1), documents[0]["ClientErrors"])
+	s.Equal(float64(0), documents[0]["ServerErrors"])
+
+	s.Equal("404", documents[1]["StatusCode"])
+	s.Equal(float64(1), documents[1]["Responses"])
+}
+
+func (s *MetricsTestSuite) TestRecordBusinessMetrics() {
+	RecordBookmarks("GET /bookmarks", 25)
+	RecordPackageSize("zip", "full", 2048)
+	RecordDistributions(3)
+
+	documents := s.getDocuments()
+	s.Len(documents, 3)
+	s.Equal(float64(25), documents[0]["BookmarksPerRequest"])
+	s.Equal(float64(2048), documents[1]["PackageSize"])
+	s.Equal("zip", documents[1]["PackageFormat"])
+	s.Equal(float64(3), documents[2]["Distributions"])
+}
+
+func (s *MetricsTestSuite) TestNoopSink() {
+	SetDefault(New(NoopSink, Config{}))
+
+	RecordDistributions(3)
+	s.Empty(s.buffer.String())
+}
+
+func (s *MetricsTestSuite) TestConfigFromEnv() {
+	os.Setenv("METRICS_NAMESPACE", "Custom")
+	os.Setenv("METRICS_DIMENSIONS", "Stage=dev, Region=us-east-1,invalid")
+	os.Setenv("AWS_LAMBDA_FUNCTION_NAME", "app-bookmarks-api")
+	defer func() {
+		os.Unsetenv("METRICS_NAMESPACE")
+		os.Unsetenv("METRICS_DIMENSIONS")
+		os.Unsetenv("AWS_LAMBDA_FUNCTION_NAME")
+	}()
+
+	s.Equal(Config{Namespace: "Custom", Dimensions: map[string]string{"Stage": "dev", "Region": "us-east-1",
+		"Service": "app-bookmarks-api"}}, ConfigFromEnv())
+}
+
+type failingTransport struct{}
+
+func (failingTransport) Do(request *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (s *MetricsTestSuite) TestInstrumentAWSConfig() {
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient:  failingTransport{},
+		Retryer: func() aws.Retryer {
+			return aws.NopRetryer{}
+		},
+	}
+	InstrumentAWSConfig(&cfg)
+
+	_, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	s.Error(err)
+
+	documents := s.getDocuments()
+	s.Len(documents, 1)
+	s.Equal("STS", documents[0]["AWSService"])
+	s.Equal("GetCallerIdentity", documents[0]["Operation"])
+	s.Equal(float64(1), documents[0]["AWSCallErrors"])
+}
@@ -0,0 +1,23 @@
+package mockutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+)
+
+func HasPrefix(prefix string) gomock.Matcher {
+	return &hasPrefixMatcher{prefix: prefix}
+}
+
+type hasPrefixMatcher struct{ prefix string }
+
+func (m hasPrefixMatcher) Matches(x interface{}) bool {
+	s, ok := x.(string)
+	return ok && strings.HasPrefix(s, m.prefix)
+}
+
+func (m hasPrefixMatcher) String() string {
+	return fmt.Sprintf("has prefix %q", m.prefix)
+}
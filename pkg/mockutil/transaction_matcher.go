@@ -0,0 +1,39 @@
+package mockutil
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/dynamodb/model"
+)
+
+// TransactionOf matches the transaction which writes an entity of the type of val, followed by the outbox events
+// of the event types.
+func TransactionOf(val interface{}, eventTypes ...string) gomock.Matcher {
+	return &transactionMatcher{argType: getType(val), eventTypes: eventTypes}
+}
+
+type transactionMatcher struct {
+	argType    reflect.Type
+	eventTypes []string
+}
+
+func (m transactionMatcher) Matches(x interface{}) bool {
+	items, ok := x.([]model.TransactWriteItem)
+	if !ok || len(items) != len(m.eventTypes)+1 || getType(items[0].Entity) != m.argType {
+		return false
+	}
+
+	for i, eventType := range m.eventTypes {
+		outboxEvent, ok := items[i+1].Entity.(*model.OutboxEvent)
+		if !ok || outboxEvent.EventType != eventType {
+			return false
+		}
+	}
+	return true
+}
+
+func (m transactionMatcher) String() string {
+	return fmt.Sprintf("is transaction of type %v with events %v", m.argType, m.eventTypes)
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// ObjectVersion is a version, or a delete marker, of an object in a versioned bucket. The objects of the buckets
+// without versioning have the single version "null".
+type ObjectVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	DeleteMarker bool
+	LastModified time.Time
+	Size         int64
+}
+
+// VersionMarker positions the listing of the object versions after the version of the key.
+type VersionMarker struct {
+	KeyMarker       string
+	VersionIdMarker string
+}
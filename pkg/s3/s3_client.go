@@ -13,7 +13,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/pkg/errors"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3/model"
 	"github.com/pranav-patil/go-serverless-api/pkg/util"
 	"github.com/rs/zerolog/log"
 )
@@ -23,12 +25,18 @@ import (
 type S3Client interface {
 	CreateBucket(bucket, region string) error
 	PutObject(bucket, key, contentType, encoding string, content *[]byte) error
+	PutObjectVersion(bucket, key, contentType, encoding string, content *[]byte) (string, error)
 	GetObject(bucket, key string) ([]byte, error)
+	GetObjectVersion(bucket, key, versionId string) ([]byte, error)
 	DeleteObject(bucket, key string) error
+	DeleteObjectVersion(bucket, key, versionId string) error
 	DeleteObjectsWithPrefix(bucket, prefix string) error
 	DeleteObjects(bucket string, objectKeys []string) error
+	DeleteObjectVersions(bucket string, versions []model.ObjectVersion) (int, error)
 	ObjectExists(bucket, key string) (bool, error)
 	ListObjects(bucket, prefix string) ([]types.Object, error)
+	ListObjectVersions(bucket, prefix string, marker *model.VersionMarker) ([]model.ObjectVersion,
+		*model.VersionMarker, error)
 	DeleteBucket(bucket string) error
 	CopyObject(sourceBucket, destinationBucket, key string) error
 	NewSignedGetURL(bucket, key string, lifetimeSecs int64) (string, error)
@@ -62,6 +70,7 @@ func NewS3Client() (S3Client, error) {
 		return s3Api, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
 		o.UsePathStyle = usePathStyle
 	})
@@ -87,14 +96,21 @@ func (api *s3Api) CreateBucket(bucket, region string) error {
 }
 
 func (api *s3Api) PutObject(bucket, key, contentType, encoding string, content *[]byte) error {
+	_, err := api.PutObjectVersion(bucket, key, contentType, encoding, content)
+	return err
+}
+
+// PutObjectVersion puts the object and returns the version id of the object, which is empty when the bucket
+// is not versioned.
+func (api *s3Api) PutObjectVersion(bucket, key, contentType, encoding string, content *[]byte) (string, error) {
 	if content == nil {
-		return fmt.Errorf("put content is nil")
+		return "", fmt.Errorf("put content is nil")
 	}
 
 	if encoding == GZip {
 		response, err := util.Compress(string(*content))
 		if err != nil {
-			return err
+			return "", err
 		}
 		content = &response
 	}
@@ -107,20 +123,32 @@ func (api *s3Api) PutObject(bucket, key, contentType, encoding string, content *
 		ContentEncoding: aws.String(encoding),
 	}
 
-	_, err := api.S3.PutObject(context.TODO(), objectInput)
+	objectOutput, err := api.S3.PutObject(context.TODO(), objectInput)
 	if err != nil {
 		log.Error().Msgf("S3 PutObject Error: %v", err.Error())
+		return "", err
 	}
 
-	return err
+	return aws.ToString(objectOutput.VersionId), nil
 }
 
 func (api *s3Api) GetObject(bucket, key string) ([]byte, error) {
-	objectInput := &s3.GetObjectInput{
+	return api.getObject(&s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
-	}
+	})
+}
+
+// GetObjectVersion returns the content of the version of the object, which may be a noncurrent version.
+func (api *s3Api) GetObjectVersion(bucket, key, versionId string) ([]byte, error) {
+	return api.getObject(&s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+	})
+}
 
+func (api *s3Api) getObject(objectInput *s3.GetObjectInput) ([]byte, error) {
 	objectOutput, err := api.S3.GetObject(context.TODO(), objectInput)
 	if err != nil {
 		log.Error().Msgf("S3 GetObject Error: %v", err.Error())
@@ -164,6 +192,23 @@ func (api *s3Api) DeleteObject(bucket, key string) error {
 	return err
 }
 
+// DeleteObjectVersion permanently deletes the version of the object, so that the previous version of the object
+// becomes the current version again.
+func (api *s3Api) DeleteObjectVersion(bucket, key, versionId string) error {
+	objectInput := &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+	}
+
+	_, err := api.S3.DeleteObject(context.TODO(), objectInput)
+	if err != nil {
+		log.Error().Msgf("S3 DeleteObjectVersion Error: %v", err.Error())
+	}
+
+	return err
+}
+
 func (api *s3Api) DeleteObjectsWithPrefix(bucket, prefix string) error {
 	contents, err := api.ListObjects(bucket, prefix)
 	if err != nil {
@@ -184,13 +229,49 @@ func (api *s3Api) DeleteObjects(bucket string, objectKeys []string) error {
 	for _, key := range objectKeys {
 		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
 	}
-	_, err := api.S3.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
+	output, err := api.S3.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
 		Bucket: aws.String(bucket),
 		Delete: &types.Delete{Objects: objectIds},
 	})
 	if err != nil {
 		log.Error().Msgf("S3 DeleteObjects Error for bucket %v: %v", bucket, err.Error())
+		return err
+	}
+	return deleteObjectsError(bucket, output.Errors)
+}
+
+// DeleteObjectVersions permanently deletes the versions and delete markers, up to 1000 at a time, and returns
+// the count of the deleted ones. The versions which failed to delete are returned in the error.
+func (api *s3Api) DeleteObjectVersions(bucket string, versions []model.ObjectVersion) (int, error) {
+	objectIds := make([]types.ObjectIdentifier, 0, len(versions))
+	for _, version := range versions {
+		objectIds = append(objectIds, types.ObjectIdentifier{
+			Key:       aws.String(version.Key),
+			VersionId: aws.String(version.VersionId),
+		})
 	}
+
+	output, err := api.S3.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{Objects: objectIds, Quiet: true},
+	})
+	if err != nil {
+		log.Error().Msgf("S3 DeleteObjectVersions Error for bucket %v: %v", bucket, err.Error())
+		return 0, err
+	}
+	return len(objectIds) - len(output.Errors), deleteObjectsError(bucket, output.Errors)
+}
+
+// deleteObjectsError reports the objects which DeleteObjects failed to delete, as the request itself succeeds.
+func deleteObjectsError(bucket string, deleteErrors []types.Error) error {
+	if len(deleteErrors) == 0 {
+		return nil
+	}
+
+	first := deleteErrors[0]
+	err := fmt.Errorf("failed to delete %d objects of bucket %s, first %s: %s %s", len(deleteErrors), bucket,
+		aws.ToString(first.Key), aws.ToString(first.Code), aws.ToString(first.Message))
+	log.Error().Msgf("S3 DeleteObjects Error: %v", err)
 	return err
 }
 
@@ -225,6 +306,56 @@ func (api *s3Api) ListObjects(bucket, prefix string) ([]types.Object, error) {
 	return contents, err
 }
 
+// ListObjectVersions returns a page of up to 1000 versions and delete markers of the objects with the prefix, ordered
+// by the keys and from the latest version of each key, along with the marker of the next page, which is nil after
+// the last page.
+func (api *s3Api) ListObjectVersions(bucket, prefix string, marker *model.VersionMarker) ([]model.ObjectVersion,
+	*model.VersionMarker, error) {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if marker != nil {
+		input.KeyMarker = aws.String(marker.KeyMarker)
+		input.VersionIdMarker = aws.String(marker.VersionIdMarker)
+	}
+
+	result, err := api.S3.ListObjectVersions(context.TODO(), input)
+	if err != nil {
+		log.Error().Msgf("S3 ListObjectVersions Error for bucket %v: %v", bucket, err.Error())
+		return nil, nil, err
+	}
+
+	versions := make([]model.ObjectVersion, 0, len(result.Versions)+len(result.DeleteMarkers))
+	for _, version := range result.Versions {
+		versions = append(versions, model.ObjectVersion{
+			Key:          aws.ToString(version.Key),
+			VersionId:    aws.ToString(version.VersionId),
+			IsLatest:     version.IsLatest,
+			LastModified: aws.ToTime(version.LastModified),
+			Size:         version.Size,
+		})
+	}
+
+	for _, deleteMarker := range result.DeleteMarkers {
+		versions = append(versions, model.ObjectVersion{
+			Key:          aws.ToString(deleteMarker.Key),
+			VersionId:    aws.ToString(deleteMarker.VersionId),
+			IsLatest:     deleteMarker.IsLatest,
+			DeleteMarker: true,
+			LastModified: aws.ToTime(deleteMarker.LastModified),
+		})
+	}
+
+	if !result.IsTruncated {
+		return versions, nil, nil
+	}
+	return versions, &model.VersionMarker{
+		KeyMarker:       aws.ToString(result.NextKeyMarker),
+		VersionIdMarker: aws.ToString(result.NextVersionIdMarker),
+	}, nil
+}
+
 func (api *s3Api) DeleteBucket(bucket string) error {
 	bucketInput := &s3.DeleteBucketInput{
 		Bucket: aws.String(bucket),
@@ -9,9 +9,11 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/golang/mock/gomock"
 
 	"github.com/pranav-patil/go-serverless-api/pkg/s3/mocks"
+	"github.com/pranav-patil/go-serverless-api/pkg/s3/model"
 	"github.com/pranav-patil/go-serverless-api/pkg/util"
 	"github.com/stretchr/testify/suite"
 )
@@ -106,3 +108,57 @@ func (s *S3ClientTestSuite) TestPutObject() {
 
 	s.NoError(err)
 }
+
+func (s *S3ClientTestSuite) TestListObjectVersions() {
+	mockS3Client := mocks.NewMockAWSS3Client(s.ctrl)
+
+	ctx := context.TODO()
+	mockS3Client.EXPECT().ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+		Bucket:          aws.String(s3BucketName),
+		Prefix:          aws.String("Bookmarks/1/"),
+		KeyMarker:       aws.String("Bookmarks/1/1.0.1"),
+		VersionIdMarker: aws.String("v1"),
+	}).Return(&s3.ListObjectVersionsOutput{
+		Versions: []types.ObjectVersion{
+			{Key: aws.String("Bookmarks/1/1.0.2"), VersionId: aws.String("v3"), IsLatest: false, Size: 10},
+		},
+		DeleteMarkers: []types.DeleteMarkerEntry{
+			{Key: aws.String("Bookmarks/1/1.0.2"), VersionId: aws.String("v4"), IsLatest: true},
+		},
+		IsTruncated:         true,
+		NextKeyMarker:       aws.String("Bookmarks/1/1.0.2"),
+		NextVersionIdMarker: aws.String("v3"),
+	}, nil)
+
+	api := s3Api{S3: mockS3Client}
+	versions, next, err := api.ListObjectVersions(s3BucketName, "Bookmarks/1/",
+		&model.VersionMarker{KeyMarker: "Bookmarks/1/1.0.1", VersionIdMarker: "v1"})
+
+	s.NoError(err)
+	s.Equal([]model.ObjectVersion{
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v3", Size: 10},
+		{Key: "Bookmarks/1/1.0.2", VersionId: "v4", IsLatest: true, DeleteMarker: true},
+	}, versions)
+	s.Equal(&model.VersionMarker{KeyMarker: "Bookmarks/1/1.0.2", VersionIdMarker: "v3"}, next)
+}
+
+func (s *S3ClientTestSuite) TestDeleteObjectVersionsWithErrors() {
+	mockS3Client := mocks.NewMockAWSS3Client(s.ctrl)
+
+	mockS3Client.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, input *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput,
+			error) {
+			s.Len(input.Delete.Objects, 2)
+			s.Equal("v2", aws.ToString(input.Delete.Objects[1].VersionId))
+			return &s3.DeleteObjectsOutput{Errors: []types.Error{
+				{Key: aws.String(anyS3Key), Code: aws.String("AccessDenied"), Message: aws.String("Access Denied")},
+			}}, nil
+		})
+
+	api := s3Api{S3: mockS3Client}
+	count, err := api.DeleteObjectVersions(s3BucketName,
+		[]model.ObjectVersion{{Key: anyS3Key, VersionId: "v1"}, {Key: anyS3Key, VersionId: "v2"}})
+
+	s.Equal(1, count)
+	s.ErrorContains(err, "AccessDenied")
+}
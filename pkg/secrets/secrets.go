@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/rs/zerolog/log"
+)
+
+// GetSecretString reads the secret string from secrets manager, it is replaced by the tests.
+var GetSecretString = getSecretsManagerString
+
+// Loader loads a value, such as a key, from the secret of SecretID in secrets manager, or from the file of the
+// FileEnv environment variable in the local and test environments. The value is loaded once and reused by the
+// later invocations of the lambda.
+type Loader[T any] struct {
+	SecretID string
+	FileEnv  string
+	// ParseSecret parses the value from the secret string.
+	ParseSecret func(secretString string) (T, error)
+	// ParseFile parses the value from the file content, which is parsed as the secret string when nil.
+	ParseFile func(content []byte) (T, error)
+
+	mutex  sync.Mutex
+	loaded bool
+	value  T
+}
+
+// Load returns the loaded value, reading it on the first call or after a failed read.
+func (l *Loader[T]) Load() (T, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.loaded {
+		return l.value, nil
+	}
+
+	value, err := l.read()
+	if err != nil {
+		return value, err
+	}
+
+	l.value, l.loaded = value, true
+	return value, nil
+}
+
+// Reset drops the loaded value, so that the next load reads the secret again.
+func (l *Loader[T]) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var zero T
+	l.value, l.loaded = zero, false
+}
+
+func (l *Loader[T]) read() (T, error) {
+	var zero T
+
+	if env.IsLocalOrTestEnv() {
+		keyFile := os.Getenv(l.FileEnv)
+		if keyFile == "" {
+			return zero, fmt.Errorf("environment variable %s is not set", l.FileEnv)
+		}
+
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return zero, err
+		}
+
+		if l.ParseFile != nil {
+			return l.ParseFile(content)
+		}
+		return l.ParseSecret(string(content))
+	}
+
+	secretString, err := GetSecretString(l.SecretID)
+	if err != nil {
+		log.Error().Msgf("error in reading secret %s: %v", l.SecretID, err)
+		return zero, err
+	}
+	return l.ParseSecret(secretString)
+}
+
+func getSecretsManagerString(secretID string) (string, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return "", err
+	}
+
+	secretClient := secretsmanager.NewFromConfig(cfg)
+	result, err := secretClient.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.SecretString), nil
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretsTestSuite struct {
+	suite.Suite
+
+	loader *Loader[string]
+}
+
+func TestSecretsSuite(t *testing.T) {
+	suite.Run(t, new(SecretsTestSuite))
+}
+
+func (s *SecretsTestSuite) SetupTest() {
+	s.loader = &Loader[string]{
+		SecretID: "bookmarks/config/key.json",
+		FileEnv:  "KEY_FILE",
+		ParseSecret: func(secretString string) (string, error) {
+			return "secret:" + secretString, nil
+		},
+	}
+
+	getSecretString := GetSecretString
+	s.T().Cleanup(func() {
+		GetSecretString = getSecretString
+	})
+}
+
+func (s *SecretsTestSuite) TestLoadFromFile() {
+	keyFile := filepath.Join(s.T().TempDir(), "key.json")
+	s.NoError(os.WriteFile(keyFile, []byte("key"), 0o600))
+	s.T().Setenv("STAGE", "testing")
+	s.T().Setenv("KEY_FILE", keyFile)
+
+	value, err := s.loader.Load()
+	s.NoError(err)
+	s.Equal("secret:key", value)
+
+	// The file content is parsed apart from the secret string when required.
+	s.loader.Reset()
+	s.loader.ParseFile = func(content []byte) (string, error) {
+		return "file:" + string(content), nil
+	}
+
+	value, err = s.loader.Load()
+	s.NoError(err)
+	s.Equal("file:key", value)
+
+	// The value is loaded once per lambda container.
+	s.NoError(os.Remove(keyFile))
+	value, err = s.loader.Load()
+	s.NoError(err)
+	s.Equal("file:key", value)
+}
+
+func (s *SecretsTestSuite) TestLoadWithoutFile() {
+	s.T().Setenv("STAGE", "testing")
+	s.T().Setenv("KEY_FILE", "")
+
+	_, err := s.loader.Load()
+	s.ErrorContains(err, "KEY_FILE")
+}
+
+func (s *SecretsTestSuite) TestLoadFromSecret() {
+	s.T().Setenv("STAGE", "production")
+
+	var secretIDs []string
+	GetSecretString = func(id string) (string, error) {
+		secretIDs = append(secretIDs, id)
+		return "key", nil
+	}
+
+	value, err := s.loader.Load()
+	s.NoError(err)
+	s.Equal("secret:key", value)
+
+	_, err = s.loader.Load()
+	s.NoError(err)
+	s.Equal([]string{"bookmarks/config/key.json"}, secretIDs)
+}
+
+func (s *SecretsTestSuite) TestLoadWhenSecretFails() {
+	s.T().Setenv("STAGE", "production")
+	GetSecretString = func(string) (string, error) {
+		return "", errors.New("AccessDeniedException")
+	}
+
+	_, err := s.loader.Load()
+	s.ErrorContains(err, "AccessDeniedException")
+
+	// The failures are not cached.
+	GetSecretString = func(string) (string, error) {
+		return "key", nil
+	}
+	s.loader.ParseSecret = func(secretString string) (string, error) {
+		return strings.ToUpper(secretString), nil
+	}
+
+	value, err := s.loader.Load()
+	s.NoError(err)
+	s.Equal("KEY", value)
+}
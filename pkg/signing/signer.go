@@ -0,0 +1,139 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/secrets"
+)
+
+const (
+	Algorithm = "Ed25519"
+
+	SigningKeySecretID = "bookmarks/config/package-signing-key.json"
+	SigningKeyFileEnv  = "PACKAGE_SIGNING_KEY_FILE"
+)
+
+var ErrInvalidSigningKey = errors.New("invalid Ed25519 signing key")
+
+// Signer signs the package manifests, which the devices verify with the published public key.
+type Signer interface {
+	Sign(message []byte) []byte
+	PublicKey() ed25519.PublicKey
+	KeyId() string
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+	keyId      string
+}
+
+type signingKeySecret struct {
+	PrivateKey string `json:"private_key"`
+}
+
+var packageSigner = &secrets.Loader[Signer]{
+	SecretID:    SigningKeySecretID,
+	FileEnv:     SigningKeyFileEnv,
+	ParseSecret: parseSigningKeySecret,
+	// The local key file holds the PEM of the private key.
+	ParseFile: func(keyPEM []byte) (Signer, error) {
+		privateKey, err := ParsePrivateKeyPEM(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519Signer(privateKey), nil
+	},
+}
+
+func NewEd25519Signer(privateKey ed25519.PrivateKey) Signer {
+	return &ed25519Signer{privateKey: privateKey, keyId: GetKeyId(privateKey.Public().(ed25519.PublicKey))}
+}
+
+// NewPackageSigner returns the signer with the package signing key, which is read from secrets manager,
+// or from the file of the PACKAGE_SIGNING_KEY_FILE environment variable in the local and test environments.
+func NewPackageSigner() (Signer, error) {
+	return packageSigner.Load()
+}
+
+func (s *ed25519Signer) Sign(message []byte) []byte {
+	return ed25519.Sign(s.privateKey, message)
+}
+
+func (s *ed25519Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+func (s *ed25519Signer) KeyId() string {
+	return s.keyId
+}
+
+// GetKeyId returns the id of the public key, which lets the devices pick the key across key rotations.
+func GetKeyId(publicKey ed25519.PublicKey) string {
+	digest := sha256.Sum256(publicKey)
+	return hex.EncodeToString(digest[:8])
+}
+
+func Verify(publicKey ed25519.PublicKey, message, signature []byte) bool {
+	return len(publicKey) == ed25519.PublicKeySize && ed25519.Verify(publicKey, message, signature)
+}
+
+// ParsePrivateKeyPEM parses the Ed25519 private key from a PKCS #8 "PRIVATE KEY" PEM block.
+func ParsePrivateKeyPEM(keyPEM []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%w: no PRIVATE KEY PEM block", ErrInvalidSigningKey)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSigningKey, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key type %T", ErrInvalidSigningKey, key)
+	}
+	return privateKey, nil
+}
+
+func MarshalPrivateKeyPEM(privateKey ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func MarshalPublicKeyPEM(publicKey ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// parseSigningKeySecret parses the signer from the secret, a json object with the PEM of the private key.
+func parseSigningKeySecret(secretString string) (Signer, error) {
+	var secret signingKeySecret
+	err := json.Unmarshal([]byte(secretString), &secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSigningKey, err)
+	}
+
+	if secret.PrivateKey == "" {
+		return nil, fmt.Errorf("%w: private key is empty", ErrInvalidSigningKey)
+	}
+
+	privateKey, err := ParsePrivateKeyPEM([]byte(secret.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	return NewEd25519Signer(privateKey), nil
+}
@@ -0,0 +1,126 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pranav-patil/go-serverless-api/pkg/secrets"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignerTestSuite struct {
+	suite.Suite
+
+	privateKey ed25519.PrivateKey
+	keyPEM     []byte
+}
+
+func TestSignerSuite(t *testing.T) {
+	suite.Run(t, new(SignerTestSuite))
+}
+
+func (s *SignerTestSuite) SetupTest() {
+	var err error
+	_, s.privateKey, err = ed25519.GenerateKey(rand.Reader)
+	s.NoError(err)
+
+	s.keyPEM, err = MarshalPrivateKeyPEM(s.privateKey)
+	s.NoError(err)
+
+	packageSigner.Reset()
+	getSecretString := secrets.GetSecretString
+	s.T().Cleanup(func() {
+		secrets.GetSecretString = getSecretString
+	})
+}
+
+func (s *SignerTestSuite) TestSignAndVerify() {
+	signer := NewEd25519Signer(s.privateKey)
+	message := []byte(`{"version":"1.0.89"}`)
+
+	signature := signer.Sign(message)
+
+	s.True(Verify(signer.PublicKey(), message, signature))
+	s.False(Verify(signer.PublicKey(), []byte(`{"version":"1.0.90"}`), signature))
+	s.Len(signer.KeyId(), 16)
+}
+
+func (s *SignerTestSuite) TestParsePrivateKeyPEM() {
+	privateKey, err := ParsePrivateKeyPEM(s.keyPEM)
+
+	s.NoError(err)
+	s.Equal(s.privateKey, privateKey)
+
+	publicKeyPEM, err := MarshalPublicKeyPEM(privateKey.Public().(ed25519.PublicKey))
+	s.NoError(err)
+
+	_, err = ParsePrivateKeyPEM(publicKeyPEM)
+	s.ErrorIs(err, ErrInvalidSigningKey)
+}
+
+func (s *SignerTestSuite) TestNewPackageSignerFromFile() {
+	keyFile := filepath.Join(s.T().TempDir(), "signing-key.pem")
+	s.NoError(os.WriteFile(keyFile, s.keyPEM, 0o600))
+	s.T().Setenv("STAGE", "testing")
+	s.T().Setenv(SigningKeyFileEnv, keyFile)
+
+	signer, err := NewPackageSigner()
+
+	s.NoError(err)
+	s.Equal(s.privateKey.Public(), signer.PublicKey())
+
+	// The key is read once per lambda container.
+	s.NoError(os.Remove(keyFile))
+	cached, err := NewPackageSigner()
+	s.NoError(err)
+	s.Same(signer, cached)
+}
+
+func (s *SignerTestSuite) TestNewPackageSignerWithoutKeyFile() {
+	s.T().Setenv("STAGE", "testing")
+	s.T().Setenv(SigningKeyFileEnv, "")
+
+	_, err := NewPackageSigner()
+
+	s.Error(err)
+}
+
+func (s *SignerTestSuite) TestNewPackageSignerFromSecret() {
+	s.T().Setenv("STAGE", "production")
+	secret, err := json.Marshal(signingKeySecret{PrivateKey: string(s.keyPEM)})
+	s.NoError(err)
+
+	var secretID string
+	secrets.GetSecretString = func(id string) (string, error) {
+		secretID = id
+		return string(secret), nil
+	}
+
+	signer, err := NewPackageSigner()
+
+	s.NoError(err)
+	s.Equal(SigningKeySecretID, secretID)
+	s.Equal(s.privateKey.Public(), signer.PublicKey())
+}
+
+func (s *SignerTestSuite) TestNewPackageSignerWhenSecretFails() {
+	s.T().Setenv("STAGE", "production")
+	secrets.GetSecretString = func(string) (string, error) {
+		return "", errors.New("AccessDeniedException")
+	}
+
+	_, err := NewPackageSigner()
+	s.Error(err)
+
+	secrets.GetSecretString = func(string) (string, error) {
+		return `{"private_key": ""}`, nil
+	}
+
+	_, err = NewPackageSigner()
+	s.ErrorIs(err, ErrInvalidSigningKey)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -40,6 +41,7 @@ func NewSNSClient() (SNSClient, error) {
 		return snsAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	snsClient := sns.NewFromConfig(cfg)
 	snsAPI.SNS = snsClient
 	return snsAPI, nil
@@ -0,0 +1,312 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pranav-patil/go-serverless-api/pkg/sizedwaitgroup"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultTypeAttribute   = "messageType"
+	DefaultMaxReceiveCount = 5
+
+	receiveCountAttribute = "ApproximateReceiveCount"
+	maxReceiveBatchSize   = 10
+	maxWaitTimeSeconds    = 20
+	pollRetryDelay        = 5 * time.Second
+)
+
+// ErrPoisonMessage marks the message which fails on every delivery, such as a malformed message.
+// The handlers wrap it in their error to move the message to the dead letter queue without retrying it.
+var ErrPoisonMessage = errors.New("poison message")
+
+// Message is a queue message received either from the Lambda SQS event or by polling the queue.
+type Message struct {
+	MessageId     string
+	ReceiptHandle string
+	Body          string
+	Type          string
+	ReceiveCount  int
+	Attributes    map[string]string // String message attributes
+}
+
+type MessageHandler func(ctx context.Context, message *Message) error
+
+// BatchItemFailure identifies the failed message of a batch, which SQS delivers again.
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// BatchResponse is the partial batch response of a Lambda SQS trigger with the ReportBatchItemFailures
+// function response type, so that only the failed messages of the batch are retried.
+type BatchResponse struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+type ConsumerConfig struct {
+	QueueURL string
+	// DeadLetterQueueURL receives the poison messages, which are otherwise left to the redrive policy of the queue.
+	DeadLetterQueueURL string
+	// Concurrency is the number of messages of a batch handled at a time, one by default.
+	Concurrency int
+	// VisibilityTimeout of the queue, which is extended at every half of it while a message is handled.
+	// Zero leaves the visibility timeout of the queue as is.
+	VisibilityTimeout time.Duration
+	// MaxReceiveCount is the number of deliveries after which a failed message is moved to the dead letter queue.
+	MaxReceiveCount int
+	// TypeAttribute names the message attribute with the message type, which is otherwise read from the
+	// "type" field of the JSON body.
+	TypeAttribute string
+}
+
+// Consumer dispatches the queue messages to the handlers registered for their message types, the same way
+// for a Lambda SQS trigger and for a long polling worker.
+type Consumer struct {
+	client         SQSClient
+	config         ConsumerConfig
+	handlers       map[string]MessageHandler
+	defaultHandler MessageHandler
+}
+
+func NewConsumer(client SQSClient, config ConsumerConfig) *Consumer {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.MaxReceiveCount <= 0 {
+		config.MaxReceiveCount = DefaultMaxReceiveCount
+	}
+	if config.TypeAttribute == "" {
+		config.TypeAttribute = DefaultTypeAttribute
+	}
+
+	return &Consumer{
+		client:   client,
+		config:   config,
+		handlers: map[string]MessageHandler{},
+	}
+}
+
+func (c *Consumer) Handle(messageType string, handler MessageHandler) {
+	c.handlers[messageType] = handler
+}
+
+// HandleDefault registers the handler of the messages without a handler for their type.
+func (c *Consumer) HandleDefault(handler MessageHandler) {
+	c.defaultHandler = handler
+}
+
+// HandleLambdaEvent handles the messages of the Lambda SQS event and reports the failed messages,
+// while SQS deletes the remaining messages of the batch.
+func (c *Consumer) HandleLambdaEvent(ctx context.Context, event events.SQSEvent) (BatchResponse, error) {
+	messages := make([]*Message, 0, len(event.Records))
+
+	for i := range event.Records {
+		record := &event.Records[i]
+		message := &Message{
+			MessageId:     record.MessageId,
+			ReceiptHandle: record.ReceiptHandle,
+			Body:          record.Body,
+			Attributes:    map[string]string{},
+		}
+		message.ReceiveCount, _ = strconv.Atoi(record.Attributes[receiveCountAttribute])
+
+		for name, attribute := range record.MessageAttributes {
+			if attribute.StringValue != nil {
+				message.Attributes[name] = *attribute.StringValue
+			}
+		}
+		messages = append(messages, message)
+	}
+
+	response := BatchResponse{BatchItemFailures: []BatchItemFailure{}}
+	for _, message := range c.process(ctx, messages) {
+		response.BatchItemFailures = append(response.BatchItemFailures,
+			BatchItemFailure{ItemIdentifier: message.MessageId})
+	}
+	return response, nil
+}
+
+// Poll long polls the queue and handles the received messages until the context is done. The handled messages
+// are deleted, while the failed messages are received again once their visibility timeout expires.
+func (c *Consumer) Poll(ctx context.Context) error {
+	for ctx.Err() == nil {
+		received, err := c.client.ReceiveMessagesWithWait(c.config.QueueURL, maxReceiveBatchSize, maxWaitTimeSeconds)
+		if err != nil {
+			log.Error().Msgf("Failure in receiving messages from %s: %v", c.config.QueueURL, err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+
+		messages := make([]*Message, 0, len(received))
+		for i := range received {
+			messages = append(messages, toMessage(&received[i]))
+		}
+
+		failed := map[string]bool{}
+		for _, message := range c.process(ctx, messages) {
+			failed[message.MessageId] = true
+		}
+
+		for _, message := range messages {
+			if failed[message.MessageId] {
+				continue
+			}
+
+			if err = c.client.DeleteMessage(c.config.QueueURL, message.ReceiptHandle); err != nil {
+				log.Error().Msgf("Failure in deleting message %s: %v", message.MessageId, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func toMessage(received *types.Message) *Message {
+	message := &Message{Attributes: map[string]string{}}
+
+	if received.MessageId != nil {
+		message.MessageId = *received.MessageId
+	}
+	if received.ReceiptHandle != nil {
+		message.ReceiptHandle = *received.ReceiptHandle
+	}
+	if received.Body != nil {
+		message.Body = *received.Body
+	}
+	message.ReceiveCount, _ = strconv.Atoi(received.Attributes[receiveCountAttribute])
+
+	for name, attribute := range received.MessageAttributes {
+		if attribute.StringValue != nil {
+			message.Attributes[name] = *attribute.StringValue
+		}
+	}
+	return message
+}
+
+// process handles the messages and returns the failed messages which are to be delivered again.
+// The poison messages are moved to the dead letter queue instead, when there is one.
+func (c *Consumer) process(ctx context.Context, messages []*Message) []*Message {
+	var failed []*Message
+	var mutex sync.Mutex
+
+	swg := sizedwaitgroup.New(c.config.Concurrency)
+	for _, message := range messages {
+		swg.Add()
+
+		go func(message *Message) {
+			defer swg.Done()
+
+			err := c.handle(ctx, message)
+			if err == nil {
+				return
+			}
+
+			log.Error().Msgf("Failure in handling %s message %s on receive %d: %v", message.Type,
+				message.MessageId, message.ReceiveCount, err)
+
+			if errors.Is(err, ErrPoisonMessage) || message.ReceiveCount >= c.config.MaxReceiveCount {
+				if c.moveToDeadLetterQueue(message) {
+					return
+				}
+			}
+
+			mutex.Lock()
+			failed = append(failed, message)
+			mutex.Unlock()
+		}(message)
+	}
+	swg.Wait()
+
+	return failed
+}
+
+func (c *Consumer) handle(ctx context.Context, message *Message) error {
+	message.Type = c.getMessageType(message)
+
+	handler, ok := c.handlers[message.Type]
+	if !ok {
+		handler = c.defaultHandler
+	}
+	if handler == nil {
+		return fmt.Errorf("%w: no handler for message type %q", ErrPoisonMessage, message.Type)
+	}
+
+	if c.config.VisibilityTimeout > 0 && message.ReceiptHandle != "" {
+		done := make(chan struct{})
+		defer close(done)
+		go c.extendVisibility(message, done)
+	}
+
+	return handler(ctx, message)
+}
+
+func (c *Consumer) getMessageType(message *Message) string {
+	if messageType, ok := message.Attributes[c.config.TypeAttribute]; ok {
+		return messageType
+	}
+
+	body := struct {
+		Type string `json:"type"`
+	}{}
+	_ = json.Unmarshal([]byte(message.Body), &body)
+	return body.Type
+}
+
+// extendVisibility keeps the message hidden from the other consumers until done, at every half of the
+// visibility timeout.
+func (c *Consumer) extendVisibility(message *Message, done <-chan struct{}) {
+	ticker := time.NewTicker(c.config.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := c.client.ChangeMessageVisibility(c.config.QueueURL, message.ReceiptHandle,
+				int32(c.config.VisibilityTimeout.Seconds()))
+			if err != nil {
+				log.Error().Msgf("Failure in extending visibility of message %s: %v", message.MessageId, err)
+			}
+		}
+	}
+}
+
+// moveToDeadLetterQueue sends the message with its attributes to the dead letter queue, and returns whether
+// the message was moved.
+func (c *Consumer) moveToDeadLetterQueue(message *Message) bool {
+	if c.config.DeadLetterQueueURL == "" {
+		return false
+	}
+
+	attributes := map[string]string{}
+	for name, value := range message.Attributes {
+		attributes[name] = value
+	}
+	if message.Type != "" {
+		attributes[c.config.TypeAttribute] = message.Type
+	}
+
+	_, err := c.client.SendMessageWithAttributes(c.config.DeadLetterQueueURL, message.Body, attributes)
+	if err != nil {
+		log.Error().Msgf("Failure in moving message %s to dead letter queue: %v", message.MessageId, err)
+		return false
+	}
+
+	log.Warn().Msgf("Moved %s message %s to dead letter queue after %d receives", message.Type,
+		message.MessageId, message.ReceiveCount)
+	return true
+}
@@ -0,0 +1,141 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pranav-patil/go-serverless-api/pkg/sqs/mocks"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsumerTestSuite struct {
+	suite.Suite
+
+	ctrl          *gomock.Controller
+	mockSQSClient *mocks.MockSQSClient
+}
+
+func TestConsumerSuite(t *testing.T) {
+	suite.Run(t, new(ConsumerTestSuite))
+}
+
+func (s *ConsumerTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *ConsumerTestSuite) SetupTest() {
+	s.mockSQSClient = mocks.NewMockSQSClient(s.ctrl)
+}
+
+func (s *ConsumerTestSuite) TestHandleLambdaEventDispatchesByType() {
+	consumer := NewConsumer(s.mockSQSClient, ConsumerConfig{QueueURL: "queue", Concurrency: 2})
+
+	var handled []string
+	consumer.Handle("BookmarkAdded", func(ctx context.Context, message *Message) error {
+		handled = append(handled, "added:"+message.MessageId)
+		return nil
+	})
+	consumer.Handle("BookmarksReplaced", func(ctx context.Context, message *Message) error {
+		return errors.New("throttled")
+	})
+
+	messageType := "BookmarkAdded"
+	response, err := consumer.HandleLambdaEvent(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: `{}`, Attributes: map[string]string{"ApproximateReceiveCount": "1"},
+			MessageAttributes: map[string]events.SQSMessageAttribute{"messageType": {StringValue: &messageType}}},
+		{MessageId: "m2", Body: `{"type":"BookmarksReplaced"}`,
+			Attributes: map[string]string{"ApproximateReceiveCount": "1"}},
+	}})
+
+	s.NoError(err)
+	s.Equal([]string{"added:m1"}, handled)
+	s.Equal(BatchResponse{BatchItemFailures: []BatchItemFailure{{ItemIdentifier: "m2"}}}, response)
+}
+
+func (s *ConsumerTestSuite) TestHandleLambdaEventMovesPoisonMessages() {
+	consumer := NewConsumer(s.mockSQSClient, ConsumerConfig{QueueURL: "queue", DeadLetterQueueURL: "dlq",
+		MaxReceiveCount: 3})
+	consumer.HandleDefault(func(ctx context.Context, message *Message) error {
+		if message.Body == "invalid" {
+			return fmt.Errorf("%w: invalid body", ErrPoisonMessage)
+		}
+		return errors.New("throttled")
+	})
+
+	s.mockSQSClient.EXPECT().SendMessageWithAttributes(gomock.Eq("dlq"), gomock.Eq("invalid"),
+		gomock.Eq(map[string]string{})).Return(nil, nil)
+	s.mockSQSClient.EXPECT().SendMessageWithAttributes(gomock.Eq("dlq"), gomock.Eq(`{"type":"Retried"}`),
+		gomock.Eq(map[string]string{"messageType": "Retried"})).Return(nil, nil)
+
+	response, err := consumer.HandleLambdaEvent(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: "invalid", Attributes: map[string]string{"ApproximateReceiveCount": "1"}},
+		{MessageId: "m2", Body: `{"type":"Retried"}`, Attributes: map[string]string{"ApproximateReceiveCount": "3"}},
+		{MessageId: "m3", Body: `{"type":"Retried"}`, Attributes: map[string]string{"ApproximateReceiveCount": "2"}},
+	}})
+
+	s.NoError(err)
+	s.Equal(BatchResponse{BatchItemFailures: []BatchItemFailure{{ItemIdentifier: "m3"}}}, response)
+}
+
+func (s *ConsumerTestSuite) TestHandleLambdaEventWithoutHandler() {
+	consumer := NewConsumer(s.mockSQSClient, ConsumerConfig{QueueURL: "queue"})
+
+	response, err := consumer.HandleLambdaEvent(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: `{"type":"Unknown"}`},
+	}})
+
+	// The unhandled message is left to the redrive policy without a dead letter queue.
+	s.NoError(err)
+	s.Equal(BatchResponse{BatchItemFailures: []BatchItemFailure{{ItemIdentifier: "m1"}}}, response)
+}
+
+func (s *ConsumerTestSuite) TestHandleExtendsVisibility() {
+	consumer := NewConsumer(s.mockSQSClient, ConsumerConfig{QueueURL: "queue", VisibilityTimeout: 2 * time.Second})
+	consumer.HandleDefault(func(ctx context.Context, message *Message) error {
+		time.Sleep(1500 * time.Millisecond)
+		return nil
+	})
+
+	s.mockSQSClient.EXPECT().ChangeMessageVisibility(gomock.Eq("queue"), gomock.Eq("r1"), gomock.Eq(int32(2))).
+		Return(nil)
+
+	response, err := consumer.HandleLambdaEvent(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", ReceiptHandle: "r1", Body: `{}`},
+	}})
+
+	s.NoError(err)
+	s.Empty(response.BatchItemFailures)
+}
+
+func (s *ConsumerTestSuite) TestPoll() {
+	consumer := NewConsumer(s.mockSQSClient, ConsumerConfig{QueueURL: "queue"})
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	consumer.HandleDefault(func(ctx context.Context, message *Message) error {
+		cancel()
+		if message.MessageId == "m2" {
+			return errors.New("throttled")
+		}
+		return nil
+	})
+
+	s.mockSQSClient.EXPECT().ReceiveMessagesWithWait(gomock.Eq("queue"), gomock.Eq(10), gomock.Eq(int32(20))).
+		Return([]types.Message{
+			{MessageId: aws.String("m1"), ReceiptHandle: aws.String("r1"), Body: aws.String(`{}`),
+				Attributes: map[string]string{"ApproximateReceiveCount": "1"}},
+			{MessageId: aws.String("m2"), ReceiptHandle: aws.String("r2"), Body: aws.String(`{}`),
+				Attributes: map[string]string{"ApproximateReceiveCount": "1"}},
+		}, nil)
+
+	// Only the handled message is deleted, the failed message is received again.
+	s.mockSQSClient.EXPECT().DeleteMessage(gomock.Eq("queue"), gomock.Eq("r1")).Return(nil)
+
+	s.NoError(consumer.Poll(ctx))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/pkg/errors"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -18,10 +19,16 @@ type sqsAPI struct {
 	SQS *sqs.Client
 }
 
+//go:generate mockgen -destination mocks/sqs_client_mock.go -package mocks . SQSClient
+
 type SQSClient interface {
 	SendMessage(queueName, message string) (*sqs.SendMessageOutput, error)
+	SendDelayedMessage(queueURL, message string, delaySeconds int32) (*sqs.SendMessageOutput, error)
+	SendMessageWithAttributes(queueURL, message string, attrs map[string]string) (*sqs.SendMessageOutput, error)
 	SendBatchMessages(queueURL string, messages []string) error
 	ReceiveMessages(queueURL string, maxRecvNum int) ([]types.Message, error)
+	ReceiveMessagesWithWait(queueURL string, maxRecvNum int, waitSeconds int32) ([]types.Message, error)
+	ChangeMessageVisibility(queueURL, receiptHandle string, visibilityTimeout int32) error
 	DeleteMessage(queueURL, receiptHandle string) error
 }
 
@@ -42,6 +49,7 @@ func NewSQSClient() (SQSClient, error) {
 		return sqsAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	sqsClient := sqs.NewFromConfig(cfg)
 	sqsAPI.SQS = sqsClient
 	return sqsAPI, nil
@@ -57,6 +65,39 @@ func (api *sqsAPI) SendMessage(queueURL, message string) (*sqs.SendMessageOutput
 	)
 }
 
+// SendDelayedMessage sends the message which becomes visible to the consumers after the delay of up to 15 minutes.
+func (api *sqsAPI) SendDelayedMessage(queueURL, message string, delaySeconds int32) (*sqs.SendMessageOutput, error) {
+	return api.SQS.SendMessage(
+		context.TODO(),
+		&sqs.SendMessageInput{
+			MessageBody:  &message,
+			QueueUrl:     aws.String(queueURL),
+			DelaySeconds: delaySeconds,
+		},
+	)
+}
+
+func (api *sqsAPI) SendMessageWithAttributes(queueURL, message string,
+	attrs map[string]string) (*sqs.SendMessageOutput, error) {
+	attributes := map[string]types.MessageAttributeValue{}
+
+	for key, value := range attrs {
+		attributes[key] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	return api.SQS.SendMessage(
+		context.TODO(),
+		&sqs.SendMessageInput{
+			MessageBody:       &message,
+			QueueUrl:          aws.String(queueURL),
+			MessageAttributes: attributes,
+		},
+	)
+}
+
 func (api *sqsAPI) SendBatchMessages(queueURL string, messages []string) error {
 	var messageEntries []types.SendMessageBatchRequestEntry
 
@@ -78,12 +119,21 @@ func (api *sqsAPI) SendBatchMessages(queueURL string, messages []string) error {
 }
 
 func (api *sqsAPI) ReceiveMessages(queueURL string, maxRecvNum int) ([]types.Message, error) {
+	return api.ReceiveMessagesWithWait(queueURL, maxRecvNum, 0)
+}
+
+// ReceiveMessagesWithWait long polls the queue for up to 20 seconds until a message is available.
+// The messages are received with their attributes, including the approximate receive count.
+func (api *sqsAPI) ReceiveMessagesWithWait(queueURL string, maxRecvNum int,
+	waitSeconds int32) ([]types.Message, error) {
 	output, err := api.SQS.ReceiveMessage(
 		context.TODO(),
 		&sqs.ReceiveMessageInput{
 			QueueUrl:              aws.String(queueURL),
 			MaxNumberOfMessages:   int32(maxRecvNum),
 			MessageAttributeNames: []string{"All"},
+			AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+			WaitTimeSeconds:       waitSeconds,
 		},
 	)
 
@@ -94,6 +144,17 @@ func (api *sqsAPI) ReceiveMessages(queueURL string, maxRecvNum int) ([]types.Mes
 	return output.Messages, nil
 }
 
+// ChangeMessageVisibility hides the received message from the other consumers for the timeout in seconds
+// from now, which extends the visibility timeout of a message still being processed.
+func (api *sqsAPI) ChangeMessageVisibility(queueURL, receiptHandle string, visibilityTimeout int32) error {
+	_, err := api.SQS.ChangeMessageVisibility(context.TODO(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: visibilityTimeout,
+	})
+	return err
+}
+
 func (api *sqsAPI) DeleteMessage(queueURL, receiptHandle string) error {
 	_, err := api.SQS.DeleteMessage(context.TODO(), &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(queueURL),
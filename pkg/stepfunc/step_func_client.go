@@ -3,11 +3,13 @@ package stepfunc
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sfn"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -18,6 +20,7 @@ type StepFuncClient interface {
 	StartExecution(stateMachineArn, executionName string, initialState interface{}) error
 	SendTaskSuccess(taskToken, message string) error
 	SendTaskFailure(taskToken, errMessage, errCause string) error
+	StopExecution(executionArn, errMessage, errCause string) error
 }
 
 type sfnAPI struct {
@@ -41,6 +44,7 @@ func NewStepFunctionClient() (StepFuncClient, error) {
 		return stepFuncAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	stepFuncAPI.Sfn = sfn.NewFromConfig(cfg)
 	return stepFuncAPI, nil
 }
@@ -90,3 +94,22 @@ func (api *sfnAPI) SendTaskFailure(taskToken, errMessage, errCause string) error
 
 	return err
 }
+
+func (api *sfnAPI) StopExecution(executionArn, errMessage, errCause string) error {
+	stopExecutionIn := &sfn.StopExecutionInput{
+		ExecutionArn: aws.String(executionArn),
+		Error:        aws.String(errMessage),
+		Cause:        aws.String(errCause),
+	}
+	_, err := api.Sfn.StopExecution(context.TODO(), stopExecutionIn)
+	if err != nil {
+		log.Error().Msgf("StopExecution Error: %v", err.Error())
+	}
+
+	return err
+}
+
+// GetExecutionArn returns the arn of the named execution of the state machine.
+func GetExecutionArn(stateMachineArn, executionName string) string {
+	return strings.Replace(stateMachineArn, ":stateMachine:", ":execution:", 1) + ":" + executionName
+}
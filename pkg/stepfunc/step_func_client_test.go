@@ -109,3 +109,26 @@ func (s *StepFuncClientTestSuite) TestSendTaskFailure() {
 
 	s.NoError(err)
 }
+
+func (s *StepFuncClientTestSuite) TestStopExecution() {
+	executionArn := "arn:aws:states:us-east-1:123456789012:execution:distribution:test_execu_name"
+	errMessage := "Cancelled"
+	errCause := "test_error_cause"
+	stopExecutionIn := &sfn.StopExecutionInput{
+		ExecutionArn: &executionArn,
+		Error:        &errMessage,
+		Cause:        &errCause,
+	}
+
+	ctx := context.TODO()
+	s.mockStepFnClient.EXPECT().StopExecution(ctx, stopExecutionIn).Return(&sfn.StopExecutionOutput{}, nil).Times(1)
+
+	err := s.api.StopExecution(executionArn, errMessage, errCause)
+
+	s.NoError(err)
+}
+
+func (s *StepFuncClientTestSuite) TestGetExecutionArn() {
+	s.Equal("arn:aws:states:us-east-1:123456789012:execution:distribution:test_execu_name",
+		GetExecutionArn("arn:aws:states:us-east-1:123456789012:stateMachine:distribution", "test_execu_name"))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/pranav-patil/go-serverless-api/pkg/env"
+	"github.com/pranav-patil/go-serverless-api/pkg/metrics"
 	"github.com/pranav-patil/go-serverless-api/pkg/mockutil"
 	"github.com/rs/zerolog/log"
 )
@@ -36,6 +37,7 @@ func NewSTSClient() (STSClient, error) {
 		return stsAPI, err
 	}
 
+	metrics.InstrumentAWSConfig(&cfg)
 	stsClient := sts.NewFromConfig(cfg)
 	stsAPI.STS = stsClient
 	return stsAPI, nil
@@ -34,7 +34,7 @@ func StructToMap(in interface{}, tagName string, ignoreDefaultValue bool,
 		}
 
 		// boolean value with false are ignored as they can be defaults
-		if ignoreDefaultValue && reflect.Zero(field.Type).Interface() == v.Field(i).Interface() {
+		if ignoreDefaultValue && v.Field(i).IsZero() {
 			continue
 		}
 
@@ -167,6 +167,21 @@ func (s *ConverterUtilTestSuite) TestStructToMapWithTags() {
 	}
 }
 
+func (s *ConverterUtilTestSuite) TestStructToMapWithMapField() {
+	type Device struct {
+		DeviceId  string            `dynamodbav:"deviceId"`
+		Instances map[string]string `dynamodbav:"instances,omitempty"`
+	}
+
+	deviceMap, err := StructToMap(&Device{DeviceId: "1"}, "dynamodbav", true)
+	s.Nil(err)
+	s.EqualValues(map[string]interface{}{"deviceId": "1"}, deviceMap)
+
+	deviceMap, err = StructToMap(&Device{DeviceId: "1", Instances: map[string]string{"a": "b"}}, "dynamodbav", true)
+	s.Nil(err)
+	s.EqualValues(map[string]interface{}{"deviceId": "1", "instances": map[string]string{"a": "b"}}, deviceMap)
+}
+
 func (s *ConverterUtilTestSuite) TestGetStructField() {
 	testCases := []struct {
 		testName       string
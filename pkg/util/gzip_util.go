@@ -9,6 +9,7 @@ import (
 	"crypto/sha1" //nolint:gosec // even though sha1 is insecure we still want to use it for data integrity of bookmark pkge
 	"encoding/hex"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -63,11 +64,13 @@ func ByteCompress(payload []byte) ([]byte, error) {
 	return b.Bytes(), err
 }
 
+// CreateZipFile archives the files in the order of their names, hence the same files make the same archive.
 func CreateZipFile(files map[string]string) (res []byte, err error) {
 	buffer := new(bytes.Buffer)
 	writer := zip.NewWriter(buffer)
 
-	for filename, content := range files {
+	for _, filename := range sortedFileNames(files) {
+		content := files[filename]
 		var f io.Writer
 		f, err = writer.Create(filename)
 		if err != nil {
@@ -86,19 +89,49 @@ func CreateZipFile(files map[string]string) (res []byte, err error) {
 	return buffer.Bytes(), nil
 }
 
+// ReadZipFile returns the content of the files in the zip archive by their names.
+func ReadZipFile(content []byte) (map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(reader.File))
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[file.Name] = string(data)
+	}
+	return files, nil
+}
+
+// CreateTarFile archives the files in the order of their names with a fixed modification time, hence the same
+// files make the same archive.
 func CreateTarFile(files map[string]string) (res []byte, err error) {
 	buffer := new(bytes.Buffer)
 	tarWriter := tar.NewWriter(buffer)
 
-	for filename, content := range files {
-		data := []byte(content)
+	for _, filename := range sortedFileNames(files) {
+		data := []byte(files[filename])
 
 		header := &tar.Header{
 			Name:     filename,
 			Size:     int64(len(data)),
 			Typeflag: tar.TypeReg,
 			Mode:     0o755,
-			ModTime:  time.Now(),
+			ModTime:  time.Unix(0, 0),
 		}
 
 		err = tarWriter.WriteHeader(header)
@@ -119,6 +152,40 @@ func CreateTarFile(files map[string]string) (res []byte, err error) {
 	return buffer.Bytes(), nil
 }
 
+func sortedFileNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadTarFile returns the content of the regular files in the tar archive by their names.
+func ReadTarFile(content []byte) (map[string]string, error) {
+	files := make(map[string]string)
+	tarReader := tar.NewReader(bytes.NewReader(content))
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = string(data)
+	}
+}
+
 func SHA1Checksum(data []byte) string {
 	hasher := sha1.New() //nolint:gosec // even though sha1 is insecure we still want to use it for data integrity of bookmark pkge
 	hasher.Write(data)
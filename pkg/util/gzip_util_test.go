@@ -56,6 +56,20 @@ func (s *GZipUtilTestSuite) TestCreateZipFile() {
 	s.NotNil(buf)
 }
 
+func (s *GZipUtilTestSuite) TestReadZipFile() {
+	files := map[string]string{"foo.txt": "version:1.4.6.8", "bar.pkg": "4,5,6,7,2,5,54,75"}
+
+	buf, err := CreateZipFile(files)
+	s.Nil(err)
+
+	result, err := ReadZipFile(buf)
+	s.Nil(err)
+	s.Equal(files, result)
+
+	_, err = ReadZipFile([]byte("not a zip archive"))
+	s.Error(err)
+}
+
 func (s *GZipUtilTestSuite) TestCreateTarFile() {
 	files := map[string]string{"foo.txt": "version:1.4.6.8", "bar.pkg": "4,5,6,7,2,5,54,75"}
 
@@ -63,3 +77,36 @@ func (s *GZipUtilTestSuite) TestCreateTarFile() {
 	s.Nil(err)
 	s.NotNil(buf)
 }
+
+func (s *GZipUtilTestSuite) TestReadTarFile() {
+	files := map[string]string{"foo.txt": "version:1.4.6.8", "bar.pkg": "4,5,6,7,2,5,54,75", "empty.pkg": ""}
+
+	buf, err := CreateTarFile(files)
+	s.Nil(err)
+
+	result, err := ReadTarFile(buf)
+	s.Nil(err)
+	s.Equal(files, result)
+}
+
+func (s *GZipUtilTestSuite) TestReadTarFileWithInvalidContent() {
+	_, err := ReadTarFile([]byte("not a tar archive, but long enough to hold a truncated tar header"))
+	s.Error(err)
+}
+
+func (s *GZipUtilTestSuite) TestArchivesAreReproducible() {
+	files := map[string]string{"foo.txt": "version:1.4.6.8", "bar.pkg": "4,5,6,7,2,5,54,75", "baz.pkg": "1"}
+
+	for name, create := range map[string]func(map[string]string) ([]byte, error){
+		"tar": CreateTarFile,
+		"zip": CreateZipFile,
+	} {
+		s.Run(name, func() {
+			first, err := create(files)
+			s.Nil(err)
+			second, err := create(files)
+			s.Nil(err)
+			s.Equal(SHA1Checksum(first), SHA1Checksum(second))
+		})
+	}
+}
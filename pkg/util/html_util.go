@@ -0,0 +1,197 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	MaxInlineResourceBytes = 32 * 1024
+	MaxInlineStyleBytes    = 64 * 1024
+)
+
+// Elements which are dropped along with all of their content.
+var strippedElements = map[string]bool{
+	"script":   true,
+	"noscript": true,
+	"iframe":   true,
+	"frame":    true,
+	"frameset": true,
+	"object":   true,
+	"applet":   true,
+	"template": true,
+}
+
+// Void elements which either execute, load external content or redirect the page.
+var strippedVoidElements = map[string]bool{
+	"embed": true,
+	"base":  true,
+	"link":  true,
+}
+
+// Schemes of the urls kept in the url attributes, besides the relative urls. The data urls are kept only for images.
+var allowedURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+	"data":   true,
+}
+
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"poster":     true,
+	"background": true,
+	"xlink:href": true,
+}
+
+// SanitizeHTML strips scripts, event handlers, embedded frames/objects and the URLs of other than the allowed
+// schemes from the HTML document, and drops inline data resources or styles exceeding the size limits.
+func SanitizeHTML(content []byte) ([]byte, error) {
+	var output bytes.Buffer
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	skipDepth := 0
+	skipStyle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, err
+			}
+			return output.Bytes(), nil
+		}
+
+		token := tokenizer.Token()
+		tagName := strings.ToLower(token.Data)
+
+		switch tokenType {
+		case html.StartTagToken:
+			if strippedElements[tagName] {
+				skipDepth++
+				continue
+			}
+			if skipDepth > 0 || strippedVoidElements[tagName] || isMetaRefresh(&token) {
+				continue
+			}
+			if tagName == "style" {
+				skipStyle = true
+			}
+			token.Attr = sanitizeAttributes(token.Attr)
+		case html.EndTagToken:
+			if skipDepth > 0 {
+				if strippedElements[tagName] {
+					skipDepth--
+				}
+				continue
+			}
+			if strippedVoidElements[tagName] {
+				continue
+			}
+			if tagName == "style" {
+				skipStyle = false
+			}
+		case html.SelfClosingTagToken:
+			if skipDepth > 0 || strippedElements[tagName] || strippedVoidElements[tagName] || isMetaRefresh(&token) {
+				continue
+			}
+			token.Attr = sanitizeAttributes(token.Attr)
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			if skipStyle {
+				// Styles are raw text, hence written as is instead of escaping the token.
+				if len(token.Data) <= MaxInlineStyleBytes {
+					output.WriteString(token.Data)
+				}
+				continue
+			}
+		case html.CommentToken, html.DoctypeToken:
+			if skipDepth > 0 || tokenType == html.CommentToken {
+				continue
+			}
+		}
+
+		output.WriteString(token.String())
+	}
+}
+
+func sanitizeAttributes(attrs []html.Attribute) []html.Attribute {
+	sanitized := make([]html.Attribute, 0, len(attrs))
+
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+
+		if strings.HasPrefix(key, "on") || key == "srcdoc" {
+			continue
+		}
+		if key == "style" && len(attr.Val) > MaxInlineStyleBytes {
+			continue
+		}
+		if urlAttributes[key] || key == "srcset" {
+			if !isAllowedURL(attr.Val) {
+				continue
+			}
+		}
+		sanitized = append(sanitized, attr)
+	}
+
+	return sanitized
+}
+
+// isAllowedURL reports whether the url is relative or of an allowed scheme, and within the size limit when it
+// is a data url. The ASCII whitespace and control characters are ignored, as the browsers strip them from the
+// urls, which would otherwise let "java\tscript:" through.
+func isAllowedURL(value string) bool {
+	url := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value))
+
+	scheme := urlScheme(url)
+	switch {
+	case scheme == "":
+		return true
+	case scheme == "data":
+		return strings.HasPrefix(url, "data:image/") && len(value) <= MaxInlineResourceBytes
+	default:
+		return allowedURLSchemes[scheme]
+	}
+}
+
+// urlScheme returns the scheme of the url, or "" when the url is relative.
+func urlScheme(url string) string {
+	for i, r := range url {
+		switch {
+		case r == ':':
+			return url[:i]
+		case r >= 'a' && r <= 'z', i > 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+			continue
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isMetaRefresh(token *html.Token) bool {
+	if !strings.EqualFold(token.Data, "meta") {
+		return false
+	}
+
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, "http-equiv") && strings.EqualFold(strings.TrimSpace(attr.Val), "refresh") {
+			return true
+		}
+	}
+	return false
+}
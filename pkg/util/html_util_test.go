@@ -0,0 +1,70 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HTMLUtilTestSuite struct {
+	suite.Suite
+}
+
+func TestHTMLUtilSuite(t *testing.T) {
+	suite.Run(t, new(HTMLUtilTestSuite))
+}
+
+func (s *HTMLUtilTestSuite) TestSanitizeHTML() {
+	testCases := []struct {
+		testName     string
+		inputHTML    string
+		expectedHTML string
+	}{
+		{"Scripts and noscript removed",
+			`<html><head><script>alert("x")</script></head><body><p>Text</p><noscript><img src="a.png"></noscript></body></html>`,
+			`<html><head></head><body><p>Text</p></body></html>`},
+		{"Event handlers and javascript urls removed",
+			`<a href="javascript:alert(1)" onclick="steal()">Link</a><img src="b.png" onerror="x()">`,
+			`<a>Link</a><img src="b.png">`},
+		{"Javascript urls with embedded whitespace and control characters removed",
+			"<a href=\"java\tscript:alert(1)\">A</a><a href=\" java\nscript:alert(1)\">B</a>" +
+				"<a href=\"java\x01script:alert(1)\">C</a><a href=\"JaVaScRiPt&#9;:alert(1)\">D</a>",
+			`<a>A</a><a>B</a><a>C</a><a>D</a>`},
+		{"Urls of other schemes than allowed removed",
+			`<a href="vbscript:x">A</a><a href="data:text/html,<script>x()</script>">B</a><a href="file:///etc">C</a>`,
+			`<a>A</a><a>B</a><a>C</a>`},
+		{"Relative, http, mailto and data image urls kept",
+			`<a href="/docs?a=b:c">A</a><a href="https://example.com">B</a><a href="mailto:a@example.com">C</a><img src="data:image/png;base64,AAAA">`,
+			`<a href="/docs?a=b:c">A</a><a href="https://example.com">B</a><a href="mailto:a@example.com">C</a><img src="data:image/png;base64,AAAA">`},
+		{"Frames, objects and embeds removed",
+			`<div><iframe src="https://ads.example.com"></iframe><object data="x.swf"><param name="a" value="b"></object><embed src="y.swf"></div>`,
+			`<div></div>`},
+		{"Base, link, meta refresh and comments removed",
+			`<head><base href="https://evil.com/"><link rel="stylesheet" href="s.css"><meta http-equiv="refresh" content="0;url=https://evil.com"><meta charset="utf-8"><!-- tracking --></head>`,
+			`<head><meta charset="utf-8"></head>`},
+		{"Inline styles kept unescaped",
+			`<style>div > p { color: red; }</style><p style="color: blue">Styled</p>`,
+			`<style>div > p { color: red; }</style><p style="color: blue">Styled</p>`},
+	}
+
+	for _, testCase := range testCases {
+		s.Run(testCase.testName, func() {
+			result, err := SanitizeHTML([]byte(testCase.inputHTML))
+			s.NoError(err)
+			s.Equal(testCase.expectedHTML, string(result))
+		})
+	}
+}
+
+func (s *HTMLUtilTestSuite) TestSanitizeHTMLLimitsInlineResources() {
+	largeImage := "data:image/png;base64," + strings.Repeat("A", MaxInlineResourceBytes)
+	smallImage := "data:image/png;base64,AAAA"
+	largeStyle := strings.Repeat("p{}", MaxInlineStyleBytes)
+
+	input := `<img src="` + largeImage + `"><img src="` + smallImage + `"><style>` + largeStyle + `</style>`
+	result, err := SanitizeHTML([]byte(input))
+
+	s.NoError(err)
+	s.Equal(`<img><img src="`+smallImage+`"><style></style>`, string(result))
+}
@@ -70,3 +70,49 @@ func ConvertToIP4(ipInt uint32) string {
 	b3 := strconv.FormatUint(uint64(ipInt&mask), 10)
 	return b0 + "." + b1 + "." + b2 + "." + b3
 }
+
+// Ranges which are not covered by the netip.Addr classification methods, but must never be reached
+// by outbound requests: shared address space, benchmarking, documentation, reserved and NAT64.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublicIP reports whether the address is globally routable, rejecting private, loopback,
+// link-local (including the 169.254.169.254 and fd00:ec2::254 metadata endpoints), multicast,
+// unspecified and reserved ranges. IPv4-mapped IPv6 addresses are checked as IPv4.
+func IsPublicIP(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAddrPort parses the host:port address returned by a resolver or dialer into an IP address.
+func ParseAddrPort(address string) (netip.Addr, error) {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%s is not a valid IP address and port: %w", address, err)
+	}
+	return addrPort.Addr(), nil
+}
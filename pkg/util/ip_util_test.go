@@ -1,6 +1,7 @@
 package util
 
 import (
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -209,3 +210,44 @@ func (s *IPUtilTestSuite) TestConvertToIP4() {
 		})
 	}
 }
+
+func (s *IPUtilTestSuite) TestIsPublicIP() {
+	testCases := []struct {
+		testName string
+		inputIP  string
+		expected bool
+	}{
+		{"Public IPv4", "216.104.20.24", true},
+		{"Public IPv6", "2606:4700:4700::1111", true},
+		{"Loopback", "127.0.0.1", false},
+		{"Private Class A", "10.20.30.40", false},
+		{"Private Class B", "172.16.0.1", false},
+		{"Private Class C", "192.168.1.200", false},
+		{"Shared Address Space", "100.64.0.1", false},
+		{"Unspecified", "0.0.0.0", false},
+		{"AWS Metadata", "169.254.169.254", false},
+		{"AWS Metadata IPv6", "fd00:ec2::254", false},
+		{"Multicast", "224.0.0.1", false},
+		{"Broadcast", "255.255.255.255", false},
+		{"Documentation", "198.51.100.7", false},
+		{"IPv6 Loopback", "::1", false},
+		{"IPv6 Link Local", "fe80::1", false},
+		{"IPv4 Mapped Loopback", "::ffff:127.0.0.1", false},
+		{"NAT64 Private", "64:ff9b::a00:1", false},
+	}
+
+	for _, testCase := range testCases {
+		s.Run(testCase.testName, func() {
+			s.Equal(testCase.expected, IsPublicIP(netip.MustParseAddr(testCase.inputIP)))
+		})
+	}
+}
+
+func (s *IPUtilTestSuite) TestParseAddrPort() {
+	addr, err := ParseAddrPort("[fd00:ec2::254]:80")
+	s.NoError(err)
+	s.Equal("fd00:ec2::254", addr.String())
+
+	_, err = ParseAddrPort("localhost:80")
+	s.Error(err)
+}